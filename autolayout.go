@@ -0,0 +1,99 @@
+package meme
+
+import (
+	"cmp"
+	"image"
+	"slices"
+)
+
+// Сколько раз авторазметка пытается развести одну подпись с остальными
+const maxAutoLayoutSteps = 20
+
+// layoutSlots вписывает подписи в слоты. Если resolve, подписи, которые
+// перекрывают друг друга, разводятся: сначала размещаются более важные
+// (Priority), каждую следующую пробуем сдвинуть вверх или вниз за
+// пересекающуюся подпись в пределах холста, а если места нет - уменьшаем
+// шрифт до MinFontSize. Если не помогло и это, остаётся перекрытие.
+func (g *Generator) layoutSlots(canvas image.Rectangle, slots []TextSlot, texts []string, resolve bool) (_ []*slotText, err error) {
+	fits := make([]*slotText, len(slots))
+	defer func() {
+		if err != nil {
+			for _, f := range fits {
+				if f != nil {
+					f.face.Close()
+				}
+			}
+		}
+	}()
+	for i, s := range slots {
+		if fits[i], err = g.fitSlotText(s, texts[i], s.FontSize); err != nil {
+			return nil, err
+		}
+	}
+	if !resolve {
+		return fits, nil
+	}
+
+	order := make([]int, len(slots))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(slots[b].Priority, slots[a].Priority)
+	})
+
+	var placed []image.Rectangle
+	for _, i := range order {
+		for range maxAutoLayoutSteps {
+			if !overlapsAny(fits[i].box, placed) {
+				break
+			}
+			gap := max(fits[i].face.Metrics().Height.Ceil()/4, 2)
+			if box, ok := shiftClear(fits[i].box, placed, canvas, gap); ok {
+				fits[i].box = box
+				break
+			}
+			if fits[i].size*0.9 < fits[i].minSize {
+				break
+			}
+			smaller, err := g.fitSlotText(slots[i], texts[i], fits[i].size*0.9)
+			if err != nil {
+				return nil, err
+			}
+			fits[i].face.Close()
+			fits[i] = smaller
+		}
+		placed = append(placed, fits[i].box)
+	}
+	return fits, nil
+}
+
+// overlapsAny проверяет пересечение r с любым из прямоугольников
+func overlapsAny(r image.Rectangle, rects []image.Rectangle) bool {
+	for _, p := range rects {
+		if r.Overlaps(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// shiftClear ищет ближайший вертикальный сдвиг r, при котором он не
+// пересекает placed и остаётся на холсте: вплотную над или под одним
+// из уже размещённых прямоугольников с промежутком gap
+func shiftClear(r image.Rectangle, placed []image.Rectangle, canvas image.Rectangle, gap int) (image.Rectangle, bool) {
+	best, found := image.Rectangle{}, false
+	bestShift := 0
+	for _, p := range placed {
+		for _, dy := range []int{p.Max.Y + gap - r.Min.Y, p.Min.Y - gap - r.Max.Y} {
+			cand := r.Add(image.Pt(0, dy))
+			if !cand.In(canvas) || overlapsAny(cand, placed) {
+				continue
+			}
+			if !found || max(dy, -dy) < bestShift {
+				best, bestShift, found = cand, max(dy, -dy), true
+			}
+		}
+	}
+	return best, found
+}
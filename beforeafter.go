@@ -0,0 +1,113 @@
+package meme
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"time"
+)
+
+// SplitDirection - как делится холст сравнения
+type SplitDirection int
+
+const (
+	SplitVertical   SplitDirection = iota // "до" слева, "после" справа
+	SplitHorizontal                       // "до" сверху, "после" снизу
+)
+
+// BeforeAfterOptions задает оформление сравнения "до/после"
+type BeforeAfterOptions struct {
+	Direction SplitDirection
+
+	// Подписи половин; пустая строка - без подписи
+	BeforeLabel, AfterLabel string
+	LabelSize               float64 // максимальный размер подписей, 0 - 40
+
+	DividerWidth int         // толщина линии раздела, 0 - 6
+	DividerColor color.Color // nil - BorderColor генератора
+
+	// Frame - обернуть сравнение в демотиватор с подписями и рамкой генератора
+	Frame bool
+}
+
+// Значения BeforeAfterOptions по умолчанию
+const (
+	defaultBeforeAfterLabelSize = 40
+	defaultDividerWidth         = 6
+)
+
+// BeforeAfter составляет сравнение двух фото: after масштабируется с
+// обрезкой под размер before, половины разделяет линия, а подписи
+// рисуются поверх фото в верхней части каждой половины шрифтом и цветами
+// генератора с обводкой не тоньше 2px.
+func (g *Generator) BeforeAfter(before, after image.Image, opts BeforeAfterOptions) (out *image.RGBA, err error) {
+	defer g.observeGeneration(time.Now(), &err)
+	defer recoverPanic(&err)
+	cfg := g.config
+	for _, img := range []image.Image{before, after} {
+		if err := g.validateInput(img); err != nil {
+			return nil, err
+		}
+	}
+	if opts.DividerWidth < 0 || opts.LabelSize < 0 {
+		return nil, &ConfigError{Field: "BeforeAfterOptions", Reason: "sizes must not be negative"}
+	}
+	divider := opts.DividerWidth
+	if divider == 0 {
+		divider = defaultDividerWidth
+	}
+	labelSize := opts.LabelSize
+	if labelSize == 0 {
+		labelSize = defaultBeforeAfterLabelSize
+	}
+	dividerColor := opts.DividerColor
+	if dividerColor == nil {
+		dividerColor = cfg.BorderColor
+	}
+
+	half := image.Rect(0, 0, before.Bounds().Dx(), before.Bounds().Dy())
+	var first, second image.Rectangle
+	switch opts.Direction {
+	case SplitVertical:
+		first, second = half, half.Add(image.Pt(half.Dx()+divider, 0))
+	case SplitHorizontal:
+		first, second = half, half.Add(image.Pt(0, half.Dy()+divider))
+	default:
+		return nil, &ConfigError{Field: "BeforeAfterOptions.Direction", Reason: "unknown direction"}
+	}
+	canvas := first.Union(second)
+	if err := checkCanvas(canvas); err != nil {
+		return nil, err
+	}
+	out = image.NewRGBA(canvas)
+	draw.Draw(out, canvas, image.NewUniform(dividerColor), image.Point{}, draw.Src)
+	drawSource(out, first, before, before.Bounds().Min)
+	drawCover(out, second, after)
+
+	for _, p := range []struct {
+		label string
+		r     image.Rectangle
+	}{{opts.BeforeLabel, first}, {opts.AfterLabel, second}} {
+		if p.label == "" {
+			continue
+		}
+		r := p.r
+		r.Max.Y = min(r.Max.Y, r.Min.Y+int(labelSize*2))
+		slot := TextSlot{
+			Name:         "label",
+			Rect:         r,
+			FontSize:     labelSize,
+			Color:        cfg.TextColor,
+			OutlineColor: cfg.TextOutlineColor,
+			OutlineWidth: max(cfg.TextOutlineWidth, 2),
+			Uppercase:    cfg.TextUppercase,
+		}
+		if err := g.drawSlotText(out, slot, p.label); err != nil {
+			return nil, err
+		}
+	}
+	if !opts.Frame {
+		return out, nil
+	}
+	return g.generateInto(nil, out)
+}
@@ -0,0 +1,41 @@
+package meme
+
+import "image"
+
+// blurRGBA размывает изображение на месте тремя проходами бокс-фильтра
+// радиуса radius по горизонтали и вертикали (приближение гауссова размытия).
+// Пиксели за краем считаются равными крайним.
+func blurRGBA(img *image.RGBA, radius int) {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	if radius <= 0 || w == 0 || h == 0 {
+		return
+	}
+	tmp := make([]uint8, len(img.Pix))
+	for range 3 {
+		for y := range h {
+			boxBlurLine(tmp, img.Pix, y*img.Stride, 4, w, radius)
+		}
+		for x := range w {
+			boxBlurLine(img.Pix, tmp, 4*x, img.Stride, h, radius)
+		}
+	}
+}
+
+// boxBlurLine усредняет n пикселей линии, начинающейся с off, с шагом step
+// по окну 2*radius+1 и пишет результат в те же позиции dst
+func boxBlurLine(dst, src []uint8, off, step, n, radius int) {
+	window := 2*radius + 1
+	at := func(i, c int) int {
+		return int(src[off+min(max(i, 0), n-1)*step+c])
+	}
+	for c := range 4 {
+		sum := 0
+		for i := -radius; i <= radius; i++ {
+			sum += at(i, c)
+		}
+		for i := range n {
+			dst[off+i*step+c] = uint8((sum + window/2) / window)
+			sum += at(i+radius+1, c) - at(i-radius, c)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Package botmeme содержит тонкие адаптеры для чат-ботов: слеш-команда
+// Discord или Slack превращается в вызов генератора, а результат - во
+// вложение ответа. Ошибки пользователя (неверный шрифт, слишком большое
+// фото) возвращаются скрытыми сообщениями, видимыми только автору команды,
+// и переводятся через meme.LocalizeError.
+//
+//	http.Handle("/discord", botmeme.NewDiscord(publicKey, botmeme.Options{}))
+//	http.Handle("/slack", botmeme.NewSlack(signingSecret, upload, botmeme.Options{}))
+//
+// Адаптеры не зависят от SDK мессенджеров: они разбирают только нужные
+// поля запросов, а загрузку файлов в Slack выполняет переданная функция.
+package botmeme
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/go-goblin/meme"
+)
+
+// DefaultMaxBytes - предел размера исходного фото по умолчанию
+const DefaultMaxBytes = 8 << 20
+
+// Options - общие настройки адаптеров
+type Options struct {
+	// Config - базовая конфигурация; подписи берутся из команды.
+	// nil - meme.DefaultConfig().
+	Config *meme.Config
+
+	// Setup вызывается для генератора каждой команды
+	Setup func(*meme.Generator)
+
+	MaxBytes int64       // предел размера фото, 0 - 8 МиБ
+	Format   meme.Format // формат вложения, пусто - PNG
+
+	// Fetch загружает фото по адресу вложения. nil - meme.FetchImage с
+	// пределом MaxBytes. Вызывающий закрывает результат.
+	Fetch func(ctx context.Context, url string) (io.ReadCloser, error)
+
+	// Lang - язык сообщений об ошибках, если мессенджер не сообщил язык
+	// пользователя; пусто - английский текст ошибки
+	Lang string
+}
+
+// bot - общая часть адаптеров
+type bot struct {
+	opts Options
+}
+
+func newBot(opts Options) bot {
+	if opts.Config == nil {
+		opts.Config = meme.DefaultConfig()
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultMaxBytes
+	}
+	if opts.Format == "" {
+		opts.Format = meme.FormatPNG
+	}
+	if opts.Fetch == nil {
+		fo := &meme.FetchOptions{MaxBytes: opts.MaxBytes}
+		opts.Fetch = func(ctx context.Context, url string) (io.ReadCloser, error) {
+			return meme.FetchImage(ctx, url, fo)
+		}
+	}
+	return bot{opts: opts}
+}
+
+// generate загружает фото по url и создает мем с подписями
+func (b *bot) generate(ctx context.Context, url, top, bottom string) ([]byte, error) {
+	cfg := *b.opts.Config
+	cfg.TopText, cfg.BottomText = top, bottom
+	if cfg.MaxBytes == 0 || cfg.MaxBytes > b.opts.MaxBytes {
+		cfg.MaxBytes = b.opts.MaxBytes
+	}
+	// Подписи проверяем до загрузки фото, чтобы не качать его зря
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	body, err := b.opts.Fetch(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("fetching image: %w", err)
+	}
+	defer body.Close()
+
+	g := meme.NewGenerator(&cfg)
+	if b.opts.Setup != nil {
+		b.opts.Setup(g)
+	}
+	return g.GenerateBytes(body, &meme.EncodeOptions{Format: b.opts.Format})
+}
+
+// filename - имя файла вложения
+func (b *bot) filename() string {
+	return "meme." + string(b.opts.Format)
+}
+
+// message возвращает текст ошибки для пользователя на языке lang
+// ("ru", "en-US", ...), а без перевода - на языке из Options
+func (b *bot) message(err error, lang string) string {
+	if lang, _, _ = strings.Cut(lang, "-"); lang != "" {
+		if msg := meme.LocalizeError(err, lang); msg != err.Error() {
+			return msg
+		}
+	}
+	return meme.LocalizeError(err, b.opts.Lang)
+}
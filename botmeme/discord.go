@@ -0,0 +1,292 @@
+package botmeme
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"time"
+
+	"github.com/go-goblin/meme"
+)
+
+// Типы взаимодействий и ответов Discord, используемые адаптером
+const (
+	discordPing      = 1
+	discordCommand   = 2
+	discordPong      = 1
+	discordMessage   = 4  // CHANNEL_MESSAGE_WITH_SOURCE
+	discordDeferred  = 5  // DEFERRED_CHANNEL_MESSAGE_WITH_SOURCE
+	discordEphemeral = 64 // флаг сообщения, видимого только автору
+	discordMaxBody   = 1 << 20
+
+	// Адрес API для ответов через вебхук взаимодействия
+	discordAPI = "https://discord.com/api/v10"
+)
+
+// DiscordInteraction - поля взаимодействия Discord, нужные адаптеру.
+// Команда должна иметь опцию-вложение "image" (или строку "url") и
+// строковые опции "top" и "bottom".
+type DiscordInteraction struct {
+	Type   int    `json:"type"`
+	Locale string `json:"locale"`
+
+	// ApplicationID и Token адресуют вебхук для ответа после подтверждения
+	ApplicationID string `json:"application_id"`
+	Token         string `json:"token"`
+
+	Data struct {
+		Name     string          `json:"name"`
+		Options  []DiscordOption `json:"options"`
+		Resolved struct {
+			Attachments map[string]DiscordAttachment `json:"attachments"`
+		} `json:"resolved"`
+	} `json:"data"`
+}
+
+// DiscordOption - значение опции слеш-команды
+type DiscordOption struct {
+	Name  string          `json:"name"`
+	Type  int             `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// DiscordAttachment - вложение из resolved.attachments
+type DiscordAttachment struct {
+	ID          string `json:"id"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	URL         string `json:"url"`
+}
+
+// option возвращает строковое значение опции или id вложения
+func (in *DiscordInteraction) option(name string) string {
+	for _, o := range in.Data.Options {
+		if o.Name == name {
+			var s string
+			json.Unmarshal(o.Value, &s)
+			return s
+		}
+	}
+	return ""
+}
+
+// DiscordResponse - ответ на команду: сообщение и, при успехе, файл
+type DiscordResponse struct {
+	Content   string
+	Ephemeral bool
+	Filename  string
+	File      []byte
+}
+
+// Discord обрабатывает взаимодействия Discord, приходящие по HTTP
+// (Interactions Endpoint URL приложения). Discord ждет ответа не дольше
+// трех секунд: обработчик сразу откладывает ответ, а мем создает в фоне и
+// отправляет через вебхук взаимодействия; ошибки приходят автору скрытым
+// сообщением.
+type Discord struct {
+	bot
+	publicKey ed25519.PublicKey
+	client    *http.Client
+}
+
+var _ http.Handler = (*Discord)(nil)
+
+// NewDiscord создает обработчик; publicKey - открытый ключ приложения
+// из Developer Portal, им проверяется подпись каждого запроса
+func NewDiscord(publicKey ed25519.PublicKey, opts Options) *Discord {
+	return &Discord{bot: newBot(opts), publicKey: publicKey, client: http.DefaultClient}
+}
+
+// Handle выполняет команду. Ошибки пользователя возвращаются скрытым
+// сообщением, поэтому результат всегда можно отправить как есть.
+func (d *Discord) Handle(ctx context.Context, in *DiscordInteraction) *DiscordResponse {
+	url, bad := d.source(in)
+	if bad != nil {
+		return bad
+	}
+	data, err := d.generate(ctx, url, in.option("top"), in.option("bottom"))
+	if err != nil {
+		return &DiscordResponse{Content: d.message(err, in.Locale), Ephemeral: true}
+	}
+	return &DiscordResponse{Filename: d.filename(), File: data}
+}
+
+// source возвращает адрес фото команды или ответ с ошибкой пользователя
+func (d *Discord) source(in *DiscordInteraction) (string, *DiscordResponse) {
+	url := in.option("url")
+	if id := in.option("image"); id != "" {
+		att, ok := in.Data.Resolved.Attachments[id]
+		if !ok {
+			return "", &DiscordResponse{Content: "attachment not found", Ephemeral: true}
+		}
+		// Размер известен заранее: не качаем заведомо слишком большое фото
+		if att.Size > d.opts.MaxBytes {
+			err := fmt.Errorf("%w: %d bytes, limit is %d", meme.ErrInputTooLarge, att.Size, d.opts.MaxBytes)
+			return "", &DiscordResponse{Content: d.message(err, in.Locale), Ephemeral: true}
+		}
+		url = att.URL
+	}
+	if url == "" {
+		return "", &DiscordResponse{Content: "attach an image", Ephemeral: true}
+	}
+	return url, nil
+}
+
+// ServeHTTP проверяет подпись, отвечает на PING, откладывает ответ на
+// команду и выполняет ее в фоне
+func (d *Discord) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, discordMaxBody))
+	if err != nil {
+		http.Error(w, "reading body", http.StatusBadRequest)
+		return
+	}
+	if !d.verify(r.Header, body) {
+		// Discord периодически шлет запросы с неверной подписью и ждет 401
+		http.Error(w, "invalid request signature", http.StatusUnauthorized)
+		return
+	}
+	var in DiscordInteraction
+	if err := json.Unmarshal(body, &in); err != nil {
+		http.Error(w, "invalid interaction", http.StatusBadRequest)
+		return
+	}
+	switch in.Type {
+	case discordPing:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]int{"type": discordPong})
+	case discordCommand:
+		// Ошибки без загрузки фото показываем сразу, не откладывая ответ
+		url, bad := d.source(&in)
+		if bad != nil {
+			bad.Write(w)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]int{"type": discordDeferred})
+		go func() {
+			// Токен взаимодействия действует 15 минут
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+			defer cancel()
+			data, err := d.generate(ctx, url, in.option("top"), in.option("bottom"))
+			if err != nil {
+				// Отложенный ответ виден всем, поэтому ошибка уходит
+				// отдельным скрытым сообщением, а ответ удаляется
+				d.webhook(ctx, &in, http.MethodPost, "", &DiscordResponse{Content: d.message(err, in.Locale), Ephemeral: true})
+				d.webhook(ctx, &in, http.MethodDelete, "/messages/@original", nil)
+				return
+			}
+			d.webhook(ctx, &in, http.MethodPatch, "/messages/@original", &DiscordResponse{Filename: d.filename(), File: data})
+		}()
+	default:
+		http.Error(w, "unsupported interaction type", http.StatusBadRequest)
+	}
+}
+
+// verify проверяет подпись Ed25519 от timestamp+body
+func (d *Discord) verify(h http.Header, body []byte) bool {
+	sig, err := hex.DecodeString(h.Get("X-Signature-Ed25519"))
+	if err != nil || len(sig) != ed25519.SignatureSize || len(d.publicKey) != ed25519.PublicKeySize {
+		return false
+	}
+	msg := append([]byte(h.Get("X-Signature-Timestamp")), body...)
+	return ed25519.Verify(d.publicKey, msg, sig)
+}
+
+// webhook отправляет сообщение resp (nil - без тела) через вебхук
+// взаимодействия in: path "" - новое сообщение, "/messages/@original" -
+// отложенный ответ
+func (d *Discord) webhook(ctx context.Context, in *DiscordInteraction, method, path string, resp *DiscordResponse) {
+	if in.ApplicationID == "" || in.Token == "" {
+		return
+	}
+	var (
+		contentType string
+		body        []byte
+	)
+	if resp != nil {
+		var err error
+		if contentType, body, err = resp.encode(resp.message()); err != nil {
+			return
+		}
+	}
+	endpoint := discordAPI + "/webhooks/" + url.PathEscape(in.ApplicationID) + "/" + url.PathEscape(in.Token) + path
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if r, err := d.client.Do(req); err == nil {
+		r.Body.Close()
+	}
+}
+
+// Write отправляет ответ на взаимодействие: JSON или, если есть файл,
+// multipart с payload_json и files[0]
+func (resp *DiscordResponse) Write(w http.ResponseWriter) error {
+	contentType, body, err := resp.encode(map[string]any{"type": discordMessage, "data": resp.message()})
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", contentType)
+	_, err = w.Write(body)
+	return err
+}
+
+// message возвращает поля сообщения Discord
+func (resp *DiscordResponse) message() map[string]any {
+	data := map[string]any{"content": resp.Content}
+	if resp.Ephemeral {
+		data["flags"] = discordEphemeral
+	}
+	if resp.File != nil {
+		data["attachments"] = []map[string]any{{"id": 0, "filename": resp.Filename}}
+	}
+	return data
+}
+
+// encode собирает тело запроса с JSON v: сам JSON или, если есть файл,
+// multipart с payload_json и files[0]
+func (resp *DiscordResponse) encode(v any) (contentType string, body []byte, err error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return "", nil, err
+	}
+	if resp.File == nil {
+		return "application/json", payload, nil
+	}
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Disposition", `form-data; name="payload_json"`)
+	h.Set("Content-Type", "application/json")
+	pw, err := mw.CreatePart(h)
+	if err != nil {
+		return "", nil, err
+	}
+	pw.Write(payload)
+	fw, err := mw.CreateFormFile("files[0]", resp.Filename)
+	if err != nil {
+		return "", nil, err
+	}
+	fw.Write(resp.File)
+	if err := mw.Close(); err != nil {
+		return "", nil, err
+	}
+	return mw.FormDataContentType(), buf.Bytes(), nil
+}
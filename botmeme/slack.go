@@ -0,0 +1,171 @@
+package botmeme
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Ограничения обработчика Slack
+const (
+	slackMaxBody = 64 << 10
+	slackMaxSkew = 5 * time.Minute // допустимое отклонение X-Slack-Request-Timestamp
+)
+
+// SlackCommand - поля слеш-команды Slack, нужные адаптеру
+type SlackCommand struct {
+	Command     string
+	Text        string
+	UserID      string
+	ChannelID   string
+	ResponseURL string
+}
+
+// SlackUploader отправляет готовый файл в канал, например через
+// files.uploadV2 из SDK Slack. Вызывается в отдельной горутине.
+type SlackUploader func(ctx context.Context, cmd *SlackCommand, filename string, data []byte) error
+
+// Slack обрабатывает слеш-команду вида
+//
+//	/meme https://example.com/cat.jpg верхний текст | нижний текст
+//
+// Слеш-команды Slack не передают файлы, поэтому фото задается ссылкой.
+// Slack ждет ответа не дольше трех секунд: обработчик сразу подтверждает
+// команду, а мем создает в фоне; ошибки приходят автору скрытым сообщением
+// через response_url.
+type Slack struct {
+	bot
+	secret []byte
+	upload SlackUploader
+	client *http.Client
+}
+
+var _ http.Handler = (*Slack)(nil)
+
+// NewSlack создает обработчик; signingSecret - Signing Secret приложения
+func NewSlack(signingSecret string, upload SlackUploader, opts Options) *Slack {
+	return &Slack{bot: newBot(opts), secret: []byte(signingSecret), upload: upload, client: http.DefaultClient}
+}
+
+// ParseSlackText разбирает текст команды: ссылка, затем подписи через "|".
+// Ссылки Slack присылает в виде <https://...> или <https://...|подпись>.
+func ParseSlackText(text string) (image, top, bottom string, err error) {
+	text = strings.TrimSpace(text)
+	link, rest, _ := strings.Cut(text, " ")
+	if strings.HasPrefix(link, "<") && strings.HasSuffix(link, ">") {
+		link, _, _ = strings.Cut(link[1:len(link)-1], "|")
+	}
+	if u, perr := url.Parse(link); perr != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", "", "", errors.New("usage: /meme <image url> top text | bottom text")
+	}
+	top, bottom, _ = strings.Cut(rest, "|")
+	return link, strings.TrimSpace(top), strings.TrimSpace(bottom), nil
+}
+
+// Handle создает мем и загружает его через SlackUploader. Возвращаемая
+// ошибка уже переведена для показа пользователю.
+func (s *Slack) Handle(ctx context.Context, cmd *SlackCommand) error {
+	image, top, bottom, err := ParseSlackText(cmd.Text)
+	if err != nil {
+		return err
+	}
+	data, err := s.generate(ctx, image, top, bottom)
+	if err != nil {
+		return errors.New(s.message(err, ""))
+	}
+	return s.upload(ctx, cmd, s.filename(), data)
+}
+
+// ServeHTTP проверяет подпись, подтверждает команду и выполняет ее в фоне
+func (s *Slack) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, slackMaxBody))
+	if err != nil {
+		http.Error(w, "reading body", http.StatusBadRequest)
+		return
+	}
+	if !s.verify(r.Header, body, time.Now()) {
+		http.Error(w, "invalid request signature", http.StatusUnauthorized)
+		return
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+	cmd := &SlackCommand{
+		Command:     form.Get("command"),
+		Text:        form.Get("text"),
+		UserID:      form.Get("user_id"),
+		ChannelID:   form.Get("channel_id"),
+		ResponseURL: form.Get("response_url"),
+	}
+	// Ошибку разбора показываем сразу, не дожидаясь фоновой работы
+	if _, _, _, err := ParseSlackText(cmd.Text); err != nil {
+		writeSlack(w, err.Error())
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+		if err := s.Handle(ctx, cmd); err != nil {
+			s.reply(ctx, cmd.ResponseURL, err.Error())
+		}
+	}()
+	w.WriteHeader(http.StatusOK)
+}
+
+// verify проверяет подпись v0=HMAC-SHA256("v0:timestamp:body") и свежесть запроса
+func (s *Slack) verify(h http.Header, body []byte, now time.Time) bool {
+	ts := h.Get("X-Slack-Request-Timestamp")
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || now.Sub(time.Unix(sec, 0)).Abs() > slackMaxSkew {
+		return false
+	}
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte("v0:" + ts + ":"))
+	mac.Write(body)
+	want := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(want), []byte(h.Get("X-Slack-Signature")))
+}
+
+// reply отправляет скрытое сообщение автору команды через response_url
+func (s *Slack) reply(ctx context.Context, responseURL, text string) {
+	// Ссылку отвечать разрешаем только на адреса Slack
+	if u, err := url.Parse(responseURL); err != nil || u.Scheme != "https" || !strings.HasSuffix(u.Hostname(), ".slack.com") {
+		return
+	}
+	payload, _ := json.Marshal(slackMessage{ResponseType: "ephemeral", Text: text})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, responseURL, bytes.NewReader(payload))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if resp, err := s.client.Do(req); err == nil {
+		resp.Body.Close()
+	}
+}
+
+// slackMessage - ответ на слеш-команду
+type slackMessage struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
+
+func writeSlack(w http.ResponseWriter, text string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(slackMessage{ResponseType: "ephemeral", Text: text})
+}
@@ -0,0 +1,116 @@
+package meme
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"math"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// ErrOutputTooLarge - результат не укладывается в EncodeOptions.MaxOutputBytes
+// даже при наименьших качестве и размере
+var ErrOutputTooLarge = errors.New("encoded image does not fit the size limit")
+
+// Пределы подбора под MaxOutputBytes
+const (
+	minBudgetJPEGQuality  = 30 // ниже JPEG заметно рассыпается на блоки
+	maxBudgetNearLossless = 5
+	minBudgetSide         = 64 // меньшую сторону меньше уже не уменьшаем
+)
+
+// encodeWithin кодирует img так, чтобы файл уложился в opts.MaxOutputBytes:
+// сначала снижает качество, затем уменьшает изображение и повторяет
+func encodeWithin(img image.Image, opts *EncodeOptions) ([]byte, error) {
+	limit := opts.MaxOutputBytes
+	o := *opts
+	o.MaxOutputBytes = 0
+	for {
+		data, err := encodeSmallest(img, &o, limit)
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(data)) <= limit {
+			return data, nil
+		}
+		// Размер файла примерно пропорционален площади: уменьшаем с запасом
+		b := img.Bounds()
+		scale := min(max(math.Sqrt(float64(limit)/float64(len(data)))*0.9, 0.25), 0.9)
+		w, h := int(float64(b.Dx())*scale), int(float64(b.Dy())*scale)
+		if min(w, h) < minBudgetSide {
+			return nil, fmt.Errorf("%w: %d bytes at %dx%d, limit is %d", ErrOutputTooLarge, len(data), b.Dx(), b.Dy(), limit)
+		}
+		small := image.NewRGBA(image.Rect(0, 0, w, h))
+		xdraw.CatmullRom.Scale(small, small.Bounds(), img, b, xdraw.Src, nil)
+		img = small
+	}
+}
+
+// encodeSmallest возвращает файл наилучшего качества не больше limit, а если
+// такого нет - наименьший из возможных без уменьшения
+func encodeSmallest(img image.Image, o *EncodeOptions, limit int64) ([]byte, error) {
+	fits := func(data []byte) bool { return int64(len(data)) <= limit }
+	switch o.Format {
+	case FormatJPEG:
+		top := o.Quality
+		if top <= 0 {
+			top = DefaultJPEGQuality
+		}
+		top = min(top, 100)
+		data, err := encodeQuality(img, o, top)
+		if err != nil || fits(data) || top <= minBudgetJPEGQuality {
+			return data, err
+		}
+		// Двоичный поиск наибольшего подходящего качества
+		lo, hi := minBudgetJPEGQuality, top-1
+		var best []byte
+		for lo <= hi {
+			mid := (lo + hi) / 2
+			if data, err = encodeQuality(img, o, mid); err != nil {
+				return nil, err
+			}
+			if fits(data) {
+				best, lo = data, mid+1
+			} else {
+				hi = mid - 1
+			}
+		}
+		if best != nil {
+			return best, nil
+		}
+		return encodeQuality(img, o, minBudgetJPEGQuality)
+	case FormatWebP:
+		var data []byte
+		for level := min(o.NearLossless, maxBudgetNearLossless); level <= maxBudgetNearLossless; level++ {
+			q := *o
+			q.NearLossless = level
+			var err error
+			if data, err = encodeCopy(img, &q); err != nil || fits(data) {
+				return data, err
+			}
+		}
+		return data, nil
+	default:
+		return encodeCopy(img, o)
+	}
+}
+
+// encodeQuality - encodeCopy с качеством JPEG quality
+func encodeQuality(img image.Image, o *EncodeOptions, quality int) ([]byte, error) {
+	q := *o
+	q.Quality = quality
+	return encodeCopy(img, &q)
+}
+
+// encodeCopy кодирует готовый файл в собственный срез
+func encodeCopy(img image.Image, o *EncodeOptions) ([]byte, error) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	data, err := encodeFile(buf, img, o)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.Clone(data), nil
+}
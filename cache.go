@@ -0,0 +1,207 @@
+package meme
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"io"
+	"sync"
+)
+
+// Cache - хранилище готовых закодированных результатов.
+// Реализация должна быть безопасна для конкурентного использования.
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte)
+}
+
+// SetCache подключает кеш результатов к GenerateBytes (nil - без кеша).
+// Ключ кеша не может учесть код хуков (AddHook, AddWidget) и своих
+// рендереров (SetTextRenderer, SetFrameRenderer), поэтому генератор с
+// ними кеш не использует: иначе генераторы с разными рендерерами и общим
+// хранилищем получали бы результаты друг друга.
+func (g *Generator) SetCache(c Cache) {
+	g.cache = c
+}
+
+// GenerateBytes читает изображение из r, создает демотиватор и кодирует его.
+// Если подключен кеш, результат ищется по хешу входных байт, конфигурации
+// и настроек кодирования, и повторный запрос не требует отрисовки.
+// В кеше хранится итоговый файл, то есть уже после Signer. Metadata входит
+// в ключ, поэтому для попаданий в кеш время создания должно быть фиксированным.
+func (g *Generator) GenerateBytes(r io.Reader, opts *EncodeOptions) ([]byte, error) {
+	return g.GenerateBytesContext(context.Background(), r, opts)
+}
+
+// GenerateBytesContext - GenerateBytes с отменой через ctx: после отмены
+// r больше не читается, а генерация прерывается до кодирования
+func (g *Generator) GenerateBytesContext(ctx context.Context, r io.Reader, opts *EncodeOptions) ([]byte, error) {
+	data, err := readInput(contextReader{ctx, r}, g.config.MaxBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	var key string
+	cache := g.cache
+	if !g.cacheable() {
+		cache = nil
+	}
+	if cache != nil {
+		if key, err = g.cacheKey(data, opts); err != nil {
+			return nil, err
+		}
+		if cached, ok := cache.Get(key); ok {
+			return cached, nil
+		}
+	}
+
+	out, err := g.GenerateFromContext(ctx, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	done := g.stage(StageEncode)
+	encoded, err := EncodeBytes(out, opts)
+	done()
+	if err != nil {
+		return nil, err
+	}
+	if opts != nil {
+		g.observeOutput(opts.Format, len(encoded))
+	} else {
+		g.observeOutput(FormatPNG, len(encoded))
+	}
+	if cache != nil {
+		cache.Set(key, encoded)
+	}
+	return encoded, nil
+}
+
+// cacheable сообщает, что результат определяется входом, конфигурацией и
+// настройками кодирования, то есть без хуков и своих рендереров
+func (g *Generator) cacheable() bool {
+	return len(g.hooks) == 0 && g.text == nil && g.frame == nil
+}
+
+// readInput читает входные данные целиком, не больше limit байт (0 - без ограничения)
+func readInput(r io.Reader, limit int64) ([]byte, error) {
+	if limit > 0 {
+		r = io.LimitReader(r, limit+1)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading image: %w", err)
+	}
+	if limit > 0 && int64(len(data)) > limit {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrInputTooLarge, limit)
+	}
+	return data, nil
+}
+
+// cacheKey вычисляет ключ кеша: SHA-256 от входа, конфигурации и настроек кодирования
+func (g *Generator) cacheKey(data []byte, opts *EncodeOptions) (string, error) {
+	h := sha256.New()
+	h.Write(data)
+
+	// Цвета приводим к одной модели, а данные шрифта хешируем отдельно,
+	// чтобы не сериализовать мегабайты в JSON
+	cfg := *g.config
+	fontData := cfg.FontData
+	cfg.FontData = nil
+	for _, c := range []*color.Color{&cfg.BackgroundColor, &cfg.BorderColor, &cfg.TextColor, &cfg.TextOutlineColor} {
+		if *c != nil {
+			*c = color.NRGBA64Model.Convert(*c)
+		}
+	}
+	fontSum := sha256.Sum256(fontData)
+	h.Write(fontSum[:])
+
+	enc := json.NewEncoder(h)
+	if err := enc.Encode((*rawConfig)(&cfg)); err != nil {
+		return "", fmt.Errorf("computing cache key: %w", err)
+	}
+	if opts != nil {
+		// Signer не сериализуется; считаем, что для генератора он один
+		o := *opts
+		o.Signer = nil
+		if err := enc.Encode(&o); err != nil {
+			return "", fmt.Errorf("computing cache key: %w", err)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// LRUCache - кеш в памяти, ограниченный суммарным размером значений.
+// При переполнении вытесняются давно не использованные записи.
+type LRUCache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	size     int64
+	order    *list.List // от свежих к старым
+	items    map[string]*list.Element
+}
+
+type lruEntry struct {
+	key   string
+	value []byte
+}
+
+// NewLRUCache создает кеш, который хранит не более maxBytes байт результатов
+func NewLRUCache(maxBytes int64) *LRUCache {
+	return &LRUCache{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// Get возвращает сохранённый результат и отмечает его как недавно использованный
+func (c *LRUCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*lruEntry).value, true
+}
+
+// Set сохраняет результат. Значения больше всего кеша не сохраняются.
+func (c *LRUCache) Set(key string, value []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if int64(len(value)) > c.maxBytes {
+		return
+	}
+	if el, ok := c.items[key]; ok {
+		entry := el.Value.(*lruEntry)
+		c.size += int64(len(value)) - int64(len(entry.value))
+		entry.value = value
+		c.order.MoveToFront(el)
+	} else {
+		c.items[key] = c.order.PushFront(&lruEntry{key: key, value: value})
+		c.size += int64(len(value))
+	}
+	for c.size > c.maxBytes {
+		oldest := c.order.Back()
+		entry := oldest.Value.(*lruEntry)
+		c.order.Remove(oldest)
+		delete(c.items, entry.key)
+		c.size -= int64(len(entry.value))
+	}
+}
+
+// Len возвращает число записей в кеше
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
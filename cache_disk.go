@@ -0,0 +1,230 @@
+package meme
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DiskCache - кеш результатов в каталоге, переживающий перезапуск
+// процесса. Файл называется по SHA-256 ключа и лежит в подкаталоге по
+// первым двум символам имени. Суммарный размер файлов ограничен; при
+// переполнении удаляются давно не использованные, время использования -
+// время изменения файла, которое обновляет Get.
+//
+// Запись атомарна: временный файл переименовывается, поэтому параллельный
+// Get, в том числе из другого процесса, не увидит файл наполовину. Файл
+// начинается с CRC-32 результата; испорченный файл Get удаляет и
+// считает промахом. Размер
+// учитывается только для файлов, записанных или прочитанных этим
+// процессом, и файлов, найденных при открытии кеша: несколько процессов на
+// одном каталоге могут вместе превысить предел. Другие файлы в каталоге
+// не учитываются и не удаляются.
+type DiskCache struct {
+	dir      string
+	maxBytes int64
+
+	mu    sync.Mutex
+	size  int64
+	order *list.List // от свежих к старым
+	items map[string]*list.Element
+}
+
+var _ Cache = (*DiskCache)(nil)
+
+type diskEntry struct {
+	name string // путь относительно dir
+	size int64
+}
+
+// Незавершенные записи; при открытии кеша они удаляются
+const diskCacheTemp = ".tmp-"
+
+// Заголовок файла - CRC-32 (IEEE) результата
+const diskCacheHeader = 4
+
+// NewDiskCache открывает кеш в каталоге dir, создавая его при
+// необходимости, и учитывает уже сохраненные файлы. maxBytes - предел
+// суммарного размера файлов.
+func NewDiskCache(dir string, maxBytes int64) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("opening disk cache: %w", err)
+	}
+	c := &DiskCache{dir: dir, maxBytes: maxBytes, order: list.New(), items: make(map[string]*list.Element)}
+
+	type found struct {
+		name    string
+		size    int64
+		modTime time.Time
+	}
+	var files []found
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		if strings.HasPrefix(d.Name(), diskCacheTemp) && len(filepath.Dir(rel)) == 2 {
+			os.Remove(path)
+			return nil
+		}
+		if !isDiskCacheName(rel) {
+			return nil // чужие файлы не учитываем и не вытесняем
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil // файл удален во время обхода
+		}
+		files = append(files, found{rel, info.Size(), info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("opening disk cache: %w", err)
+	}
+	// Старые файлы оказываются в конце списка и вытесняются первыми
+	slices.SortFunc(files, func(a, b found) int { return a.modTime.Compare(b.modTime) })
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, f := range files {
+		c.items[f.name] = c.order.PushFront(&diskEntry{name: f.name, size: f.size})
+		c.size += f.size
+	}
+	c.evict()
+	return c, nil
+}
+
+// name возвращает путь файла для ключа относительно dir
+func (c *DiskCache) name(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	h := hex.EncodeToString(sum[:])
+	return filepath.Join(h[:2], h[2:])
+}
+
+// isDiskCacheName сообщает, что путь похож на результат name
+func isDiskCacheName(rel string) bool {
+	rel = filepath.ToSlash(rel)
+	if len(rel) != 2*sha256.Size+1 || rel[2] != '/' {
+		return false
+	}
+	_, err := hex.DecodeString(rel[:2] + rel[3:])
+	return err == nil
+}
+
+// Get читает результат и отмечает его как недавно использованный
+func (c *DiskCache) Get(key string) ([]byte, bool) {
+	name := c.name(key)
+	path := filepath.Join(c.dir, name)
+	data, err := os.ReadFile(path)
+	valid := err == nil && validDiskCacheFile(data)
+	if err == nil && !valid {
+		os.Remove(path) // испорчен снаружи, запишется заново
+	}
+	if !valid {
+		c.mu.Lock()
+		c.remove(name)
+		c.mu.Unlock()
+		return nil, false
+	}
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.touch(name, int64(len(data)))
+	c.evict()
+	return data[diskCacheHeader:], true
+}
+
+// validDiskCacheFile проверяет контрольную сумму файла кеша
+func validDiskCacheFile(data []byte) bool {
+	return len(data) >= diskCacheHeader &&
+		binary.BigEndian.Uint32(data) == crc32.ChecksumIEEE(data[diskCacheHeader:])
+}
+
+// Set сохраняет результат. Значения больше всего кеша не сохраняются,
+// ошибки записи (например, закончилось место) игнорируются.
+func (c *DiskCache) Set(key string, value []byte) {
+	if int64(diskCacheHeader+len(value)) > c.maxBytes {
+		return
+	}
+	name := c.name(key)
+	path := filepath.Join(c.dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), diskCacheTemp+"*")
+	if err != nil {
+		return
+	}
+	data := make([]byte, diskCacheHeader+len(value))
+	binary.BigEndian.PutUint32(data, crc32.ChecksumIEEE(value))
+	copy(data[diskCacheHeader:], value)
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.touch(name, int64(len(data)))
+	c.evict()
+}
+
+// touch переносит запись в начало списка, добавляя ее при необходимости
+func (c *DiskCache) touch(name string, size int64) {
+	if el, ok := c.items[name]; ok {
+		entry := el.Value.(*diskEntry)
+		c.size += size - entry.size
+		entry.size = size
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[name] = c.order.PushFront(&diskEntry{name: name, size: size})
+	c.size += size
+}
+
+// remove забывает запись, не трогая файл
+func (c *DiskCache) remove(name string) {
+	if el, ok := c.items[name]; ok {
+		c.order.Remove(el)
+		delete(c.items, name)
+		c.size -= el.Value.(*diskEntry).size
+	}
+}
+
+// evict удаляет старые файлы, пока размер превышает предел
+func (c *DiskCache) evict() {
+	for c.size > c.maxBytes {
+		entry := c.order.Back().Value.(*diskEntry)
+		c.remove(entry.name)
+		os.Remove(filepath.Join(c.dir, entry.name))
+	}
+}
+
+// Len возвращает число записей в кеше
+func (c *DiskCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Size возвращает суммарный размер файлов кеша в байтах
+func (c *DiskCache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
@@ -0,0 +1,232 @@
+package meme
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testPNG - закодированное серое фото размера w x h
+func testPNG(t testing.TB, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// countingCache - LRUCache, считающий попадания и записи
+type countingCache struct {
+	*LRUCache
+	hits, sets int
+}
+
+func (c *countingCache) Get(key string) ([]byte, bool) {
+	v, ok := c.LRUCache.Get(key)
+	if ok {
+		c.hits++
+	}
+	return v, ok
+}
+
+func (c *countingCache) Set(key string, value []byte) {
+	c.sets++
+	c.LRUCache.Set(key, value)
+}
+
+// redFrame заливает фон и рамку красным
+type redFrame struct{}
+
+func (redFrame) Draw(dst draw.Image, clip image.Rectangle, _ Layout, _ *Config) {
+	draw.Draw(dst, clip, image.NewUniform(color.RGBA{0xff, 0, 0, 0xff}), image.Point{}, draw.Src)
+}
+
+func TestCacheSkipsCustomRendering(t *testing.T) {
+	input := testPNG(t, 64, 64)
+	cache := &countingCache{LRUCache: NewLRUCache(1 << 20)}
+
+	plain := NewGenerator(DefaultConfig())
+	plain.SetCache(cache)
+	first, err := plain.GenerateBytes(bytes.NewReader(input), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := plain.GenerateBytes(bytes.NewReader(input), nil); err != nil {
+		t.Fatal(err)
+	}
+	if cache.hits != 1 || cache.sets != 1 {
+		t.Fatalf("plain generator: %d hits, %d sets; want 1 and 1", cache.hits, cache.sets)
+	}
+
+	// Генераторы с хуком или своим рендерером делят хранилище, но не
+	// получают чужой результат и не записывают свой
+	framed := NewGenerator(DefaultConfig())
+	framed.SetCache(cache)
+	framed.SetFrameRenderer(redFrame{})
+	hooked := NewGenerator(DefaultConfig())
+	hooked.SetCache(cache)
+	hooked.AddHook(HookBeforeEncode, func(dc *DrawContext) error {
+		dc.Canvas.Set(0, 0, color.RGBA{0, 0xff, 0, 0xff})
+		return nil
+	})
+	for name, g := range map[string]*Generator{"frame renderer": framed, "hook": hooked} {
+		out, err := g.GenerateBytes(bytes.NewReader(input), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Equal(out, first) {
+			t.Errorf("%s: got the plain generator's cached result", name)
+		}
+	}
+	if cache.hits != 1 || cache.sets != 1 {
+		t.Errorf("custom rendering used the cache: %d hits, %d sets", cache.hits, cache.sets)
+	}
+}
+
+// diskValue - результат размером 100 байт, файл кеша на 4 байта больше
+var diskValue = bytes.Repeat([]byte{7}, 100)
+
+const diskFileSize = diskCacheHeader + 100
+
+// diskPath возвращает путь файла кеша для key
+func diskPath(c *DiskCache, key string) string {
+	return filepath.Join(c.dir, c.name(key))
+}
+
+// checkDiskCache сравнивает учтенные записи и размер с ожидаемыми
+func checkDiskCache(t *testing.T, c *DiskCache, entries int, size int64) {
+	t.Helper()
+	if c.Len() != entries || c.Size() != size {
+		t.Errorf("%d entries of %d bytes, want %d of %d", c.Len(), c.Size(), entries, size)
+	}
+}
+
+func TestDiskCacheEviction(t *testing.T) {
+	c, err := NewDiskCache(t.TempDir(), 3*diskFileSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a", "b", "c"} {
+		c.Set(key, diskValue)
+	}
+	checkDiskCache(t, c, 3, 3*diskFileSize)
+
+	// a прочитан недавно, поэтому место для d освобождает b
+	if v, ok := c.Get("a"); !ok || !bytes.Equal(v, diskValue) {
+		t.Fatalf("Get(a) = %d bytes, %v", len(v), ok)
+	}
+	c.Set("d", diskValue)
+	checkDiskCache(t, c, 3, 3*diskFileSize)
+	if _, ok := c.Get("b"); ok {
+		t.Error("b was not evicted")
+	}
+	if _, err := os.Stat(diskPath(c, "b")); !os.IsNotExist(err) {
+		t.Errorf("evicted file: %v", err)
+	}
+
+	// Значение больше кеша не сохраняется и ничего не вытесняет
+	c.Set("big", make([]byte, 3*diskFileSize))
+	if _, ok := c.Get("big"); ok {
+		t.Error("value larger than the cache was stored")
+	}
+	checkDiskCache(t, c, 3, 3*diskFileSize)
+
+	// Перезапись учитывает новый размер
+	c.Set("a", diskValue[:50])
+	checkDiskCache(t, c, 3, 3*diskFileSize-50)
+}
+
+func TestDiskCacheReopen(t *testing.T) {
+	dir := t.TempDir()
+	c, err := NewDiskCache(dir, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for i, key := range []string{"old", "mid", "new"} {
+		c.Set(key, diskValue)
+		mtime := now.Add(time.Duration(i-3) * time.Hour)
+		if err := os.Chtimes(diskPath(c, key), mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Брошенная запись и чужой файл
+	tmp := filepath.Join(filepath.Dir(diskPath(c, "old")), diskCacheTemp+"1")
+	foreign := filepath.Join(dir, "notes.txt")
+	for _, path := range []string{tmp, foreign} {
+		if err := os.WriteFile(path, diskValue, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Меньший предел при открытии вытесняет самый старый файл
+	c, err = NewDiskCache(dir, 2*diskFileSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDiskCache(t, c, 2, 2*diskFileSize)
+	if _, err := os.Stat(diskPath(c, "old")); !os.IsNotExist(err) {
+		t.Errorf("oldest file: %v", err)
+	}
+	for _, key := range []string{"mid", "new"} {
+		if v, ok := c.Get(key); !ok || !bytes.Equal(v, diskValue) {
+			t.Errorf("Get(%s) after reopen = %d bytes, %v", key, len(v), ok)
+		}
+	}
+	if _, err := os.Stat(tmp); !os.IsNotExist(err) {
+		t.Errorf("leftover temp file: %v", err)
+	}
+	if _, err := os.Stat(foreign); err != nil {
+		t.Errorf("foreign file: %v", err)
+	}
+}
+
+func TestDiskCacheCorruptFile(t *testing.T) {
+	c, err := NewDiskCache(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"flipped", "truncated", "deleted"} {
+		c.Set(key, diskValue)
+	}
+
+	flipped, err := os.ReadFile(diskPath(c, "flipped"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	flipped[len(flipped)-1] ^= 0xff
+	if err := os.WriteFile(diskPath(c, "flipped"), flipped, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(diskPath(c, "truncated"), 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(diskPath(c, "deleted")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Испорченный файл - промах: запись забыта, файл удален
+	for _, key := range []string{"flipped", "truncated", "deleted"} {
+		if v, ok := c.Get(key); ok {
+			t.Errorf("Get(%s) = %d bytes from a damaged file", key, len(v))
+		}
+		if _, err := os.Stat(diskPath(c, key)); !os.IsNotExist(err) {
+			t.Errorf("%s: damaged file kept: %v", key, err)
+		}
+	}
+	checkDiskCache(t, c, 0, 0)
+
+	// Следующая запись восстанавливает файл
+	c.Set("flipped", diskValue)
+	if v, ok := c.Get("flipped"); !ok || !bytes.Equal(v, diskValue) {
+		t.Errorf("Get after rewrite = %d bytes, %v", len(v), ok)
+	}
+	checkDiskCache(t, c, 1, diskFileSize)
+}
@@ -0,0 +1,149 @@
+package meme
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"strconv"
+	"time"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// Callout - пронумерованная метка на фото и пункт легенды с тем же номером
+type Callout struct {
+	At   image.Point // центр кружка в координатах исходного фото
+	Text string      // пункт легенды; пусто - только кружок на фото
+}
+
+// CalloutOptions задает оформление меток и легенды
+type CalloutOptions struct {
+	Radius     int         // радиус кружка на фото, 0 - 1/30 меньшей стороны фото, не меньше 10
+	Color      color.Color // заливка кружков, nil - красный
+	LabelColor color.Color // цифры в кружках, nil - белый
+
+	LegendSize float64 // размер шрифта легенды, 0 - 0.6 FontSize генератора
+}
+
+// Значения CalloutOptions по умолчанию
+const (
+	minCalloutRadius   = 10
+	calloutLegendRatio = 0.6
+)
+
+var calloutRed = color.RGBA{0xe5, 0x39, 0x35, 0xff}
+
+// Annotate рисует демотиватор "объясняю картинку": на фото ставятся
+// пронумерованные кружки в точках callouts, а под подписями - легенда
+// "1 текст", "2 текст" цветом и шрифтом подписей генератора. Длинные
+// пункты переносятся по ширине холста.
+func (g *Generator) Annotate(img image.Image, callouts []Callout, opts CalloutOptions) (out *image.RGBA, err error) {
+	defer g.observeGeneration(time.Now(), &err)
+	defer recoverPanic(&err)
+	cfg := g.config
+	if err := g.validateInput(img); err != nil {
+		return nil, err
+	}
+	if len(callouts) == 0 {
+		return nil, &ConfigError{Field: "callouts", Reason: "must not be empty"}
+	}
+	if opts.Radius < 0 || opts.LegendSize < 0 {
+		return nil, &ConfigError{Field: "CalloutOptions", Reason: "sizes must not be negative"}
+	}
+	b := img.Bounds()
+	for i, c := range callouts {
+		if !c.At.In(b) {
+			return nil, &ConfigError{Field: "callouts", Reason: fmt.Sprintf("callout %d is outside the image", i+1)}
+		}
+	}
+	radius := opts.Radius
+	if radius == 0 {
+		radius = max(min(b.Dx(), b.Dy())/30, minCalloutRadius)
+	}
+	fill := colorOr(opts.Color, calloutRed)
+	ink := colorOr(opts.LabelColor, color.White)
+
+	photo := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	drawSource(photo, photo.Bounds(), img, b.Min)
+	for i, c := range callouts {
+		p := c.At.Sub(b.Min)
+		r := image.Rect(p.X-radius, p.Y-radius, p.X+radius, p.Y+radius)
+		if err := g.drawCalloutMark(photo, r, i+1, fill, ink); err != nil {
+			return nil, err
+		}
+	}
+	framed, err := g.generateInto(nil, photo)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []int
+	for i, c := range callouts {
+		if c.Text != "" {
+			items = append(items, i)
+		}
+	}
+	if len(items) == 0 {
+		return framed, nil
+	}
+
+	size := opts.LegendSize
+	if size == 0 {
+		size = cfg.FontSize * calloutLegendRatio
+	}
+	face, err := g.loadFont(size)
+	if err != nil {
+		return nil, fmt.Errorf("loading font: %w", err)
+	}
+	defer face.Close()
+
+	// Легенда: кружок с номером, справа - текст с переносом по ширине
+	done := g.stage(StageLayout)
+	tr := g.textRenderer()
+	fb := framed.Bounds()
+	lineH := face.Metrics().Height.Ceil()
+	margin := max(cfg.Padding, lineH)
+	mark := lineH
+	textX := margin + mark + lineH/3
+	lines := make([][]string, len(items))
+	height := 0
+	for k, i := range items {
+		lines[k] = wrapText(tr, face, callouts[i].Text, max(fb.Dx()-margin-textX, 1))
+		height += len(lines[k])*lineH + lineH/3
+	}
+	canvas := image.Rect(0, 0, fb.Dx(), fb.Dy()+height+margin/2)
+	done()
+	if err := checkCanvas(canvas); err != nil {
+		return nil, err
+	}
+
+	defer g.stage(StageOutline)()
+	out = image.NewRGBA(canvas)
+	draw.Draw(out, canvas, image.NewUniform(cfg.BackgroundColor), image.Point{}, draw.Src)
+	draw.Draw(out, image.Rect(0, 0, fb.Dx(), fb.Dy()), framed, fb.Min, draw.Src)
+	y := fb.Dy()
+	ascent := face.Metrics().Ascent.Ceil()
+	for k, i := range items {
+		r := image.Rect(margin, y+(lineH-mark)/2, margin+mark, y+(lineH+mark)/2)
+		if err := g.drawCalloutMark(out, r, i+1, fill, ink); err != nil {
+			return nil, err
+		}
+		for n, l := range lines[k] {
+			tr.Draw(out, face, l, fixed.P(textX, y+n*lineH+ascent), TextStyle{Color: cfg.TextColor, Linear: cfg.LinearBlending})
+		}
+		y += len(lines[k])*lineH + lineH/3
+	}
+	return out, nil
+}
+
+// drawCalloutMark рисует кружок с номером n во вписанном в r круге
+func (g *Generator) drawCalloutMark(dst draw.Image, r image.Rectangle, n int, fill, ink color.Color) error {
+	// Тонкое кольцо цвета цифры отделяет метку от пестрого фона
+	ring := max(r.Dx()/12, 1)
+	fillRounded(dst, r, r.Dx()/2, ink)
+	inner := r.Inset(ring)
+	fillRounded(dst, inner, inner.Dx()/2, fill)
+	slot := TextSlot{Name: "callout", Rect: inner.Inset(inner.Dx() / 6), FontSize: float64(inner.Dy()) * 0.7, MinFontSize: 6, Color: ink}
+	return g.drawSlotText(dst, slot, strconv.Itoa(n))
+}
@@ -0,0 +1,49 @@
+package meme
+
+import (
+	"fmt"
+	"image"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Функции, доступные в шаблонах подписей
+var captionFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// ExpandCaption подставляет data в подпись с плейсхолдерами text/template,
+// например "{{.User}} когда {{.Event}}". Отсутствующий ключ - ошибка, чтобы
+// при массовой генерации не получить мем с "<no value>". Подписи без "{{"
+// возвращаются как есть.
+func ExpandCaption(caption string, data any) (string, error) {
+	if !strings.Contains(caption, "{{") {
+		return caption, nil
+	}
+	tmpl, err := template.New("caption").Funcs(captionFuncs).Option("missingkey=error").Parse(caption)
+	if err != nil {
+		return "", fmt.Errorf("parsing caption template: %w", err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("expanding caption template: %w", err)
+	}
+	return b.String(), nil
+}
+
+// GenerateWithData создает демотиватор, подставляя data в плейсхолдеры
+// TopText и BottomText. Конфигурация генератора не изменяется, поэтому один
+// генератор может параллельно выпускать персонализированные мемы.
+func (g *Generator) GenerateWithData(img image.Image, data any) (out *image.RGBA, err error) {
+	defer g.observeGeneration(time.Now(), &err)
+	cfg := *g.config
+	if cfg.TopText, err = ExpandCaption(cfg.TopText, data); err != nil {
+		return nil, err
+	}
+	if cfg.BottomText, err = ExpandCaption(cfg.BottomText, data); err != nil {
+		return nil, err
+	}
+	return g.derive(&cfg).generateInto(nil, img)
+}
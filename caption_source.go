@@ -0,0 +1,122 @@
+package meme
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"strings"
+	"time"
+)
+
+// CaptionSource выдает подписи для пакетной генерации по одной паре.
+// Когда подписи закончились, Next возвращает io.EOF.
+type CaptionSource interface {
+	Next() (top, bottom string, err error)
+}
+
+// SliceCaptions возвращает источник, перебирающий подписи по порядку
+func SliceCaptions(captions []NestCaption) CaptionSource {
+	return &sliceSource{captions: captions}
+}
+
+type sliceSource struct {
+	captions []NestCaption
+	pos      int
+}
+
+func (s *sliceSource) Next() (string, string, error) {
+	if s.pos >= len(s.captions) {
+		return "", "", io.EOF
+	}
+	c := s.captions[s.pos]
+	s.pos++
+	return c.TopText, c.BottomText, nil
+}
+
+// CSVCaptions читает подписи из CSV: первый столбец - верхняя подпись,
+// второй (необязательный) - нижняя. Строка заголовка "top,bottom" пропускается.
+func CSVCaptions(r io.Reader) CaptionSource {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	return &csvSource{r: cr, first: true}
+}
+
+type csvSource struct {
+	r     *csv.Reader
+	first bool
+}
+
+func (s *csvSource) Next() (string, string, error) {
+	for {
+		record, err := s.r.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return "", "", io.EOF
+			}
+			return "", "", fmt.Errorf("reading captions: %w", err)
+		}
+		first := s.first
+		s.first = false
+		if first && len(record) >= 1 && strings.EqualFold(strings.TrimSpace(record[0]), "top") {
+			continue
+		}
+		switch len(record) {
+		case 0:
+			continue
+		case 1:
+			return record[0], "", nil
+		default:
+			return record[0], record[1], nil
+		}
+	}
+}
+
+// ChanCaptions возвращает источник, читающий подписи из канала до его закрытия
+func ChanCaptions(ch <-chan NestCaption) CaptionSource {
+	return chanSource(ch)
+}
+
+type chanSource <-chan NestCaption
+
+func (s chanSource) Next() (string, string, error) {
+	c, ok := <-s
+	if !ok {
+		return "", "", io.EOF
+	}
+	return c.TopText, c.BottomText, nil
+}
+
+// GenerateBatch создает по демотиватору из img для каждой пары подписей
+// из src и передает результат в fn вместе с порядковым номером. Буфер
+// пикселей переиспользуется между итерациями, поэтому out действителен
+// только до возврата из fn. Останавливается на первой ошибке источника,
+// генерации или fn; возвращает число обработанных пар.
+func (g *Generator) GenerateBatch(img image.Image, src CaptionSource, fn func(i int, out *image.RGBA) error) (int, error) {
+	// Один производный генератор на весь пакет: кеш шрифтов общий для итераций
+	cfg := *g.config
+	dg := g.derive(&cfg)
+	var buf *image.RGBA
+	for i := 0; ; i++ {
+		top, bottom, err := src.Next()
+		if errors.Is(err, io.EOF) {
+			return i, nil
+		}
+		if err != nil {
+			return i, err
+		}
+
+		cfg.TopText, cfg.BottomText = top, bottom
+		start := time.Now()
+		out, err := dg.generateInto(buf, img)
+		g.observeGeneration(start, &err)
+		if err != nil {
+			return i, fmt.Errorf("caption %d: %w", i, err)
+		}
+		buf = out
+		if err := fn(i, out); err != nil {
+			return i, err
+		}
+	}
+}
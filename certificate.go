@@ -0,0 +1,158 @@
+package meme
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"time"
+
+	"golang.org/x/image/font/gofont/gosmallcaps"
+)
+
+// CertificateOptions задаёт содержимое и оформление грамоты
+type CertificateOptions struct {
+	Title       string // пусто - "Certificate"
+	Subtitle    string // строка над именем, например "presented to"
+	Recipient   string
+	Description string // за что выдана
+	Date        string // выводится слева внизу над линией
+
+	Seal image.Image // печать: вписывается в круг справа внизу; nil - без печати
+
+	Width, Height int // 0 - 1400x1000
+
+	// Шрифт заголовка и имени. Среди встроенных шрифтов нет шрифта с
+	// засечками, поэтому по умолчанию используется капитель Go Smallcaps.
+	TitleFontPath string
+	TitleFontData []byte
+
+	Background  color.Color // nil - цвет бумаги
+	InkColor    color.Color // nil - темно-серый, для текста
+	AccentColor color.Color // nil - золотой, для рамки, заголовка и печати
+}
+
+// Значения CertificateOptions по умолчанию
+const (
+	defaultCertificateWidth  = 1400
+	defaultCertificateHeight = 1000
+	defaultCertificateTitle  = "Certificate"
+)
+
+var (
+	certificatePaper  = color.RGBA{0xfb, 0xf7, 0xec, 0xff}
+	certificateInk    = color.RGBA{0x2b, 0x2b, 0x2b, 0xff}
+	certificateAccent = color.RGBA{0xb8, 0x91, 0x2f, 0xff}
+)
+
+// Certificate создает шуточную грамоту: двойная рамка с ромбами по углам,
+// заголовок по центру, имя получателя на линии, описание, дата и печать
+// из переданной картинки.
+func (g *Generator) Certificate(opts CertificateOptions) (out *image.RGBA, err error) {
+	defer g.observeGeneration(time.Now(), &err)
+	defer recoverPanic(&err)
+	if opts.Width < 0 || opts.Height < 0 {
+		return nil, &ConfigError{Field: "CertificateOptions", Reason: "sizes must not be negative"}
+	}
+	width, height := opts.Width, opts.Height
+	if width == 0 {
+		width = defaultCertificateWidth
+	}
+	if height == 0 {
+		height = defaultCertificateHeight
+	}
+	canvas := image.Rect(0, 0, width, height)
+	if err := checkCanvas(canvas); err != nil {
+		return nil, err
+	}
+	title := opts.Title
+	if title == "" {
+		title = defaultCertificateTitle
+	}
+	titleFontPath, titleFontData := opts.TitleFontPath, opts.TitleFontData
+	if titleFontPath == "" && len(titleFontData) == 0 {
+		titleFontData = gosmallcaps.TTF
+	}
+	paper, ink, accent := opts.Background, opts.InkColor, opts.AccentColor
+	if paper == nil {
+		paper = certificatePaper
+	}
+	if ink == nil {
+		ink = certificateInk
+	}
+	if accent == nil {
+		accent = certificateAccent
+	}
+
+	out = image.NewRGBA(canvas)
+	draw.Draw(out, canvas, image.NewUniform(paper), image.Point{}, draw.Src)
+
+	// Двойная рамка: толстая внешняя и тонкая внутренняя, ромбы в углах внутренней
+	short := min(width, height)
+	outer := canvas.Inset(short / 25)
+	inner := outer.Inset(short / 60)
+	drawFrame(out, outer, max(short/160, 2), accent)
+	drawFrame(out, inner, max(short/500, 1), accent)
+	diamond := short / 45
+	for _, p := range []image.Point{inner.Min, {inner.Max.X, inner.Min.Y}, {inner.Min.X, inner.Max.Y}, inner.Max} {
+		fillDiamond(out, p, diamond, accent)
+		fillDiamond(out, p, diamond/2, paper)
+	}
+
+	// Вертикальная разметка в долях высоты
+	row := func(from, to float64) image.Rectangle {
+		return image.Rect(inner.Min.X+short/20, int(float64(height)*from), inner.Max.X-short/20, int(float64(height)*to))
+	}
+	h := float64(height)
+	slots := []struct {
+		slot TextSlot
+		text string
+	}{
+		{TextSlot{Name: "title", Rect: row(0.12, 0.28), FontPath: titleFontPath, FontData: titleFontData, FontSize: h / 8, Color: accent, Uppercase: true}, title},
+		{TextSlot{Name: "subtitle", Rect: row(0.30, 0.37), FontSize: h / 28, Color: ink}, opts.Subtitle},
+		{TextSlot{Name: "recipient", Rect: row(0.39, 0.53), FontPath: titleFontPath, FontData: titleFontData, FontSize: h / 11, Color: ink}, opts.Recipient},
+		{TextSlot{Name: "description", Rect: row(0.58, 0.70), FontSize: h / 28, Color: ink}, opts.Description},
+	}
+	for _, s := range slots {
+		if s.text == "" {
+			continue
+		}
+		if err := g.drawSlotText(out, s.slot, s.text); err != nil {
+			return nil, err
+		}
+	}
+
+	// Линия под именем
+	lineY := int(h * 0.54)
+	line := max(short/500, 1)
+	draw.Draw(out, image.Rect(width/4, lineY, width*3/4, lineY+line), image.NewUniform(accent), image.Point{}, draw.Src)
+
+	// Дата слева внизу над линией, печать справа
+	bottom := inner.Max.Y - short/20
+	if opts.Date != "" {
+		dateRect := image.Rect(inner.Min.X+short/12, bottom-short/14, inner.Min.X+short/12+width/4, bottom)
+		slot := TextSlot{Name: "date", Rect: dateRect, FontSize: h / 30, Color: ink}
+		if err := g.drawSlotText(out, slot, opts.Date); err != nil {
+			return nil, err
+		}
+		draw.Draw(out, image.Rect(dateRect.Min.X, bottom, dateRect.Max.X, bottom+line), image.NewUniform(ink), image.Point{}, draw.Src)
+	}
+	if opts.Seal != nil {
+		d := short / 6
+		seal := image.Rect(inner.Max.X-short/12-d, bottom-d, inner.Max.X-short/12, bottom)
+		ring := seal.Inset(-max(d/25, 2))
+		draw.DrawMask(out, ring, image.NewUniform(accent), image.Point{}, roundedMask(ring.Dx(), ring.Dy(), ring.Dx()/2), image.Point{}, draw.Over)
+		tmp := image.NewRGBA(image.Rect(0, 0, d, d))
+		drawCover(tmp, tmp.Bounds(), opts.Seal)
+		draw.DrawMask(out, seal, tmp, image.Point{}, roundedMask(d, d, d/2), image.Point{}, draw.Over)
+	}
+	return out, nil
+}
+
+// fillDiamond заливает ромб с центром c и полудиагональю r
+func fillDiamond(dst draw.Image, c image.Point, r int, col color.Color) {
+	src := image.NewUniform(col)
+	for dy := -r; dy <= r; dy++ {
+		w := r - max(dy, -dy)
+		draw.Draw(dst, image.Rect(c.X-w, c.Y+dy, c.X+w+1, c.Y+dy+1), src, image.Point{}, draw.Src)
+	}
+}
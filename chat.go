@@ -0,0 +1,262 @@
+package meme
+
+import (
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/math/fixed"
+)
+
+// ChatMessage - одно сообщение переписки
+type ChatMessage struct {
+	Sender   string
+	Text     string
+	Time     string      // например "12:34"; пусто - без времени
+	Outgoing bool        // свое сообщение: справа и без аватара
+	Avatar   image.Image // nil - круг с первой буквой Sender
+}
+
+// ChatOptions задаёт оформление "скриншота" переписки
+type ChatOptions struct {
+	Width    int     // ширина холста, 0 - 720
+	FontSize float64 // размер текста сообщений, 0 - 28
+
+	// Framed оборачивает переписку в рамку демотиватора
+	// с подписями TopText и BottomText конфигурации
+	Framed bool
+
+	Background    color.Color // nil - голубовато-серый фон
+	IncomingColor color.Color // nil - белый
+	OutgoingColor color.Color // nil - светло-зеленый
+	TextColor     color.Color // nil - черный
+}
+
+// Значения ChatOptions по умолчанию
+const (
+	defaultChatWidth    = 720
+	defaultChatFontSize = 28
+
+	chatBubbleRatio = 0.7  // максимальная ширина пузыря от ширины холста
+	chatSmallRatio  = 0.65 // размер имени и времени от размера текста
+)
+
+var (
+	defaultChatBackground = color.RGBA{0xdf, 0xe7, 0xee, 0xff}
+	defaultChatOutgoing   = color.RGBA{0xef, 0xfd, 0xde, 0xff}
+	chatTimeColor         = color.RGBA{0x8a, 0x8a, 0x8a, 0xff}
+)
+
+// chatBubble - рассчитанная геометрия сообщения
+type chatBubble struct {
+	msg     ChatMessage
+	lines   []string
+	rect    image.Rectangle // пузырь на холсте
+	hasName bool
+}
+
+// ChatScreenshot рисует переписку в виде пузырей сообщений: входящие слева
+// с аватаром и именем отправителя, свои - справа, время - в углу пузыря.
+// Шрифт берется из конфигурации; если он не задан, используется Go Regular.
+func (g *Generator) ChatScreenshot(messages []ChatMessage, opts ChatOptions) (out *image.RGBA, err error) {
+	defer g.observeGeneration(time.Now(), &err)
+	defer recoverPanic(&err)
+	cfg := g.config
+	if len(messages) == 0 {
+		return nil, &ConfigError{Field: "messages", Reason: "must not be empty"}
+	}
+	if opts.Width < 0 || opts.FontSize < 0 {
+		return nil, &ConfigError{Field: "ChatOptions", Reason: "sizes must not be negative"}
+	}
+	for i, m := range messages {
+		if n := utf8.RuneCountInString(m.Text); cfg.MaxTextLength > 0 && n > cfg.MaxTextLength {
+			return nil, fmt.Errorf("%w: message %d has %d characters, limit is %d", ErrTextTooLong, i, n, cfg.MaxTextLength)
+		}
+	}
+
+	width := opts.Width
+	if width == 0 {
+		width = defaultChatWidth
+	}
+	size := opts.FontSize
+	if size == 0 {
+		size = defaultChatFontSize
+	}
+	fontPath, fontData := cfg.FontPath, cfg.FontData
+	if fontPath == "" && len(fontData) == 0 {
+		fontData = goregular.TTF
+	}
+	done := g.stage(StageFontLoad)
+	face, err := g.loadFontFrom(fontPath, fontData, size)
+	if err != nil {
+		done()
+		return nil, err
+	}
+	defer face.Close()
+	small, err := g.loadFontFrom(fontPath, fontData, size*chatSmallRatio)
+	done()
+	if err != nil {
+		return nil, err
+	}
+	defer small.Close()
+
+	// Геометрия: сначала считаем пузыри, затем выделяем холст нужной высоты
+	done = g.stage(StageLayout)
+	unit := int(math.Round(size))
+	margin, pad, gap := unit*3/5, unit/2, unit/3
+	avatarD := unit * 2
+	lineH := face.Metrics().Height.Ceil()
+	smallH := small.Metrics().Height.Ceil()
+	maxText := int(float64(width)*chatBubbleRatio) - 2*pad
+
+	tr := g.textRenderer()
+	bubbles := make([]chatBubble, len(messages))
+	y := margin
+	for i, m := range messages {
+		b := chatBubble{msg: m, lines: wrapText(tr, face, m.Text, maxText)}
+		b.hasName = !m.Outgoing && m.Sender != ""
+		w := 0
+		for _, l := range b.lines {
+			w = max(w, tr.Measure(face, l).Ceil())
+		}
+		h := len(b.lines) * lineH
+		if b.hasName {
+			w = max(w, tr.Measure(small, m.Sender).Ceil())
+			h += smallH
+		}
+		if m.Time != "" {
+			// Время - отдельной строкой справа внизу пузыря
+			w = max(w, tr.Measure(small, m.Time).Ceil())
+			h += smallH
+		}
+		bw, bh := w+2*pad, h+2*pad
+		x := margin + avatarD + margin/2
+		if m.Outgoing {
+			x = width - margin - bw
+		}
+		b.rect = image.Rect(x, y, x+bw, y+bh)
+		bubbles[i] = b
+		y += max(bh, avatarD) + gap
+	}
+	canvas := image.Rect(0, 0, width, y-gap+margin)
+	done()
+	if err := checkCanvas(canvas); err != nil {
+		return nil, err
+	}
+
+	bg := opts.Background
+	if bg == nil {
+		bg = defaultChatBackground
+	}
+	incoming := opts.IncomingColor
+	if incoming == nil {
+		incoming = color.White
+	}
+	outgoing := opts.OutgoingColor
+	if outgoing == nil {
+		outgoing = defaultChatOutgoing
+	}
+	textColor := opts.TextColor
+	if textColor == nil {
+		textColor = color.Black
+	}
+
+	done = g.stage(StageOutline)
+	out = image.NewRGBA(canvas)
+	draw.Draw(out, canvas, image.NewUniform(bg), image.Point{}, draw.Src)
+	for _, b := range bubbles {
+		fill := incoming
+		if b.msg.Outgoing {
+			fill = outgoing
+		}
+		fillRounded(out, b.rect, unit/2, fill)
+
+		ty := b.rect.Min.Y + pad
+		if b.hasName {
+			tr.Draw(out, small, b.msg.Sender, fixed.P(b.rect.Min.X+pad, ty+small.Metrics().Ascent.Ceil()), TextStyle{Color: senderColor(b.msg.Sender), Linear: g.config.LinearBlending})
+			ty += smallH
+		}
+		for _, l := range b.lines {
+			tr.Draw(out, face, l, fixed.P(b.rect.Min.X+pad, ty+face.Metrics().Ascent.Ceil()), TextStyle{Color: textColor, Linear: g.config.LinearBlending})
+			ty += lineH
+		}
+		if b.msg.Time != "" {
+			tw := tr.Measure(small, b.msg.Time).Ceil()
+			tr.Draw(out, small, b.msg.Time, fixed.P(b.rect.Max.X-pad-tw, ty+small.Metrics().Ascent.Ceil()), TextStyle{Color: chatTimeColor, Linear: g.config.LinearBlending})
+		}
+
+		if !b.msg.Outgoing {
+			// Аватар выравнивается по нижнему краю пузыря
+			top := max(b.rect.Max.Y-avatarD, b.rect.Min.Y)
+			g.drawAvatar(out, image.Rect(margin, top, margin+avatarD, top+avatarD), b.msg, small)
+		}
+	}
+	done()
+
+	if opts.Framed {
+		return g.generateInto(nil, out)
+	}
+	return out, nil
+}
+
+// drawAvatar рисует аватар в круге r: картинку отправителя или
+// цветной круг с первой буквой имени
+func (g *Generator) drawAvatar(dst *image.RGBA, r image.Rectangle, m ChatMessage, face font.Face) {
+	mask := roundedMask(r.Dx(), r.Dy(), r.Dx()/2)
+	if m.Avatar != nil {
+		tmp := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+		drawCover(tmp, tmp.Bounds(), m.Avatar)
+		draw.DrawMask(dst, r, tmp, image.Point{}, mask, image.Point{}, draw.Over)
+		return
+	}
+	draw.DrawMask(dst, r, image.NewUniform(senderColor(m.Sender)), image.Point{}, mask, image.Point{}, draw.Over)
+	letter, _ := utf8.DecodeRuneInString(strings.ToUpper(m.Sender))
+	if letter == utf8.RuneError {
+		return
+	}
+	s := string(letter)
+	tr := g.textRenderer()
+	metrics := face.Metrics()
+	x := r.Min.X + (r.Dx()-tr.Measure(face, s).Ceil())/2
+	y := r.Min.Y + (r.Dy()+metrics.Ascent.Ceil()-metrics.Descent.Ceil())/2
+	tr.Draw(dst, face, s, fixed.P(x, y), TextStyle{Color: color.White, Linear: g.config.LinearBlending})
+}
+
+// senderColor выбирает устойчивый цвет по имени отправителя
+func senderColor(name string) color.RGBA {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return hsl(float64(h.Sum32()%360), 0.55, 0.45)
+}
+
+// fillRounded заливает прямоугольник со скругленными углами радиуса radius
+func fillRounded(dst draw.Image, r image.Rectangle, radius int, c color.Color) {
+	mask := roundedMask(r.Dx(), r.Dy(), radius)
+	draw.DrawMask(dst, r, image.NewUniform(c), image.Point{}, mask, image.Point{}, draw.Over)
+}
+
+// roundedMask строит маску w x h со скругленными углами и сглаженным краем
+func roundedMask(w, h, radius int) *image.Alpha {
+	mask := image.NewAlpha(image.Rect(0, 0, w, h))
+	radius = min(radius, w/2, h/2)
+	rf := float64(radius)
+	for y := range h {
+		for x := range w {
+			// Расстояние от центра ближайшего угла; вне углов пиксель полностью закрашен
+			cx := min(max(float64(x)+0.5, rf), float64(w)-rf)
+			cy := min(max(float64(y)+0.5, rf), float64(h)-rf)
+			d := math.Hypot(float64(x)+0.5-cx, float64(y)+0.5-cy)
+			a := min(max(rf-d+0.5, 0), 1)
+			mask.Pix[y*mask.Stride+x] = uint8(a * 255)
+		}
+	}
+	return mask
+}
@@ -0,0 +1,93 @@
+package meme
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"maps"
+	"slices"
+	"sync"
+
+	"golang.org/x/image/font/opentype"
+)
+
+// fontCache - разобранные шрифты: ключ fontFileKey или fontDataKey ->
+// *opentype.Font. *opentype.Font только читается при создании face, поэтому
+// один разобранный шрифт можно использовать из нескольких генераторов сразу.
+type fontCache struct {
+	mu    sync.RWMutex
+	fonts map[string]*opentype.Font
+}
+
+// fontFileKey - ключ кеша шрифтов для файла path
+func fontFileKey(path string) string {
+	return "file:" + path
+}
+
+// fontDataKey - ключ кеша шрифтов для данных шрифта: имя встроенного
+// шрифта, если data - его байты, иначе SHA-256 содержимого
+func fontDataKey(data []byte) string {
+	for name, b := range builtinFonts {
+		if len(b) == len(data) && &b[0] == &data[0] {
+			return "builtin:" + name
+		}
+	}
+	sum := sha256.Sum256(data)
+	return "data:" + hex.EncodeToString(sum[:])
+}
+
+func newFontCache() *fontCache {
+	return &fontCache{fonts: make(map[string]*opentype.Font)}
+}
+
+func (c *fontCache) get(key string) (*opentype.Font, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	f, ok := c.fonts[key]
+	return f, ok
+}
+
+func (c *fontCache) put(key string, f *opentype.Font) {
+	c.mu.Lock()
+	c.fonts[key] = f
+	c.mu.Unlock()
+}
+
+func (c *fontCache) reset() {
+	c.mu.Lock()
+	c.fonts = make(map[string]*opentype.Font)
+	c.mu.Unlock()
+}
+
+// Clone возвращает генератор с той же конфигурацией: так обработчик
+// запроса получает свой генератор из общего базового стиля. Подключенные
+// кеш результатов, метрики, журнал, хуки и рендереры переносятся, а кеш
+// разобранных шрифтов остается общим, поэтому копия не читает и не
+// разбирает заново ни файлы шрифтов, ни те же FontData. Другую конфигурацию копии задает
+// WithConfig.
+func (g *Generator) Clone() *Generator {
+	return g.derive(cloneConfig(g.config))
+}
+
+// WithConfig возвращает генератор с копией конфигурации cfg (тема
+// подставляется, как в NewGenerator), который, как и Clone, переносит
+// подключенные кеш, метрики, журнал, хуки и рендереры и делит с g кеш
+// шрифтов. Серверу это позволяет собирать генератор на каждый запрос, не
+// разбирая шрифты заново, даже если FontData задается в каждом запросе.
+func (g *Generator) WithConfig(cfg *Config) *Generator {
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+	return g.derive(cloneConfig(withTheme(cfg)))
+}
+
+// cloneConfig копирует конфигурацию вместе с фильтрами и их параметрами.
+// FontData не копируется: копия ссылается на те же байты, поэтому их
+// нельзя изменять на месте.
+func cloneConfig(c *Config) *Config {
+	cfg := *c
+	cfg.Filters = slices.Clone(cfg.Filters)
+	for i, f := range cfg.Filters {
+		cfg.Filters[i].Params = maps.Clone(f.Params)
+	}
+	return &cfg
+}
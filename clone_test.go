@@ -0,0 +1,82 @@
+package meme
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+func TestCloneSharesFontData(t *testing.T) {
+	// Разные срезы с одинаковым содержимым, как FontData в разных запросах
+	cfg := DefaultConfig()
+	cfg.FontData = bytes.Clone(goregular.TTF)
+	base := NewGenerator(cfg)
+	other := DefaultConfig()
+	other.FontData = bytes.Clone(goregular.TTF)
+
+	parsed := func(g *Generator) any {
+		t.Helper()
+		face, err := g.loadFont(32)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer face.Close()
+		return face.(*vectorFace).font
+	}
+	first := parsed(base.Clone())
+	if second := parsed(base.WithConfig(other)); second != first {
+		t.Error("WithConfig parsed the same FontData again")
+	}
+	if third := parsed(base.Clone()); third != first {
+		t.Error("Clone parsed the same FontData again")
+	}
+
+	// Встроенный шрифт тоже разбирается один раз
+	builtin := DefaultConfig()
+	builtin.FontData = GetAvailableFonts()["regular"]
+	if parsed(base.WithConfig(builtin)) != parsed(base.WithConfig(builtin)) {
+		t.Error("built-in font parsed twice")
+	}
+}
+
+func TestWithFont(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "font.ttf")
+	if err := os.WriteFile(path, goregular.TTF, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	base := NewGenerator(DefaultConfig())
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+
+	// WithFont не трогает base, поэтому безопасен во время генерации на нем
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if _, err := base.Generate(img); err != nil {
+			t.Error(err)
+		}
+	}()
+	g, err := base.WithFont(path)
+	wg.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if base.Config().FontPath != "" {
+		t.Errorf("base font path changed to %q", base.Config().FontPath)
+	}
+	if got := g.Config(); got.FontPath != path || !bytes.Equal(got.FontData, goregular.TTF) {
+		t.Errorf("derived font path %q, %d bytes", got.FontPath, len(got.FontData))
+	}
+	if _, err := g.Generate(img); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := base.WithFont(filepath.Join(t.TempDir(), "missing.ttf")); !errors.Is(err, ErrFontNotFound) {
+		t.Errorf("missing font: %v", err)
+	}
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-goblin/meme"
+)
+
+// job - одна строка манифеста пакетной обработки
+type job struct {
+	Input  string `json:"input"`
+	Top    string `json:"top"`
+	Bottom string `json:"bottom"`
+	Output string `json:"output"`
+
+	line   int    // номер строки манифеста для сообщений об ошибках
+	output string // итоговый путь результата, см. resolveOutputs
+}
+
+// runBatch обрабатывает манифест: meme batch [flags] jobs.csv
+//
+// CSV должен начинаться с заголовка со столбцами input, top, bottom, output
+// в любом порядке; JSONL (.jsonl, .ndjson) - объекты с теми же полями.
+// Пустой output заменяется шаблоном --name; в шаблоне доступны {basename}
+// (имя входа без расширения), {name}, {ext} и {index} (номер строки).
+// Входы, выходы и --out-dir могут быть объектами s3://bucket/key и
+// gs://bucket/key; относительные пути дополняются так же, как локальные.
+// Входом может быть и http(s)-адрес.
+func runBatch(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("meme batch", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	workers := fs.Int("j", runtime.NumCPU(), "number of parallel workers")
+	outDir := fs.String("out-dir", "", "directory for relative output paths (default next to the manifest)")
+	name := fs.String("name", "{basename}_meme.png", "output name template for rows without output")
+	format := fs.String("format", "", "output format for all rows (default from output extension)")
+	quality := fs.Int("quality", 0, "JPEG quality 1-100")
+	var style styleFlags
+	style.register(fs)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "usage: meme batch [flags] jobs.csv|jobs.jsonl")
+		return exitUsage
+	}
+	if *workers < 1 {
+		fmt.Fprintln(stderr, "meme: -j must be at least 1")
+		return exitUsage
+	}
+	cfg, err := style.config()
+	if err != nil {
+		fmt.Fprintf(stderr, "meme: %v\n", err)
+		return exitUsage
+	}
+
+	manifest := fs.Arg(0)
+	jobs, err := readManifest(manifest)
+	if err != nil {
+		fmt.Fprintf(stderr, "meme: %v\n", err)
+		return exitInput
+	}
+	// Относительные пути входа - от каталога манифеста, выхода - от --out-dir
+	base := filepath.Dir(manifest)
+	if *outDir == "" {
+		*outDir = base
+	}
+
+	var st stores
+	queue := make(chan job)
+	var (
+		mu     sync.Mutex
+		failed int
+		wg     sync.WaitGroup
+	)
+	// Строки с уже занятым выходом не запускаются, иначе результаты
+	// молча перезаписали бы друг друга
+	jobs, dups := resolveOutputs(jobs, *outDir, *name)
+	for _, d := range dups {
+		failed++
+		fmt.Fprintf(stderr, "line %d (%s): %v\n", d.job.line, d.job.Input, d.err)
+	}
+	total := len(jobs) + len(dups)
+
+	// Подписи передаются в каждый вызов через Content, поэтому один
+	// генератор безопасно делят все исполнители
+	g := meme.NewGenerator(cfg)
+	for range *workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range queue {
+				output, err := processJob(context.Background(), &st, g, j, base, *format, *quality)
+				mu.Lock()
+				if err != nil {
+					failed++
+					fmt.Fprintf(stderr, "line %d (%s): %v\n", j.line, j.Input, err)
+				} else {
+					fmt.Fprintf(stdout, "line %d: %s\n", j.line, output)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, j := range jobs {
+		queue <- j
+	}
+	close(queue)
+	wg.Wait()
+
+	fmt.Fprintf(stderr, "%d succeeded, %d failed\n", total-failed, failed)
+	if failed > 0 {
+		return exitError
+	}
+	return exitOK
+}
+
+// rowError - ошибка строки манифеста, найденная до запуска
+type rowError struct {
+	job job
+	err error
+}
+
+// resolveOutputs заполняет job.output: пустой output заменяется шаблоном
+// nameTemplate, относительный путь дополняется outDir. Строки, чей выход
+// совпал с выходом одной из предыдущих, возвращаются отдельно как ошибки.
+func resolveOutputs(jobs []job, outDir, nameTemplate string) ([]job, []rowError) {
+	var (
+		ok   []job
+		dups []rowError
+	)
+	used := make(map[string]int, len(jobs)) // выход -> строка, которая его заняла
+	for _, j := range jobs {
+		output := j.Output
+		if output == "" {
+			output = nameTemplate
+		}
+		j.output = joinLocation(outDir, expandName(output, j))
+		if line, taken := used[j.output]; taken {
+			dups = append(dups, rowError{j, fmt.Errorf("output %s is already used by line %d", j.output, line)})
+			continue
+		}
+		used[j.output] = j.line
+		ok = append(ok, j)
+	}
+	return ok, dups
+}
+
+// processJob создает мем для одной строки манифеста и возвращает путь результата
+func processJob(ctx context.Context, st *stores, g *meme.Generator, j job, base, format string, quality int) (string, error) {
+	if j.Input == "" {
+		return "", errors.New("input is empty")
+	}
+	input := joinLocation(base, j.Input)
+	output := j.output
+	opts, err := encodeOptions(format, output, quality)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := st.open(ctx, input, g.Config().MaxBytes)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	data, err := g.GenerateContentBytes(f, meme.Content{TopText: j.Top, BottomText: j.Bottom}, opts)
+	if err != nil {
+		return "", err
+	}
+	dst, key, err := st.file(ctx, output)
+	if err != nil {
+		return "", err
+	}
+	return output, dst.Put(ctx, key, data)
+}
+
+// expandName подставляет поля строки в шаблон имени выходного файла
+func expandName(template string, j job) string {
+	name := filepath.Base(j.Input)
+	ext := filepath.Ext(name)
+	return strings.NewReplacer(
+		"{basename}", strings.TrimSuffix(name, ext),
+		"{name}", name,
+		"{ext}", strings.TrimPrefix(ext, "."),
+		"{index}", strconv.Itoa(j.line),
+	).Replace(template)
+}
+
+// readManifest читает задания из CSV или JSONL по расширению файла
+func readManifest(path string) ([]job, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson":
+		return readJSONL(f)
+	case ".csv":
+		return readCSV(f)
+	}
+	return nil, fmt.Errorf("unsupported manifest %s: expected .csv or .jsonl", path)
+}
+
+func readJSONL(r io.Reader) ([]job, error) {
+	var jobs []job
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" {
+			continue
+		}
+		var j job
+		dec := json.NewDecoder(strings.NewReader(text))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&j); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		j.line = line
+		jobs = append(jobs, j)
+	}
+	return jobs, sc.Err()
+}
+
+func readCSV(r io.Reader) ([]job, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	columns := map[string]int{}
+	for i, h := range header {
+		columns[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	if _, ok := columns["input"]; !ok {
+		return nil, errors.New("header must contain an input column")
+	}
+
+	var jobs []job
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return jobs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return record[i]
+			}
+			return ""
+		}
+		jobs = append(jobs, job{Input: field("input"), Top: field("top"), Bottom: field("bottom"), Output: field("output"), line: line})
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/go-goblin/meme"
+)
+
+// devDebounce - пауза после последнего изменения файла перед перерисовкой:
+// редакторы сохраняют файл в несколько операций
+const devDebounce = 100 * time.Millisecond
+
+// runDev запускает страницу предпросмотра: meme dev [flags] config.yaml image.jpg
+//
+// Страница перерисовывает мем при каждом сохранении файла конфигурации или
+// изображения (изменения приходят через Server-Sent Events) и при вводе
+// подписей в полях страницы. Ошибки конфигурации показываются на странице,
+// сервер при этом продолжает работать.
+func runDev(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("meme dev", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	addr := fs.String("addr", "127.0.0.1:8081", "listen address")
+	top := fs.String("top", "", "initial top caption (default from the config)")
+	bottom := fs.String("bottom", "", "initial bottom caption (default from the config)")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() != 2 {
+		fmt.Fprintln(stderr, "usage: meme dev [flags] config.yaml image.jpg")
+		return exitUsage
+	}
+	d := &devServer{
+		configPath: filepath.Clean(fs.Arg(0)),
+		imagePath:  filepath.Clean(fs.Arg(1)),
+		top:        *top,
+		bottom:     *bottom,
+		clients:    map[chan int]struct{}{},
+	}
+	if _, err := os.Stat(d.imagePath); err != nil {
+		fmt.Fprintf(stderr, "meme: %v\n", err)
+		return exitInput
+	}
+	if d.top == "" && d.bottom == "" {
+		// Начальные подписи - из конфигурации, если она уже разбирается
+		if cfg, err := meme.LoadConfig(d.configPath); err == nil {
+			d.top, d.bottom = cfg.TopText, cfg.BottomText
+		}
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		fmt.Fprintf(stderr, "meme: %v\n", err)
+		return exitError
+	}
+	defer watcher.Close()
+	// Следим за каталогами: многие редакторы сохраняют файл через
+	// переименование, и наблюдение за самим файлом после этого теряется
+	for _, dir := range uniqueDirs(d.configPath, d.imagePath) {
+		if err := watcher.Add(dir); err != nil {
+			fmt.Fprintf(stderr, "meme: %v\n", err)
+			return exitInput
+		}
+	}
+	go d.watch(watcher, stderr)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", d.page)
+	mux.HandleFunc("GET /render", d.render)
+	mux.HandleFunc("GET /events", d.events)
+	srv := &http.Server{Addr: *addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+	fmt.Fprintf(stdout, "previewing %s on http://%s/\n", d.configPath, *addr)
+	select {
+	case err := <-errc:
+		fmt.Fprintf(stderr, "meme: %v\n", err)
+		return exitError
+	case <-ctx.Done():
+	}
+	// Соединения событий бесконечны, поэтому закрываем сервер без ожидания
+	srv.Close()
+	return exitOK
+}
+
+// uniqueDirs возвращает каталоги файлов без повторов
+func uniqueDirs(paths ...string) []string {
+	var dirs []string
+	seen := map[string]bool{}
+	for _, p := range paths {
+		dir := filepath.Dir(p)
+		if abs, err := filepath.Abs(dir); err == nil && !seen[abs] {
+			seen[abs] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// devServer - состояние режима dev
+type devServer struct {
+	configPath, imagePath string
+	top, bottom           string // начальные подписи для полей страницы
+
+	mu      sync.Mutex
+	version int
+	clients map[chan int]struct{}
+}
+
+// watch рассылает событие после изменения конфигурации или изображения
+func (d *devServer) watch(w *fsnotify.Watcher, stderr io.Writer) {
+	var timer *time.Timer
+	for {
+		select {
+		case ev, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			if name := filepath.Clean(ev.Name); name != d.configPath && name != d.imagePath {
+				continue
+			}
+			if ev.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+				continue
+			}
+			if timer == nil {
+				timer = time.AfterFunc(devDebounce, d.changed)
+			} else {
+				timer.Reset(devDebounce)
+			}
+		case err, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+			fmt.Fprintf(stderr, "meme: watch: %v\n", err)
+		}
+	}
+}
+
+// changed увеличивает версию и будит клиентов
+func (d *devServer) changed() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.version++
+	for ch := range d.clients {
+		// Клиенту важна только последняя версия
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- d.version
+	}
+}
+
+// events отдает номера версий как Server-Sent Events
+func (d *devServer) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	ch := make(chan int, 1)
+	d.mu.Lock()
+	d.clients[ch] = struct{}{}
+	d.mu.Unlock()
+	defer func() {
+		d.mu.Lock()
+		delete(d.clients, ch)
+		d.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	// Браузер переподключится сам, если сервер перезапустят
+	io.WriteString(w, "retry: 1000\n\n")
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case v := <-ch:
+			fmt.Fprintf(w, "data: %d\n\n", v)
+			flusher.Flush()
+		}
+	}
+}
+
+// render рисует мем по текущим файлам; подписи из параметров top и bottom
+// заменяют подписи конфигурации
+func (d *devServer) render(w http.ResponseWriter, r *http.Request) {
+	data, err := d.generate(r)
+	if err != nil {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		io.WriteString(w, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Write(data)
+}
+
+func (d *devServer) generate(r *http.Request) ([]byte, error) {
+	// Конфигурация читается заново при каждой отрисовке
+	cfg, err := meme.LoadConfig(d.configPath)
+	if err != nil {
+		return nil, err
+	}
+	q := r.URL.Query()
+	if q.Has("top") {
+		cfg.TopText = q.Get("top")
+	}
+	if q.Has("bottom") {
+		cfg.BottomText = q.Get("bottom")
+	}
+	f, err := os.Open(d.imagePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return meme.NewGenerator(cfg).GenerateBytes(f, &meme.EncodeOptions{Format: meme.FormatPNG})
+}
+
+func (d *devServer) page(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	devPage.Execute(w, struct{ Config, Top, Bottom string }{d.configPath, d.top, d.bottom})
+}
+
+var devPage = template.Must(template.New("dev").Parse(`<!doctype html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>meme dev: {{.Config}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 1.5rem; background: #222; color: #eee; }
+form { display: flex; gap: .5rem; margin-bottom: 1rem; }
+input { flex: 1; font-size: 1rem; padding: .4rem; }
+img { max-width: 100%; display: block; }
+#error { color: #ff6b6b; white-space: pre-wrap; font-family: monospace; }
+#status { color: #888; font-size: .85rem; }
+</style>
+</head>
+<body>
+<form onsubmit="return false">
+<input id="top" placeholder="top caption" value="{{.Top}}">
+<input id="bottom" placeholder="bottom caption" value="{{.Bottom}}">
+</form>
+<div id="status"></div>
+<div id="error"></div>
+<img id="meme" alt="">
+<script>
+const img = document.getElementById("meme");
+const error = document.getElementById("error");
+const status = document.getElementById("status");
+let timer, current;
+
+async function render() {
+  const q = new URLSearchParams({
+    top: document.getElementById("top").value,
+    bottom: document.getElementById("bottom").value,
+  });
+  const started = performance.now();
+  const resp = await fetch("/render?" + q);
+  if (!resp.ok) {
+    error.textContent = await resp.text();
+    return;
+  }
+  error.textContent = "";
+  if (current) URL.revokeObjectURL(current);
+  current = URL.createObjectURL(await resp.blob());
+  img.src = current;
+  status.textContent = "rendered in " + Math.round(performance.now() - started) + " ms at " + new Date().toLocaleTimeString();
+}
+
+function schedule() {
+  clearTimeout(timer);
+  timer = setTimeout(render, 150);
+}
+
+document.getElementById("top").addEventListener("input", schedule);
+document.getElementById("bottom").addEventListener("input", schedule);
+new EventSource("/events").onmessage = render;
+render();
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,252 @@
+// Команда meme создает демотиватор из изображения.
+//
+//	meme -i in.jpg -o out.png --top "Текст" --bottom "Подпись" --theme classic-black
+//	cat in.jpg | meme --top "Текст" > out.png
+//
+// Без -i изображение читается из stdin, без -o результат пишется в stdout.
+// Вместо файла -i принимает http(s)-адрес (см. meme.FetchImage) или
+// объект s3://bucket/key, gs://bucket/key.
+// Формат входа определяется по содержимому, формат выхода - по расширению
+// -o или флагу --format (по умолчанию PNG).
+//
+// С --preview результат выводится прямо в терминал (Kitty, iTerm2 или
+// полублоками ANSI); без -o файл при этом не записывается.
+//
+// Пакетная обработка по манифесту CSV или JSONL:
+//
+//	meme batch -j 8 --name "{basename}_meme.png" jobs.csv
+//
+// Наблюдение за каталогом: новые изображения подписываются и попадают в --out:
+//
+//	meme watch ./incoming --out ./done --top "Текст"
+//
+// Предпросмотр в браузере при правке стиля: страница перерисовывается при
+// каждом сохранении конфигурации:
+//
+//	meme dev style.yaml photo.jpg
+//
+// HTTP API с кешем результатов (см. пакет httpmeme):
+//
+//	meme serve --addr :8080 --theme classic-black --rate 5
+//	MEME_SIGNING_KEY=secret meme sign --url https://example.com/cat.jpg --top "Текст"
+//
+// Сервер инструментов для LLM-агентов (Model Context Protocol, stdio):
+//
+//	meme mcp --theme classic-black
+//
+// Коды выхода:
+//
+//	0 - успех
+//	1 - ошибка генерации или кодирования (в пакетном режиме - хотя бы в одной строке)
+//	2 - неверные аргументы
+//	3 - не удалось прочитать или декодировать вход
+//	4 - не удалось записать результат
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/go-goblin/meme"
+)
+
+// Коды выхода
+const (
+	exitOK     = 0
+	exitError  = 1
+	exitUsage  = 2
+	exitInput  = 3
+	exitOutput = 4
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run разбирает аргументы и выполняет команду; возвращает код выхода
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "batch":
+			return runBatch(args[1:], stdout, stderr)
+		case "watch":
+			return runWatch(args[1:], stdout, stderr)
+		case "dev":
+			return runDev(args[1:], stdout, stderr)
+		case "mcp":
+			return runMCP(args[1:], stdin, stdout, stderr)
+		case "serve":
+			return runServe(args[1:], stdout, stderr)
+		case "sign":
+			return runSign(args[1:], stdout, stderr)
+		}
+	}
+
+	fs := flag.NewFlagSet("meme", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	input := fs.String("i", "", "input image file or http(s) url (default stdin)")
+	output := fs.String("o", "", "output file (default stdout)")
+	top := fs.String("top", "", "top caption")
+	bottom := fs.String("bottom", "", "bottom caption")
+	format := fs.String("format", "", "output format: png, jpeg, webp (default from -o extension)")
+	quality := fs.Int("quality", 0, "JPEG quality 1-100")
+	maxBytes := fs.Int64("max-bytes", 0, "output size limit in bytes: lower quality, then downscale to fit (0 - no limit)")
+	preview := fs.Bool("preview", false, "show the result in the terminal")
+	previewMode := fs.String("preview-mode", "auto", "preview protocol: auto, kitty, iterm, ansi")
+	var style styleFlags
+	style.register(fs)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "meme: unexpected argument %q\n", fs.Arg(0))
+		return exitUsage
+	}
+	protocol, err := previewProtocol(*previewMode)
+	if err != nil {
+		fmt.Fprintf(stderr, "meme: %v\n", err)
+		return exitUsage
+	}
+	if *preview && *output == "-" {
+		fmt.Fprintln(stderr, "meme: --preview cannot be combined with -o -")
+		return exitUsage
+	}
+
+	cfg, err := style.config()
+	if err != nil {
+		fmt.Fprintf(stderr, "meme: %v\n", err)
+		return exitUsage
+	}
+	cfg.TopText, cfg.BottomText = *top, *bottom
+	opts, err := encodeOptions(*format, *output, *quality)
+	if err != nil {
+		fmt.Fprintf(stderr, "meme: %v\n", err)
+		return exitUsage
+	}
+	if *maxBytes < 0 {
+		fmt.Fprintln(stderr, "meme: --max-bytes must not be negative")
+		return exitUsage
+	}
+	opts.MaxOutputBytes = *maxBytes
+
+	in := stdin
+	if *input != "" && *input != "-" {
+		f, err := new(stores).open(context.Background(), *input, cfg.MaxBytes)
+		if err != nil {
+			fmt.Fprintf(stderr, "meme: %v\n", err)
+			return exitInput
+		}
+		defer f.Close()
+		in = f
+	}
+
+	data, err := meme.NewGenerator(cfg).GenerateBytes(in, opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "meme: %v\n", err)
+		return exitCode(err)
+	}
+
+	if *preview {
+		if err := writePreview(stdout, data, protocol); err != nil {
+			fmt.Fprintf(stderr, "meme: %v\n", err)
+			return exitOutput
+		}
+		if *output == "" {
+			return exitOK
+		}
+	}
+	if *output == "" || *output == "-" {
+		if _, err := stdout.Write(data); err != nil {
+			fmt.Fprintf(stderr, "meme: writing output: %v\n", err)
+			return exitOutput
+		}
+		return exitOK
+	}
+	if err := os.WriteFile(*output, data, 0o644); err != nil {
+		fmt.Fprintf(stderr, "meme: %v\n", err)
+		return exitOutput
+	}
+	return exitOK
+}
+
+// styleFlags - флаги оформления, общие для всех режимов
+type styleFlags struct {
+	configPath string
+	theme      string
+	font       string
+	fontSize   float64
+}
+
+func (s *styleFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&s.configPath, "config", "", "JSON or YAML config file")
+	fs.StringVar(&s.theme, "theme", "", "theme name: classic-black, clean-white, vaporwave, newspaper, cvd-dark, cvd-light, high-contrast")
+	fs.StringVar(&s.font, "font", "", "font file or built-in name (regular, bold, smallcaps)")
+	fs.Float64Var(&s.fontSize, "font-size", 0, "font size; disables automatic sizing")
+}
+
+// config собирает конфигурацию: файл, затем тема и флаги поверх него
+func (s *styleFlags) config() (*meme.Config, error) {
+	cfg := meme.DefaultConfig()
+	if s.configPath != "" {
+		var err error
+		if cfg, err = meme.LoadConfig(s.configPath); err != nil {
+			return nil, err
+		}
+	}
+	if s.theme != "" {
+		if err := cfg.ApplyTheme(s.theme); err != nil {
+			return nil, err
+		}
+	}
+	if s.font != "" {
+		if data, ok := meme.GetAvailableFonts()[s.font]; ok {
+			cfg.FontPath, cfg.FontData = "", data
+		} else {
+			cfg.FontPath, cfg.FontData = s.font, nil
+		}
+	}
+	if s.fontSize > 0 {
+		cfg.FontSize, cfg.AutoFontSize = s.fontSize, false
+	}
+	return cfg, nil
+}
+
+// encodeOptions выбирает формат по флагу или расширению выходного файла
+func encodeOptions(format, output string, quality int) (*meme.EncodeOptions, error) {
+	opts := &meme.EncodeOptions{Format: meme.FormatPNG, Quality: quality}
+	switch {
+	case format != "":
+		f, err := meme.ParseFormat(format)
+		if err != nil {
+			return nil, err
+		}
+		opts.Format = f
+	case output != "" && output != "-" && filepath.Ext(output) != "":
+		f, err := meme.ParseFormat(filepath.Ext(output))
+		if err != nil {
+			return nil, err
+		}
+		opts.Format = f
+	}
+	return opts, nil
+}
+
+// exitCode сопоставляет ошибку генерации коду выхода
+func exitCode(err error) int {
+	switch meme.ErrorReason(err) {
+	case "nil_image", "empty_image", "image_too_large", "input_too_large",
+		"unknown_format", "format_not_allowed", "heif_unsupported", "decode_failed":
+		return exitInput
+	case "invalid_config", "text_too_long", "font_not_found", "invalid_font", "output_too_large":
+		return exitUsage
+	}
+	return exitError
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"github.com/go-goblin/meme/mcpmeme"
+)
+
+// runMCP запускает сервер Model Context Protocol на stdin/stdout:
+// meme mcp [flags]. Подключение в клиенте MCP:
+//
+//	{"mcpServers": {"meme": {"command": "meme", "args": ["mcp", "--theme", "classic-black"]}}}
+func runMCP(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("meme mcp", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	maxBytes := fs.Int64("max-bytes", mcpmeme.DefaultMaxBytes, "maximum input image size in bytes")
+	var style styleFlags
+	style.register(fs)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "meme: unexpected argument %q\n", fs.Arg(0))
+		return exitUsage
+	}
+	cfg, err := style.config()
+	if err != nil {
+		fmt.Fprintf(stderr, "meme: %v\n", err)
+		return exitUsage
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	// stdout занят протоколом, поэтому сообщения пишутся только в stderr
+	srv := mcpmeme.New(mcpmeme.Options{Config: cfg, MaxBytes: *maxBytes})
+	if err := srv.Serve(ctx, stdin, stdout); err != nil {
+		fmt.Fprintf(stderr, "meme: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
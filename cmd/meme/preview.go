@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg" // декодирование JPEG для предпросмотра
+	"image/png"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // декодирование WebP для предпросмотра
+)
+
+// Протоколы вывода изображения в терминал
+const (
+	previewKitty = "kitty" // Kitty graphics protocol, также WezTerm и Ghostty
+	previewITerm = "iterm" // встроенные файлы iTerm2 (OSC 1337)
+	previewANSI  = "ansi"  // полублоки с 24-битным цветом, работает почти везде
+)
+
+// kittyChunk - максимальный размер полезной нагрузки одной команды Kitty
+const kittyChunk = 4096
+
+// previewProtocol выбирает протокол: явно заданный или по переменным окружения терминала
+func previewProtocol(mode string) (string, error) {
+	switch mode {
+	case previewKitty, previewITerm, previewANSI:
+		return mode, nil
+	case "", "auto":
+	default:
+		return "", fmt.Errorf("unknown preview mode %q: expected auto, kitty, iterm or ansi", mode)
+	}
+	term, program := os.Getenv("TERM"), os.Getenv("TERM_PROGRAM")
+	switch {
+	case os.Getenv("KITTY_WINDOW_ID") != "", term == "xterm-kitty", term == "xterm-ghostty", program == "ghostty":
+		return previewKitty, nil
+	case program == "iTerm.app", program == "WezTerm", os.Getenv("LC_TERMINAL") == "iTerm2":
+		return previewITerm, nil
+	}
+	return previewANSI, nil
+}
+
+// writePreview выводит закодированное изображение data в терминал
+func writePreview(w io.Writer, data []byte, protocol string) error {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("decoding preview: %w", err)
+	}
+	if protocol == previewANSI {
+		return writeHalfblocks(w, img, terminalColumns())
+	}
+
+	// Графические протоколы принимают PNG; остальные форматы перекодируем,
+	// чтобы в предпросмотре были видны артефакты сжатия результата
+	if !bytes.HasPrefix(data, []byte("\x89PNG")) {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return err
+		}
+		data = buf.Bytes()
+	}
+	payload := base64.StdEncoding.EncodeToString(data)
+	bw := bufio.NewWriter(w)
+	if protocol == previewITerm {
+		fmt.Fprintf(bw, "\x1b]1337;File=inline=1;size=%d;preserveAspectRatio=1:%s\a\n", len(data), payload)
+		return bw.Flush()
+	}
+	// Kitty: передача частями, m=1 - будут еще части
+	for first := true; len(payload) > 0; first = false {
+		n := min(len(payload), kittyChunk)
+		more := 0
+		if n < len(payload) {
+			more = 1
+		}
+		if first {
+			fmt.Fprintf(bw, "\x1b_Ga=T,f=100,m=%d;%s\x1b\\", more, payload[:n])
+		} else {
+			fmt.Fprintf(bw, "\x1b_Gm=%d;%s\x1b\\", more, payload[:n])
+		}
+		payload = payload[n:]
+	}
+	bw.WriteString("\n")
+	return bw.Flush()
+}
+
+// writeHalfblocks рисует изображение символами "▀": цвет символа - верхний
+// пиксель, цвет фона - нижний, так что одна строка терминала несет два ряда
+func writeHalfblocks(w io.Writer, img image.Image, cols int) error {
+	b := img.Bounds()
+	if b.Empty() {
+		return nil
+	}
+	width := min(cols, b.Dx())
+	height := max(b.Dy()*width/b.Dx()/2*2, 2)
+	small := image.NewRGBA(image.Rect(0, 0, width, height))
+	// Прозрачные области показываем на черном, как их видно в большинстве терминалов
+	xdraw.Draw(small, small.Bounds(), image.NewUniform(color.Black), image.Point{}, xdraw.Src)
+	xdraw.ApproxBiLinear.Scale(small, small.Bounds(), img, b, xdraw.Over, nil)
+
+	bw := bufio.NewWriter(w)
+	for y := 0; y < height; y += 2 {
+		for x := range width {
+			t, u := small.RGBAAt(x, y), small.RGBAAt(x, y+1)
+			fmt.Fprintf(bw, "\x1b[38;2;%d;%d;%dm\x1b[48;2;%d;%d;%dm▀", t.R, t.G, t.B, u.R, u.G, u.B)
+		}
+		bw.WriteString("\x1b[0m\n")
+	}
+	return bw.Flush()
+}
+
+// terminalColumns возвращает ширину терминала из $COLUMNS, по умолчанию 80
+func terminalColumns() int {
+	if n, err := strconv.Atoi(strings.TrimSpace(os.Getenv("COLUMNS"))); err == nil && n > 0 {
+		return n
+	}
+	return 80
+}
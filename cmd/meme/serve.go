@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/go-goblin/meme"
+	"github.com/go-goblin/meme/httpmeme"
+	"github.com/go-goblin/meme/limit"
+)
+
+// runServe запускает HTTP API: meme serve [flags]
+//
+//	GET/POST /generate - см. пакет httpmeme
+//	GET      /healthz  - проверка живости, всегда 200
+//	GET      /openapi.json - описание /generate в OpenAPI 3.1
+//	         /editor/  - веб-редактор с предпросмотром (с --editor)
+//
+// С --source (каталог, s3://bucket/prefix или gs://bucket/prefix) фото
+// можно брать из хранилища: GET /generate?key=photos/cat.jpg.
+// Ответы кешируются в памяти (--cache-size) или на диске (--cache-dir) и
+// получают ETag. --rate ограничивает число запросов к /generate с одного IP
+// (или с одним значением заголовка --rate-key-header), --max-concurrent -
+// число одновременных генераций. С --queue запросы сверх очереди сразу
+// получают 503 с Retry-After.
+// Если задана переменная окружения MEME_SIGNING_KEY, принимаются только
+// ссылки, подписанные этим ключом (см. httpmeme.Sign и meme sign).
+func runServe(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("meme serve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	addr := fs.String("addr", ":8080", "listen address")
+	cacheSize := fs.Int64("cache-size", 64, "result cache size in MiB, in memory or in --cache-dir, 0 disables")
+	cacheDir := fs.String("cache-dir", "", "keep cached results in this directory across restarts")
+	rate := fs.Float64("rate", 0, "requests per second allowed per client IP, 0 disables")
+	burst := fs.Int("burst", 10, "requests a client may make at once before --rate applies")
+	keyHeader := fs.String("rate-key-header", "", "identify clients for --rate by this header (e.g. X-API-Key) instead of IP")
+	concurrent := fs.Int("max-concurrent", 0, "maximum simultaneous renders, 0 - unlimited")
+	queue := fs.Int("queue", 0, "renders that may wait for --max-concurrent before requests get 503, 0 - wait until --timeout")
+	maxBytes := fs.Int64("max-bytes", httpmeme.DefaultMaxBytes, "maximum input image size in bytes")
+	timeout := fs.Duration("timeout", httpmeme.DefaultTimeout, "maximum time per request")
+	noURL := fs.Bool("disable-url", false, "disable GET /generate?url=")
+	editor := fs.Bool("editor", false, "serve the web editor at /editor/")
+	source := fs.String("source", "", "serve GET /generate?key= from this directory, s3:// or gs:// prefix")
+	var style styleFlags
+	style.register(fs)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "meme: unexpected argument %q\n", fs.Arg(0))
+		return exitUsage
+	}
+	if *rate < 0 || *burst < 1 || *cacheSize < 0 || *concurrent < 0 || *queue < 0 {
+		fmt.Fprintln(stderr, "meme: --rate, --cache-size, --max-concurrent and --queue must not be negative, --burst must be at least 1")
+		return exitUsage
+	}
+	cfg, err := style.config()
+	if err != nil {
+		fmt.Fprintf(stderr, "meme: %v\n", err)
+		return exitUsage
+	}
+
+	opts := httpmeme.Options{Config: cfg, MaxBytes: *maxBytes, Timeout: *timeout, DisableURL: *noURL}
+	// Ключ берется из окружения, чтобы он не был виден в списке процессов
+	if key := os.Getenv(signingKeyEnv); key != "" {
+		opts.SigningKey = []byte(key)
+	}
+	if *source != "" {
+		var st stores
+		src, err := st.prefix(context.Background(), *source)
+		if err != nil {
+			fmt.Fprintf(stderr, "meme: %v\n", err)
+			return exitUsage
+		}
+		opts.Source = src
+	}
+	switch {
+	case *cacheSize == 0:
+	case *cacheDir != "":
+		c, err := meme.NewDiskCache(*cacheDir, *cacheSize<<20)
+		if err != nil {
+			fmt.Fprintf(stderr, "meme: %v\n", err)
+			return exitOutput
+		}
+		opts.Cache = c
+	default:
+		opts.Cache = meme.NewLRUCache(*cacheSize << 20)
+	}
+	if *rate > 0 {
+		opts.Limiter = limit.NewTokenBucket(*rate, *burst)
+		if *keyHeader != "" {
+			// Заголовок должен проверять прокси перед сервером, иначе клиент
+			// обойдет предел, меняя значение
+			opts.ClientKey = func(r *http.Request) string {
+				if key := r.Header.Get(*keyHeader); key != "" {
+					return "key:" + key
+				}
+				if ip, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+					return ip
+				}
+				return r.RemoteAddr
+			}
+		}
+	}
+	switch {
+	case *queue > 0:
+		// Очередь сама ограничивает число генераций
+		opts.Queue = meme.NewQueue(&meme.QueueOptions{Workers: *concurrent, Length: *queue, Deadline: *timeout})
+	case *concurrent > 0:
+		opts.Concurrency = limit.NewSemaphore(*concurrent)
+	}
+	api := httpmeme.New(opts)
+
+	mux := http.NewServeMux()
+	mux.Handle("/generate", api)
+	mux.Handle("GET /openapi.json", api.OpenAPI("/generate"))
+	if *editor {
+		// Редактор загружает файлы через POST без подписи; GET по-прежнему
+		// проверяет подпись, а загрузка по url и из хранилища отключены,
+		// чтобы редактор не обходил SigningKey. Ограничения общие с /generate
+		eopts := opts
+		eopts.AllowUnsignedUpload = true
+		eopts.DisableURL = true
+		eopts.Source = nil
+		mux.Handle("/editor/", http.StripPrefix("/editor", httpmeme.NewEditor(eopts)))
+	}
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"status":"ok"}`+"\n")
+	})
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+	fmt.Fprintf(stderr, "listening on %s\n", *addr)
+	select {
+	case err := <-errc:
+		fmt.Fprintf(stderr, "meme: %v\n", err)
+		return exitError
+	case <-ctx.Done():
+	}
+	// Даем текущим запросам завершиться
+	shutdown, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdown); err != nil {
+		fmt.Fprintf(stderr, "meme: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// signingKeyEnv - переменная окружения с ключом подписи ссылок
+const signingKeyEnv = "MEME_SIGNING_KEY"
+
+// runSign печатает подписанную ссылку: meme sign --base URL --url IMAGE --top ...
+// Вместо --url можно передать --key для сервера с --source.
+func runSign(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("meme sign", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	base := fs.String("base", "http://localhost:8080/generate", "generate endpoint")
+	image := fs.String("url", "", "image url")
+	object := fs.String("key", "", "image key in the server --source, instead of --url")
+	top := fs.String("top", "", "top caption")
+	bottom := fs.String("bottom", "", "bottom caption")
+	format := fs.String("format", "", "output format")
+	ttl := fs.Duration("ttl", 0, "link lifetime, 0 - never expires")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	key := os.Getenv(signingKeyEnv)
+	if (*image == "") == (*object == "") || key == "" || fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "usage: %s=KEY meme sign --url IMAGE|--key KEY [--top TEXT] [--bottom TEXT]\n", signingKeyEnv)
+		return exitUsage
+	}
+	q := url.Values{"url": {*image}}
+	if *object != "" {
+		q = url.Values{"key": {*object}}
+	}
+	for k, v := range map[string]string{"top": *top, "bottom": *bottom, "format": *format} {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	var expires time.Time
+	if *ttl > 0 {
+		expires = time.Now().Add(*ttl)
+	}
+	fmt.Fprintf(stdout, "%s?%s\n", *base, httpmeme.Sign([]byte(key), q, expires))
+	return exitOK
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	gcs "cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/go-goblin/meme"
+	"github.com/go-goblin/meme/storage"
+	"github.com/go-goblin/meme/storage/gcsstore"
+	"github.com/go-goblin/meme/storage/s3store"
+)
+
+// store - хранилище, из которого можно читать и в которое можно писать
+type store interface {
+	storage.Source
+	storage.Sink
+}
+
+// isObjectURL сообщает, что путь указывает в объектное хранилище
+func isObjectURL(p string) bool {
+	return strings.HasPrefix(p, "s3://") || strings.HasPrefix(p, "gs://")
+}
+
+// isHTTPURL сообщает, что путь - адрес http или https
+func isHTTPURL(p string) bool {
+	return strings.HasPrefix(p, "http://") || strings.HasPrefix(p, "https://")
+}
+
+// joinLocation дополняет относительный путь p базой: каталогом или
+// префиксом s3://bucket/dir, gs://bucket/dir
+func joinLocation(base, p string) string {
+	switch {
+	case isObjectURL(p) || isHTTPURL(p) || filepath.IsAbs(p):
+		return p
+	case isObjectURL(base):
+		return strings.TrimSuffix(base, "/") + "/" + path.Clean(filepath.ToSlash(p))
+	default:
+		return filepath.Join(base, p)
+	}
+}
+
+// stores создает клиенты S3 и GCS при первом обращении и переиспользует
+// их. Учетные данные берутся из окружения так же, как в aws и gcloud.
+type stores struct {
+	s3Once  sync.Once
+	s3      *s3.Client
+	s3Err   error
+	gcsOnce sync.Once
+	gcs     *gcs.Client
+	gcsErr  error
+}
+
+// prefix возвращает хранилище для s3://bucket/prefix, gs://bucket/prefix
+// или каталога. Ключи хранилища - пути относительно prefix.
+func (s *stores) prefix(ctx context.Context, loc string) (store, error) {
+	scheme, rest, ok := strings.Cut(loc, "://")
+	if !ok || !isObjectURL(loc) {
+		return storage.Dir(loc), nil
+	}
+	bucket, prefix, _ := strings.Cut(rest, "/")
+	if bucket == "" {
+		return nil, fmt.Errorf("%s: missing bucket name", loc)
+	}
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	if scheme == "s3" {
+		s.s3Once.Do(func() {
+			cfg, err := config.LoadDefaultConfig(ctx)
+			s.s3, s.s3Err = s3.NewFromConfig(cfg), err
+		})
+		if s.s3Err != nil {
+			return nil, fmt.Errorf("configuring s3: %w", s.s3Err)
+		}
+		return s3store.New(s.s3, bucket, prefix), nil
+	}
+	s.gcsOnce.Do(func() {
+		// Контекст клиента живет дольше одного вызова
+		s.gcs, s.gcsErr = gcs.NewClient(context.WithoutCancel(ctx))
+	})
+	if s.gcsErr != nil {
+		return nil, fmt.Errorf("configuring gcs: %w", s.gcsErr)
+	}
+	return gcsstore.New(s.gcs, bucket, prefix), nil
+}
+
+// file возвращает хранилище и ключ для одного файла или объекта
+func (s *stores) file(ctx context.Context, loc string) (store, string, error) {
+	if !isObjectURL(loc) {
+		return storage.Dir(filepath.Dir(loc)), filepath.Base(loc), nil
+	}
+	i := strings.LastIndex(loc, "/")
+	if strings.Count(loc, "/") < 3 {
+		return nil, "", fmt.Errorf("%s: missing object key", loc)
+	}
+	st, err := s.prefix(ctx, loc[:i])
+	return st, loc[i+1:], err
+}
+
+// open открывает вход: файл, объект s3:// или gs:// либо http(s)-адрес,
+// который загружается с защитой от SSRF (см. meme.FetchImage)
+func (s *stores) open(ctx context.Context, loc string, maxBytes int64) (io.ReadCloser, error) {
+	if isHTTPURL(loc) {
+		return meme.FetchImage(ctx, loc, &meme.FetchOptions{MaxBytes: maxBytes})
+	}
+	if !isObjectURL(loc) {
+		return os.Open(loc)
+	}
+	src, key, err := s.file(ctx, loc)
+	if err != nil {
+		return nil, err
+	}
+	return src.Open(ctx, key)
+}
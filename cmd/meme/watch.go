@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/go-goblin/meme"
+)
+
+// watchExtensions - расширения файлов, которые подхватывает режим watch
+var watchExtensions = map[string]bool{".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".webp": true}
+
+// runWatch следит за каталогом: meme watch [flags] ./incoming --out ./done
+//
+// Каждое новое изображение подписывается и записывается в --out под
+// именем из шаблона --name. Результат сначала пишется во временный файл
+// и затем переименовывается, поэтому программы, читающие --out (например,
+// оверлеи для стримов), никогда не видят недописанных файлов. С --move
+// исходник после обработки переносится в указанный каталог.
+func runWatch(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("meme watch", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	out := fs.String("out", "", "directory for results (required)")
+	move := fs.String("move", "", "move processed originals to this directory")
+	name := fs.String("name", "{basename}_meme.png", "output name template")
+	top := fs.String("top", "", "top caption")
+	bottom := fs.String("bottom", "", "bottom caption")
+	format := fs.String("format", "", "output format (default from --name extension)")
+	quality := fs.Int("quality", 0, "JPEG quality 1-100")
+	settle := fs.Duration("settle", 500*time.Millisecond, "wait this long after the last write before processing a file")
+	existing := fs.Bool("existing", false, "also process images already in the directory")
+	var style styleFlags
+	style.register(fs)
+	// Флаги разрешены и после каталога: meme watch ./incoming --out ./done
+	var dirs []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return exitOK
+			}
+			return exitUsage
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		dirs = append(dirs, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if len(dirs) != 1 || *out == "" {
+		fmt.Fprintln(stderr, "usage: meme watch [flags] DIR --out DIR")
+		return exitUsage
+	}
+	// Результаты в наблюдаемом каталоге снова попадали бы на обработку
+	if same, _ := sameDir(dirs[0], *out); same {
+		fmt.Fprintln(stderr, "meme: --out must differ from the watched directory")
+		return exitUsage
+	}
+	if *settle < 0 {
+		fmt.Fprintln(stderr, "meme: --settle must not be negative")
+		return exitUsage
+	}
+	cfg, err := style.config()
+	if err != nil {
+		fmt.Fprintf(stderr, "meme: %v\n", err)
+		return exitUsage
+	}
+	cfg.TopText, cfg.BottomText = *top, *bottom
+	opts, err := encodeOptions(*format, *name, *quality)
+	if err != nil {
+		fmt.Fprintf(stderr, "meme: %v\n", err)
+		return exitUsage
+	}
+	for _, d := range []string{*out, *move} {
+		if d == "" {
+			continue
+		}
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			fmt.Fprintf(stderr, "meme: %v\n", err)
+			return exitOutput
+		}
+	}
+
+	w := &watcher{
+		g:      meme.NewGenerator(cfg),
+		opts:   opts,
+		out:    *out,
+		move:   *move,
+		name:   *name,
+		stdout: stdout,
+		stderr: stderr,
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := w.run(ctx, dirs[0], *settle, *existing); err != nil {
+		fmt.Fprintf(stderr, "meme: %v\n", err)
+		return exitInput
+	}
+	return exitOK
+}
+
+// watcher обрабатывает файлы, появившиеся в каталоге
+type watcher struct {
+	g      *meme.Generator
+	opts   *meme.EncodeOptions
+	out    string
+	move   string
+	name   string
+	count  int
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// run следит за dir до отмены ctx. Файл обрабатывается, когда в него
+// не писали в течение settle: события о записи приходят частями, и
+// читать файл сразу после создания значит получить его обрезанным.
+func (w *watcher) run(ctx context.Context, dir string, settle time.Duration, existing bool) error {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer fw.Close()
+	if err := fw.Add(dir); err != nil {
+		return err
+	}
+
+	pending := map[string]time.Time{}
+	if existing {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if !e.IsDir() {
+				pending[filepath.Join(dir, e.Name())] = time.Time{}
+			}
+		}
+	}
+	fmt.Fprintf(w.stderr, "watching %s\n", dir)
+
+	tick := time.NewTicker(max(settle/4, 50*time.Millisecond))
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-fw.Errors:
+			fmt.Fprintf(w.stderr, "watch: %v\n", err)
+		case ev := <-fw.Events:
+			switch {
+			case ev.Has(fsnotify.Create), ev.Has(fsnotify.Write):
+				pending[ev.Name] = time.Now()
+			case ev.Has(fsnotify.Remove), ev.Has(fsnotify.Rename):
+				delete(pending, ev.Name)
+			}
+		case now := <-tick.C:
+			for path, last := range pending {
+				if now.Sub(last) < settle {
+					continue
+				}
+				delete(pending, path)
+				w.process(path)
+			}
+		}
+	}
+}
+
+// process подписывает один файл; ошибки выводятся и не прерывают наблюдение
+func (w *watcher) process(path string) {
+	name := filepath.Base(path)
+	if strings.HasPrefix(name, ".") || !watchExtensions[strings.ToLower(filepath.Ext(name))] {
+		return
+	}
+	if fi, err := os.Stat(path); err != nil || !fi.Mode().IsRegular() {
+		return
+	}
+	w.count++
+	output := filepath.Join(w.out, expandName(w.name, job{Input: path, line: w.count}))
+	if err := w.generate(path, output); err != nil {
+		fmt.Fprintf(w.stderr, "%s: %v\n", path, err)
+		return
+	}
+	if w.move != "" {
+		if err := os.Rename(path, filepath.Join(w.move, name)); err != nil {
+			fmt.Fprintf(w.stderr, "%s: %v\n", path, err)
+		}
+	}
+	fmt.Fprintln(w.stdout, output)
+}
+
+// generate пишет результат во временный файл рядом с output и переименовывает его
+func (w *watcher) generate(path, output string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	data, err := w.g.GenerateBytes(f, w.opts)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(output), "."+filepath.Base(output)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), output)
+}
+
+// sameDir сообщает, указывают ли пути на один каталог
+func sameDir(a, b string) (bool, error) {
+	a, err := filepath.Abs(a)
+	if err != nil {
+		return false, err
+	}
+	b, err = filepath.Abs(b)
+	if err != nil {
+		return false, err
+	}
+	return a == b, nil
+}
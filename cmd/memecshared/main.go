@@ -0,0 +1,102 @@
+//go:build cgo
+
+// Команда memecshared собирается в разделяемую библиотеку с C ABI для
+// вызова генератора из Python, Node.js, C# и других языков:
+//
+//	go build -buildmode=c-shared -o libmeme.so ./cmd/memecshared
+//
+// Вместе с библиотекой создается заголовок libmeme.h. Экспортируются:
+//
+//	unsigned char* GenerateMeme(unsigned char* input, size_t inputLen,
+//	                            char* optionsJSON, size_t* outLen, char** errOut);
+//	void MemeFree(void* p);
+//
+// optionsJSON - ключи пакета internal/options (NULL или "" - значения по
+// умолчанию), font_data - файл шрифта в base64. При успехе возвращается
+// буфер с изображением длиной *outLen, при ошибке - NULL, а в *errOut
+// записывается строка "код: сообщение" с кодом из meme.ErrorReason.
+// Буфер и строку ошибки освобождает MemeFree. Функции можно вызывать
+// из нескольких потоков.
+//
+// Пример на Python:
+//
+//	lib = ctypes.CDLL("./libmeme.so")
+//	lib.GenerateMeme.restype = ctypes.c_void_p
+//	n, err = ctypes.c_size_t(), ctypes.c_char_p()
+//	p = lib.GenerateMeme(data, len(data), b'{"top_text": "Hi"}', ctypes.byref(n), ctypes.byref(err))
+//	png = ctypes.string_at(p, n.value)
+//	lib.MemeFree(ctypes.c_void_p(p))
+package main
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"bytes"
+	"fmt"
+	"unsafe"
+
+	"github.com/go-goblin/meme"
+	"github.com/go-goblin/meme/internal/options"
+)
+
+// main не вызывается: пакет собирается только как библиотека
+func main() {}
+
+// GenerateMeme создает мем из изображения input с параметрами optionsJSON
+//
+//export GenerateMeme
+func GenerateMeme(input *C.uchar, inputLen C.size_t, optionsJSON *C.char, outLen *C.size_t, errOut **C.char) *C.uchar {
+	if outLen != nil {
+		*outLen = 0
+	}
+	if errOut != nil {
+		*errOut = nil
+	}
+	var data []byte
+	if input != nil && inputLen > 0 {
+		data = C.GoBytes(unsafe.Pointer(input), C.int(inputLen))
+	}
+	var opts []byte
+	if optionsJSON != nil {
+		opts = []byte(C.GoString(optionsJSON))
+	}
+
+	out, err := generate(data, opts)
+	if err != nil {
+		if errOut != nil {
+			*errOut = C.CString(fmt.Sprintf("%s: %v", meme.ErrorReason(err), err))
+		}
+		return nil
+	}
+	// Буфер выделяется через malloc, чтобы им могла владеть вызывающая сторона
+	buf := C.malloc(C.size_t(len(out)))
+	if buf == nil {
+		if errOut != nil {
+			*errOut = C.CString("other: out of memory")
+		}
+		return nil
+	}
+	copy(unsafe.Slice((*byte)(buf), len(out)), out)
+	if outLen != nil {
+		*outLen = C.size_t(len(out))
+	}
+	return (*C.uchar)(buf)
+}
+
+// MemeFree освобождает буфер или строку ошибки из GenerateMeme
+//
+//export MemeFree
+func MemeFree(p unsafe.Pointer) {
+	C.free(p)
+}
+
+func generate(data, opts []byte) ([]byte, error) {
+	cfg, enc, err := options.Parse(opts)
+	if err != nil {
+		return nil, err
+	}
+	return meme.NewGenerator(cfg).GenerateBytes(bytes.NewReader(data), enc)
+}
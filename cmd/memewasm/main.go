@@ -0,0 +1,113 @@
+//go:build js && wasm
+
+// Команда memewasm - сборка генератора для браузера и Node.js. Она
+// регистрирует глобальную функцию memeGenerate, а meme.js оборачивает ее
+// в модуль с функцией generate(imageBytes, options) -> Promise<Uint8Array>:
+//
+//	GOOS=js GOARCH=wasm go build -o meme.wasm ./cmd/memewasm
+//	cp "$(go env GOROOT)/lib/wasm/wasm_exec.js" .
+//
+//	import { load } from "./meme.js";
+//	const meme = await load("meme.wasm");
+//	const png = await meme.generate(bytes, { top_text: "Текст", theme: "vaporwave" });
+//
+// options - ключи пакета internal/options, но font_data - Uint8Array с
+// файлом шрифта. Файлов в wasm нет, поэтому font - только имя встроенного.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"syscall/js"
+
+	"github.com/go-goblin/meme"
+	"github.com/go-goblin/meme/internal/options"
+)
+
+func main() {
+	js.Global().Set("memeGenerate", js.FuncOf(generate))
+	// Функции вызываются из JS, пока программа жива
+	select {}
+}
+
+// generate(imageBytes, options) возвращает Promise с байтами изображения.
+// Генерация идет в отдельной горутине, чтобы не блокировать вызов из JS.
+func generate(_ js.Value, args []js.Value) any {
+	var input, opts js.Value
+	if len(args) > 0 {
+		input = args[0]
+	}
+	if len(args) > 1 {
+		opts = args[1]
+	}
+	return promise(func() (js.Value, error) {
+		if input.Type() != js.TypeObject || !input.InstanceOf(js.Global().Get("Uint8Array")) {
+			return js.Undefined(), errors.New("image must be a Uint8Array")
+		}
+		data := make([]byte, input.Length())
+		js.CopyBytesToGo(data, input)
+
+		cfg, enc, err := config(opts)
+		if err != nil {
+			return js.Undefined(), err
+		}
+		out, err := meme.NewGenerator(cfg).GenerateBytes(bytes.NewReader(data), enc)
+		if err != nil {
+			return js.Undefined(), err
+		}
+		result := js.Global().Get("Uint8Array").New(len(out))
+		js.CopyBytesToJS(result, out)
+		return result, nil
+	})
+}
+
+// config собирает конфигурацию и параметры кодирования из объекта options
+func config(opts js.Value) (*meme.Config, *meme.EncodeOptions, error) {
+	if opts.IsUndefined() || opts.IsNull() {
+		return options.Parse(nil)
+	}
+	if opts.Type() != js.TypeObject {
+		return nil, nil, errors.New("options must be an object")
+	}
+	// font_data передается как Uint8Array, а не base64: копируем отдельно
+	var fontData []byte
+	if fd := opts.Get("font_data"); fd.Truthy() {
+		if !fd.InstanceOf(js.Global().Get("Uint8Array")) {
+			return nil, nil, errors.New("font_data must be a Uint8Array")
+		}
+		fontData = make([]byte, fd.Length())
+		js.CopyBytesToGo(fontData, fd)
+	}
+	object := js.Global().Get("Object")
+	rest := object.Call("assign", object.New(), opts)
+	rest.Delete("font_data")
+	cfg, enc, err := options.Parse([]byte(js.Global().Get("JSON").Call("stringify", rest).String()))
+	if err != nil {
+		return nil, nil, err
+	}
+	if fontData != nil {
+		cfg.FontPath, cfg.FontData = "", fontData
+	}
+	return cfg, enc, nil
+}
+
+// promise возвращает Promise, который выполняется результатом fn
+func promise(fn func() (js.Value, error)) js.Value {
+	var executor js.Func
+	executor = js.FuncOf(func(_ js.Value, args []js.Value) any {
+		resolve, reject := args[0], args[1]
+		executor.Release()
+		go func() {
+			v, err := fn()
+			if err != nil {
+				e := js.Global().Get("Error").New(err.Error())
+				e.Set("reason", meme.ErrorReason(err))
+				reject.Invoke(e)
+				return
+			}
+			resolve.Invoke(v)
+		}()
+		return nil
+	})
+	return js.Global().Get("Promise").New(executor)
+}
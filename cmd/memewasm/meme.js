@@ -0,0 +1,46 @@
+// Обертка над meme.wasm для браузера и Node.js. Перед загрузкой модуля
+// должен быть подключен wasm_exec.js из поставки Go (globalThis.Go).
+//
+//	const meme = await load("meme.wasm");
+//	const png = await meme.generate(bytes, { top_text: "Текст" });
+//
+// Ошибки генерации приходят как Error с полем reason - кодом из
+// meme.ErrorReason ("text_too_long", "unknown_format", ...).
+
+let ready;
+
+// load загружает и запускает модуль; повторные вызовы возвращают тот же.
+// source - URL, Response, ArrayBuffer или Uint8Array с содержимым meme.wasm.
+export function load(source) {
+  if (!ready) {
+    ready = instantiate(source).catch((err) => {
+      ready = undefined;
+      throw err;
+    });
+  }
+  return ready;
+}
+
+async function instantiate(source) {
+  const go = new globalThis.Go();
+  let result;
+  if (source instanceof ArrayBuffer || ArrayBuffer.isView(source)) {
+    result = await WebAssembly.instantiate(source, go.importObject);
+  } else {
+    const response = source instanceof Response ? source : fetch(source);
+    result = await WebAssembly.instantiateStreaming(response, go.importObject);
+  }
+  // run завершается только вместе с программой, поэтому не ждем его
+  go.run(result.instance);
+  return { generate };
+}
+
+// generate создает мем и возвращает Promise<Uint8Array> с файлом
+// изображения; options - поля файла конфигурации, format, quality, font_data
+export function generate(imageBytes, options = {}) {
+  if (typeof globalThis.memeGenerate !== "function") {
+    return Promise.reject(new Error("meme.wasm is not loaded, call load() first"));
+  }
+  const bytes = imageBytes instanceof Uint8Array ? imageBytes : new Uint8Array(imageBytes);
+  return globalThis.memeGenerate(bytes, options);
+}
@@ -0,0 +1,250 @@
+package meme
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"time"
+)
+
+// CollageStrategy - способ раскладки фото коллажа
+type CollageStrategy int
+
+const (
+	// CollageRows - ряды во всю ширину: фото в ряду одной высоты и не
+	// обрезаются, число рядов подбирается под высоту коллажа
+	CollageRows CollageStrategy = iota
+	// CollageGrid - одинаковые ячейки с обрезкой фото; неполный последний
+	// ряд делится между оставшимися фото
+	CollageGrid
+	// CollageMosaic - прямоугольник делится пополам по длинной стороне
+	// пропорционально форме фото каждой половины, пока не останется по одному
+	CollageMosaic
+)
+
+// CollageOptions задает размер и раскладку коллажа
+type CollageOptions struct {
+	Width, Height int // размер коллажа, 0 - 1200 в ширину и 3/4 ширины в высоту
+
+	Strategy   CollageStrategy
+	Gutter     int         // промежуток между фото и краями
+	Background color.Color // цвет промежутков, nil - BackgroundColor генератора
+
+	// Frame - обернуть коллаж в демотиватор с подписями и рамкой генератора
+	Frame bool
+}
+
+// Размер коллажа по умолчанию
+const defaultCollageWidth = 1200
+
+// Collage раскладывает фото любых пропорций по прямоугольнику
+// opts.Width x opts.Height в порядке следования
+func (g *Generator) Collage(imgs []image.Image, opts CollageOptions) (out *image.RGBA, err error) {
+	defer g.observeGeneration(time.Now(), &err)
+	defer recoverPanic(&err)
+	cfg := g.config
+	if len(imgs) == 0 {
+		return nil, &ConfigError{Field: "images", Reason: "must not be empty"}
+	}
+	for _, img := range imgs {
+		if err := g.validateInput(img); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Width < 0 || opts.Height < 0 || opts.Gutter < 0 {
+		return nil, &ConfigError{Field: "CollageOptions", Reason: "sizes must not be negative"}
+	}
+	w := opts.Width
+	if w == 0 {
+		w = defaultCollageWidth
+	}
+	h := opts.Height
+	if h == 0 {
+		h = w * 3 / 4
+	}
+	canvas := image.Rect(0, 0, w, h)
+	if err := checkCanvas(canvas); err != nil {
+		return nil, err
+	}
+	area := canvas.Inset(opts.Gutter)
+	if area.Empty() {
+		return nil, &ConfigError{Field: "CollageOptions.Gutter", Reason: "leaves no room for images"}
+	}
+
+	aspects := make([]float64, len(imgs))
+	for i, img := range imgs {
+		b := img.Bounds()
+		aspects[i] = float64(b.Dx()) / float64(max(b.Dy(), 1))
+	}
+	var cells []image.Rectangle
+	switch opts.Strategy {
+	case CollageRows:
+		cells = collageRows(area, aspects, opts.Gutter)
+	case CollageGrid:
+		cells = collageGrid(area, len(imgs), opts.Gutter)
+	case CollageMosaic:
+		cells = make([]image.Rectangle, len(imgs))
+		collageMosaic(cells, area, aspects, opts.Gutter)
+	default:
+		return nil, &ConfigError{Field: "CollageOptions.Strategy", Reason: "unknown strategy"}
+	}
+
+	bg := opts.Background
+	if bg == nil {
+		bg = cfg.BackgroundColor
+	}
+	out = image.NewRGBA(canvas)
+	draw.Draw(out, canvas, image.NewUniform(bg), image.Point{}, draw.Src)
+	for i, img := range imgs {
+		drawCover(out, cells[i], img)
+	}
+	if !opts.Frame {
+		return out, nil
+	}
+	return g.generateInto(nil, out)
+}
+
+// collageRows делит фото по порядку на ряды во всю ширину area. Из всех
+// чисел рядов выбирается то, при котором высота ближе всего к area; остаток
+// высоты распределяется между рядами с небольшой обрезкой.
+func collageRows(area image.Rectangle, aspects []float64, gutter int) []image.Rectangle {
+	var best [][]int
+	bestDiff := math.Inf(1)
+	for rows := 1; rows <= len(aspects); rows++ {
+		split := splitBalanced(aspects, rows)
+		height := float64(gutter * (len(split) - 1))
+		for _, row := range split {
+			height += rowHeight(area.Dx(), aspects, row, gutter)
+		}
+		if diff := math.Abs(height - float64(area.Dy())); diff < bestDiff {
+			best, bestDiff = split, diff
+		}
+	}
+
+	// Высоты рядов пропорциональны естественным и в сумме дают area
+	natural := make([]float64, len(best))
+	var total float64
+	for i, row := range best {
+		natural[i] = rowHeight(area.Dx(), aspects, row, gutter)
+		total += natural[i]
+	}
+	free := float64(area.Dy() - gutter*(len(best)-1))
+	cells := make([]image.Rectangle, 0, len(aspects))
+	y := float64(area.Min.Y)
+	for i, row := range best {
+		top, bottom := int(math.Round(y)), int(math.Round(y+natural[i]*free/total))
+		if i == len(best)-1 {
+			bottom = area.Max.Y
+		}
+		var sum float64
+		for _, j := range row {
+			sum += aspects[j]
+		}
+		width := float64(area.Dx() - gutter*(len(row)-1))
+		x := float64(area.Min.X)
+		for k, j := range row {
+			left, right := int(math.Round(x)), int(math.Round(x+width*aspects[j]/sum))
+			if k == len(row)-1 {
+				right = area.Max.X
+			}
+			cells = append(cells, image.Rect(left, top, right, bottom))
+			x += width*aspects[j]/sum + float64(gutter)
+		}
+		y += natural[i]*free/total + float64(gutter)
+	}
+	return cells
+}
+
+// rowHeight - высота ряда шириной width, в котором фото не обрезаются
+func rowHeight(width int, aspects []float64, row []int, gutter int) float64 {
+	var sum float64
+	for _, j := range row {
+		sum += aspects[j]
+	}
+	return float64(width-gutter*(len(row)-1)) / sum
+}
+
+// splitBalanced делит индексы aspects по порядку на rows групп с близкими
+// суммами
+func splitBalanced(aspects []float64, rows int) [][]int {
+	var total float64
+	for _, a := range aspects {
+		total += a
+	}
+	groups := make([][]int, 0, rows)
+	var row []int
+	var acc float64
+	for i, a := range aspects {
+		// Фото уходит в следующий ряд, если без него ряд ближе к своей доле
+		target := total * float64(len(groups)+1) / float64(rows)
+		left := len(aspects) - i
+		need := rows - len(groups) - 1
+		if len(row) > 0 && (math.Abs(acc-target) < math.Abs(acc+a-target) || left <= need) {
+			groups = append(groups, row)
+			row = nil
+		}
+		row = append(row, i)
+		acc += a
+	}
+	return append(groups, row)
+}
+
+// collageGrid делит area на почти квадратную сетку одинаковых ячеек
+func collageGrid(area image.Rectangle, n, gutter int) []image.Rectangle {
+	cols := max(int(math.Ceil(math.Sqrt(float64(n)*float64(area.Dx())/float64(area.Dy())))), 1)
+	cols = min(cols, n)
+	rows := (n + cols - 1) / cols
+	cellH := (area.Dy() - gutter*(rows-1)) / rows
+	cells := make([]image.Rectangle, 0, n)
+	for row := range rows {
+		inRow := min(cols, n-row*cols)
+		cellW := (area.Dx() - gutter*(inRow-1)) / inRow
+		for col := range inRow {
+			origin := area.Min.Add(image.Pt(col*(cellW+gutter), row*(cellH+gutter)))
+			cells = append(cells, image.Rectangle{Min: origin, Max: origin.Add(image.Pt(cellW, cellH))})
+		}
+	}
+	return cells
+}
+
+// collageMosaic заполняет cells делением r: фото пополам, прямоугольник -
+// по длинной стороне в отношении форм половин
+func collageMosaic(cells []image.Rectangle, r image.Rectangle, aspects []float64, gutter int) {
+	if len(aspects) == 1 {
+		cells[0] = r
+		return
+	}
+	mid := len(aspects) / 2
+	// Форма группы: рядом - пропорции складываются, друг над другом -
+	// складываются обратные
+	var a, b float64
+	if r.Dx() >= r.Dy() {
+		a, b = groupAspect(aspects[:mid], false), groupAspect(aspects[mid:], false)
+		split := r.Min.X + int(float64(r.Dx()-gutter)*a/(a+b))
+		collageMosaic(cells[:mid], image.Rect(r.Min.X, r.Min.Y, split, r.Max.Y), aspects[:mid], gutter)
+		collageMosaic(cells[mid:], image.Rect(split+gutter, r.Min.Y, r.Max.X, r.Max.Y), aspects[mid:], gutter)
+		return
+	}
+	a, b = 1/groupAspect(aspects[:mid], true), 1/groupAspect(aspects[mid:], true)
+	split := r.Min.Y + int(float64(r.Dy()-gutter)*a/(a+b))
+	collageMosaic(cells[:mid], image.Rect(r.Min.X, r.Min.Y, r.Max.X, split), aspects[:mid], gutter)
+	collageMosaic(cells[mid:], image.Rect(r.Min.X, split+gutter, r.Max.X, r.Max.Y), aspects[mid:], gutter)
+}
+
+// groupAspect - пропорции группы фото, уложенных рядом (stacked - друг над
+// другом)
+func groupAspect(aspects []float64, stacked bool) float64 {
+	var sum float64
+	for _, a := range aspects {
+		if stacked {
+			sum += 1 / a
+		} else {
+			sum += a
+		}
+	}
+	if stacked {
+		return 1 / sum
+	}
+	return sum
+}
@@ -0,0 +1,40 @@
+package meme
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Наибольшее число диакритических знаков подряд у одной буквы. В живых
+// языках их не больше двух-трех (вьетнамский, деванагари), длинные стопки
+// - "zalgo", которые залезают на соседние строки и фото.
+const maxCombiningMarks = 4
+
+// isCombining сообщает, что r - комбинируемый знак
+func isCombining(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me)
+}
+
+// hasCombining сообщает, что в s есть комбинируемые знаки
+func hasCombining(s string) bool {
+	return strings.IndexFunc(s, isCombining) >= 0
+}
+
+// clampCombining отбрасывает знаки сверх maxCombiningMarks у каждой буквы
+func clampCombining(s string) string {
+	if !hasCombining(s) {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	n := 0
+	for _, r := range s {
+		if !isCombining(r) {
+			n = 0
+		} else if n++; n > maxCombiningMarks {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,93 @@
+package meme
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// drawSource вставляет исходное изображение в холст.
+// Стандартный image/draw ускоряет YCbCr 4:4:4, 4:2:2, 4:2:0 и 4:4:0, но
+// 4:1:1, 4:1:0 и CMYK проводит через медленный At: для них используются
+// прямые циклы по пикселям, а остальное рисуется как обычно.
+func drawSource(dst draw.Image, r image.Rectangle, src image.Image, sp image.Point) {
+	rgba, ok := dst.(*image.RGBA)
+	if !ok {
+		draw.Draw(dst, r, src, sp, draw.Over)
+		return
+	}
+
+	clipped := r.Intersect(rgba.Bounds())
+	if clipped.Empty() {
+		return
+	}
+	sp = sp.Add(clipped.Min.Sub(r.Min))
+	r = clipped
+
+	// Проверяем, что исходная область целиком лежит внутри src
+	if !r.Sub(r.Min).Add(sp).In(src.Bounds()) {
+		draw.Draw(rgba, r, src, sp, draw.Over)
+		return
+	}
+
+	switch s := src.(type) {
+	case *image.YCbCr:
+		if s.SubsampleRatio == image.YCbCrSubsampleRatio411 || s.SubsampleRatio == image.YCbCrSubsampleRatio410 {
+			drawYCbCr(rgba, r, s, sp)
+		} else {
+			draw.Draw(rgba, r, src, sp, draw.Over)
+		}
+	case *image.CMYK:
+		drawCMYK(rgba, r, s, sp)
+	default:
+		draw.Draw(rgba, r, src, sp, draw.Over)
+	}
+}
+
+// drawYCbCr переводит YCbCr в RGBA для любой схемы субдискретизации;
+// для схем, которые ускоряет image/draw, он медленнее draw.Draw
+func drawYCbCr(dst *image.RGBA, r image.Rectangle, src *image.YCbCr, sp image.Point) {
+	for y := 0; y < r.Dy(); y++ {
+		d := dst.PixOffset(r.Min.X, r.Min.Y+y)
+		sy := sp.Y + y
+		for x := 0; x < r.Dx(); x++ {
+			sx := sp.X + x
+			yy := src.Y[src.YOffset(sx, sy)]
+			ci := src.COffset(sx, sy)
+			red, green, blue := color.YCbCrToRGB(yy, src.Cb[ci], src.Cr[ci])
+			dst.Pix[d+0] = red
+			dst.Pix[d+1] = green
+			dst.Pix[d+2] = blue
+			dst.Pix[d+3] = 0xff
+			d += 4
+		}
+	}
+}
+
+// drawCMYK переводит CMYK в RGBA
+func drawCMYK(dst *image.RGBA, r image.Rectangle, src *image.CMYK, sp image.Point) {
+	for y := 0; y < r.Dy(); y++ {
+		d := dst.PixOffset(r.Min.X, r.Min.Y+y)
+		s := src.PixOffset(sp.X, sp.Y+y)
+		for x := 0; x < r.Dx(); x++ {
+			red, green, blue := color.CMYKToRGB(src.Pix[s], src.Pix[s+1], src.Pix[s+2], src.Pix[s+3])
+			dst.Pix[d+0] = red
+			dst.Pix[d+1] = green
+			dst.Pix[d+2] = blue
+			dst.Pix[d+3] = 0xff
+			d += 4
+			s += 4
+		}
+	}
+}
+
+// toRGBA приводит изображение к *image.RGBA с началом координат в нуле
+func toRGBA(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	if rgba, ok := img.(*image.RGBA); ok && b.Min == (image.Point{}) {
+		return rgba
+	}
+	out := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	drawSource(out, out.Bounds(), img, b.Min)
+	return out
+}
@@ -0,0 +1,76 @@
+package meme
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+// testYCbCr создает YCbCr с градиентом во всех плоскостях
+func testYCbCr(w, h int, ratio image.YCbCrSubsampleRatio) *image.YCbCr {
+	img := image.NewYCbCr(image.Rect(0, 0, w, h), ratio)
+	for i := range img.Y {
+		img.Y[i] = uint8(i * 7)
+	}
+	for i := range img.Cb {
+		img.Cb[i] = uint8(i * 3)
+		img.Cr[i] = uint8(255 - i*5)
+	}
+	return img
+}
+
+func TestDrawSourceYCbCr(t *testing.T) {
+	for _, ratio := range []image.YCbCrSubsampleRatio{
+		image.YCbCrSubsampleRatio444,
+		image.YCbCrSubsampleRatio422,
+		image.YCbCrSubsampleRatio420,
+		image.YCbCrSubsampleRatio440,
+		image.YCbCrSubsampleRatio411,
+		image.YCbCrSubsampleRatio410,
+	} {
+		src := testYCbCr(37, 23, ratio)
+		got := image.NewRGBA(image.Rect(0, 0, 40, 30))
+		drawSource(got, image.Rect(2, 3, 39, 26), src, image.Point{})
+		for y := 3; y < 26; y++ {
+			for x := 2; x < 39; x++ {
+				yc := src.YCbCrAt(x-2, y-3)
+				r, g, b := color.YCbCrToRGB(yc.Y, yc.Cb, yc.Cr)
+				want := color.RGBA{r, g, b, 0xff}
+				if c := got.RGBAAt(x, y); c != want {
+					t.Fatalf("%v: pixel (%d,%d) = %v, want %v", ratio, x, y, c, want)
+				}
+			}
+		}
+	}
+}
+
+// BenchmarkDrawSourceYCbCr сравнивает drawSource с прямым циклом drawYCbCr
+// на фото 2000x1500: для 4:2:0 быстрее image/draw, для 4:1:1 - цикл
+func BenchmarkDrawSourceYCbCr(b *testing.B) {
+	for _, bc := range []struct {
+		name  string
+		ratio image.YCbCrSubsampleRatio
+	}{
+		{"420", image.YCbCrSubsampleRatio420},
+		{"411", image.YCbCrSubsampleRatio411},
+	} {
+		src := testYCbCr(2000, 1500, bc.ratio)
+		dst := image.NewRGBA(src.Rect)
+		b.Run(bc.name+"/drawSource", func(b *testing.B) {
+			for range b.N {
+				drawSource(dst, dst.Rect, src, image.Point{})
+			}
+		})
+		b.Run(bc.name+"/drawYCbCr", func(b *testing.B) {
+			for range b.N {
+				drawYCbCr(dst, dst.Rect, src, image.Point{})
+			}
+		})
+		b.Run(bc.name+"/draw.Draw", func(b *testing.B) {
+			for range b.N {
+				draw.Draw(dst, dst.Rect, src, image.Point{}, draw.Over)
+			}
+		})
+	}
+}
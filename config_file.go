@@ -0,0 +1,386 @@
+package meme
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Конфигурация в файле JSON или YAML. Цвета задаются строками "#rrggbb",
+// шрифт - именем встроенного (см. GetAvailableFonts) или путем к файлу.
+//
+//	theme: vaporwave
+//	font: bold
+//	padding: 60
+//	text_color: "#ffee00"
+//	max_pixels: 20000000
+type configFile struct {
+	TopText    string `json:"top_text,omitempty" yaml:"top_text,omitempty"`
+	BottomText string `json:"bottom_text,omitempty" yaml:"bottom_text,omitempty"`
+	Theme      string `json:"theme,omitempty" yaml:"theme,omitempty"`
+
+	Font     string  `json:"font,omitempty" yaml:"font,omitempty"`
+	FontSize float64 `json:"font_size" yaml:"font_size"`
+
+	Padding int `json:"padding" yaml:"padding"`
+	Border  int `json:"border" yaml:"border"`
+
+	BackgroundColor  string `json:"background_color,omitempty" yaml:"background_color,omitempty"`
+	BorderColor      string `json:"border_color,omitempty" yaml:"border_color,omitempty"`
+	TextColor        string `json:"text_color,omitempty" yaml:"text_color,omitempty"`
+	TextOutlineColor string `json:"text_outline_color,omitempty" yaml:"text_outline_color,omitempty"`
+	TextOutlineWidth int    `json:"text_outline_width" yaml:"text_outline_width"`
+
+	TextUppercase       bool     `json:"text_uppercase" yaml:"text_uppercase"`
+	TopCase             TextCase `json:"top_case,omitempty" yaml:"top_case,omitempty"`
+	BottomCase          TextCase `json:"bottom_case,omitempty" yaml:"bottom_case,omitempty"`
+	TopMaxWidthRatio    float64  `json:"top_max_width_ratio,omitempty" yaml:"top_max_width_ratio,omitempty"`
+	BottomMaxWidthRatio float64  `json:"bottom_max_width_ratio,omitempty" yaml:"bottom_max_width_ratio,omitempty"`
+	LinearBlending      bool     `json:"linear_blending,omitempty" yaml:"linear_blending,omitempty"`
+	SubpixelText        bool     `json:"subpixel_text,omitempty" yaml:"subpixel_text,omitempty"`
+	TextHollow          bool     `json:"text_hollow,omitempty" yaml:"text_hollow,omitempty"`
+
+	TextExtrude  *extrudeFile `json:"text_extrude,omitempty" yaml:"text_extrude,omitempty"`
+	TextGlow     *glowFile    `json:"text_glow,omitempty" yaml:"text_glow,omitempty"`
+	TextJitter   *jitterFile  `json:"text_jitter,omitempty" yaml:"text_jitter,omitempty"`
+	TextWave     *waveFile    `json:"text_wave,omitempty" yaml:"text_wave,omitempty"`
+	AutoFontSize bool         `json:"auto_font_size" yaml:"auto_font_size"`
+
+	SafeArea *safeAreaFile `json:"safe_area,omitempty" yaml:"safe_area,omitempty"`
+
+	AutoOrient      bool `json:"auto_orient" yaml:"auto_orient"`
+	ColorManagement bool `json:"color_management" yaml:"color_management"`
+
+	Filters []FilterSpec `json:"filters,omitempty" yaml:"filters,omitempty"`
+
+	MaxPixels     int   `json:"max_pixels" yaml:"max_pixels"`
+	MaxBytes      int64 `json:"max_bytes" yaml:"max_bytes"`
+	MaxTextLength int   `json:"max_text_length" yaml:"max_text_length"`
+
+	ParallelRender bool  `json:"parallel_render" yaml:"parallel_render"`
+	Deterministic  bool  `json:"deterministic" yaml:"deterministic"`
+	Seed           int64 `json:"seed" yaml:"seed"`
+	Debug          bool  `json:"debug" yaml:"debug"`
+}
+
+// safeAreaFile - SafeArea в файле: поля платформы по имени и/или явно,
+// safe_area: {preset: tiktok} или safe_area: {bottom: 0.2, right: 0.1}.
+// Явно заданные поля заменяют поля платформы.
+type safeAreaFile struct {
+	Preset string  `json:"preset,omitempty" yaml:"preset,omitempty"`
+	Top    float64 `json:"top,omitempty" yaml:"top,omitempty"`
+	Bottom float64 `json:"bottom,omitempty" yaml:"bottom,omitempty"`
+	Left   float64 `json:"left,omitempty" yaml:"left,omitempty"`
+	Right  float64 `json:"right,omitempty" yaml:"right,omitempty"`
+}
+
+// jitterFile - Jitter в файле: text_jitter: {offset: 2, rotation: 8}
+type jitterFile struct {
+	Offset   float64 `json:"offset,omitempty" yaml:"offset,omitempty"`
+	Rotation float64 `json:"rotation,omitempty" yaml:"rotation,omitempty"`
+}
+
+// waveFile - Wave в файле: text_wave: {amplitude: 6, wavelength: 200}
+type waveFile struct {
+	Amplitude  float64 `json:"amplitude" yaml:"amplitude"`
+	Wavelength float64 `json:"wavelength,omitempty" yaml:"wavelength,omitempty"`
+}
+
+// glowFile - Glow в файле: text_glow: {radius: 12, color: "#00ffff", intensity: 1.5}
+type glowFile struct {
+	Radius    int     `json:"radius" yaml:"radius"`
+	Color     string  `json:"color,omitempty" yaml:"color,omitempty"`
+	Intensity float64 `json:"intensity,omitempty" yaml:"intensity,omitempty"`
+}
+
+// extrudeFile - Extrude в файле: text_extrude: {depth: 12, dx: 1, dy: 1, color: "#ff00aa"}
+type extrudeFile struct {
+	Depth    int    `json:"depth" yaml:"depth"`
+	DX       int    `json:"dx,omitempty" yaml:"dx,omitempty"`
+	DY       int    `json:"dy,omitempty" yaml:"dy,omitempty"`
+	Color    string `json:"color,omitempty" yaml:"color,omitempty"`
+	FarColor string `json:"far_color,omitempty" yaml:"far_color,omitempty"`
+}
+
+// rawConfig - Config без собственной сериализации (для ключа кеша)
+type rawConfig Config
+
+// LoadConfig читает конфигурацию из файла .json, .yaml или .yml.
+// Незаданные в файле поля берутся из DefaultConfig, а поля оформления при
+// выбранной в файле теме - из темы; неизвестные поля - ошибка.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+	cfg := DefaultConfig()
+	f, _ := cfg.file()
+	font := f.Font
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&f)
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(&f)
+	default:
+		return nil, fmt.Errorf("unsupported config extension %q", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+	var keys map[string]any
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		err = json.Unmarshal(data, &keys)
+	} else {
+		err = yaml.Unmarshal(data, &keys)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+	if err := cfg.applyFile(f, font, keys); err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// applyFile переносит прочитанный файл f в конфигурацию; keys - ключи,
+// которые есть в файле, font - шрифт до чтения. Если файл выбирает тему,
+// не заданные в нем поля оформления сбрасываются, и их заполняет тема,
+// как в ThemedConfig.
+func (c *Config) applyFile(f configFile, font string, keys map[string]any) error {
+	if err := c.apply(f, f.Font != font); err != nil {
+		return err
+	}
+	if _, ok := keys["theme"]; ok && c.Theme != "" {
+		keep := make(map[string]bool, len(keys))
+		for k := range keys {
+			keep[k] = true
+		}
+		c.resetThemeStyle(keep)
+	}
+	return nil
+}
+
+// MarshalJSON записывает конфигурацию с цветами в виде "#rrggbb".
+// FontData, не совпадающие со встроенным шрифтом, сериализовать нельзя.
+func (c Config) MarshalJSON() ([]byte, error) {
+	f, err := c.file()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(f)
+}
+
+// UnmarshalJSON читает конфигурацию поверх текущих значений:
+// отсутствующие в JSON поля не меняются, а если JSON выбирает тему -
+// поля оформления берутся из нее
+func (c *Config) UnmarshalJSON(data []byte) error {
+	f, _ := c.file()
+	font := f.Font
+	if err := json.Unmarshal(data, &f); err != nil {
+		return err
+	}
+	var keys map[string]any
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return err
+	}
+	return c.applyFile(f, font, keys)
+}
+
+// MarshalYAML - аналог MarshalJSON для gopkg.in/yaml.v3
+func (c Config) MarshalYAML() (any, error) {
+	return c.file()
+}
+
+// UnmarshalYAML - аналог UnmarshalJSON для gopkg.in/yaml.v3
+func (c *Config) UnmarshalYAML(node *yaml.Node) error {
+	f, _ := c.file()
+	font := f.Font
+	if err := node.Decode(&f); err != nil {
+		return err
+	}
+	var keys map[string]any
+	if err := node.Decode(&keys); err != nil {
+		return err
+	}
+	return c.applyFile(f, font, keys)
+}
+
+// file переводит конфигурацию в файловое представление. Ошибка возвращается
+// только для FontData вне встроенных шрифтов; остальные поля заполняются всегда.
+func (c *Config) file() (configFile, error) {
+	f := configFile{
+		TopText:             c.TopText,
+		BottomText:          c.BottomText,
+		Theme:               c.Theme,
+		Font:                c.FontPath,
+		FontSize:            c.FontSize,
+		Padding:             c.Padding,
+		Border:              c.Border,
+		TextOutlineWidth:    c.TextOutlineWidth,
+		TextUppercase:       c.TextUppercase,
+		TopCase:             c.TopCase,
+		BottomCase:          c.BottomCase,
+		TopMaxWidthRatio:    c.TopMaxWidthRatio,
+		BottomMaxWidthRatio: c.BottomMaxWidthRatio,
+		LinearBlending:      c.LinearBlending,
+		SubpixelText:        c.SubpixelText,
+		TextHollow:          c.TextHollow,
+		AutoFontSize:        c.AutoFontSize,
+		AutoOrient:          c.AutoOrient,
+		ColorManagement:     c.ColorManagement,
+		Filters:             c.Filters,
+		MaxPixels:           c.MaxPixels,
+		MaxBytes:            c.MaxBytes,
+		MaxTextLength:       c.MaxTextLength,
+		ParallelRender:      c.ParallelRender,
+		Deterministic:       c.Deterministic,
+		Seed:                c.Seed,
+		Debug:               c.Debug,
+	}
+	if c.BackgroundColor != nil {
+		f.BackgroundColor = FormatColor(c.BackgroundColor)
+	}
+	if c.BorderColor != nil {
+		f.BorderColor = FormatColor(c.BorderColor)
+	}
+	if c.TextColor != nil {
+		f.TextColor = FormatColor(c.TextColor)
+	}
+	if c.TextOutlineColor != nil {
+		f.TextOutlineColor = FormatColor(c.TextOutlineColor)
+	}
+	if e := c.TextExtrude; e != (Extrude{}) {
+		f.TextExtrude = &extrudeFile{Depth: e.Depth, DX: e.Direction.X, DY: e.Direction.Y}
+		if e.Color != nil {
+			f.TextExtrude.Color = FormatColor(e.Color)
+		}
+		if e.FarColor != nil {
+			f.TextExtrude.FarColor = FormatColor(e.FarColor)
+		}
+	}
+	if gl := c.TextGlow; gl != (Glow{}) {
+		f.TextGlow = &glowFile{Radius: gl.Radius, Intensity: gl.Intensity}
+		if gl.Color != nil {
+			f.TextGlow.Color = FormatColor(gl.Color)
+		}
+	}
+	if j := c.TextJitter; j != (Jitter{}) {
+		f.TextJitter = &jitterFile{Offset: j.Offset, Rotation: j.Rotation}
+	}
+	if w := c.TextWave; w != (Wave{}) {
+		f.TextWave = &waveFile{Amplitude: w.Amplitude, Wavelength: w.Wavelength}
+	}
+	if s := c.SafeArea; s != (SafeArea{}) {
+		f.SafeArea = &safeAreaFile{Top: s.Top, Bottom: s.Bottom, Left: s.Left, Right: s.Right}
+	}
+	if len(c.FontData) > 0 {
+		for name, data := range GetAvailableFonts() {
+			if bytes.Equal(c.FontData, data) {
+				f.Font = name
+				return f, nil
+			}
+		}
+		return f, &ConfigError{Field: "FontData", Reason: "custom font data cannot be serialized, use FontPath or a built-in font name"}
+	}
+	return f, nil
+}
+
+// apply переносит файловое представление в конфигурацию.
+// Шрифт меняется, только если fontChanged: иначе FontData, которые
+// нельзя записать строкой, сохраняются.
+func (c *Config) apply(f configFile, fontChanged bool) error {
+	cfg := *c
+	cfg.TopText, cfg.BottomText = f.TopText, f.BottomText
+	cfg.Theme = f.Theme
+	cfg.FontSize = f.FontSize
+	cfg.Padding, cfg.Border = f.Padding, f.Border
+	cfg.TextOutlineWidth = f.TextOutlineWidth
+	cfg.TextUppercase, cfg.AutoFontSize = f.TextUppercase, f.AutoFontSize
+	cfg.TopCase, cfg.BottomCase = f.TopCase, f.BottomCase
+	cfg.TopMaxWidthRatio, cfg.BottomMaxWidthRatio = f.TopMaxWidthRatio, f.BottomMaxWidthRatio
+	cfg.LinearBlending, cfg.SubpixelText = f.LinearBlending, f.SubpixelText
+	cfg.TextHollow = f.TextHollow
+	cfg.AutoOrient, cfg.ColorManagement = f.AutoOrient, f.ColorManagement
+	cfg.Filters = f.Filters
+	cfg.MaxPixels, cfg.MaxBytes, cfg.MaxTextLength = f.MaxPixels, f.MaxBytes, f.MaxTextLength
+	cfg.ParallelRender = f.ParallelRender
+	cfg.Deterministic, cfg.Seed = f.Deterministic, f.Seed
+	cfg.Debug = f.Debug
+
+	var err error
+	if cfg.BackgroundColor, err = optionalColor(f.BackgroundColor); err != nil {
+		return &ConfigError{Field: "BackgroundColor", Reason: err.Error()}
+	}
+	if cfg.BorderColor, err = optionalColor(f.BorderColor); err != nil {
+		return &ConfigError{Field: "BorderColor", Reason: err.Error()}
+	}
+	if cfg.TextColor, err = optionalColor(f.TextColor); err != nil {
+		return &ConfigError{Field: "TextColor", Reason: err.Error()}
+	}
+	if cfg.TextOutlineColor, err = optionalColor(f.TextOutlineColor); err != nil {
+		return &ConfigError{Field: "TextOutlineColor", Reason: err.Error()}
+	}
+	cfg.TextExtrude = Extrude{}
+	if e := f.TextExtrude; e != nil {
+		cfg.TextExtrude = Extrude{Depth: e.Depth, Direction: image.Pt(e.DX, e.DY)}
+		if cfg.TextExtrude.Color, err = optionalColor(e.Color); err != nil {
+			return &ConfigError{Field: "TextExtrude.Color", Reason: err.Error()}
+		}
+		if cfg.TextExtrude.FarColor, err = optionalColor(e.FarColor); err != nil {
+			return &ConfigError{Field: "TextExtrude.FarColor", Reason: err.Error()}
+		}
+	}
+	cfg.TextGlow = Glow{}
+	if gl := f.TextGlow; gl != nil {
+		cfg.TextGlow = Glow{Radius: gl.Radius, Intensity: gl.Intensity}
+		if cfg.TextGlow.Color, err = optionalColor(gl.Color); err != nil {
+			return &ConfigError{Field: "TextGlow.Color", Reason: err.Error()}
+		}
+	}
+	cfg.TextJitter = Jitter{}
+	if j := f.TextJitter; j != nil {
+		cfg.TextJitter = Jitter{Offset: j.Offset, Rotation: j.Rotation}
+	}
+	cfg.TextWave = Wave{}
+	if w := f.TextWave; w != nil {
+		cfg.TextWave = Wave{Amplitude: w.Amplitude, Wavelength: w.Wavelength}
+	}
+	cfg.SafeArea = SafeArea{}
+	if s := f.SafeArea; s != nil {
+		if s.Preset != "" {
+			var ok bool
+			if cfg.SafeArea, ok = LookupSafeArea(s.Preset); !ok {
+				return &ConfigError{Field: "SafeArea", Reason: fmt.Sprintf("unknown preset %q", s.Preset)}
+			}
+		}
+		for _, v := range []struct {
+			dst *float64
+			src float64
+		}{{&cfg.SafeArea.Top, s.Top}, {&cfg.SafeArea.Bottom, s.Bottom}, {&cfg.SafeArea.Left, s.Left}, {&cfg.SafeArea.Right, s.Right}} {
+			if v.src != 0 {
+				*v.dst = v.src
+			}
+		}
+	}
+
+	if fontChanged {
+		if data, ok := GetAvailableFonts()[f.Font]; ok {
+			cfg.FontPath, cfg.FontData = "", data
+		} else {
+			cfg.FontPath, cfg.FontData = f.Font, nil
+		}
+	}
+	*c = cfg
+	return nil
+}
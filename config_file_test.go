@@ -0,0 +1,100 @@
+package meme
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestConfigThemeFromFile(t *testing.T) {
+	vapor, ok := LookupTheme("vaporwave")
+	if !ok {
+		t.Fatal("vaporwave theme is not registered")
+	}
+	dir := t.TempDir()
+	load := func(name, data string) *Config {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := LoadConfig(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cfg
+	}
+	unmarshalJSON := func(data string) *Config {
+		t.Helper()
+		cfg := DefaultConfig()
+		if err := json.Unmarshal([]byte(data), cfg); err != nil {
+			t.Fatal(err)
+		}
+		return cfg
+	}
+	unmarshalYAML := func(data string) *Config {
+		t.Helper()
+		cfg := DefaultConfig()
+		if err := yaml.Unmarshal([]byte(data), cfg); err != nil {
+			t.Fatal(err)
+		}
+		return cfg
+	}
+
+	for _, tc := range []struct {
+		name string
+		cfg  *Config
+	}{
+		{"LoadConfig json", load("theme.json", `{"theme": "vaporwave"}`)},
+		{"LoadConfig yaml", load("theme.yaml", "theme: vaporwave\n")},
+		{"UnmarshalJSON", unmarshalJSON(`{"theme": "vaporwave"}`)},
+		{"UnmarshalYAML", unmarshalYAML("theme: vaporwave\n")},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := withTheme(tc.cfg)
+			for _, c := range []struct {
+				field     string
+				got, want string
+			}{
+				{"BackgroundColor", FormatColor(got.BackgroundColor), FormatColor(vapor.BackgroundColor)},
+				{"BorderColor", FormatColor(got.BorderColor), FormatColor(vapor.BorderColor)},
+				{"TextColor", FormatColor(got.TextColor), FormatColor(vapor.TextColor)},
+				{"TextOutlineColor", FormatColor(got.TextOutlineColor), FormatColor(vapor.TextOutlineColor)},
+			} {
+				if c.got != c.want {
+					t.Errorf("%s = %s, want %s", c.field, c.got, c.want)
+				}
+			}
+			if got.Border != vapor.Border || got.FontSize != vapor.FontSize || got.TextOutlineWidth != vapor.TextOutlineWidth {
+				t.Errorf("Border, FontSize, TextOutlineWidth = %d, %v, %d, want %d, %v, %d",
+					got.Border, got.FontSize, got.TextOutlineWidth, vapor.Border, vapor.FontSize, vapor.TextOutlineWidth)
+			}
+			if got.MaxPixels != DefaultMaxPixels || got.MaxTextLength != DefaultMaxTextLength {
+				t.Errorf("limits changed: MaxPixels %d, MaxTextLength %d", got.MaxPixels, got.MaxTextLength)
+			}
+		})
+	}
+
+	t.Run("explicit fields win", func(t *testing.T) {
+		got := withTheme(unmarshalJSON(`{"theme": "vaporwave", "text_color": "#ffee00", "border": 3}`))
+		if c := FormatColor(got.TextColor); c != "#ffee00" {
+			t.Errorf("TextColor = %s, want #ffee00", c)
+		}
+		if got.Border != 3 {
+			t.Errorf("Border = %d, want 3", got.Border)
+		}
+		if c, want := FormatColor(got.BackgroundColor), FormatColor(vapor.BackgroundColor); c != want {
+			t.Errorf("BackgroundColor = %s, want %s", c, want)
+		}
+	})
+
+	t.Run("without theme", func(t *testing.T) {
+		got := unmarshalJSON(`{"padding": 20}`)
+		if c := FormatColor(got.BackgroundColor); c != "#000000" || got.Border != 10 || got.Padding != 20 {
+			t.Errorf("got background %s, border %d, padding %d", c, got.Border, got.Padding)
+		}
+	})
+}
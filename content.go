@@ -0,0 +1,108 @@
+package meme
+
+import (
+	"image"
+	"io"
+	"time"
+)
+
+// Content - то, что меняется от вызова к вызову: подписи, значения
+// плейсхолдеров и эффекты над конкретным фото. Оформление (шрифты, цвета,
+// рамка, ограничения) - неизменяемый Style генератора.
+//
+// Сервис, который выпускает мемы с разными подписями из нескольких
+// горутин, настраивает генератор один раз, а подписи передает в каждом
+// вызове:
+//
+//	g := meme.NewStyle(cfg).NewGenerator() // cfg.TopText и BottomText не используются
+//	out, err := g.GenerateContent(img, meme.Content{TopText: "когда", BottomText: "тогда"})
+type Content struct {
+	TopText    string
+	BottomText string
+
+	// Data подставляется в плейсхолдеры подписей (см. ExpandCaption);
+	// nil - подписи берутся как есть
+	Data any
+
+	// Filters применяются к фото после Config.Filters
+	Filters []FilterSpec
+}
+
+// Style - оформление без подписей: шрифт, цвета, рамка, эффекты,
+// фильтры и ограничения. Значение неизменяемо: Style хранит собственную
+// копию настроек, а Config и Generator.Style возвращают новые копии,
+// поэтому один Style можно без блокировок делить между горутинами.
+type Style struct {
+	cfg *Config
+}
+
+// NewStyle копирует оформление из cfg (nil - DefaultConfig) с
+// подставленной темой; подписи cfg не сохраняются
+func NewStyle(cfg *Config) Style {
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+	c := cloneConfig(withTheme(cfg))
+	c.TopText, c.BottomText = "", ""
+	return Style{cfg: c}
+}
+
+// Config возвращает изменяемую копию оформления, например чтобы собрать
+// из нее другой Style
+func (s Style) Config() *Config {
+	if s.cfg == nil {
+		return NewStyle(nil).Config()
+	}
+	return cloneConfig(s.cfg)
+}
+
+// NewGenerator создает генератор с этим оформлением; подписи передаются
+// в GenerateContent
+func (s Style) NewGenerator() *Generator {
+	return NewGenerator(s.Config())
+}
+
+// Style возвращает оформление генератора без подписей
+func (g *Generator) Style() Style {
+	return NewStyle(g.config)
+}
+
+// withContent возвращает генератор с подписями и эффектами c поверх
+// оформления g; конфигурация g не изменяется
+func (g *Generator) withContent(c Content) (*Generator, error) {
+	cfg := cloneConfig(g.config)
+	cfg.TopText, cfg.BottomText = c.TopText, c.BottomText
+	if c.Data != nil {
+		var err error
+		if cfg.TopText, err = ExpandCaption(cfg.TopText, c.Data); err != nil {
+			return nil, err
+		}
+		if cfg.BottomText, err = ExpandCaption(cfg.BottomText, c.Data); err != nil {
+			return nil, err
+		}
+	}
+	cfg.Filters = append(cfg.Filters, c.Filters...)
+	return g.derive(cfg), nil
+}
+
+// GenerateContent создает демотиватор с подписями из c, не трогая
+// TopText и BottomText конфигурации. Безопасно вызывать из нескольких
+// горутин, пока конфигурацию никто не меняет.
+func (g *Generator) GenerateContent(img image.Image, c Content) (out *image.RGBA, err error) {
+	defer g.observeGeneration(time.Now(), &err)
+	dg, err := g.withContent(c)
+	if err != nil {
+		return nil, err
+	}
+	return dg.generateInto(nil, img)
+}
+
+// GenerateContentBytes - GenerateBytes с подписями из c. Подписи входят
+// в ключ кеша результатов.
+func (g *Generator) GenerateContentBytes(r io.Reader, c Content, opts *EncodeOptions) ([]byte, error) {
+	dg, err := g.withContent(c)
+	if err != nil {
+		return nil, err
+	}
+	return dg.GenerateBytes(r, opts)
+}
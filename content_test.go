@@ -0,0 +1,34 @@
+package meme
+
+import "testing"
+
+func TestStyleIsolation(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.TopText = "caption"
+	cfg.Filters = []FilterSpec{{Name: "blur", Params: map[string]any{"radius": 2.0}}}
+
+	g := NewGenerator(cfg)
+	cfg.Padding = 1
+	cfg.Filters[0].Params["radius"] = 9.0
+	if got := g.Config(); got.Padding == 1 || got.Filters[0].Params["radius"] != 2.0 {
+		t.Errorf("NewGenerator shares config with the caller")
+	}
+
+	g.Config().Padding = 2
+	g.Config().Filters[0].Params["radius"] = 7.0
+	if got := g.Config(); got.Padding == 2 || got.Filters[0].Params["radius"] != 2.0 {
+		t.Errorf("Config() exposes generator state")
+	}
+
+	s := g.Style()
+	if s.Config().TopText != "" {
+		t.Errorf("Style keeps captions: %q", s.Config().TopText)
+	}
+	s.Config().Border = 99
+	if s.Config().Border == 99 || s.NewGenerator().Config().Border == 99 {
+		t.Errorf("Style.Config() exposes style state")
+	}
+	if (Style{}).Config().FontSize != DefaultConfig().FontSize {
+		t.Errorf("zero Style is not DefaultConfig")
+	}
+}
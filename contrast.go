@@ -0,0 +1,99 @@
+package meme
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+)
+
+// Пороги контраста по WCAG 2.1 (уровень AA)
+const (
+	MinTextContrast      = 4.5 // обычный текст
+	MinLargeTextContrast = 3.0 // крупный текст, от largeTextSize
+	MinGraphicContrast   = 3.0 // рамки и прочая графика
+
+	// Крупным WCAG считает текст от 18pt, это 24px
+	largeTextSize = 24
+
+	// Наименьший размер шрифта layout при AutoFontSize: 48 * 0.5
+	minAutoFontSize = 24
+)
+
+// ContrastWarning - пара цветов темы с недостаточным контрастом
+type ContrastWarning struct {
+	Pair  string  // например "text/background"
+	Ratio float64 // контраст пары, от 1 до 21
+	Min   float64 // требуемый контраст
+}
+
+func (w ContrastWarning) String() string {
+	return fmt.Sprintf("%s contrast %.2f:1 is below %.1f:1", w.Pair, w.Ratio, w.Min)
+}
+
+// ContrastRatio - контраст двух цветов по WCAG: (L1+0.05)/(L2+0.05), где
+// L - относительная яркость. Прозрачность не учитывается.
+func ContrastRatio(a, b color.Color) float64 {
+	la, lb := luminance(a), luminance(b)
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
+
+// luminance - относительная яркость цвета sRGB
+func luminance(c color.Color) float64 {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	lin := func(v uint8) float64 {
+		s := float64(v) / 255
+		if s <= 0.04045 {
+			return s / 12.92
+		}
+		return math.Pow((s+0.055)/1.055, 2.4)
+	}
+	return 0.2126*lin(n.R) + 0.7152*lin(n.G) + 0.0722*lin(n.B)
+}
+
+// CheckContrast проверяет читаемость оформления, с которым генератор
+// нарисует мем по c: тема подставляется, как в NewGenerator, поэтому
+// учитываются и цвета, заданные поверх темы флагами, документом или
+// запросом. Возвращает пары цветов ниже порогов WCAG: текст к фону и рамку
+// к фону. Порог для текста зависит от FontSize (с AutoFontSize - от
+// наименьшего автоматического размера); с обводкой текст читается, если с
+// порогом проходит фон или обводка. Незаданные цвета не проверяются;
+// пустой результат - оформление проходит проверку.
+func CheckContrast(c *Config) []ContrastWarning {
+	c = withTheme(c)
+	size := c.FontSize
+	if c.AutoFontSize {
+		size = minAutoFontSize
+	}
+	return checkContrast(c.TextColor, c.BackgroundColor, c.BorderColor, c.TextOutlineColor, c.TextOutlineWidth > 0, size)
+}
+
+// CheckThemeContrast - CheckContrast для зарегистрированной темы t
+func CheckThemeContrast(t *Theme) []ContrastWarning {
+	return checkContrast(t.TextColor, t.BackgroundColor, t.BorderColor, t.TextOutlineColor, t.TextOutlineWidth > 0, t.FontSize)
+}
+
+func checkContrast(text, background, border, outlineColor color.Color, outline bool, fontSize float64) []ContrastWarning {
+	var warnings []ContrastWarning
+	if text != nil && background != nil {
+		threshold := MinTextContrast
+		if fontSize >= largeTextSize {
+			threshold = MinLargeTextContrast
+		}
+		r := ContrastRatio(text, background)
+		if outline && outlineColor != nil {
+			r = max(r, ContrastRatio(text, outlineColor))
+		}
+		if r < threshold {
+			warnings = append(warnings, ContrastWarning{Pair: "text/background", Ratio: r, Min: threshold})
+		}
+	}
+	if border != nil && background != nil {
+		if r := ContrastRatio(border, background); r < MinGraphicContrast {
+			warnings = append(warnings, ContrastWarning{Pair: "border/background", Ratio: r, Min: MinGraphicContrast})
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,41 @@
+package meme
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestCheckContrast(t *testing.T) {
+	for _, name := range []string{"classic-black", "clean-white", "cvd-dark", "cvd-light", "high-contrast"} {
+		th, ok := LookupTheme(name)
+		if !ok {
+			t.Fatalf("theme %s is not registered", name)
+		}
+		if w := CheckThemeContrast(th); len(w) > 0 {
+			t.Errorf("theme %s: %v", name, w)
+		}
+		if w := CheckContrast(ThemedConfig(name)); len(w) > 0 {
+			t.Errorf("config with theme %s: %v", name, w)
+		}
+	}
+
+	// Цвет поверх темы, как из флага или запроса
+	cfg := ThemedConfig("classic-black")
+	cfg.TextColor = color.RGBA{0x20, 0x20, 0x20, 255}
+	w := CheckContrast(cfg)
+	if len(w) != 1 || w[0].Pair != "text/background" {
+		t.Fatalf("dark text on black: got %v", w)
+	}
+
+	// Обводка спасает текст на фоне того же цвета
+	cfg.TextOutlineWidth, cfg.TextOutlineColor = 2, color.White
+	if w := CheckContrast(cfg); len(w) > 0 {
+		t.Errorf("outlined text: %v", w)
+	}
+
+	cfg = DefaultConfig()
+	cfg.BorderColor = color.RGBA{0x10, 0x10, 0x10, 255}
+	if w := CheckContrast(cfg); len(w) != 1 || w[0].Pair != "border/background" {
+		t.Errorf("dark border on black: got %v", w)
+	}
+}
@@ -0,0 +1,116 @@
+package meme
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"golang.org/x/image/font"
+)
+
+// Цвета направляющих отладочного режима
+var (
+	debugPhotoColor    = color.NRGBA{0, 255, 0, 200}     // область фото
+	debugBorderColor   = color.NRGBA{255, 255, 0, 200}   // внешний край рамки
+	debugPaddingColor  = color.NRGBA{0, 200, 255, 160}   // линии полей и линейки
+	debugSafeColor     = color.NRGBA{255, 140, 0, 160}   // безопасная зона
+	debugPlatformColor = color.NRGBA{255, 0, 0, 60}      // поля Config.SafeArea под интерфейсом платформы
+	debugCaptionColor  = color.NRGBA{255, 0, 255, 220}   // рамки подписей
+	debugBaselineColor = color.NRGBA{255, 0, 0, 220}     // базовые линии
+	debugAnchorColor   = color.NRGBA{255, 255, 255, 230} // точки привязки
+)
+
+// Шаг делений на линейках полей
+const debugRulerStep = 10
+
+// drawDebug рисует поверх результата направляющие разметки
+func (g *Generator) drawDebug(out draw.Image, face font.Face, l layout) {
+	cfg := g.config
+	canvas := l.canvas
+
+	// Поля: линии на расстоянии Padding от краёв и линейки с делениями
+	pad := cfg.Padding
+	debugRect(out, canvas.Inset(pad), debugPaddingColor)
+	for x := canvas.Min.X; x < canvas.Max.X; x += debugRulerStep {
+		tick := 3
+		if (x-canvas.Min.X)%(debugRulerStep*5) == 0 {
+			tick = 7
+		}
+		debugFill(out, image.Rect(x, canvas.Min.Y, x+1, canvas.Min.Y+tick), debugPaddingColor)
+	}
+	for y := canvas.Min.Y; y < canvas.Max.Y; y += debugRulerStep {
+		tick := 3
+		if (y-canvas.Min.Y)%(debugRulerStep*5) == 0 {
+			tick = 7
+		}
+		debugFill(out, image.Rect(canvas.Min.X, y, canvas.Min.X+tick, y+1), debugPaddingColor)
+	}
+
+	// Безопасная зона: 5% от каждого края, ближе к краю платформы могут обрезать
+	inset := min(canvas.Dx(), canvas.Dy()) / 20
+	debugRect(out, canvas.Inset(inset), debugSafeColor)
+
+	// Поля SafeArea закрашиваются: здесь подписи закрыл бы интерфейс
+	if s := l.safe; s != canvas {
+		for _, r := range []image.Rectangle{
+			image.Rect(canvas.Min.X, canvas.Min.Y, canvas.Max.X, s.Min.Y),
+			image.Rect(canvas.Min.X, s.Max.Y, canvas.Max.X, canvas.Max.Y),
+			image.Rect(canvas.Min.X, s.Min.Y, s.Min.X, s.Max.Y),
+			image.Rect(s.Max.X, s.Min.Y, canvas.Max.X, s.Max.Y),
+		} {
+			debugFill(out, r, debugPlatformColor)
+		}
+		debugRect(out, s, debugPlatformColor)
+	}
+
+	// Фото и внешний край рамки
+	debugRect(out, l.photo, debugPhotoColor)
+	debugRect(out, l.photo.Inset(-cfg.Border), debugBorderColor)
+	debugCross(out, image.Pt((l.photo.Min.X+l.photo.Max.X)/2, (l.photo.Min.Y+l.photo.Max.Y)/2), debugAnchorColor)
+
+	// Подписи: рамка глифов, базовая линия и точка привязки (центр на базовой линии)
+	for _, c := range []struct {
+		text     string
+		lines    []string
+		baseline int
+	}{{l.topText, l.topLines, l.topBaseline}, {l.bottomText, l.bottomLines, l.bottomBaseline}} {
+		if c.text == "" {
+			continue
+		}
+		for i, line := range c.lines {
+			baseline := c.baseline + i*l.lineStep
+			width := font.MeasureString(face, line).Ceil()
+			x := l.textLeft + (l.textRight-l.textLeft-width)/2
+			b, _ := font.BoundString(face, line)
+			box := image.Rect(b.Min.X.Floor(), b.Min.Y.Floor(), b.Max.X.Ceil(), b.Max.Y.Ceil()).
+				Add(image.Pt(x, baseline)).Inset(-cfg.TextOutlineWidth)
+			debugRect(out, box, debugCaptionColor)
+			debugFill(out, image.Rect(x, baseline, x+width, baseline+1), debugBaselineColor)
+			debugCross(out, image.Pt(x+width/2, baseline), debugAnchorColor)
+		}
+	}
+}
+
+// debugFill полупрозрачно закрашивает прямоугольник
+func debugFill(dst draw.Image, r image.Rectangle, c color.Color) {
+	draw.Draw(dst, r, image.NewUniform(c), image.Point{}, draw.Over)
+}
+
+// debugRect рисует контур прямоугольника толщиной 1px
+func debugRect(dst draw.Image, r image.Rectangle, c color.Color) {
+	if r.Empty() {
+		return
+	}
+	debugFill(dst, image.Rect(r.Min.X, r.Min.Y, r.Max.X, r.Min.Y+1), c)
+	debugFill(dst, image.Rect(r.Min.X, r.Max.Y-1, r.Max.X, r.Max.Y), c)
+	debugFill(dst, image.Rect(r.Min.X, r.Min.Y+1, r.Min.X+1, r.Max.Y-1), c)
+	debugFill(dst, image.Rect(r.Max.X-1, r.Min.Y+1, r.Max.X, r.Max.Y-1), c)
+}
+
+// debugCross рисует перекрестие в точке p
+func debugCross(dst draw.Image, p image.Point, c color.Color) {
+	const arm = 6
+	debugFill(dst, image.Rect(p.X-arm, p.Y, p.X+arm+1, p.Y+1), c)
+	debugFill(dst, image.Rect(p.X, p.Y-arm, p.X+1, p.Y), c)
+	debugFill(dst, image.Rect(p.X, p.Y+1, p.X+1, p.Y+arm+1), c)
+}
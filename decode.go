@@ -0,0 +1,389 @@
+package meme
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/image/webp"
+)
+
+// Ошибки определения формата входного изображения
+var (
+	ErrHEIFUnsupported  = errors.New("HEIF/HEIC is not supported: register a decoder with RegisterHEIFDecoder")
+	ErrUnknownFormat    = errors.New("unknown image format")
+	ErrFormatNotAllowed = errors.New("image format is not allowed by decode options")
+
+	// ErrDecode - файл распознан, но поврежден или обрезан
+	ErrDecode = errors.New("decoding image")
+)
+
+// Decoder описывает формат входного изображения
+type Decoder struct {
+	// Name - имя формата ("png", "jpeg", ...), используется в DecodeOptions
+	Name string
+
+	// Magic - сигнатуры начала файла; символ '?' совпадает с любым байтом
+	Magic []string
+
+	Decode       func(io.Reader) (image.Image, error)
+	DecodeConfig func(io.Reader) (image.Config, error)
+}
+
+// match проверяет, начинаются ли данные с одной из сигнатур формата
+func (d *Decoder) match(data []byte) bool {
+	for _, magic := range d.Magic {
+		if len(data) < len(magic) {
+			continue
+		}
+		ok := true
+		for i := 0; i < len(magic); i++ {
+			if magic[i] != '?' && magic[i] != data[i] {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Limits - ограничения на входные данные (0 - без ограничения)
+type Limits struct {
+	MaxBytes  int64
+	MaxPixels int
+}
+
+// DecodeOptions содержит настройки DecodeImage
+type DecodeOptions struct {
+	// Allow - разрешённые форматы; пустой список разрешает все зарегистрированные
+	Allow []string
+	// Deny - запрещённые форматы, имеет приоритет над Allow
+	Deny []string
+
+	// Limits - общие ограничения, FormatLimits - переопределения для отдельных
+	// форматов (нулевое поле наследует общее значение)
+	Limits       Limits
+	FormatLimits map[string]Limits
+
+	// Decoders - дополнительные декодеры только для этого вызова,
+	// проверяются раньше встроенных
+	Decoders []Decoder
+
+	AutoOrient      bool // поворачивать по тегу EXIF Orientation
+	ColorManagement bool // переводить ICC-профиль в sRGB
+
+	// Logger получает отладочные сообщения о запасных путях декодирования (nil - молчать)
+	Logger *slog.Logger
+}
+
+// allowed сообщает, разрешён ли формат настройками
+func (o *DecodeOptions) allowed(name string) bool {
+	if slices.Contains(o.Deny, name) {
+		return false
+	}
+	return len(o.Allow) == 0 || slices.Contains(o.Allow, name)
+}
+
+// limits возвращает ограничения для формата с учётом переопределений
+func (o *DecodeOptions) limits(name string) Limits {
+	l := o.Limits
+	if fl, ok := o.FormatLimits[name]; ok {
+		if fl.MaxBytes != 0 {
+			l.MaxBytes = fl.MaxBytes
+		}
+		if fl.MaxPixels != 0 {
+			l.MaxPixels = fl.MaxPixels
+		}
+	}
+	return l
+}
+
+// readLimit - сколько байт можно прочитать до того, как станет известен
+// формат: наибольшее из ограничений Limits и FormatLimits (0 - без ограничения)
+func (o *DecodeOptions) readLimit() int64 {
+	limit := o.Limits.MaxBytes
+	for _, fl := range o.FormatLimits {
+		if fl.MaxBytes > limit {
+			limit = fl.MaxBytes
+		}
+	}
+	return limit
+}
+
+// Встроенный реестр декодеров
+var (
+	decodersMu sync.RWMutex
+	decoders   = []Decoder{
+		{Name: "png", Magic: []string{"\x89PNG\r\n\x1a\n"}, Decode: png.Decode, DecodeConfig: png.DecodeConfig},
+		{Name: "jpeg", Magic: []string{"\xff\xd8"}, Decode: jpeg.Decode, DecodeConfig: jpeg.DecodeConfig},
+		{Name: "gif", Magic: []string{"GIF87a", "GIF89a"}, Decode: gif.Decode, DecodeConfig: gif.DecodeConfig},
+		{Name: "webp", Magic: []string{"RIFF????WEBPVP8"}, Decode: webp.Decode, DecodeConfig: webp.DecodeConfig},
+	}
+)
+
+// Бренды контейнера ISOBMFF, которыми помечаются HEIF/HEIC файлы
+var heifBrands = []string{"heic", "heix", "heim", "heis", "hevc", "hevx", "hevm", "hevs", "mif1", "msf1"}
+
+// RegisterDecoder добавляет входной формат в реестр или заменяет
+// зарегистрированный с тем же именем, в том числе встроенный: так можно
+// подключить libvips, ffmpeg или собственный кодек, а поддержку HEIC и
+// AVIF вынести в отдельные модули. Формат определяется по Magic, поэтому
+// DecodeOptions.Allow и Deny действуют и на новые форматы.
+func RegisterDecoder(d Decoder) error {
+	if d.Name == "" || len(d.Magic) == 0 || d.Decode == nil {
+		return errors.New("decoder needs Name, Magic and Decode")
+	}
+	d.Magic = slices.Clone(d.Magic)
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	for i := range decoders {
+		if decoders[i].Name == d.Name {
+			decoders[i] = d
+			return nil
+		}
+	}
+	decoders = append(decoders, d)
+	return nil
+}
+
+var heifRegisterOnce sync.Once
+
+// RegisterHEIFDecoder подключает внешний декодер HEIF/HEIC.
+// Библиотека не содержит собственного декодера HEVC, поэтому приложение
+// передаёт функции из выбранной реализации (cgo-обёртки libheif и т.п.).
+// Повторные вызовы игнорируются.
+func RegisterHEIFDecoder(decode func(io.Reader) (image.Image, error), decodeConfig func(io.Reader) (image.Config, error)) {
+	heifRegisterOnce.Do(func() {
+		d := Decoder{Name: "heif", Decode: decode, DecodeConfig: decodeConfig}
+		for _, brand := range heifBrands {
+			d.Magic = append(d.Magic, "????ftyp"+brand)
+		}
+		RegisterDecoder(d)
+	})
+}
+
+// Decoders возвращает имена зарегистрированных входных форматов в порядке
+// проверки сигнатур
+func Decoders() []string {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+	names := make([]string, len(decoders))
+	for i, d := range decoders {
+		names[i] = d.Name
+	}
+	return names
+}
+
+// isHEIF проверяет сигнатуру ftyp контейнера HEIF
+func isHEIF(data []byte) bool {
+	if len(data) < 12 || string(data[4:8]) != "ftyp" {
+		return false
+	}
+	return slices.Contains(heifBrands, string(data[8:12]))
+}
+
+// sniff определяет декодер по сигнатуре файла
+func sniff(data []byte, extra []Decoder) (Decoder, error) {
+	for _, d := range extra {
+		if d.match(data) {
+			return d, nil
+		}
+	}
+
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+	for _, d := range decoders {
+		if d.match(data) {
+			return d, nil
+		}
+	}
+	if isHEIF(data) {
+		return Decoder{}, ErrHEIFUnsupported
+	}
+	return Decoder{}, ErrUnknownFormat
+}
+
+// DecodeImage читает и декодирует изображение, определяя формат по сигнатуре.
+// Формат проверяется по спискам Allow/Deny, а размеры - по заголовку до
+// полного декодирования, поэтому функцию можно применять к недоверенным загрузкам.
+// Возвращает изображение и имя формата.
+func DecodeImage(r io.Reader, opts *DecodeOptions) (_ image.Image, _ string, err error) {
+	defer recoverPanic(&err)
+	if opts == nil {
+		opts = &DecodeOptions{}
+	}
+
+	limit := opts.readLimit()
+	src := r
+	if limit > 0 {
+		src = io.LimitReader(r, limit+1)
+	}
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return nil, "", fmt.Errorf("reading image: %w", err)
+	}
+
+	dec, err := sniff(data, opts.Decoders)
+	if err != nil {
+		return nil, "", err
+	}
+	if !opts.allowed(dec.Name) {
+		return nil, "", fmt.Errorf("%w: %s", ErrFormatNotAllowed, dec.Name)
+	}
+
+	limits := opts.limits(dec.Name)
+	// Ограничение есть только у других форматов: дочитываем остаток
+	if limits.MaxBytes == 0 && limit > 0 && int64(len(data)) > limit {
+		rest, err := io.ReadAll(r)
+		if err != nil {
+			return nil, "", fmt.Errorf("reading image: %w", err)
+		}
+		data = append(data, rest...)
+	}
+	if limits.MaxBytes > 0 && int64(len(data)) > limits.MaxBytes {
+		return nil, "", fmt.Errorf("%w: more than %d bytes", ErrInputTooLarge, limits.MaxBytes)
+	}
+
+	// Проверяем размеры по заголовку до полного декодирования,
+	// чтобы "бомба" с огромными размерами не успела выделить память
+	if dec.DecodeConfig != nil {
+		if header, err := dec.DecodeConfig(bytes.NewReader(data)); err == nil {
+			if err := checkDimensions(header.Width, header.Height, limits.MaxPixels); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+
+	img, err := dec.Decode(bytes.NewReader(data))
+	if dec.Name == "jpeg" && isCMYKWithoutAdobe(err) {
+		logDebug(opts.Logger, "CMYK JPEG without Adobe marker, decoding with injected APP14")
+		img, err = decodePlainCMYK(data)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %w", ErrDecode, err)
+	}
+	b := img.Bounds()
+	if err := checkDimensions(b.Dx(), b.Dy(), limits.MaxPixels); err != nil {
+		return nil, "", err
+	}
+
+	// CMYK сразу переводим в RGB, чтобы дальнейшая обработка работала с RGB
+	if cmyk, ok := img.(*image.CMYK); ok {
+		img = toRGBA(cmyk)
+	}
+	if opts.ColorManagement {
+		img = convertToSRGB(img, data)
+	}
+	if opts.AutoOrient {
+		if o := exifOrientation(data); o != orientNormal {
+			logDebug(opts.Logger, "applying EXIF orientation", "orientation", o)
+			img = applyOrientation(img, o)
+		}
+	}
+	logDebug(opts.Logger, "image decoded", "format", dec.Name, "width", b.Dx(), "height", b.Dy())
+	return img, dec.Name, nil
+}
+
+// isCMYKWithoutAdobe распознаёт отказ image/jpeg декодировать 4-компонентный
+// JPEG без маркера Adobe APP14
+func isCMYKWithoutAdobe(err error) bool {
+	var unsupported jpeg.UnsupportedError
+	return errors.As(err, &unsupported) && strings.Contains(string(unsupported), "APP14")
+}
+
+// decodePlainCMYK декодирует CMYK JPEG без маркера Adobe.
+// Такие файлы хранят каналы без инверсии, поэтому мы подставляем маркер
+// APP14 с transform=0 (CMYK), а после декодирования снимаем инверсию,
+// которую image/jpeg применяет для файлов Adobe.
+func decodePlainCMYK(data []byte) (image.Image, error) {
+	if len(data) < 2 {
+		return nil, image.ErrFormat
+	}
+	app14 := []byte{0xff, 0xee, 0x00, 0x0e, 'A', 'd', 'o', 'b', 'e', 0x00, 0x64, 0x00, 0x00, 0x00, 0x00, 0x00}
+	patched := make([]byte, 0, len(data)+len(app14))
+	patched = append(patched, data[:2]...)
+	patched = append(patched, app14...)
+	patched = append(patched, data[2:]...)
+
+	img, err := jpeg.Decode(bytes.NewReader(patched))
+	if err != nil {
+		return nil, err
+	}
+	if cmyk, ok := img.(*image.CMYK); ok {
+		for i := range cmyk.Pix {
+			cmyk.Pix[i] = 255 - cmyk.Pix[i]
+		}
+	}
+	return img, nil
+}
+
+// GenerateFrom читает и декодирует изображение из r и создает из него демотиватор
+func (g *Generator) GenerateFrom(r io.Reader) (*image.RGBA, error) {
+	return g.GenerateFromContext(context.Background(), r)
+}
+
+// GenerateFromContext - GenerateFrom с отменой через ctx: после отмены r
+// больше не читается, а генерация прерывается между этапами
+func (g *Generator) GenerateFromContext(ctx context.Context, r io.Reader) (out *image.RGBA, err error) {
+	defer g.observeGeneration(time.Now(), &err)
+	img, err := g.readImage(contextReader{ctx, r})
+	if err != nil {
+		return nil, err
+	}
+	return g.generateContext(ctx, nil, img)
+}
+
+// contextReader перестает читать r после отмены ctx
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
+
+// GenerateFrom64 - вариант GenerateFrom с 16 битами на канал
+func (g *Generator) GenerateFrom64(r io.Reader) (out *image.NRGBA64, err error) {
+	defer g.observeGeneration(time.Now(), &err)
+	img, err := g.readImage(r)
+	if err != nil {
+		return nil, err
+	}
+	return g.generate64(img)
+}
+
+// decodeOptions собирает настройки декодирования из конфигурации генератора
+func (g *Generator) decodeOptions() *DecodeOptions {
+	cfg := g.config
+	return &DecodeOptions{
+		Limits:          Limits{MaxBytes: cfg.MaxBytes, MaxPixels: cfg.MaxPixels},
+		AutoOrient:      cfg.AutoOrient,
+		ColorManagement: cfg.ColorManagement,
+		Logger:          g.logger,
+	}
+}
+
+// readImage читает и декодирует входное изображение с учётом лимитов конфигурации
+func (g *Generator) readImage(r io.Reader) (image.Image, error) {
+	defer g.stage(StageDecode)()
+	img, _, err := DecodeImage(r, g.decodeOptions())
+	return img, err
+}
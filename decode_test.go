@@ -0,0 +1,49 @@
+package meme
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"testing"
+)
+
+// zeroReader отдает нули бесконечно
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func TestDecodeFormatLimitsOnly(t *testing.T) {
+	var pngData, jpg bytes.Buffer
+	if err := png.Encode(&pngData, image.NewGray(image.Rect(0, 0, 64, 64))); err != nil {
+		t.Fatal(err)
+	}
+	if err := jpeg.Encode(&jpg, image.NewGray(image.Rect(0, 0, 64, 64)), nil); err != nil {
+		t.Fatal(err)
+	}
+	const limit = 256
+	if jpg.Len() <= limit {
+		t.Fatalf("jpeg is only %d bytes", jpg.Len())
+	}
+	opts := &DecodeOptions{FormatLimits: map[string]Limits{"png": {MaxBytes: limit}}}
+
+	// Бесконечный поток читается только до ограничения, а не до конца
+	r := io.MultiReader(bytes.NewReader(pngData.Bytes()), zeroReader{})
+	if _, _, err := DecodeImage(r, opts); !errors.Is(err, ErrInputTooLarge) {
+		t.Errorf("endless png: %v, want ErrInputTooLarge", err)
+	}
+
+	// Формат без ограничения дочитывается целиком
+	img, format, err := DecodeImage(bytes.NewReader(jpg.Bytes()), opts)
+	if err != nil {
+		t.Fatalf("jpeg: %v", err)
+	}
+	if format != "jpeg" || img.Bounds().Dx() != 64 {
+		t.Errorf("jpeg decoded as %s %v", format, img.Bounds())
+	}
+}
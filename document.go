@@ -0,0 +1,365 @@
+package meme
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DocumentVersion - текущая версия формата документа .meme
+const DocumentVersion = 1
+
+// ErrUnsupportedDocument - документ более новой версии, чем понимает библиотека
+var ErrUnsupportedDocument = errors.New("unsupported document version")
+
+// Document - редактируемая композиция мема в формате JSON (.meme): ссылка на
+// исходное изображение или шаблон, подписи, оформление и слои поверх результата.
+// Хранит ссылки, а не пиксели, поэтому веб-редактор может сохранять и
+// загружать незаконченную работу, а библиотека - отрисовывать её.
+type Document struct {
+	Version int `json:"version"`
+
+	// Source - ссылка на исходное изображение демотиватора
+	Source string `json:"source,omitempty"`
+	// Template - имя зарегистрированного шаблона вместо демотиватора
+	Template string `json:"template,omitempty"`
+
+	// Captions - "top" и "bottom" для демотиватора или имена слотов шаблона
+	Captions map[string]string `json:"captions,omitempty"`
+	// Images - ссылки на картинки для слотов шаблона
+	Images map[string]string `json:"images,omitempty"`
+
+	// Style - оформление; nil - настройки генератора
+	Style *DocumentStyle `json:"style,omitempty"`
+
+	// Layers рисуются поверх результата в порядке перечисления
+	Layers []Layer `json:"layers,omitempty"`
+}
+
+// DocumentStyle - оформление демотиватора в документе; цвета - "#rrggbb".
+// Шрифт (здесь и в слоях) - имя встроенного шрифта или файл шрифта из
+// конфигурации генератора: другие файлы документ открыть не может.
+type DocumentStyle struct {
+	FontPath         string  `json:"font_path,omitempty"`
+	FontSize         float64 `json:"font_size,omitempty"`
+	AutoFontSize     bool    `json:"auto_font_size"`
+	Padding          int     `json:"padding"`
+	Border           int     `json:"border"`
+	BackgroundColor  string  `json:"background_color"`
+	BorderColor      string  `json:"border_color"`
+	TextColor        string  `json:"text_color"`
+	TextOutlineColor string  `json:"text_outline_color,omitempty"`
+	TextOutlineWidth int     `json:"text_outline_width,omitempty"`
+	TextUppercase    bool    `json:"text_uppercase"`
+}
+
+// Layer - дополнительный слой: текст или картинка в прямоугольнике результата
+type Layer struct {
+	Type   string    `json:"type"` // "text" или "image"
+	Rect   LayerRect `json:"rect"`
+	Hidden bool      `json:"hidden,omitempty"`
+
+	// Для картинки
+	Source string `json:"source,omitempty"`
+
+	// Для текста
+	Text         string  `json:"text,omitempty"`
+	FontPath     string  `json:"font_path,omitempty"`
+	FontSize     float64 `json:"font_size,omitempty"`
+	Color        string  `json:"color,omitempty"`
+	OutlineColor string  `json:"outline_color,omitempty"`
+	OutlineWidth int     `json:"outline_width,omitempty"`
+	Align        string  `json:"align,omitempty"` // left, center, right
+	Uppercase    bool    `json:"uppercase,omitempty"`
+}
+
+// LayerRect - прямоугольник слоя в пикселях результата
+type LayerRect struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+// Предел полей и рамки в оформлении документа: документ может прийти от
+// пользователя, а холст растёт вместе с ними
+const maxDocumentPadding = 4096
+
+// ImageResolver загружает изображение по ссылке из документа
+type ImageResolver func(ref string) (image.Image, error)
+
+// NewDocument создает документ демотиватора по текущей конфигурации генератора
+func (g *Generator) NewDocument(source string) *Document {
+	cfg := g.config
+	doc := &Document{
+		Version: DocumentVersion,
+		Source:  source,
+		Style:   styleFromConfig(cfg),
+	}
+	if cfg.TopText != "" || cfg.BottomText != "" {
+		doc.Captions = map[string]string{"top": cfg.TopText, "bottom": cfg.BottomText}
+	}
+	return doc
+}
+
+// LoadDocument читает документ .meme
+func LoadDocument(r io.Reader) (*Document, error) {
+	var doc Document
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parsing document: %w", err)
+	}
+	if doc.Version < 1 || doc.Version > DocumentVersion {
+		return nil, fmt.Errorf("%w: %d (supported up to %d)", ErrUnsupportedDocument, doc.Version, DocumentVersion)
+	}
+	return &doc, nil
+}
+
+// LoadDocumentFile читает документ из файла
+func LoadDocumentFile(path string) (*Document, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening document: %w", err)
+	}
+	defer f.Close()
+	return LoadDocument(f)
+}
+
+// Save записывает документ в формате JSON
+func (d *Document) Save(w io.Writer) error {
+	doc := *d
+	if doc.Version == 0 {
+		doc.Version = DocumentVersion
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(&doc)
+}
+
+// SaveFile записывает документ в файл
+func (d *Document) SaveFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating document: %w", err)
+	}
+	if err := d.Save(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// FileResolver загружает изображения из файлов внутри каталога dir с
+// ограничениями и настройками декодирования генератора. Документы могут
+// прийти от пользователя, поэтому ссылки - только относительные пути
+// внутри dir: абсолютные пути, "../" и символические ссылки наружу
+// отклоняются.
+func (g *Generator) FileResolver(dir string) ImageResolver {
+	return func(ref string) (image.Image, error) {
+		f, err := os.OpenInRoot(dir, filepath.FromSlash(ref))
+		if err != nil {
+			return nil, fmt.Errorf("opening image: %w", err)
+		}
+		defer f.Close()
+		return g.readImage(f)
+	}
+}
+
+// RenderDocument отрисовывает документ: демотиватор или шаблон, затем слои
+func (g *Generator) RenderDocument(d *Document, resolve ImageResolver) (out *image.RGBA, err error) {
+	defer g.observeGeneration(time.Now(), &err)
+	defer recoverPanic(&err)
+	cfg := *g.config
+	if d.Style != nil {
+		if err := d.Style.apply(&cfg); err != nil {
+			return nil, err
+		}
+	}
+	if d.Template == "" {
+		cfg.TopText, cfg.BottomText = d.Captions["top"], d.Captions["bottom"]
+	}
+	dg := g.derive(&cfg)
+
+	if d.Template != "" {
+		in := TemplateInput{Text: d.Captions, Images: make(map[string]image.Image)}
+		for slot, ref := range d.Images {
+			if in.Images[slot], err = resolve(ref); err != nil {
+				return nil, fmt.Errorf("image %s: %w", slot, err)
+			}
+		}
+		out, err = dg.GenerateTemplate(d.Template, in)
+	} else {
+		if d.Source == "" {
+			return nil, &ConfigError{Field: "source", Reason: "must be set when template is empty"}
+		}
+		var src image.Image
+		if src, err = resolve(d.Source); err != nil {
+			return nil, err
+		}
+		out, err = dg.generateInto(nil, src)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i, layer := range d.Layers {
+		if layer.Hidden {
+			continue
+		}
+		if err := dg.drawLayer(out, layer, resolve); err != nil {
+			return nil, fmt.Errorf("layer %d: %w", i, err)
+		}
+	}
+	return out, nil
+}
+
+// drawLayer рисует слой документа на результате
+func (g *Generator) drawLayer(out *image.RGBA, l Layer, resolve ImageResolver) error {
+	r, err := l.Rect.clip(out.Bounds())
+	if err != nil {
+		return err
+	}
+	if r.Empty() {
+		return nil
+	}
+	switch l.Type {
+	case "image":
+		img, err := resolve(l.Source)
+		if err != nil {
+			return err
+		}
+		drawCover(out, r, img)
+		return nil
+
+	case "text":
+		slot := TextSlot{
+			Name:         "layer",
+			Rect:         r,
+			FontSize:     l.FontSize,
+			OutlineWidth: l.OutlineWidth,
+			Uppercase:    l.Uppercase,
+		}
+		if l.FontPath != "" {
+			if slot.FontPath, slot.FontData, err = documentFont(g.config, l.FontPath); err != nil {
+				return err
+			}
+		}
+		if slot.Color, err = optionalColor(l.Color); err != nil {
+			return err
+		}
+		if slot.OutlineColor, err = optionalColor(l.OutlineColor); err != nil {
+			return err
+		}
+		switch l.Align {
+		case "", "center":
+		case "left":
+			slot.Align = AlignLeft
+		case "right":
+			slot.Align = AlignRight
+		default:
+			return fmt.Errorf("unknown align %q", l.Align)
+		}
+		return g.drawSlotText(out, slot, l.Text)
+	}
+	return fmt.Errorf("unknown layer type %q", l.Type)
+}
+
+// clip проверяет размеры слоя и обрезает его по холсту. Масштабирование
+// выделяет буферы по размеру прямоугольника назначения, поэтому слой больше
+// холста из чужого документа отклоняется, а не рисуется.
+func (lr LayerRect) clip(canvas image.Rectangle) (image.Rectangle, error) {
+	if lr.W <= 0 || lr.H <= 0 || lr.W > canvas.Dx() || lr.H > canvas.Dy() {
+		return image.Rectangle{}, &ConfigError{
+			Field:  "rect",
+			Reason: fmt.Sprintf("%dx%d must be positive and fit the %dx%d canvas", lr.W, lr.H, canvas.Dx(), canvas.Dy()),
+		}
+	}
+	r := image.Rect(lr.X, lr.Y, lr.X+lr.W, lr.Y+lr.H)
+	return r.Intersect(canvas), nil
+}
+
+// documentFont выбирает шрифт font_path документа. Документ не должен
+// читать произвольные файлы, поэтому допускаются имя встроенного шрифта и
+// файл, который уже задан в конфигурации генератора cfg.
+func documentFont(cfg *Config, name string) (string, []byte, error) {
+	if data, ok := GetAvailableFonts()[name]; ok {
+		return "", data, nil
+	}
+	if cfg.FontPath != "" && name == cfg.FontPath {
+		return cfg.FontPath, nil, nil
+	}
+	return "", nil, fmt.Errorf("%w: font %q is not allowed in documents, use a built-in font name", ErrFontNotFound, name)
+}
+
+// styleFromConfig сохраняет оформление конфигурации
+func styleFromConfig(cfg *Config) *DocumentStyle {
+	s := &DocumentStyle{
+		FontPath:         cfg.FontPath,
+		FontSize:         cfg.FontSize,
+		AutoFontSize:     cfg.AutoFontSize,
+		Padding:          cfg.Padding,
+		Border:           cfg.Border,
+		TextOutlineWidth: cfg.TextOutlineWidth,
+		TextUppercase:    cfg.TextUppercase,
+	}
+	if cfg.BackgroundColor != nil {
+		s.BackgroundColor = FormatColor(cfg.BackgroundColor)
+	}
+	if cfg.BorderColor != nil {
+		s.BorderColor = FormatColor(cfg.BorderColor)
+	}
+	if cfg.TextColor != nil {
+		s.TextColor = FormatColor(cfg.TextColor)
+	}
+	if cfg.TextOutlineColor != nil {
+		s.TextOutlineColor = FormatColor(cfg.TextOutlineColor)
+	}
+	return s
+}
+
+// apply переносит оформление в конфигурацию; пустые цвета не меняются
+func (s *DocumentStyle) apply(cfg *Config) error {
+	if s.FontPath != "" {
+		var err error
+		if cfg.FontPath, cfg.FontData, err = documentFont(cfg, s.FontPath); err != nil {
+			return err
+		}
+	}
+	if s.Padding > maxDocumentPadding {
+		return &ConfigError{Field: "padding", Reason: fmt.Sprintf("must be at most %d", maxDocumentPadding)}
+	}
+	if s.Border > maxDocumentPadding {
+		return &ConfigError{Field: "border", Reason: fmt.Sprintf("must be at most %d", maxDocumentPadding)}
+	}
+	cfg.FontSize = s.FontSize
+	cfg.AutoFontSize = s.AutoFontSize
+	cfg.Padding = s.Padding
+	cfg.Border = s.Border
+	cfg.TextOutlineWidth = s.TextOutlineWidth
+	cfg.TextUppercase = s.TextUppercase
+	for _, c := range []struct {
+		value string
+		dst   *color.Color
+	}{
+		{s.BackgroundColor, &cfg.BackgroundColor},
+		{s.BorderColor, &cfg.BorderColor},
+		{s.TextColor, &cfg.TextColor},
+		{s.TextOutlineColor, &cfg.TextOutlineColor},
+	} {
+		parsed, err := optionalColor(c.value)
+		if err != nil {
+			return err
+		}
+		if parsed != nil {
+			*c.dst = parsed
+		}
+	}
+	return nil
+}
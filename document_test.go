@@ -0,0 +1,115 @@
+package meme
+
+import (
+	"errors"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileResolverConfined(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "docs")
+	if err := os.MkdirAll(filepath.Join(dir, "img"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writePNG := func(path string) {
+		t.Helper()
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if err := png.Encode(f, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writePNG(filepath.Join(dir, "img", "photo.png"))
+	writePNG(filepath.Join(root, "secret.png"))
+	if err := os.Symlink(filepath.Join(root, "secret.png"), filepath.Join(dir, "link.png")); err != nil {
+		t.Fatal(err)
+	}
+
+	resolve := NewGenerator(nil).FileResolver(dir)
+	if _, err := resolve("img/photo.png"); err != nil {
+		t.Errorf("relative path: %v", err)
+	}
+	for _, ref := range []string{
+		"../secret.png",
+		"img/../../secret.png",
+		filepath.Join(root, "secret.png"),
+		"link.png",
+	} {
+		if _, err := resolve(ref); err == nil {
+			t.Errorf("%q: resolved outside of %s", ref, dir)
+		}
+	}
+}
+
+func TestDocumentFont(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.FontPath = "/srv/fonts/brand.ttf"
+	for _, tc := range []struct {
+		name, font string
+		ok         bool
+	}{
+		{"built-in", "bold", true},
+		{"generator font", "/srv/fonts/brand.ttf", true},
+		{"other file", "/etc/passwd", false},
+		{"relative file", "../fonts/brand.ttf", false},
+	} {
+		_, _, err := documentFont(cfg, tc.font)
+		if ok := err == nil; ok != tc.ok {
+			t.Errorf("%s: err = %v", tc.name, err)
+		}
+		if err != nil && !errors.Is(err, ErrFontNotFound) {
+			t.Errorf("%s: got %v, want ErrFontNotFound", tc.name, err)
+		}
+	}
+
+	s := &DocumentStyle{FontPath: "/etc/passwd"}
+	if err := s.apply(DefaultConfig()); !errors.Is(err, ErrFontNotFound) {
+		t.Errorf("style font_path: got %v, want ErrFontNotFound", err)
+	}
+}
+
+func TestRenderDocumentHostile(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 200, 200))
+	resolve := func(string) (image.Image, error) { return src, nil }
+	g := NewGenerator(nil)
+
+	for _, tc := range []struct {
+		name  string
+		layer Layer
+	}{
+		{"huge image", Layer{Type: "image", Source: "a.png", Rect: LayerRect{W: 40000, H: 40000}}},
+		{"huge text", Layer{Type: "text", Text: "hi", Rect: LayerRect{W: 1 << 30, H: 1 << 30}}},
+		{"zero", Layer{Type: "image", Source: "a.png", Rect: LayerRect{W: 0, H: 10}}},
+		{"negative", Layer{Type: "text", Text: "hi", Rect: LayerRect{W: -10, H: 10}}},
+	} {
+		d := &Document{Version: DocumentVersion, Source: "a.png", Layers: []Layer{tc.layer}}
+		if _, err := g.RenderDocument(d, resolve); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("%s: got %v, want ErrInvalidConfig", tc.name, err)
+		}
+	}
+
+	// Слой, выходящий за холст, обрезается
+	d := &Document{Version: DocumentVersion, Source: "a.png", Layers: []Layer{
+		{Type: "image", Source: "a.png", Rect: LayerRect{X: -50, Y: 100, W: 100, H: 100}},
+	}}
+	if _, err := g.RenderDocument(d, resolve); err != nil {
+		t.Errorf("partly outside: %v", err)
+	}
+
+	for _, style := range []*DocumentStyle{
+		{Padding: 1 << 20, Border: 1, AutoFontSize: true},
+		{Padding: 1, Border: 1 << 20, AutoFontSize: true},
+	} {
+		d := &Document{Version: DocumentVersion, Source: "a.png", Style: style}
+		if _, err := g.RenderDocument(d, resolve); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("padding %d, border %d: got %v, want ErrInvalidConfig", style.Padding, style.Border, err)
+		}
+	}
+}
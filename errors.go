@@ -0,0 +1,119 @@
+package meme
+
+import (
+	"errors"
+	"sync"
+)
+
+// Ошибки шрифтов и подписей
+var (
+	ErrFontNotFound = errors.New("font file not found")
+	ErrInvalidFont  = errors.New("invalid font")
+	ErrTextTooLong  = errors.New("caption is too long")
+
+	ErrTemplateNotFound = errors.New("template not found")
+	ErrUnknownSlot      = errors.New("unknown template slot")
+)
+
+// Коды ошибок в порядке проверки: первый совпавший через errors.Is
+var errorCodes = []struct {
+	target error
+	code   string
+}{
+	{ErrNilImage, "nil_image"},
+	{ErrEmptyImage, "empty_image"},
+	{ErrImageTooLarge, "image_too_large"},
+	{ErrInputTooLarge, "input_too_large"},
+	{ErrInvalidConfig, "invalid_config"},
+	{ErrTextTooLong, "text_too_long"},
+	{ErrFontNotFound, "font_not_found"},
+	{ErrInvalidFont, "invalid_font"},
+	{ErrTemplateNotFound, "template_not_found"},
+	{ErrUnknownSlot, "unknown_slot"},
+	{ErrUnknownFormat, "unknown_format"},
+	{ErrFormatNotAllowed, "format_not_allowed"},
+	{ErrHEIFUnsupported, "heif_unsupported"},
+	{ErrDecode, "decode_failed"},
+	{ErrURLNotAllowed, "url_not_allowed"},
+	{ErrOutputTooLarge, "output_too_large"},
+	{ErrBusy, "busy"},
+	{ErrPanic, "panic"},
+}
+
+// ErrorReason возвращает код ошибки для метрик, логов и ответов API:
+// "nil_image", "image_too_large", "font_not_found" и т.д., "other" для
+// остальных ошибок и пустую строку для nil
+func ErrorReason(err error) string {
+	if err == nil {
+		return ""
+	}
+	for _, c := range errorCodes {
+		if errors.Is(err, c.target) {
+			return c.code
+		}
+	}
+	return "other"
+}
+
+// Переводы сообщений об ошибках: язык -> код ошибки -> текст
+var (
+	errorMessagesMu sync.RWMutex
+	errorMessages   = map[string]map[string]string{
+		"ru": {
+			"nil_image":          "изображение не задано",
+			"empty_image":        "изображение имеет нулевой размер",
+			"image_too_large":    "изображение слишком большое",
+			"input_too_large":    "входной файл слишком большой",
+			"invalid_config":     "некорректная конфигурация",
+			"text_too_long":      "слишком длинная подпись",
+			"font_not_found":     "файл шрифта не найден",
+			"invalid_font":       "некорректный файл шрифта",
+			"template_not_found": "шаблон не найден",
+			"unknown_slot":       "в шаблоне нет такого поля",
+			"unknown_format":     "неизвестный формат изображения",
+			"format_not_allowed": "формат изображения запрещён",
+			"heif_unsupported":   "формат HEIF/HEIC не поддерживается",
+			"decode_failed":      "изображение повреждено",
+			"url_not_allowed":    "адрес изображения запрещён",
+			"output_too_large":   "результат не укладывается в предел размера",
+			"busy":               "сервер перегружен, попробуйте позже",
+			"panic":              "внутренняя ошибка",
+			"other":              "не удалось создать мем",
+		},
+	}
+)
+
+// RegisterErrorMessages добавляет или заменяет переводы для языка lang.
+// Ключи - коды из ErrorReason.
+func RegisterErrorMessages(lang string, messages map[string]string) {
+	errorMessagesMu.Lock()
+	defer errorMessagesMu.Unlock()
+	table := errorMessages[lang]
+	if table == nil {
+		table = make(map[string]string, len(messages))
+		errorMessages[lang] = table
+	}
+	for code, msg := range messages {
+		table[code] = msg
+	}
+}
+
+// LocalizeError возвращает сообщение для показа пользователю на языке lang.
+// Для ConfigError добавляется имя поля. Если перевода нет, возвращается
+// исходный текст ошибки на английском.
+func LocalizeError(err error, lang string) string {
+	if err == nil {
+		return ""
+	}
+	errorMessagesMu.RLock()
+	msg, ok := errorMessages[lang][ErrorReason(err)]
+	errorMessagesMu.RUnlock()
+	if !ok {
+		return err.Error()
+	}
+	var cfgErr *ConfigError
+	if errors.As(err, &cfgErr) {
+		msg += ": " + cfgErr.Field
+	}
+	return msg
+}
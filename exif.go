@@ -0,0 +1,207 @@
+package meme
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+)
+
+// Значения тега EXIF Orientation (0x0112)
+const (
+	orientNormal     = 1
+	orientFlipH      = 2
+	orientRotate180  = 3
+	orientFlipV      = 4
+	orientTranspose  = 5
+	orientRotate90   = 6 // поворот на 90° по часовой стрелке
+	orientTransverse = 7
+	orientRotate270  = 8
+)
+
+const exifTagOrientation = 0x0112
+
+// findExif извлекает TIFF-блок EXIF из JPEG (APP1), PNG (eXIf) или WebP (чанк EXIF)
+func findExif(data []byte) []byte {
+	switch {
+	case len(data) > 2 && data[0] == 0xff && data[1] == 0xd8:
+		return findJPEGExif(data)
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return findPNGChunk(data, "eXIf")
+	case len(data) > 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		exif := findRIFFChunk(data, "EXIF")
+		// Некоторые кодировщики оставляют префикс "Exif\0\0" и в WebP
+		return bytes.TrimPrefix(exif, []byte("Exif\x00\x00"))
+	}
+	return nil
+}
+
+func findJPEGExif(data []byte) []byte {
+	for p := 2; p+4 <= len(data); {
+		if data[p] != 0xff {
+			return nil
+		}
+		marker := data[p+1]
+		if marker == 0xd8 || (marker >= 0xd0 && marker <= 0xd7) || marker == 0x01 {
+			p += 2
+			continue
+		}
+		// Начало данных скана или конец файла: метаданных дальше нет
+		if marker == 0xda || marker == 0xd9 {
+			return nil
+		}
+		size := int(binary.BigEndian.Uint16(data[p+2:]))
+		if size < 2 || p+2+size > len(data) {
+			return nil
+		}
+		segment := data[p+4 : p+2+size]
+		if marker == 0xe1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return segment[6:]
+		}
+		p += 2 + size
+	}
+	return nil
+}
+
+func findPNGChunk(data []byte, name string) []byte {
+	for p := 8; p+8 <= len(data); {
+		size := int(binary.BigEndian.Uint32(data[p:]))
+		if size < 0 || p+12+size > len(data) {
+			return nil
+		}
+		typ := string(data[p+4 : p+8])
+		if typ == name {
+			return data[p+8 : p+8+size]
+		}
+		if typ == "IDAT" || typ == "IEND" {
+			return nil
+		}
+		p += 12 + size
+	}
+	return nil
+}
+
+func findRIFFChunk(data []byte, name string) []byte {
+	for p := 12; p+8 <= len(data); {
+		size := int(binary.LittleEndian.Uint32(data[p+4:]))
+		if size < 0 || p+8+size > len(data) {
+			return nil
+		}
+		if string(data[p:p+4]) == name {
+			return data[p+8 : p+8+size]
+		}
+		p += 8 + size + size&1
+	}
+	return nil
+}
+
+// exifOrientation возвращает значение тега Orientation или 1, если тега нет
+func exifOrientation(data []byte) int {
+	tiff := findExif(data)
+	if len(tiff) < 8 {
+		return orientNormal
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return orientNormal
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return orientNormal
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			break
+		}
+		if order.Uint16(tiff[entry:]) != exifTagOrientation {
+			continue
+		}
+		// Тип SHORT, значение лежит прямо в записи
+		v := int(order.Uint16(tiff[entry+8:]))
+		if v >= orientNormal && v <= orientRotate270 {
+			return v
+		}
+		break
+	}
+	return orientNormal
+}
+
+// applyOrientation поворачивает и отражает изображение так, чтобы оно
+// отображалось как задумано. Изображения с Pix сохраняют свой тип, в
+// том числе 16-битные; остальные приводятся к *image.RGBA.
+func applyOrientation(img image.Image, orientation int) image.Image {
+	if orientation <= orientNormal || orientation > orientRotate270 {
+		return img
+	}
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dr := image.Rect(0, 0, w, h)
+	if orientation >= orientTranspose {
+		dr = image.Rect(0, 0, h, w)
+	}
+
+	switch src := img.(type) {
+	case *image.NRGBA64:
+		dst := image.NewNRGBA64(dr)
+		orientPix(dst.Pix, dst.Stride, src.Pix[src.PixOffset(b.Min.X, b.Min.Y):], src.Stride, w, h, 8, orientation)
+		return dst
+	case *image.RGBA64:
+		dst := image.NewRGBA64(dr)
+		orientPix(dst.Pix, dst.Stride, src.Pix[src.PixOffset(b.Min.X, b.Min.Y):], src.Stride, w, h, 8, orientation)
+		return dst
+	case *image.Gray16:
+		dst := image.NewGray16(dr)
+		orientPix(dst.Pix, dst.Stride, src.Pix[src.PixOffset(b.Min.X, b.Min.Y):], src.Stride, w, h, 2, orientation)
+		return dst
+	case *image.NRGBA:
+		dst := image.NewNRGBA(dr)
+		orientPix(dst.Pix, dst.Stride, src.Pix[src.PixOffset(b.Min.X, b.Min.Y):], src.Stride, w, h, 4, orientation)
+		return dst
+	case *image.Gray:
+		dst := image.NewGray(dr)
+		orientPix(dst.Pix, dst.Stride, src.Pix[src.PixOffset(b.Min.X, b.Min.Y):], src.Stride, w, h, 1, orientation)
+		return dst
+	}
+	src := toRGBA(img)
+	dst := image.NewRGBA(dr)
+	orientPix(dst.Pix, dst.Stride, src.Pix, src.Stride, w, h, 4, orientation)
+	return dst
+}
+
+// orientPix переносит пиксели размером bpp байт из src (w x h, начало в
+// нуле) в dst с поворотом и отражением orientation
+func orientPix(dst []byte, dstStride int, src []byte, srcStride, w, h, bpp, orientation int) {
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case orientFlipH:
+				dx, dy = w-1-x, y
+			case orientRotate180:
+				dx, dy = w-1-x, h-1-y
+			case orientFlipV:
+				dx, dy = x, h-1-y
+			case orientTranspose:
+				dx, dy = y, x
+			case orientRotate90:
+				dx, dy = h-1-y, x
+			case orientTransverse:
+				dx, dy = h-1-y, w-1-x
+			case orientRotate270:
+				dx, dy = y, w-1-x
+			}
+			s := y*srcStride + x*bpp
+			d := dy*dstStride + dx*bpp
+			copy(dst[d:d+bpp], src[s:s+bpp])
+		}
+	}
+}
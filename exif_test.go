@@ -0,0 +1,198 @@
+package meme
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// exifTIFF собирает TIFF-блок EXIF с единственным тегом Orientation
+func exifTIFF(order binary.ByteOrder, orientation uint16) []byte {
+	var buf bytes.Buffer
+	if order == binary.LittleEndian {
+		buf.WriteString("II")
+	} else {
+		buf.WriteString("MM")
+	}
+	binary.Write(&buf, order, uint16(42))
+	binary.Write(&buf, order, uint32(8))
+	binary.Write(&buf, order, uint16(1))
+	binary.Write(&buf, order, uint16(exifTagOrientation))
+	binary.Write(&buf, order, uint16(3)) // SHORT
+	binary.Write(&buf, order, uint32(1))
+	binary.Write(&buf, order, orientation)
+	binary.Write(&buf, order, uint16(0))
+	binary.Write(&buf, order, uint32(0))
+	return buf.Bytes()
+}
+
+// withPNGChunk вставляет чанк сразу после IHDR
+func withPNGChunk(t *testing.T, data []byte, typ string, chunk []byte) []byte {
+	t.Helper()
+	const ihdrEnd = 8 + 8 + 13 + 4
+	if len(data) < ihdrEnd || string(data[12:16]) != "IHDR" {
+		t.Fatal("not a PNG with IHDR")
+	}
+	var buf bytes.Buffer
+	buf.Write(data[:ihdrEnd])
+	binary.Write(&buf, binary.BigEndian, uint32(len(chunk)))
+	buf.WriteString(typ)
+	buf.Write(chunk)
+	binary.Write(&buf, binary.BigEndian, crc32.ChecksumIEEE(append([]byte(typ), chunk...)))
+	buf.Write(data[ihdrEnd:])
+	return buf.Bytes()
+}
+
+func TestApplyOrientationKeeps16Bit(t *testing.T) {
+	src := image.NewNRGBA64(image.Rect(0, 0, 3, 2))
+	marked := color.NRGBA64{R: 0x1234, G: 0x5678, B: 0x9abc, A: 0x8001}
+	src.SetNRGBA64(0, 0, marked)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+	data := withPNGChunk(t, buf.Bytes(), "eXIf", exifTIFF(binary.BigEndian, orientRotate90))
+
+	img, _, err := DecodeImage(bytes.NewReader(data), &DecodeOptions{AutoOrient: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, ok := img.(*image.NRGBA64)
+	if !ok {
+		t.Fatalf("rotated image is %T, want *image.NRGBA64", img)
+	}
+	if b := out.Bounds(); b.Dx() != 2 || b.Dy() != 3 {
+		t.Fatalf("rotated size %v, want 2x3", b)
+	}
+	// Поворот на 90° по часовой: левый верхний угол уходит в правый верхний
+	if c := out.NRGBA64At(1, 0); c != marked {
+		t.Errorf("pixel (1,0) = %v, want %v", c, marked)
+	}
+}
+
+func TestApplyOrientation(t *testing.T) {
+	// Пиксель (0,0) картинки 3x2 после каждого преобразования
+	want := map[int]image.Point{
+		orientFlipH:      {2, 0},
+		orientRotate180:  {2, 1},
+		orientFlipV:      {0, 1},
+		orientTranspose:  {0, 0},
+		orientRotate90:   {1, 0},
+		orientTransverse: {1, 2},
+		orientRotate270:  {0, 2},
+	}
+	marked := color.Gray16{Y: 0xabcd}
+	for orientation, p := range want {
+		for _, src := range []image.Image{image.NewGray16(image.Rect(0, 0, 3, 2)), image.NewRGBA(image.Rect(10, 10, 13, 12))} {
+			b := src.Bounds()
+			src.(interface{ Set(x, y int, c color.Color) }).Set(b.Min.X, b.Min.Y, marked)
+			out := applyOrientation(src, orientation)
+			if _, same := out.(*image.Gray16); same != is16Bit(src) {
+				t.Errorf("orientation %d: %T became %T", orientation, src, out)
+			}
+			if got := color.Gray16Model.Convert(out.At(p.X, p.Y)).(color.Gray16); got != color.Gray16Model.Convert(src.At(b.Min.X, b.Min.Y)) {
+				t.Errorf("orientation %d, %T: pixel %v = %v", orientation, src, p, got)
+			}
+		}
+	}
+}
+
+// withJPEGSegment вставляет сегмент marker сразу после SOI
+func withJPEGSegment(t *testing.T, data []byte, marker byte, payload []byte) []byte {
+	t.Helper()
+	if len(data) < 2 || data[0] != 0xff || data[1] != 0xd8 {
+		t.Fatal("not a JPEG")
+	}
+	var buf bytes.Buffer
+	buf.Write(data[:2])
+	buf.Write([]byte{0xff, marker})
+	binary.Write(&buf, binary.BigEndian, uint16(len(payload)+2))
+	buf.Write(payload)
+	buf.Write(data[2:])
+	return buf.Bytes()
+}
+
+// riffWebP собирает контейнер WebP из чанков
+func riffWebP(chunks ...[]byte) []byte {
+	var body bytes.Buffer
+	body.WriteString("WEBP")
+	for _, c := range chunks {
+		body.Write(c)
+		if len(c)%2 == 1 {
+			body.WriteByte(0)
+		}
+	}
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(body.Len()))
+	buf.Write(body.Bytes())
+	return buf.Bytes()
+}
+
+func riffChunk(name string, data []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(name)
+	binary.Write(&buf, binary.LittleEndian, uint32(len(data)))
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+func TestExifOrientation(t *testing.T) {
+	var jpg, pngData bytes.Buffer
+	if err := jpeg.Encode(&jpg, image.NewGray(image.Rect(0, 0, 8, 8)), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(&pngData, image.NewGray(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatal(err)
+	}
+	app1 := func(tiff []byte) []byte { return append([]byte("Exif\x00\x00"), tiff...) }
+	le, be := exifTIFF(binary.LittleEndian, orientRotate90), exifTIFF(binary.BigEndian, orientRotate270)
+
+	for _, tc := range []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"jpeg little endian", withJPEGSegment(t, jpg.Bytes(), 0xe1, app1(le)), orientRotate90},
+		{"jpeg big endian", withJPEGSegment(t, jpg.Bytes(), 0xe1, app1(be)), orientRotate270},
+		{"jpeg after other segment", withJPEGSegment(t, withJPEGSegment(t, jpg.Bytes(), 0xe1, app1(le)), 0xe0, []byte("JFIF\x00")), orientRotate90},
+		{"png", withPNGChunk(t, pngData.Bytes(), "eXIf", be), orientRotate270},
+		{"webp", riffWebP(riffChunk("VP8L", []byte{1, 2, 3}), riffChunk("EXIF", le)), orientRotate90},
+		{"webp with prefix", riffWebP(riffChunk("EXIF", app1(be))), orientRotate270},
+		{"no exif", jpg.Bytes(), orientNormal},
+
+		// Испорченные данные дают нормальную ориентацию, а не панику
+		{"app1 without Exif prefix", withJPEGSegment(t, jpg.Bytes(), 0xe1, le), orientNormal},
+		{"app1 size past end", append([]byte{0xff, 0xd8, 0xff, 0xe1, 0xff, 0xff}, app1(le)...), orientNormal},
+		{"app1 size below 2", []byte{0xff, 0xd8, 0xff, 0xe1, 0x00, 0x01, 0x00, 0x00}, orientNormal},
+		{"garbage between segments", []byte{0xff, 0xd8, 0x00, 0xff, 0xe1, 0x00, 0x02}, orientNormal},
+		{"bad byte order", withJPEGSegment(t, jpg.Bytes(), 0xe1, app1(append([]byte("XX"), le[2:]...))), orientNormal},
+		{"short tiff", withJPEGSegment(t, jpg.Bytes(), 0xe1, app1(le[:6])), orientNormal},
+		{"ifd past end", withJPEGSegment(t, jpg.Bytes(), 0xe1, app1(append(le[:4:4], 0xff, 0xff, 0, 0))), orientNormal},
+		{"entries past end", withJPEGSegment(t, jpg.Bytes(), 0xe1, app1(le[:14])), orientNormal},
+		{"orientation out of range", withJPEGSegment(t, jpg.Bytes(), 0xe1, app1(exifTIFF(binary.LittleEndian, 9))), orientNormal},
+		{"png chunk past end", append(pngData.Bytes()[:8:8], 0x7f, 0xff, 0xff, 0xff, 'e', 'X', 'I', 'f'), orientNormal},
+		{"webp chunk past end", riffWebP([]byte("EXIF\xff\xff\xff\x7f")), orientNormal},
+	} {
+		if got := exifOrientation(tc.data); got != tc.want {
+			t.Errorf("%s: orientation %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestExifOrientationTruncated(t *testing.T) {
+	var jpg bytes.Buffer
+	if err := jpeg.Encode(&jpg, image.NewGray(image.Rect(0, 0, 8, 8)), nil); err != nil {
+		t.Fatal(err)
+	}
+	data := withJPEGSegment(t, jpg.Bytes(), 0xe1, append([]byte("Exif\x00\x00"), exifTIFF(binary.BigEndian, orientRotate90)...))
+	// Любой обрезанный файл разбирается без паники
+	for n := range len(data) {
+		exifOrientation(data[:n])
+	}
+}
@@ -0,0 +1,246 @@
+package meme
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Format - формат выходного файла
+type Format string
+
+const (
+	FormatPNG  Format = "png"
+	FormatJPEG Format = "jpeg"
+	FormatWebP Format = "webp" // WebP без потерь (VP8L)
+)
+
+// Encoder описывает выходной формат
+type Encoder struct {
+	Format Format
+
+	// Extensions - расширения файлов без точки для ParseFormat; пусто -
+	// только имя формата
+	Extensions []string
+
+	// MIMEType - тип содержимого для ответов HTTP, пусто - "image/" + Format
+	MIMEType string
+
+	// Encode кодирует пиксели; Metadata, StripMetadata и Signer
+	// применяются к результату после него
+	Encode func(w io.Writer, img image.Image, opts *EncodeOptions) error
+}
+
+// Встроенный реестр кодировщиков
+var (
+	encodersMu sync.RWMutex
+	encoders   = []Encoder{
+		{Format: FormatPNG, Encode: func(w io.Writer, img image.Image, _ *EncodeOptions) error { return png.Encode(w, img) }},
+		{Format: FormatJPEG, Extensions: []string{"jpeg", "jpg"}, Encode: encodeJPEG},
+		{Format: FormatWebP, Encode: func(w io.Writer, img image.Image, opts *EncodeOptions) error {
+			return encodeWebP(w, img, opts.NearLossless)
+		}},
+	}
+)
+
+// RegisterEncoder добавляет выходной формат в реестр или заменяет
+// зарегистрированный с тем же именем, в том числе встроенный. Метаданные
+// (EncodeOptions.Metadata) встраиваются только во встроенные форматы.
+func RegisterEncoder(e Encoder) error {
+	if e.Format == "" || e.Encode == nil {
+		return errors.New("encoder needs Format and Encode")
+	}
+	e.Extensions = slices.Clone(e.Extensions)
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+	for i := range encoders {
+		if encoders[i].Format == e.Format {
+			encoders[i] = e
+			return nil
+		}
+	}
+	encoders = append(encoders, e)
+	return nil
+}
+
+// lookupEncoder возвращает кодировщик формата; пустой формат - PNG
+func lookupEncoder(f Format) (Encoder, bool) {
+	if f == "" {
+		f = FormatPNG
+	}
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+	for _, e := range encoders {
+		if e.Format == f {
+			return e, true
+		}
+	}
+	return Encoder{}, false
+}
+
+// Formats возвращает зарегистрированные выходные форматы: сначала
+// встроенные, затем в порядке регистрации
+func Formats() []Format {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+	formats := make([]Format, len(encoders))
+	for i, e := range encoders {
+		formats[i] = e.Format
+	}
+	return formats
+}
+
+// MIMEType возвращает тип содержимого формата f; для незарегистрированных
+// форматов - "application/octet-stream"
+func MIMEType(f Format) string {
+	e, ok := lookupEncoder(f)
+	switch {
+	case !ok:
+		return "application/octet-stream"
+	case e.MIMEType != "":
+		return e.MIMEType
+	}
+	return "image/" + string(e.Format)
+}
+
+// ParseFormat разбирает название формата или расширение файла:
+// "png", "jpeg", "jpg", "webp" и зарегистрированные через RegisterEncoder
+// (регистр и точка в начале не важны)
+func ParseFormat(s string) (Format, error) {
+	name := strings.ToLower(strings.TrimPrefix(s, "."))
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+	for _, e := range encoders {
+		if string(e.Format) == name || slices.Contains(e.Extensions, name) {
+			return e.Format, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported output format: %s", s)
+}
+
+// EncodeOptions содержит настройки кодирования результата
+type EncodeOptions struct {
+	Format Format
+
+	// Quality - качество JPEG (1-100), 0 означает значение по умолчанию
+	Quality int
+
+	// NearLossless - уровень огрубления цветов для WebP (0 - без потерь, максимум 5).
+	// Каждый уровень отбрасывает ещё один младший бит в цветовых каналах.
+	NearLossless int
+
+	// Metadata - метаданные для встраивания в файл (nil - без метаданных)
+	Metadata *Metadata
+
+	// StripMetadata гарантирует отсутствие EXIF, XMP и текстовых чанков в
+	// результате: Metadata игнорируется, а закодированный файл дополнительно
+	// очищается. Рекомендуется для ботов, пересылающих пользовательские фото.
+	StripMetadata bool
+
+	// Signer вызывается после кодирования для подписи результата (nil - без подписи)
+	Signer Signer
+
+	// MaxOutputBytes - предел размера файла вместе с метаданными и подписью
+	// (0 - без предела), например для платформ с ограничением загрузки.
+	// Чтобы уложиться, Encode снижает качество JPEG от Quality, огрубляет
+	// цвета WebP до NearLossless 5 и затем уменьшает изображение; если
+	// не помогает и это, возвращается ErrOutputTooLarge.
+	MaxOutputBytes int64
+}
+
+// DefaultJPEGQuality - качество JPEG по умолчанию
+const DefaultJPEGQuality = 90
+
+// Encode кодирует изображение в выбранный формат
+func Encode(w io.Writer, img image.Image, opts *EncodeOptions) (err error) {
+	defer recoverPanic(&err)
+	if opts == nil {
+		opts = &EncodeOptions{Format: FormatPNG}
+	}
+
+	if opts.MaxOutputBytes > 0 {
+		data, err := encodeWithin(img, opts)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+	if opts.Metadata == nil && !opts.StripMetadata && opts.Signer == nil {
+		return encodeImage(w, img, opts)
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+	data, err := encodeFile(buf, img, opts)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// encodeFile кодирует изображение в buf вместе с метаданными и подписью
+// и возвращает готовый файл
+func encodeFile(buf *bytes.Buffer, img image.Image, opts *EncodeOptions) (data []byte, err error) {
+	if err := encodeImage(buf, img, opts); err != nil {
+		return nil, err
+	}
+	data = buf.Bytes()
+
+	switch {
+	case opts.StripMetadata:
+		data, err = StripMetadata(data)
+	case opts.Metadata != nil:
+		data, err = embedMetadata(data, opts.Format, opts.Metadata)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if opts.Signer != nil {
+		format := opts.Format
+		if format == "" {
+			format = FormatPNG
+		}
+		if data, err = sign(opts.Signer, data, format); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// encodeImage кодирует пиксели без какой-либо постобработки
+func encodeImage(w io.Writer, img image.Image, opts *EncodeOptions) error {
+	e, ok := lookupEncoder(opts.Format)
+	if !ok {
+		return fmt.Errorf("unsupported output format: %s", opts.Format)
+	}
+	return e.Encode(w, img, opts)
+}
+
+func encodeJPEG(w io.Writer, img image.Image, opts *EncodeOptions) error {
+	quality := opts.Quality
+	if quality <= 0 {
+		quality = DefaultJPEGQuality
+	}
+	if quality > 100 {
+		quality = 100
+	}
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+}
+
+// EncodeBytes кодирует изображение и возвращает результат в виде байтов
+func EncodeBytes(img image.Image, opts *EncodeOptions) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, img, opts); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,51 @@
+package meme
+
+import (
+	"image"
+	"image/color"
+)
+
+// Extrude - объемные "ретро" буквы: силуэт подписи повторяется Depth раз
+// со сдвигом Direction за основной заливкой, цвет слоев плавно меняется
+// от Color у букв до FarColor в глубине
+type Extrude struct {
+	Depth     int         // число слоев, 0 - без объема
+	Direction image.Point // сдвиг одного слоя в пикселях, нулевой - (1, 1): вправо-вниз
+
+	Color    color.Color // ближний слой, nil - цвет обводки или черный
+	FarColor color.Color // дальний слой, nil - как Color
+}
+
+// Предел глубины: каждый слой - отдельный проход по маске подписи
+const maxExtrudeDepth = 256
+
+// step возвращает сдвиг одного слоя
+func (e Extrude) step() image.Point {
+	if e.Direction == (image.Point{}) {
+		return image.Pt(1, 1)
+	}
+	return e.Direction
+}
+
+// draw рисует слои от дальнего к ближнему; fill рисует маску со сдвигом
+func (e Extrude) draw(silhouette *image.Alpha, near color.Color, fill func(c color.Color, m *image.Alpha, off image.Point)) {
+	if e.Color != nil {
+		near = e.Color
+	}
+	far := near
+	if e.FarColor != nil {
+		far = e.FarColor
+	}
+	n := color.NRGBAModel.Convert(near).(color.NRGBA)
+	f := color.NRGBAModel.Convert(far).(color.NRGBA)
+	lerp := func(a, b uint8, t float64) uint8 { return uint8(float64(a) + (float64(b)-float64(a))*t + 0.5) }
+	step := e.step()
+	for i := min(e.Depth, maxExtrudeDepth); i >= 1; i-- {
+		t := 0.0
+		if e.Depth > 1 {
+			t = float64(i-1) / float64(e.Depth-1)
+		}
+		c := color.NRGBA{lerp(n.R, f.R, t), lerp(n.G, f.G, t), lerp(n.B, f.B, t), lerp(n.A, f.A, t)}
+		fill(c, silhouette, step.Mul(i))
+	}
+}
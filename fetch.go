@@ -0,0 +1,282 @@
+package meme
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrURLNotAllowed - адрес изображения запрещен FetchImage: не http(s),
+// приватная сеть или слишком много перенаправлений
+var ErrURLNotAllowed = errors.New("image url is not allowed")
+
+// Значения FetchOptions по умолчанию
+const (
+	DefaultFetchMaxBytes     = 20 << 20
+	DefaultFetchTimeout      = 30 * time.Second
+	DefaultFetchMaxRedirects = 5
+)
+
+// FetchOptions задает ограничения FetchImage. Нулевое значение безопасно
+// для адресов, присланных пользователями.
+type FetchOptions struct {
+	MaxBytes     int64         // предел размера ответа, 0 - 20 МиБ
+	Timeout      time.Duration // предел времени на загрузку вместе с телом, 0 - 30 секунд
+	MaxRedirects int           // 0 - 5, отрицательное - без перенаправлений
+
+	// ContentTypes - разрешенные типы содержимого; элемент с "/" в конце
+	// задает префикс. nil - "image/" и "application/octet-stream".
+	ContentTypes []string
+
+	// AllowPrivate разрешает адреса loopback, частных и служебных сетей.
+	// Нужно только для тестов и сервисов во внутренней сети.
+	AllowPrivate bool
+
+	UserAgent string // пусто - "go-goblin-meme"
+}
+
+var defaultContentTypes = []string{"image/", "application/octet-stream"}
+
+// FetchImage загружает изображение по http(s)-адресу с защитой от SSRF:
+// адрес проверяется после разрешения имени при каждом соединении, в
+// том числе после перенаправлений, поэтому DNS-записи на внутренние адреса
+// не помогают. Переменные окружения прокси игнорируются. Тело ответа,
+// превысившее MaxBytes, возвращает ErrInputTooLarge при чтении. Вызывающий
+// закрывает результат; Timeout действует до закрытия.
+func FetchImage(ctx context.Context, rawURL string, opts *FetchOptions) (io.ReadCloser, error) {
+	if opts == nil {
+		opts = &FetchOptions{}
+	}
+	maxBytes := orDefault(opts.MaxBytes, DefaultFetchMaxBytes)
+	timeout := orDefault(opts.Timeout, DefaultFetchTimeout)
+	maxRedirects := orDefault(opts.MaxRedirects, DefaultFetchMaxRedirects)
+
+	u, err := checkFetchURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	ua := opts.UserAgent
+	if ua == "" {
+		ua = "go-goblin-meme"
+	}
+	req.Header.Set("User-Agent", ua)
+	req.Header.Set("Accept", "image/*")
+
+	client := &http.Client{
+		Transport: fetchTransport(opts.AllowPrivate),
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > maxRedirects {
+				return fmt.Errorf("%w: more than %d redirects", ErrURLNotAllowed, max(maxRedirects, 0))
+			}
+			_, err := checkFetchURL(req.URL.String())
+			return err
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	fail := func(err error) (io.ReadCloser, error) {
+		resp.Body.Close()
+		cancel()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fail(fmt.Errorf("unexpected status %s", resp.Status))
+	}
+	if !contentTypeAllowed(resp.Header.Get("Content-Type"), opts.ContentTypes) {
+		return fail(fmt.Errorf("%w: content type %q", ErrUnknownFormat, resp.Header.Get("Content-Type")))
+	}
+	if resp.ContentLength > maxBytes {
+		return fail(fmt.Errorf("%w: %d bytes, limit is %d", ErrInputTooLarge, resp.ContentLength, maxBytes))
+	}
+	return &fetchBody{rc: resp.Body, left: maxBytes, limit: maxBytes, cancel: cancel}, nil
+}
+
+// PublicHTTPClient возвращает клиент с защитой FetchImage от SSRF для
+// других запросов на адреса от пользователей, например вебхуков:
+// соединения только с публичными адресами, без прокси, не больше
+// DefaultFetchMaxRedirects перенаправлений и только на http(s).
+// timeout - предел времени на запрос, 0 - без предела.
+func PublicHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Transport: fetchTransport(false),
+		Timeout:   timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > DefaultFetchMaxRedirects {
+				return fmt.Errorf("%w: more than %d redirects", ErrURLNotAllowed, DefaultFetchMaxRedirects)
+			}
+			_, err := checkFetchURL(req.URL.String())
+			return err
+		},
+	}
+}
+
+// orDefault возвращает def для нулевого значения
+func orDefault[T int | int64 | time.Duration](v, def T) T {
+	if v == 0 {
+		return def
+	}
+	return v
+}
+
+// checkFetchURL разбирает адрес и допускает только http и https без
+// учетных данных; сам хост проверяется при соединении
+func checkFetchURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrURLNotAllowed, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return nil, fmt.Errorf("%w: %q is not an absolute http or https url", ErrURLNotAllowed, raw)
+	}
+	if u.User != nil {
+		return nil, fmt.Errorf("%w: credentials in url", ErrURLNotAllowed)
+	}
+	return u, nil
+}
+
+// Транспорты FetchImage и PublicHTTPClient общие для всех вызовов: новый
+// транспорт на каждый запрос оставлял бы в своем пуле соединение с двумя
+// горутинами до закрытия его удаленной стороной
+var (
+	publicTransport  = newFetchTransport(false)
+	privateTransport = newFetchTransport(true)
+)
+
+// fetchTransport возвращает общий транспорт для публичных или любых адресов
+func fetchTransport(allowPrivate bool) *http.Transport {
+	if allowPrivate {
+		return privateTransport
+	}
+	return publicTransport
+}
+
+// newFetchTransport - транспорт без прокси, проверяющий каждый адрес соединения
+func newFetchTransport(allowPrivate bool) *http.Transport {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if !allowPrivate {
+		// Control вызывается для уже разрешенного адреса, поэтому проверка
+		// не обходится подменой DNS между проверкой и соединением
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			ap, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrURLNotAllowed, err)
+			}
+			if !publicAddr(ap.Addr()) {
+				return fmt.Errorf("%w: %s is not a public address", ErrURLNotAllowed, ap.Addr())
+			}
+			return nil
+		}
+	}
+	return &http.Transport{
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 20 * time.Second,
+		MaxIdleConns:          16,
+		MaxIdleConnsPerHost:   2,
+		IdleConnTimeout:       30 * time.Second,
+	}
+}
+
+// Служебные сети, не покрытые методами netip.Addr
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "эта" сеть
+	netip.MustParsePrefix("100.64.0.0/10"),   // CGNAT
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF
+	netip.MustParsePrefix("192.0.2.0/24"),    // документация
+	netip.MustParsePrefix("192.88.99.0/24"),  // ретрансляторы 6to4
+	netip.MustParsePrefix("198.18.0.0/15"),   // тесты производительности
+	netip.MustParsePrefix("198.51.100.0/24"), // документация
+	netip.MustParsePrefix("203.0.113.0/24"),  // документация
+	netip.MustParsePrefix("240.0.0.0/4"),     // зарезервировано, включая broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64 ведет на IPv4, в т.ч. внутренние
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // локальный NAT64
+	netip.MustParsePrefix("100::/64"),        // discard
+	netip.MustParsePrefix("2001::/32"),       // Teredo встраивает IPv4-адрес
+	netip.MustParsePrefix("2001:db8::/32"),   // документация
+	netip.MustParsePrefix("2002::/16"),       // 6to4 встраивает IPv4-адрес
+	netip.MustParsePrefix("fec0::/10"),       // устаревшие site-local
+}
+
+// publicAddr сообщает, что адрес принадлежит публичному интернету
+func publicAddr(a netip.Addr) bool {
+	a = a.Unmap()
+	if !a.IsValid() || a.IsLoopback() || a.IsPrivate() || a.IsUnspecified() ||
+		a.IsLinkLocalUnicast() || a.IsLinkLocalMulticast() || a.IsInterfaceLocalMulticast() || a.IsMulticast() {
+		return false
+	}
+	for _, p := range reservedPrefixes {
+		if p.Contains(a) {
+			return false
+		}
+	}
+	return true
+}
+
+// contentTypeAllowed проверяет тип содержимого ответа
+func contentTypeAllowed(header string, allowed []string) bool {
+	if allowed == nil {
+		allowed = defaultContentTypes
+	}
+	mt, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		// Без заголовка формат все равно определится по содержимому
+		return header == "" && slices.Contains(allowed, "application/octet-stream")
+	}
+	for _, t := range allowed {
+		if mt == t || strings.HasSuffix(t, "/") && strings.HasPrefix(mt, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchBody ограничивает тело ответа и отменяет контекст при закрытии
+type fetchBody struct {
+	rc     io.ReadCloser
+	left   int64
+	limit  int64
+	cancel context.CancelFunc
+}
+
+func (b *fetchBody) Read(p []byte) (int, error) {
+	if b.left < 0 {
+		return 0, fmt.Errorf("%w: more than %d bytes", ErrInputTooLarge, b.limit)
+	}
+	// Читаем на байт больше предела, чтобы отличить ровно MaxBytes от большего
+	if int64(len(p)) > b.left+1 {
+		p = p[:b.left+1]
+	}
+	n, err := b.rc.Read(p)
+	b.left -= int64(n)
+	if b.left < 0 {
+		return n + int(b.left), fmt.Errorf("%w: more than %d bytes", ErrInputTooLarge, b.limit)
+	}
+	return n, err
+}
+
+func (b *fetchBody) Close() error {
+	defer b.cancel()
+	return b.rc.Close()
+}
@@ -0,0 +1,227 @@
+package meme
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"slices"
+	"sync"
+)
+
+// Filter - эффект над фото: размытие, ч/б, "прожарка" и т.п. Фильтр
+// должен быть безопасен для одновременного использования и не изменять
+// входное изображение.
+type Filter interface {
+	// Name - имя, под которым фильтр зарегистрирован (см. RegisterFilter)
+	Name() string
+	// Params - параметры, с которыми NewFilter(Name(), Params()) создаст
+	// такой же фильтр; значения должны сериализоваться в JSON
+	Params() map[string]any
+	// Apply возвращает обработанное изображение
+	Apply(ctx context.Context, img image.Image) (image.Image, error)
+}
+
+// FilterFactory создает фильтр по параметрам из конфигурации. Числа из
+// JSON приходят как float64, из YAML - как int.
+type FilterFactory func(params map[string]any) (Filter, error)
+
+// FilterSpec - фильтр в конфигурации или шаблоне:
+//
+//	filters:
+//	  - {name: grayscale}
+//	  - {name: blur, params: {radius: 4}}
+type FilterSpec struct {
+	Name   string         `json:"name" yaml:"name"`
+	Params map[string]any `json:"params,omitempty" yaml:"params,omitempty"`
+}
+
+var (
+	filtersMu sync.RWMutex
+	filters   = map[string]FilterFactory{
+		"blur":      newBlurFilter,
+		"grayscale": newGrayscaleFilter,
+	}
+)
+
+// RegisterFilter добавляет фильтр в реестр под именем name (заменяя
+// существующий с тем же именем), чтобы его можно было выбрать по имени в
+// Config.Filters, файлах конфигурации и шаблонах
+func RegisterFilter(name string, factory FilterFactory) {
+	filtersMu.Lock()
+	filters[name] = factory
+	filtersMu.Unlock()
+}
+
+// NewFilter создает зарегистрированный фильтр с параметрами params
+func NewFilter(name string, params map[string]any) (Filter, error) {
+	filtersMu.RLock()
+	factory, ok := filters[name]
+	filtersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown filter %q", name)
+	}
+	f, err := factory(params)
+	if err != nil {
+		return nil, fmt.Errorf("filter %s: %w", name, err)
+	}
+	return f, nil
+}
+
+// Filters возвращает отсортированные имена зарегистрированных фильтров
+func Filters() []string {
+	filtersMu.RLock()
+	defer filtersMu.RUnlock()
+	names := make([]string, 0, len(filters))
+	for name := range filters {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Chain возвращает фильтр, применяющий filters по очереди
+func Chain(filters ...Filter) Filter {
+	return chain(slices.Clone(filters))
+}
+
+type chain []Filter
+
+func (chain) Name() string { return "chain" }
+
+// Params возвращает описания фильтров цепочки под ключом "filters"
+func (c chain) Params() map[string]any {
+	specs := make([]FilterSpec, len(c))
+	for i, f := range c {
+		specs[i] = FilterSpec{Name: f.Name(), Params: f.Params()}
+	}
+	return map[string]any{"filters": specs}
+}
+
+func (c chain) Apply(ctx context.Context, img image.Image) (image.Image, error) {
+	for _, f := range c {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var err error
+		if img, err = f.Apply(ctx, img); err != nil {
+			return nil, fmt.Errorf("filter %s: %w", f.Name(), err)
+		}
+	}
+	return img, nil
+}
+
+// newChain создает цепочку из описаний
+func newChain(specs []FilterSpec) (Filter, error) {
+	c := make(chain, len(specs))
+	for i, s := range specs {
+		f, err := NewFilter(s.Name, s.Params)
+		if err != nil {
+			return nil, err
+		}
+		c[i] = f
+	}
+	return c, nil
+}
+
+// applyFilters применяет Config.Filters к фото
+func (g *Generator) applyFilters(ctx context.Context, img image.Image) (image.Image, error) {
+	if len(g.config.Filters) == 0 {
+		return img, nil
+	}
+	c, err := newChain(g.config.Filters)
+	if err != nil {
+		return nil, &ConfigError{Field: "Filters", Reason: err.Error()}
+	}
+	out, err := c.Apply(ctx, img)
+	if err != nil {
+		return nil, err
+	}
+	if out == nil {
+		return nil, fmt.Errorf("filters returned %w", ErrNilImage)
+	}
+	return out, nil
+}
+
+// intParam читает целый параметр name; def - значение, если его нет
+func intParam(params map[string]any, name string, def int) (int, error) {
+	v, ok := params[name]
+	if !ok {
+		return def, nil
+	}
+	switch n := v.(type) {
+	case int:
+		return n, nil
+	case int64:
+		return int(n), nil
+	case float64:
+		if n == math.Trunc(n) && math.Abs(n) < 1<<31 {
+			return int(n), nil
+		}
+	}
+	return 0, fmt.Errorf("parameter %s must be an integer, got %v", name, v)
+}
+
+// checkParams сообщает о параметрах, которых фильтр не знает
+func checkParams(params map[string]any, known ...string) error {
+	for name := range params {
+		if !slices.Contains(known, name) {
+			return fmt.Errorf("unknown parameter %q", name)
+		}
+	}
+	return nil
+}
+
+// blurFilter - гауссово размытие радиуса radius
+type blurFilter struct{ radius int }
+
+func newBlurFilter(params map[string]any) (Filter, error) {
+	if err := checkParams(params, "radius"); err != nil {
+		return nil, err
+	}
+	r, err := intParam(params, "radius", 3)
+	if err != nil {
+		return nil, err
+	}
+	if r < 0 || r > 100 {
+		return nil, fmt.Errorf("radius must be between 0 and 100, got %d", r)
+	}
+	return blurFilter{r}, nil
+}
+
+func (blurFilter) Name() string             { return "blur" }
+func (f blurFilter) Params() map[string]any { return map[string]any{"radius": f.radius} }
+
+func (f blurFilter) Apply(_ context.Context, img image.Image) (image.Image, error) {
+	out := image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	drawSource(out, out.Bounds(), img, img.Bounds().Min)
+	blurRGBA(out, f.radius)
+	return out, nil
+}
+
+// grayscaleFilter переводит фото в оттенки серого, сохраняя прозрачность
+type grayscaleFilter struct{}
+
+func newGrayscaleFilter(params map[string]any) (Filter, error) {
+	if err := checkParams(params); err != nil {
+		return nil, err
+	}
+	return grayscaleFilter{}, nil
+}
+
+func (grayscaleFilter) Name() string           { return "grayscale" }
+func (grayscaleFilter) Params() map[string]any { return nil }
+
+func (grayscaleFilter) Apply(_ context.Context, img image.Image) (image.Image, error) {
+	b := img.Bounds()
+	out := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			g := color.GrayModel.Convert(color.RGBA{c.R, c.G, c.B, 0xff}).(color.Gray).Y
+			out.SetNRGBA(x-b.Min.X, y-b.Min.Y, color.NRGBA{g, g, g, c.A})
+		}
+	}
+	return out, nil
+}
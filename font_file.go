@@ -0,0 +1,37 @@
+//go:build !js
+
+package meme
+
+import (
+	"fmt"
+	"os"
+)
+
+// maxFontFileSize - предел размера файла шрифта
+const maxFontFileSize = 10 << 20
+
+// readFontFile читает файл шрифта, проверяя существование и размер
+func readFontFile(path string) ([]byte, error) {
+	// Проверяем существование файла
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrFontNotFound, path)
+		}
+		return nil, fmt.Errorf("accessing font file: %w", err)
+	}
+
+	// Проверяем размер файла (не должен быть слишком большим или маленьким)
+	if fileInfo.Size() == 0 {
+		return nil, fmt.Errorf("%w: font file is empty", ErrInvalidFont)
+	}
+	if fileInfo.Size() > maxFontFileSize {
+		return nil, fmt.Errorf("%w: font file is too large: %d bytes", ErrInvalidFont, fileInfo.Size())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading font file: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,11 @@
+//go:build js
+
+package meme
+
+import "fmt"
+
+// readFontFile в js/wasm недоступен: файловой системы нет, шрифт
+// передается через Config.FontData или выбирается из встроенных
+func readFontFile(path string) ([]byte, error) {
+	return nil, fmt.Errorf("%w: %s (font files are not available in js/wasm, use FontData)", ErrFontNotFound, path)
+}
@@ -0,0 +1,52 @@
+package meme
+
+import (
+	"image"
+	"image/draw"
+)
+
+// FrameRenderer рисует фон и рамку демотиватора вокруг фото - паспарту.
+// Геометрию задает генератор: размер холста и место фото считаются по
+// Config (Padding, Border), а рендерер решает, как выглядит все вокруг
+// фото: рваная бумага, кинопленка, окно браузера и т.п. Фото и подписи
+// рисуются поверх результата Draw.
+//
+// С ParallelRender Draw вызывается для нескольких полос холста сразу,
+// поэтому реализация должна рисовать только внутри clip и быть безопасна
+// для одновременного использования.
+type FrameRenderer interface {
+	// Draw заполняет участок clip холста dst; l.Photo - область фото
+	Draw(dst draw.Image, clip image.Rectangle, l Layout, cfg *Config)
+}
+
+// BorderFrameRenderer - рендерер по умолчанию: заливка BackgroundColor и
+// рамка BorderColor шириной Border вокруг фото
+type BorderFrameRenderer struct{}
+
+var _ FrameRenderer = BorderFrameRenderer{}
+
+// Draw рисует фон и рамку
+func (BorderFrameRenderer) Draw(dst draw.Image, clip image.Rectangle, l Layout, cfg *Config) {
+	draw.Draw(dst, clip, &image.Uniform{cfg.BackgroundColor}, image.Point{}, draw.Src)
+	if cfg.Border <= 0 {
+		return
+	}
+	border := l.Photo.Inset(-cfg.Border).Intersect(clip)
+	draw.Draw(dst, border, &image.Uniform{cfg.BorderColor}, image.Point{}, draw.Src)
+}
+
+// SetFrameRenderer заменяет отрисовку фона и рамки демотиватора (nil -
+// BorderFrameRenderer). Как и SetCache, нельзя вызывать одновременно с
+// генерацией; со своим рендерером кеш результатов не используется (см.
+// SetCache).
+func (g *Generator) SetFrameRenderer(r FrameRenderer) {
+	g.frame = r
+}
+
+// frameRenderer возвращает подключенный рендерер или рендерер по умолчанию
+func (g *Generator) frameRenderer() FrameRenderer {
+	if g.frame == nil {
+		return BorderFrameRenderer{}
+	}
+	return g.frame
+}
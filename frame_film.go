@@ -0,0 +1,57 @@
+package meme
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// FilmStripFrameRenderer - кадр кинопленки: темная пленка вместо фона и
+// рамки и ряды перфорации над фото и у нижнего края, под подписями.
+// Размер отверстий - треть Padding, поэтому при маленьком Padding пленка
+// получается узкой.
+type FilmStripFrameRenderer struct {
+	FilmColor color.Color // nil - почти черный
+	HoleColor color.Color // nil - BorderColor конфигурации
+}
+
+var _ FrameRenderer = FilmStripFrameRenderer{}
+
+// Цвет пленки по умолчанию
+var defaultFilmColor = color.RGBA{0x16, 0x14, 0x12, 0xff}
+
+// Draw рисует пленку и перфорацию
+func (f FilmStripFrameRenderer) Draw(dst draw.Image, clip image.Rectangle, l Layout, cfg *Config) {
+	film := colorOr(f.FilmColor, defaultFilmColor)
+	draw.Draw(dst, clip, &image.Uniform{film}, image.Point{}, draw.Src)
+
+	// Отверстие - скругленный прямоугольник 4:3, шаг ряда - две ширины
+	h := cfg.Padding / 3
+	if h < 2 {
+		return
+	}
+	w := h * 4 / 3
+	step := 2 * w
+	mask := roundedMask(w, h, h/4)
+	hole := &image.Uniform{colorOr(f.HoleColor, cfg.BorderColor)}
+
+	// Ряды по центру верхнего поля и на том же расстоянии от нижнего края
+	margin := (l.Photo.Min.Y - l.Canvas.Min.Y) / 2
+	rows := []int{l.Canvas.Min.Y + margin - h/2, l.Canvas.Max.Y - margin - h + h/2}
+	// Ряд центрирован по ширине: поля по краям одинаковые
+	n := max((l.Canvas.Dx()-w)/step+1, 1)
+	x0 := l.Canvas.Min.X + (l.Canvas.Dx()-(n-1)*step-w)/2
+	for _, y := range rows {
+		if y+h <= clip.Min.Y || y >= clip.Max.Y {
+			continue
+		}
+		for i := range n {
+			r := image.Rect(0, 0, w, h).Add(image.Pt(x0+i*step, y))
+			ir := r.Intersect(clip)
+			if ir.Empty() {
+				continue
+			}
+			draw.DrawMask(dst, ir, hole, image.Point{}, mask, ir.Min.Sub(r.Min), draw.Over)
+		}
+	}
+}
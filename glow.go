@@ -0,0 +1,90 @@
+package meme
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// Glow - неоновое свечение вокруг подписи: силуэт букв размывается в
+// два прохода (узкий ореол и широкий) и прибавляется к яркости фона.
+// В отличие от тени свечение не смещено и не затемняет фон, а в отличие
+// от обводки не имеет четкой границы.
+type Glow struct {
+	Radius    int         // радиус широкого ореола в пикселях, 0 - без свечения
+	Color     color.Color // nil - цвет подписи
+	Intensity float64     // яркость, 0 - 1; больше 1 - ярче и шире насыщенная зона
+}
+
+// Предел радиуса: слой свечения шире подписи на 3*Radius с каждой стороны
+const maxGlowRadius = 128
+
+// draw прибавляет свечение силуэта silhouette цвета near к dst
+func (gl Glow) draw(dst draw.Image, silhouette *image.Alpha, near color.Color) {
+	if gl.Color != nil {
+		near = gl.Color
+	}
+	radius := min(gl.Radius, maxGlowRadius)
+	intensity := gl.Intensity
+	if intensity <= 0 {
+		intensity = 1
+	}
+	// Три прохода бокс-фильтра расширяют силуэт на 3*radius
+	r := silhouette.Rect.Inset(-3 * radius).Intersect(dst.Bounds())
+	if r.Empty() {
+		return
+	}
+	c := color.NRGBAModel.Convert(near).(color.NRGBA)
+
+	layer := func(radius int) *image.RGBA {
+		l := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+		for y := max(silhouette.Rect.Min.Y, r.Min.Y); y < min(silhouette.Rect.Max.Y, r.Max.Y); y++ {
+			for x := max(silhouette.Rect.Min.X, r.Min.X); x < min(silhouette.Rect.Max.X, r.Max.X); x++ {
+				a := uint32(silhouette.AlphaAt(x, y).A) * uint32(c.A) / 0xff
+				i := l.PixOffset(x-r.Min.X, y-r.Min.Y)
+				l.Pix[i+0] = uint8(uint32(c.R) * a / 0xff)
+				l.Pix[i+1] = uint8(uint32(c.G) * a / 0xff)
+				l.Pix[i+2] = uint8(uint32(c.B) * a / 0xff)
+				l.Pix[i+3] = uint8(a)
+			}
+		}
+		blurRGBA(l, radius)
+		return l
+	}
+	wide := layer(radius)
+	tight := layer(max(radius/3, 1))
+
+	// Аддитивное смешивание: свечение только добавляет света
+	rgba, fast := dst.(*image.RGBA)
+	add := func(d, a, b uint8) uint8 {
+		return uint8(min(float64(d)+(float64(a)+float64(b))*intensity, 0xff))
+	}
+	for y := 0; y < r.Dy(); y++ {
+		for x := 0; x < r.Dx(); x++ {
+			i := wide.PixOffset(x, y)
+			if wide.Pix[i+3] == 0 && tight.Pix[i+3] == 0 {
+				continue
+			}
+			px, py := r.Min.X+x, r.Min.Y+y
+			var d color.RGBA
+			if fast {
+				d = rgba.RGBAAt(px, py)
+			} else {
+				d = color.RGBAModel.Convert(dst.At(px, py)).(color.RGBA)
+			}
+			out := color.RGBA{
+				R: add(d.R, wide.Pix[i+0], tight.Pix[i+0]),
+				G: add(d.G, wide.Pix[i+1], tight.Pix[i+1]),
+				B: add(d.B, wide.Pix[i+2], tight.Pix[i+2]),
+				A: add(d.A, wide.Pix[i+3], tight.Pix[i+3]),
+			}
+			// Предумноженный цвет не может быть больше альфы
+			out.R, out.G, out.B = min(out.R, out.A), min(out.G, out.A), min(out.B, out.A)
+			if fast {
+				rgba.SetRGBA(px, py, out)
+			} else {
+				dst.Set(px, py, out)
+			}
+		}
+	}
+}
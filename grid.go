@@ -0,0 +1,179 @@
+package meme
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// Panel - ячейка сетки: картинка (например, готовый мем) и подпись к ней
+type Panel struct {
+	Image   image.Image
+	Caption string
+}
+
+// CaptionPosition - где располагается подпись ячейки
+type CaptionPosition int
+
+const (
+	CaptionBelow CaptionPosition = iota // под картинкой (сравнения)
+	CaptionLeft                         // слева от картинки ("expanding brain")
+)
+
+// GridOptions задаёт раскладку сетки
+type GridOptions struct {
+	Columns int // число столбцов, 0 - 1
+
+	// Размер области картинки в ячейке; картинка вписывается с обрезкой.
+	// 0 - 400 в ширину и 3/4 ширины в высоту.
+	CellWidth, CellHeight int
+
+	Gutter      int         // промежуток между ячейками и краями
+	Border      int         // рамка вокруг каждой картинки
+	BorderColor color.Color // nil - BorderColor генератора
+	Background  color.Color // nil - BackgroundColor генератора
+
+	Title     string
+	TitleSize float64 // 0 - 48
+
+	CaptionPosition CaptionPosition
+	CaptionSize     float64 // максимальный размер подписи, 0 - 32
+}
+
+// Значения GridOptions по умолчанию
+const (
+	defaultGridCellWidth   = 400
+	defaultGridTitleSize   = 48
+	defaultGridCaptionSize = 32
+)
+
+// Grid раскладывает панели по строкам и столбцам с промежутками,
+// рамками и общим заголовком. Подписи оформляются цветами и шрифтом
+// генератора и уменьшаются, пока не поместятся в ячейку.
+func (g *Generator) Grid(panels []Panel, opts GridOptions) (_ *image.RGBA, err error) {
+	defer recoverPanic(&err)
+	cfg := g.config
+	if len(panels) == 0 {
+		return nil, &ConfigError{Field: "panels", Reason: "must not be empty"}
+	}
+	for _, p := range panels {
+		if p.Image == nil {
+			return nil, ErrNilImage
+		}
+	}
+	if opts.Gutter < 0 || opts.Border < 0 || opts.CellWidth < 0 || opts.CellHeight < 0 || opts.Columns < 0 {
+		return nil, &ConfigError{Field: "GridOptions", Reason: "sizes must not be negative"}
+	}
+
+	cols := max(opts.Columns, 1)
+	rows := (len(panels) + cols - 1) / cols
+	cellW := opts.CellWidth
+	if cellW == 0 {
+		cellW = defaultGridCellWidth
+	}
+	cellH := opts.CellHeight
+	if cellH == 0 {
+		cellH = cellW * 3 / 4
+	}
+	titleSize := opts.TitleSize
+	if titleSize <= 0 {
+		titleSize = defaultGridTitleSize
+	}
+	captionSize := opts.CaptionSize
+	if captionSize <= 0 {
+		captionSize = defaultGridCaptionSize
+	}
+
+	// Размер ячейки вместе с областью подписи
+	hasCaptions := false
+	for _, p := range panels {
+		hasCaptions = hasCaptions || p.Caption != ""
+	}
+	captionW, captionH := 0, 0
+	if hasCaptions {
+		if opts.CaptionPosition == CaptionLeft {
+			captionW = cellW
+		} else {
+			captionH = int(captionSize * 2.5)
+		}
+	}
+	totalW := captionW + cellW
+	totalH := cellH + captionH
+
+	titleH := 0
+	if opts.Title != "" {
+		titleH = int(titleSize * 2)
+	}
+
+	gutter := opts.Gutter
+	canvas := image.Rect(0, 0, gutter+cols*(totalW+gutter), titleH+gutter+rows*(totalH+gutter))
+	if err := checkCanvas(canvas); err != nil {
+		return nil, err
+	}
+	out := image.NewRGBA(canvas)
+
+	bg := opts.Background
+	if bg == nil {
+		bg = cfg.BackgroundColor
+	}
+	borderColor := opts.BorderColor
+	if borderColor == nil {
+		borderColor = cfg.BorderColor
+	}
+	draw.Draw(out, canvas, image.NewUniform(bg), image.Point{}, draw.Src)
+
+	textSlot := func(name string, r image.Rectangle, size float64) TextSlot {
+		return TextSlot{
+			Name:         name,
+			Rect:         r,
+			FontSize:     size,
+			Color:        cfg.TextColor,
+			OutlineColor: cfg.TextOutlineColor,
+			OutlineWidth: cfg.TextOutlineWidth,
+			Uppercase:    cfg.TextUppercase,
+		}
+	}
+
+	if opts.Title != "" {
+		r := image.Rect(gutter, 0, canvas.Max.X-gutter, titleH+gutter)
+		if err := g.drawSlotText(out, textSlot("title", r, titleSize), opts.Title); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, p := range panels {
+		col, row := i%cols, i/cols
+		origin := image.Pt(gutter+col*(totalW+gutter), titleH+gutter+row*(totalH+gutter))
+
+		photo := image.Rect(0, 0, cellW, cellH).Add(origin).Add(image.Pt(captionW, 0))
+		drawCover(out, photo, p.Image)
+		drawFrame(out, photo, opts.Border, borderColor)
+
+		if p.Caption == "" {
+			continue
+		}
+		var captionRect image.Rectangle
+		if opts.CaptionPosition == CaptionLeft {
+			captionRect = image.Rect(0, 0, captionW, cellH).Add(origin).Inset(gutter / 2)
+		} else {
+			captionRect = image.Rect(0, cellH, cellW, cellH+captionH).Add(origin)
+		}
+		if err := g.drawSlotText(out, textSlot("caption", captionRect, captionSize), p.Caption); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// drawFrame рисует рамку толщиной width по внутреннему краю r
+func drawFrame(dst draw.Image, r image.Rectangle, width int, c color.Color) {
+	if width <= 0 {
+		return
+	}
+	src := image.NewUniform(c)
+	width = min(width, r.Dx()/2, r.Dy()/2)
+	draw.Draw(dst, image.Rect(r.Min.X, r.Min.Y, r.Max.X, r.Min.Y+width), src, image.Point{}, draw.Src)
+	draw.Draw(dst, image.Rect(r.Min.X, r.Max.Y-width, r.Max.X, r.Max.Y), src, image.Point{}, draw.Src)
+	draw.Draw(dst, image.Rect(r.Min.X, r.Min.Y, r.Min.X+width, r.Max.Y), src, image.Point{}, draw.Src)
+	draw.Draw(dst, image.Rect(r.Max.X-width, r.Min.Y, r.Max.X, r.Max.Y), src, image.Point{}, draw.Src)
+}
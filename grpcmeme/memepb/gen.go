@@ -0,0 +1,4 @@
+// Package memepb содержит сгенерированный код для meme.proto.
+package memepb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative meme.proto
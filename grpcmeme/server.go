@@ -0,0 +1,284 @@
+// Package grpcmeme реализует gRPC-сервис meme.v1.MemeService поверх генератора.
+//
+//	srv := grpc.NewServer(grpc.MaxRecvMsgSize(32 << 20))
+//	memepb.RegisterMemeServiceServer(srv, grpcmeme.NewServer(grpcmeme.Options{}))
+//
+// По умолчанию gRPC принимает сообщения до 4 МиБ; для больших фото
+// увеличьте grpc.MaxRecvMsgSize вместе с Options.MaxBytes.
+package grpcmeme
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/go-goblin/meme"
+	"github.com/go-goblin/meme/grpcmeme/memepb"
+	"github.com/go-goblin/meme/limit"
+)
+
+// DefaultMaxBytes - предел размера изображения по умолчанию
+const DefaultMaxBytes = 20 << 20
+
+// Options задаёт поведение сервера
+type Options struct {
+	// Config - базовая конфигурация; подписи и Style берутся из запроса.
+	// nil - meme.DefaultConfig().
+	Config *meme.Config
+
+	// Setup вызывается для генератора каждого запроса
+	Setup func(*meme.Generator)
+
+	MaxBytes int64 // предел размера изображения, 0 - 20 МиБ
+
+	// Fetch загружает изображение по url. nil - meme.FetchImage с пределом
+	// MaxBytes и защитой от SSRF. Вызывающий закрывает результат.
+	Fetch func(ctx context.Context, url string) (io.ReadCloser, error)
+
+	// DisableURL запрещает запросы с url
+	DisableURL bool
+
+	// Limiter ограничивает частоту запросов клиента (каждый элемент
+	// GenerateStream - отдельный запрос); лишние получают
+	// ResourceExhausted. nil - без предела.
+	Limiter limit.Limiter
+
+	// ClientKey определяет клиента для Limiter, например по API-ключу из
+	// метаданных. nil - IP-адрес из peer.FromContext.
+	ClientKey func(ctx context.Context) string
+
+	// Concurrency ограничивает число одновременных генераций; запросы
+	// сверх предела ждут места до отмены. nil - без предела.
+	Concurrency *limit.Semaphore
+
+	// Queue ограничивает очередь генерации: при переполнении запрос сразу
+	// получает Unavailable с кодом busy (см. meme.Queue)
+	Queue *meme.Queue
+}
+
+// errRateLimited - ответ на запрос сверх Options.Limiter
+var errRateLimited = status.Error(codes.ResourceExhausted, "rate_limited: rate limit exceeded")
+
+// Server реализует memepb.MemeServiceServer
+type Server struct {
+	memepb.UnimplementedMemeServiceServer
+	opts Options
+	base *meme.Generator // источник кеша шрифтов для генераторов запросов
+}
+
+var _ memepb.MemeServiceServer = (*Server)(nil)
+
+// NewServer создает сервер с настройками opts
+func NewServer(opts Options) *Server {
+	if opts.Config == nil {
+		opts.Config = meme.DefaultConfig()
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultMaxBytes
+	}
+	if opts.Fetch == nil {
+		fo := &meme.FetchOptions{MaxBytes: opts.MaxBytes}
+		opts.Fetch = func(ctx context.Context, url string) (io.ReadCloser, error) {
+			return meme.FetchImage(ctx, url, fo)
+		}
+	}
+	if opts.ClientKey == nil {
+		opts.ClientKey = peerIP
+	}
+	return &Server{opts: opts, base: meme.NewGenerator(opts.Config)}
+}
+
+// peerIP - ключ клиента по умолчанию
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// Generate создает один демотиватор; ошибки возвращаются статусом gRPC
+// с кодом meme.ErrorReason в сообщении
+func (s *Server) Generate(ctx context.Context, req *memepb.GenerateRequest) (*memepb.GenerateResponse, error) {
+	resp, err := s.generate(ctx, req)
+	if _, ok := status.FromError(err); ok {
+		return resp, err
+	}
+	return nil, status.Error(statusCode(err), fmt.Sprintf("%s: %v", meme.ErrorReason(err), err))
+}
+
+// GenerateStream обрабатывает запросы по одному в порядке поступления.
+// Ошибка элемента передается в GenerateResponse.Error, поток прерывают
+// только ошибки транспорта.
+func (s *Server) GenerateStream(stream memepb.MemeService_GenerateStreamServer) error {
+	ctx := stream.Context()
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		resp, err := s.generate(ctx, req)
+		if errors.Is(err, errRateLimited) {
+			resp = &memepb.GenerateResponse{Id: req.GetId(), Error: &memepb.Error{Reason: "rate_limited", Message: "rate limit exceeded"}}
+		} else if err != nil {
+			resp = &memepb.GenerateResponse{Id: req.GetId(), Error: &memepb.Error{Reason: meme.ErrorReason(err), Message: err.Error()}}
+		}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) generate(ctx context.Context, req *memepb.GenerateRequest) (*memepb.GenerateResponse, error) {
+	if s.opts.Limiter != nil && !s.opts.Limiter.Allow(s.opts.ClientKey(ctx)) {
+		return nil, errRateLimited
+	}
+	// Место семафора и тело загрузки освобождает владелец cleanup:
+	// generate, если до генерации не дошло, иначе сама генерация, когда
+	// она закончится, - после отмены ctx она может пережить вызов
+	var cleanup []func()
+	defer func() {
+		for _, f := range cleanup {
+			f()
+		}
+	}()
+	if s.opts.Concurrency != nil {
+		if err := s.opts.Concurrency.Acquire(ctx); err != nil {
+			return nil, status.FromContextError(err).Err()
+		}
+		cleanup = append(cleanup, s.opts.Concurrency.Release)
+	}
+	cfg, err := s.config(req)
+	if err != nil {
+		return nil, err
+	}
+	format, contentType, err := outputFormat(req.GetFormat())
+	if err != nil {
+		return nil, err
+	}
+
+	var in io.Reader
+	switch src := req.GetSource().(type) {
+	case *memepb.GenerateRequest_Image:
+		in = bytes.NewReader(src.Image)
+	case *memepb.GenerateRequest_Url:
+		if s.opts.DisableURL {
+			return nil, status.Error(codes.PermissionDenied, "fetching by url is disabled")
+		}
+		body, err := s.opts.Fetch(ctx, src.Url)
+		if err != nil && meme.ErrorReason(err) == "other" {
+			return nil, status.Errorf(codes.Unavailable, "fetching image: %v", err)
+		}
+		if err != nil {
+			// Запрещенный адрес, слишком большой файл и т.п. - ошибка запроса
+			return nil, fmt.Errorf("fetching image: %w", err)
+		}
+		cleanup = append(cleanup, func() { body.Close() })
+		in = body
+	default:
+		return nil, status.Error(codes.InvalidArgument, "either image or url is required")
+	}
+
+	g := s.base.WithConfig(cfg)
+	if s.opts.Setup != nil {
+		s.opts.Setup(g)
+	}
+	type rendered struct {
+		bounds image.Rectangle
+		data   []byte
+	}
+	done := cleanup
+	cleanup = nil
+	res, err := meme.Submit(ctx, s.opts.Queue, func(ctx context.Context) (rendered, error) {
+		out, err := g.GenerateFromContext(ctx, in)
+		if err != nil {
+			return rendered{}, err
+		}
+		if err := ctx.Err(); err != nil {
+			return rendered{}, err
+		}
+		data, err := meme.EncodeBytes(out, &meme.EncodeOptions{Format: format, Quality: int(req.GetQuality())})
+		return rendered{out.Bounds(), data}, err
+	}, func() {
+		for _, f := range done {
+			f()
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	b := res.bounds
+	return &memepb.GenerateResponse{
+		Id:          req.GetId(),
+		Image:       res.data,
+		ContentType: contentType,
+		Width:       int32(b.Dx()),
+		Height:      int32(b.Dy()),
+	}, nil
+}
+
+// config собирает конфигурацию запроса поверх базовой
+func (s *Server) config(req *memepb.GenerateRequest) (*meme.Config, error) {
+	cfg := *s.opts.Config
+	// Тема меняет оформление, но не ограничения сервера
+	st := req.GetStyle()
+	err := cfg.ApplyOverride(meme.StyleOverride{
+		Theme: st.GetTheme(), Font: st.GetFont(), FontSize: st.GetFontSize(),
+		BackgroundColor: st.GetBackgroundColor(), BorderColor: st.GetBorderColor(), TextColor: st.GetTextColor(),
+		Uppercase: st.GetUppercase(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	cfg.TopText, cfg.BottomText = req.GetTop(), req.GetBottom()
+	if cfg.MaxBytes == 0 || cfg.MaxBytes > s.opts.MaxBytes {
+		cfg.MaxBytes = s.opts.MaxBytes
+	}
+	return &cfg, nil
+}
+
+// outputFormat переводит формат из запроса в meme.Format и MIME-тип
+func outputFormat(f memepb.Format) (meme.Format, string, error) {
+	switch f {
+	case memepb.Format_FORMAT_UNSPECIFIED, memepb.Format_FORMAT_PNG:
+		return meme.FormatPNG, "image/png", nil
+	case memepb.Format_FORMAT_JPEG:
+		return meme.FormatJPEG, "image/jpeg", nil
+	case memepb.Format_FORMAT_WEBP:
+		return meme.FormatWebP, "image/webp", nil
+	}
+	return "", "", status.Errorf(codes.InvalidArgument, "unsupported format %v", f)
+}
+
+// statusCode сопоставляет ошибку генерации коду gRPC
+func statusCode(err error) codes.Code {
+	switch meme.ErrorReason(err) {
+	case "input_too_large", "image_too_large":
+		return codes.ResourceExhausted
+	case "url_not_allowed":
+		return codes.PermissionDenied
+	case "busy":
+		return codes.Unavailable
+	case "nil_image", "empty_image", "decode_failed", "unknown_format", "format_not_allowed", "heif_unsupported",
+		"invalid_config", "text_too_long", "font_not_found", "invalid_font":
+		return codes.InvalidArgument
+	case "panic", "other":
+		return codes.Internal
+	}
+	return codes.Unknown
+}
@@ -0,0 +1,285 @@
+package grpcmeme
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/go-goblin/meme"
+	"github.com/go-goblin/meme/grpcmeme/memepb"
+	"github.com/go-goblin/meme/limit"
+)
+
+// testClient запускает сервер с настройками opts поверх bufconn
+func testClient(t *testing.T, opts Options) memepb.MemeServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	memepb.RegisterMemeServiceServer(srv, NewServer(opts))
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return memepb.NewMemeServiceClient(conn)
+}
+
+// testImage - закодированное серое фото 32x32
+func testImage(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 32, 32))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// failWith подключает хук, который прерывает генерацию ошибкой err
+func failWith(err error) func(*meme.Generator) {
+	return func(g *meme.Generator) {
+		g.AddHook(meme.HookBeforeLayout, func(*meme.DrawContext) error { return err })
+	}
+}
+
+// limiterFunc - Limiter из функции
+type limiterFunc func(key string) bool
+
+func (f limiterFunc) Allow(key string) bool { return f(key) }
+
+// trackedBody - тело загрузки, запоминающее Close
+type trackedBody struct {
+	io.Reader
+	closed atomic.Bool
+}
+
+func (b *trackedBody) Close() error {
+	b.closed.Store(true)
+	return nil
+}
+
+// waitFor ждет, пока cond не станет верным
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !cond(); {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// free сообщает, свободно ли место семафора
+func free(sem *limit.Semaphore) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if sem.Acquire(ctx) != nil {
+		return false
+	}
+	sem.Release()
+	return true
+}
+
+func TestStatusCode(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want codes.Code
+	}{
+		{meme.ErrInputTooLarge, codes.ResourceExhausted},
+		{meme.ErrImageTooLarge, codes.ResourceExhausted},
+		{meme.ErrBusy, codes.Unavailable},
+		{meme.ErrTextTooLong, codes.InvalidArgument},
+		{&meme.ConfigError{Field: "Theme", Reason: "unknown"}, codes.InvalidArgument},
+		{&meme.PanicError{Value: "boom"}, codes.Internal},
+		{errors.New("boom"), codes.Internal},
+	} {
+		if got := statusCode(tc.err); got != tc.want {
+			t.Errorf("statusCode(%v) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	img := testImage(t)
+	withImage := &memepb.GenerateRequest{Id: "1", Top: "top", Source: &memepb.GenerateRequest_Image{Image: img}}
+	withURL := &memepb.GenerateRequest{Source: &memepb.GenerateRequest_Url{Url: "https://example.com/a.png"}}
+
+	c := testClient(t, Options{})
+	resp, err := c.Generate(context.Background(), withImage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetId() != "1" || resp.GetContentType() != "image/png" || resp.GetWidth() <= 32 || resp.GetHeight() <= 32 {
+		t.Errorf("response: id %q, %s, %dx%d", resp.GetId(), resp.GetContentType(), resp.GetWidth(), resp.GetHeight())
+	}
+	if _, _, err := image.Decode(bytes.NewReader(resp.GetImage())); err != nil {
+		t.Errorf("decoding response image: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		opts   Options
+		req    *memepb.GenerateRequest
+		code   codes.Code
+		reason string // начало сообщения статуса
+	}{
+		{"no source", Options{}, &memepb.GenerateRequest{}, codes.InvalidArgument, ""},
+		{"bad format", Options{}, &memepb.GenerateRequest{Format: memepb.Format(99), Source: withImage.Source}, codes.InvalidArgument, ""},
+		{"unknown theme", Options{}, &memepb.GenerateRequest{Style: &memepb.Style{Theme: "nope"}, Source: withImage.Source}, codes.InvalidArgument, "invalid_config:"},
+		{"not an image", Options{}, &memepb.GenerateRequest{Source: &memepb.GenerateRequest_Image{Image: []byte("text")}}, codes.InvalidArgument, "unknown_format:"},
+		{"too large", Options{MaxBytes: 64}, withImage, codes.ResourceExhausted, "input_too_large:"},
+		{"caption too long", Options{Setup: failWith(meme.ErrTextTooLong)}, withImage, codes.InvalidArgument, "text_too_long:"},
+		{"busy", Options{Setup: failWith(meme.ErrBusy)}, withImage, codes.Unavailable, "busy:"},
+		{"internal", Options{Setup: failWith(errors.New("boom"))}, withImage, codes.Internal, "other:"},
+		{"rate limited", Options{Limiter: limiterFunc(func(string) bool { return false })}, withImage, codes.ResourceExhausted, "rate_limited:"},
+		{"url disabled", Options{DisableURL: true}, withURL, codes.PermissionDenied, ""},
+		{"fetch failed", Options{Fetch: func(context.Context, string) (io.ReadCloser, error) {
+			return nil, errors.New("connection refused")
+		}}, withURL, codes.Unavailable, ""},
+		{"url not allowed", Options{Fetch: func(context.Context, string) (io.ReadCloser, error) {
+			return nil, meme.ErrURLNotAllowed
+		}}, withURL, codes.PermissionDenied, "url_not_allowed:"},
+	} {
+		_, err := testClient(t, tc.opts).Generate(context.Background(), tc.req)
+		st := status.Convert(err)
+		if st.Code() != tc.code || !strings.HasPrefix(st.Message(), tc.reason) {
+			t.Errorf("%s: %v %q, want %v %q...", tc.name, st.Code(), st.Message(), tc.code, tc.reason)
+		}
+	}
+}
+
+func TestGenerateStream(t *testing.T) {
+	img := testImage(t)
+	var requests atomic.Int32
+	c := testClient(t, Options{Limiter: limiterFunc(func(string) bool { return requests.Add(1) <= 3 })})
+	stream, err := c.GenerateStream(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, req := range []*memepb.GenerateRequest{
+		{Id: "ok", Source: &memepb.GenerateRequest_Image{Image: img}},
+		{Id: "bad", Source: &memepb.GenerateRequest_Image{Image: []byte("text")}},
+		{Id: "after error", Source: &memepb.GenerateRequest_Image{Image: img}},
+		{Id: "limited", Source: &memepb.GenerateRequest_Image{Image: img}},
+	} {
+		if err := stream.Send(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Ошибка элемента не прерывает поток, ответы идут в порядке запросов
+	want := []struct{ id, reason string }{{"ok", ""}, {"bad", "unknown_format"}, {"after error", ""}, {"limited", "rate_limited"}}
+	for _, w := range want {
+		resp, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("%s: %v", w.id, err)
+		}
+		if resp.GetId() != w.id || resp.GetError().GetReason() != w.reason {
+			t.Errorf("got %q with error %q, want %q with %q", resp.GetId(), resp.GetError().GetReason(), w.id, w.reason)
+		}
+		if (len(resp.GetImage()) > 0) != (w.reason == "") {
+			t.Errorf("%s: %d image bytes", w.id, len(resp.GetImage()))
+		}
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Errorf("end of stream: %v", err)
+	}
+}
+
+func TestGenerateCancelCleanup(t *testing.T) {
+	img := testImage(t)
+	req := &memepb.GenerateRequest{Source: &memepb.GenerateRequest_Url{Url: "https://example.com/a.png"}}
+
+	// setup создает сервер с общим семафором и телом загрузки; opts
+	// дополняет настройки
+	setup := func(t *testing.T, opts Options) (memepb.MemeServiceClient, *limit.Semaphore, *trackedBody) {
+		body := &trackedBody{Reader: bytes.NewReader(img)}
+		opts.Concurrency = limit.NewSemaphore(1)
+		opts.Fetch = func(context.Context, string) (io.ReadCloser, error) { return body, nil }
+		return testClient(t, opts), opts.Concurrency, body
+	}
+	// call выполняет req в фоне с отменяемым ctx
+	call := func(c memepb.MemeServiceClient) (context.CancelFunc, <-chan error) {
+		ctx, cancel := context.WithCancel(context.Background())
+		errc := make(chan error, 1)
+		go func() {
+			_, err := c.Generate(ctx, req)
+			errc <- err
+		}()
+		return cancel, errc
+	}
+
+	t.Run("rendering", func(t *testing.T) {
+		started, release := make(chan struct{}), make(chan struct{})
+		c, sem, body := setup(t, Options{
+			Queue: meme.NewQueue(&meme.QueueOptions{Workers: 1}),
+			Setup: func(g *meme.Generator) {
+				g.AddHook(meme.HookBeforeLayout, func(*meme.DrawContext) error {
+					close(started)
+					<-release
+					return nil
+				})
+			},
+		})
+		cancel, errc := call(c)
+		<-started
+		cancel()
+		if err := <-errc; status.Code(err) != codes.Canceled {
+			t.Fatalf("cancelled request: %v", err)
+		}
+
+		// Генерация еще идет: место и тело принадлежат ей
+		if free(sem) || body.closed.Load() {
+			t.Errorf("released before rendering finished: semaphore free %v, body closed %v", free(sem), body.closed.Load())
+		}
+		close(release)
+		waitFor(t, "body close", body.closed.Load)
+		waitFor(t, "semaphore release", func() bool { return free(sem) })
+	})
+
+	t.Run("queued", func(t *testing.T) {
+		q := meme.NewQueue(&meme.QueueOptions{Workers: 1, Length: 1})
+		c, sem, body := setup(t, Options{Queue: q})
+
+		// Единственный исполнитель занят, запрос ждет в очереди
+		release := make(chan struct{})
+		defer close(release)
+		busy := make(chan struct{})
+		go q.Do(context.Background(), func(context.Context) error {
+			close(busy)
+			<-release
+			return nil
+		})
+		<-busy
+		cancel, errc := call(c)
+		waitFor(t, "queued request", func() bool { return q.Waiting() == 1 })
+		cancel()
+		if err := <-errc; status.Code(err) != codes.Canceled {
+			t.Fatalf("cancelled request: %v", err)
+		}
+		// Генерация не запустится - освобождает сам вызов
+		waitFor(t, "body close", body.closed.Load)
+		waitFor(t, "semaphore release", func() bool { return free(sem) })
+	})
+}
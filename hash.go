@@ -0,0 +1,84 @@
+package meme
+
+import (
+	"image"
+	"math"
+	"math/bits"
+	"slices"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// Hash возвращает перцептивный хеш изображения (pHash): изображение
+// уменьшается до 32x32 в оттенках серого, и 64 бита кодируют, выше или ниже
+// медианы лежат низкочастотные коэффициенты DCT. Хеш устойчив к масштабу,
+// перекодированию и небольшой цветокоррекции; сравнивайте хеши через Distance.
+func Hash(img image.Image) uint64 {
+	const size, block = 32, 8
+	gray := grayThumbnail(img, size, size)
+
+	// Считаем только нужные 8x8 коэффициенты DCT-II
+	var cosines [block][size]float64
+	for u := range block {
+		for x := range size {
+			cosines[u][x] = math.Cos(float64((2*x+1)*u) * math.Pi / (2 * size))
+		}
+	}
+	var coeffs [block * block]float64
+	for v := range block {
+		for u := range block {
+			var sum float64
+			for y := range size {
+				row := gray.Pix[y*gray.Stride:]
+				cv := cosines[v][y]
+				for x := range size {
+					sum += float64(row[x]) * cosines[u][x] * cv
+				}
+			}
+			coeffs[v*block+u] = sum
+		}
+	}
+
+	// Постоянную составляющую (яркость) в медиану не включаем
+	sorted := slices.Clone(coeffs[1:])
+	slices.Sort(sorted)
+	median := (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+
+	var h uint64
+	for i, c := range coeffs {
+		if c > median {
+			h |= 1 << i
+		}
+	}
+	return h
+}
+
+// DHash возвращает разностный хеш: каждый бит сообщает, светлее ли пиксель
+// соседа справа на уменьшенной до 9x8 копии. Быстрее Hash, но хуже
+// переносит кадрирование и изменение контраста.
+func DHash(img image.Image) uint64 {
+	gray := grayThumbnail(img, 9, 8)
+	var h uint64
+	for y := range 8 {
+		row := gray.Pix[y*gray.Stride:]
+		for x := range 8 {
+			if row[x] > row[x+1] {
+				h |= 1 << (y*8 + x)
+			}
+		}
+	}
+	return h
+}
+
+// Distance возвращает расстояние Хэмминга между хешами: 0 - одинаковые
+// изображения, до ~10 для Hash обычно означает дубликат
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// grayThumbnail уменьшает изображение до w x h в оттенках серого
+func grayThumbnail(img image.Image, w, h int) *image.Gray {
+	gray := image.NewGray(image.Rect(0, 0, w, h))
+	xdraw.BiLinear.Scale(gray, gray.Bounds(), img, img.Bounds(), xdraw.Src, nil)
+	return gray
+}
@@ -0,0 +1,139 @@
+package meme
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/draw"
+)
+
+// Hook - точка конвейера генерации, в которой вызываются хуки
+type Hook string
+
+const (
+	// HookBeforeLayout - до расчета геометрии: хук может заменить
+	// DrawContext.Source, например наложить штамп на само фото
+	HookBeforeLayout Hook = "before_layout"
+
+	// HookAfterCompose - фон, рамка и фото нарисованы, подписей еще нет
+	HookAfterCompose Hook = "after_compose"
+
+	// HookBeforeEncode - изображение готово, перед возвратом из Generate
+	// и кодированием
+	HookBeforeEncode Hook = "before_encode"
+)
+
+// Layout - рассчитанная геометрия демотиватора
+type Layout struct {
+	Canvas image.Rectangle // размер результата
+	Photo  image.Rectangle // область фото на холсте
+
+	TopText, BottomText string  // подписи после смены регистра
+	FontSize            float64 // размер шрифта подписей
+
+	TopBaseline, BottomBaseline int // базовые линии подписей
+
+	// Строки подписей после переноса (Config.TopMaxWidthRatio,
+	// Config.BottomMaxWidthRatio); базовая линия каждой следующей строки
+	// ниже на LineStep
+	TopLines, BottomLines []string
+	LineStep              int
+
+	// Safe - область холста вне полей Config.SafeArea; весь холст без полей
+	Safe image.Rectangle
+}
+
+// DrawContext - состояние генерации, которое получает хук
+type DrawContext struct {
+	Hook   Hook
+	Config *Config     // конфигурация генератора, изменять нельзя
+	Source image.Image // исходное фото
+
+	// Canvas - холст результата; nil в HookBeforeLayout
+	Canvas draw.Image
+	// Layout - геометрия; нулевая в HookBeforeLayout
+	Layout Layout
+}
+
+// HookFunc рисует на холсте или заменяет исходное фото; ошибка
+// прерывает генерацию
+type HookFunc func(*DrawContext) error
+
+type hookEntry struct {
+	hook Hook
+	fn   HookFunc
+}
+
+// AddHook подключает fn в точке h, чтобы дорисовать логотип, штамп с
+// датой и т.п. без своей версии Generate. Хуки вызываются в порядке
+// подключения одним потоком, даже с ParallelRender. Как и SetCache,
+// AddHook нельзя вызывать одновременно с генерацией. Генератор с хуками
+// не использует кеш результатов (см. SetCache).
+//
+//	g.AddHook(meme.HookAfterCompose, func(dc *meme.DrawContext) error {
+//		draw.Draw(dc.Canvas, logoRect(dc.Layout.Photo), logo, image.Point{}, draw.Over)
+//		return nil
+//	})
+func (g *Generator) AddHook(h Hook, fn HookFunc) {
+	g.hooks = append(g.hooks, hookEntry{h, fn})
+}
+
+// runHooks вызывает хуки точки dc.Hook
+func (g *Generator) runHooks(dc *DrawContext) error {
+	for _, e := range g.hooks {
+		if e.hook != dc.Hook {
+			continue
+		}
+		if err := e.fn(dc); err != nil {
+			return fmt.Errorf("%s hook: %w", dc.Hook, err)
+		}
+	}
+	return nil
+}
+
+// beforeLayout применяет Config.Filters, вызывает хуки HookBeforeLayout и
+// возвращает фото, которое нужно рисовать
+func (g *Generator) beforeLayout(ctx context.Context, img image.Image) (image.Image, error) {
+	filtered, err := g.applyFilters(ctx, img)
+	if err != nil {
+		return nil, err
+	}
+	if filtered != img {
+		// Фильтр мог изменить размер: проверяем результат как исходное фото
+		if err := g.validateInput(filtered); err != nil {
+			return nil, fmt.Errorf("filters: %w", err)
+		}
+		img = filtered
+	}
+	if len(g.hooks) == 0 {
+		return img, nil
+	}
+	dc := &DrawContext{Hook: HookBeforeLayout, Config: g.config, Source: img}
+	if err := g.runHooks(dc); err != nil {
+		return nil, err
+	}
+	if dc.Source != img {
+		// Замененное фото проверяется так же, как исходное
+		if err := g.validateInput(dc.Source); err != nil {
+			return nil, fmt.Errorf("%s hook: %w", HookBeforeLayout, err)
+		}
+	}
+	return dc.Source, nil
+}
+
+// exported переводит внутреннюю геометрию в Layout
+func (l layout) exported() Layout {
+	return Layout{
+		Canvas:         l.canvas,
+		Photo:          l.photo,
+		TopText:        l.topText,
+		BottomText:     l.bottomText,
+		FontSize:       l.fontSize,
+		TopBaseline:    l.topBaseline,
+		BottomBaseline: l.bottomBaseline,
+		TopLines:       l.topLines,
+		BottomLines:    l.bottomLines,
+		LineStep:       l.lineStep,
+		Safe:           l.safe,
+	}
+}
@@ -0,0 +1,67 @@
+package httpmeme
+
+import (
+	_ "embed"
+	"html/template"
+	"net/http"
+	"slices"
+
+	"github.com/go-goblin/meme"
+)
+
+//go:embed editor.html
+var editorHTML string
+
+var editorPage = template.Must(template.New("editor").Parse(editorHTML))
+
+// Editor - веб-редактор мемов для внутренних инструментов: загрузка фото,
+// подписи, тема, шрифт и цвета с предпросмотром через POST /generate
+type Editor struct {
+	api *Handler
+}
+
+var _ http.Handler = (*Editor)(nil)
+
+// NewEditor создает редактор вместе с обработчиком API. Редактор
+// отвечает на "/" и "/generate" и рассчитан на монтирование с префиксом:
+//
+//	http.Handle("/meme-editor/", http.StripPrefix("/meme-editor", httpmeme.NewEditor(opts)))
+//
+// Предпросмотр загружает файл через POST, поэтому с SigningKey нужна
+// AllowUnsignedUpload, а сам редактор стоит закрыть авторизацией.
+func NewEditor(opts Options) *Editor {
+	return &Editor{api: New(opts)}
+}
+
+// ServeHTTP отдает страницу редактора и проксирует запросы к API
+func (e *Editor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/generate":
+		e.api.ServeHTTP(w, r)
+	case "/", "":
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed", "method_not_allowed")
+			return
+		}
+		e.page(w)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (e *Editor) page(w http.ResponseWriter) {
+	fonts := make([]string, 0, len(meme.GetAvailableFonts()))
+	for name := range meme.GetAvailableFonts() {
+		fonts = append(fonts, name)
+	}
+	slices.Sort(fonts)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	// Страница не встраивается в чужие сайты
+	w.Header().Set("Content-Security-Policy", "default-src 'self'; img-src 'self' blob:; style-src 'unsafe-inline'; script-src 'unsafe-inline'; frame-ancestors 'none'")
+	editorPage.Execute(w, struct {
+		Themes, Fonts []string
+		MaxBytes      int64
+		MaxTextLength int
+	}{meme.Themes(), fonts, e.api.opts.MaxBytes, e.api.opts.Config.MaxTextLength})
+}
@@ -0,0 +1,605 @@
+// Package httpmeme предоставляет http.Handler для встраивания генератора
+// мемов в сервисы.
+//
+//	h := httpmeme.New(httpmeme.Options{Config: meme.ThemedConfig("classic-black")})
+//	http.Handle("/generate", h)
+//
+// Эндпоинты:
+//
+//	POST /generate  multipart/form-data: файл "image" и JSON "options"
+//	GET  /generate?url=...&top=...&bottom=...
+//	GET  /generate?key=...&top=...&bottom=...  (с Options.Source)
+//
+// Формат ответа выбирается параметром format, а без него - по заголовку
+// Accept (image/webp, image/jpeg, image/png и форматы, добавленные через
+// meme.RegisterEncoder); по умолчанию PNG. Ошибки
+// возвращаются в JSON: {"error": "...", "reason": "text_too_long"}.
+// Параметры проверяются по схеме Request до генерации; при нарушениях
+// ответ 400 с reason "invalid_request" перечисляет все неверные поля:
+// {"fields": [{"field": "quality", "message": "must be at most 100"}]}.
+// Описание API в OpenAPI 3.1 отдает Handler.OpenAPI.
+//
+// С Options.Cache ответ собирается целиком, получает ETag и по
+// If-None-Match отдается как 304 Not Modified; без кеша результат
+// кодируется прямо в соединение.
+//
+// Нагрузку ограничивают Options.Limiter (частота запросов клиента, 429)
+// и Options.Concurrency (число одновременных генераций), см. пакет limit;
+// с Options.Queue запросы сверх очереди сразу получают 503.
+//
+// Для внутренних инструментов есть веб-редактор с предпросмотром, см.
+// NewEditor.
+//
+// С Options.SigningKey обработчик принимает только GET со ссылками,
+// подписанными функцией Sign (как в imgproxy): публичный адрес нельзя
+// использовать для подписи произвольных картинок злоумышленника.
+package httpmeme
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"io/fs"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-goblin/meme"
+	"github.com/go-goblin/meme/internal/jsonschema"
+	"github.com/go-goblin/meme/limit"
+	"github.com/go-goblin/meme/storage"
+)
+
+// Значения Options по умолчанию
+const (
+	DefaultMaxBytes = 20 << 20
+	DefaultTimeout  = 30 * time.Second
+
+	maxOptionsBytes = 64 << 10 // ограничение на JSON с параметрами
+)
+
+// Options задаёт поведение обработчика
+type Options struct {
+	// Config - базовая конфигурация; подписи и формат берутся из запроса.
+	// nil - meme.DefaultConfig().
+	Config *meme.Config
+
+	// Setup вызывается для генератора каждого запроса, например чтобы
+	// подключить метрики, журнал или кеш результатов
+	Setup func(*meme.Generator)
+
+	MaxBytes int64         // предел размера изображения, 0 - 20 МиБ
+	Timeout  time.Duration // предел времени на запрос целиком, 0 - 30 секунд
+
+	// Fetch загружает изображение для GET ?url=. nil - meme.FetchImage с
+	// пределами MaxBytes и Timeout: только http(s), без приватных сетей,
+	// не больше 5 перенаправлений. Вызывающий закрывает результат.
+	Fetch func(ctx context.Context, url string) (io.ReadCloser, error)
+
+	// Cache хранит готовые ответы (nil - без кеша и без ETag). Генератор
+	// с хуками или своими рендерерами его не использует (см. meme.Generator.SetCache),
+	// но ответ все равно получает ETag.
+	Cache meme.Cache
+
+	// SigningKey включает проверку подписи GET-запросов (см. Sign).
+	// Загрузка файла через POST подписать нельзя, поэтому при заданном
+	// ключе она запрещена, если не включена AllowUnsignedUpload.
+	SigningKey          []byte
+	AllowUnsignedUpload bool
+
+	// DisableURL отключает GET ?url=, оставляя только загрузку файла
+	DisableURL bool
+
+	// Source включает GET ?key=: изображение читается из хранилища, например
+	// s3store. Ключи, как и url, стоит подписывать (см. SigningKey).
+	Source storage.Source
+
+	// Limiter ограничивает частоту запросов клиента, например
+	// limit.NewTokenBucket; лишние запросы получают 429. nil - без предела.
+	Limiter limit.Limiter
+
+	// ClientKey определяет клиента для Limiter: API-ключ, пользователь и
+	// т.п. nil - IP-адрес из RemoteAddr; за прокси его нужно заменить.
+	ClientKey func(*http.Request) string
+
+	// Concurrency ограничивает число одновременных генераций; запросы
+	// сверх предела ждут места в пределах Timeout. nil - без предела.
+	Concurrency *limit.Semaphore
+
+	// Queue ограничивает очередь генерации: при переполнении запрос сразу
+	// получает 503 с Retry-After вместо ожидания (см. meme.Queue)
+	Queue *meme.Queue
+}
+
+// Request - параметры генерации: поле "options" в POST или параметры GET
+// с теми же именами. По тегам строится схема запроса (см. Handler.OpenAPI),
+// и запрос проверяется по ней до генерации.
+type Request struct {
+	Top     string `json:"top" doc:"Main caption."`
+	Bottom  string `json:"bottom" doc:"Secondary caption below the main one."`
+	Format  string `json:"format" doc:"Output format; empty - chosen by the Accept header."`
+	Quality int    `json:"quality" minimum:"0" maximum:"100" doc:"JPEG quality 1-100, 0 - encoder default."`
+
+	// Оформление поверх Options.Config; пустые поля не меняют его.
+	// Тема заменяет оформление целиком, но не ограничения сервера.
+	Theme           string  `json:"theme,omitempty" doc:"Visual theme replacing the server style."`
+	Font            string  `json:"font,omitempty" doc:"Built-in font."` // только встроенные, см. meme.GetAvailableFonts
+	FontSize        float64 `json:"font_size,omitempty" minimum:"0" maximum:"1000" doc:"Font size in pixels, 0 - style default."`
+	BackgroundColor string  `json:"background_color,omitempty" format:"color" doc:"Background color: #rgb, #rgba, #rrggbb or #rrggbbaa."`
+	BorderColor     string  `json:"border_color,omitempty" format:"color" doc:"Border color."`
+	TextColor       string  `json:"text_color,omitempty" format:"color" doc:"Text color."`
+	Uppercase       bool    `json:"uppercase,omitempty" doc:"Render captions in upper case."`
+}
+
+// Handler обслуживает /generate
+type Handler struct {
+	opts   Options
+	schema *jsonschema.Schema // схема Request
+	base   *meme.Generator    // источник кеша шрифтов для генераторов запросов
+}
+
+var _ http.Handler = (*Handler)(nil)
+
+// New создает обработчик с настройками opts
+func New(opts Options) *Handler {
+	if opts.Config == nil {
+		opts.Config = meme.DefaultConfig()
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultMaxBytes
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.Fetch == nil {
+		fo := &meme.FetchOptions{MaxBytes: opts.MaxBytes, Timeout: opts.Timeout}
+		opts.Fetch = func(ctx context.Context, url string) (io.ReadCloser, error) {
+			return meme.FetchImage(ctx, url, fo)
+		}
+	}
+	if opts.ClientKey == nil {
+		opts.ClientKey = clientIP
+	}
+	// Обработчик не привязан к пути: его можно смонтировать куда угодно
+	return &Handler{opts: opts, schema: requestSchema(opts.Config.MaxTextLength), base: meme.NewGenerator(opts.Config)}
+}
+
+// ServeHTTP реализует http.Handler. Timeout ограничивает контекст запроса
+// (загрузку по url, ожидание очереди) и сроки чтения и записи соединения;
+// ответ не буферизуется, как в http.TimeoutHandler, а пишется сразу.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	deadline := time.Now().Add(h.opts.Timeout)
+	ctx, cancel := context.WithDeadline(r.Context(), deadline)
+	defer cancel()
+	// Не все ResponseWriter поддерживают сроки (например, httptest):
+	// тогда остается только контекст
+	rc := http.NewResponseController(w)
+	rc.SetReadDeadline(deadline)
+	rc.SetWriteDeadline(deadline)
+	h.serve(w, r.WithContext(ctx))
+}
+
+func (h *Handler) serve(w http.ResponseWriter, r *http.Request) {
+	// Ограничения проверяются до чтения тела и загрузки по url
+	if h.opts.Limiter != nil && !h.opts.Limiter.Allow(h.opts.ClientKey(r)) {
+		w.Header().Set("Retry-After", "1")
+		writeError(w, http.StatusTooManyRequests, "rate limit exceeded", "rate_limited")
+		return
+	}
+	// Место семафора возвращает владелец release: обработчик, если до
+	// генерации не дошло, иначе сама генерация, когда она закончится, -
+	// после таймаута она может пережить обработчик
+	var release func()
+	if h.opts.Concurrency != nil {
+		if err := h.opts.Concurrency.Acquire(r.Context()); err != nil {
+			w.Header().Set("Retry-After", "1")
+			writeError(w, http.StatusServiceUnavailable, "server is busy", "busy")
+			return
+		}
+		release = h.opts.Concurrency.Release
+		defer func() {
+			if release != nil {
+				release()
+			}
+		}()
+	}
+
+	var (
+		req  Request
+		body io.ReadCloser
+		err  error
+	)
+	switch r.Method {
+	case http.MethodPost:
+		if len(h.opts.SigningKey) > 0 && !h.opts.AllowUnsignedUpload {
+			writeError(w, http.StatusForbidden, "uploads are disabled, use a signed url", "invalid_signature")
+			return
+		}
+		req, body, err = h.parsePost(w, r)
+	case http.MethodGet, http.MethodHead:
+		req, body, err = h.parseGet(r)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, POST")
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed", "method_not_allowed")
+		return
+	}
+	if err != nil {
+		writeRequestError(w, err)
+		return
+	}
+	defer body.Close()
+
+	format, err := negotiate(req.Format, r.Header.Get("Accept"))
+	if err != nil {
+		writeError(w, http.StatusNotAcceptable, err.Error(), "unknown_format")
+		return
+	}
+	g, err := h.generator(req)
+	if err != nil {
+		writeRequestError(w, err)
+		return
+	}
+	encode := &meme.EncodeOptions{Format: format, Quality: req.Quality}
+	if h.opts.Cache != nil {
+		h.serveCached(w, r, g, body, encode, &release)
+		return
+	}
+	out, err := render(r.Context(), h.opts.Queue, &release, func(ctx context.Context) (*image.RGBA, error) {
+		return g.GenerateFromContext(ctx, body)
+	})
+	if err != nil {
+		writeRequestError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", meme.MIMEType(format))
+	w.Header().Add("Vary", "Accept")
+	if r.Method == http.MethodHead {
+		return
+	}
+	// Кодируем прямо в ответ, не накапливая файл целиком в памяти
+	if err := meme.Encode(w, out, encode); err != nil {
+		// Заголовки уже отправлены, сообщить клиенту об ошибке нельзя
+		return
+	}
+}
+
+// serveCached отвечает через кеш результатов генератора. ETag - хеш
+// готового файла, так что он совпадает для одинаковых ответов независимо
+// от того, каким запросом они получены.
+func (h *Handler) serveCached(w http.ResponseWriter, r *http.Request, g *meme.Generator, body io.Reader, opts *meme.EncodeOptions, release *func()) {
+	g.SetCache(h.opts.Cache)
+	data, err := render(r.Context(), h.opts.Queue, release, func(ctx context.Context) ([]byte, error) {
+		return g.GenerateBytesContext(ctx, body, opts)
+	})
+	if err != nil {
+		writeRequestError(w, err)
+		return
+	}
+	sum := sha256.Sum256(data)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Add("Vary", "Accept")
+	if etagMatch(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", meme.MIMEType(opts.Format))
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	if r.Method != http.MethodHead {
+		w.Write(data)
+	}
+}
+
+// render выполняет генерацию через очередь q, если она задана (см.
+// meme.Submit), и забирает *release: место семафора вернется, когда
+// генерация действительно закончится
+func render[T any](ctx context.Context, q *meme.Queue, release *func(), fn func(ctx context.Context) (T, error)) (T, error) {
+	done := *release
+	*release = nil
+	return meme.Submit(ctx, q, fn, done)
+}
+
+// etagMatch проверяет If-None-Match: список тегов или "*", слабые теги сравниваются без W/
+func etagMatch(header, etag string) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+		if t == "*" || t == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// generator создает генератор для одного запроса
+func (h *Handler) generator(req Request) (*meme.Generator, error) {
+	cfg := *h.opts.Config
+	// Тема меняет оформление, но не ограничения сервера
+	err := cfg.ApplyOverride(meme.StyleOverride{
+		Theme: req.Theme, Font: req.Font, FontSize: req.FontSize,
+		BackgroundColor: req.BackgroundColor, BorderColor: req.BorderColor, TextColor: req.TextColor,
+		Uppercase: req.Uppercase,
+	})
+	if err != nil {
+		return nil, err
+	}
+	cfg.TopText, cfg.BottomText = req.Top, req.Bottom
+	if cfg.MaxBytes == 0 || cfg.MaxBytes > h.opts.MaxBytes {
+		cfg.MaxBytes = h.opts.MaxBytes
+	}
+
+	g := h.base.WithConfig(&cfg)
+	if h.opts.Setup != nil {
+		h.opts.Setup(g)
+	}
+	return g, nil
+}
+
+// clientIP - ключ клиента по умолчанию
+func clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
+
+// requestError - ошибка разбора запроса со статусом ответа
+type requestError struct {
+	status int
+	reason string
+	err    error
+	fields []jsonschema.Problem // нарушения схемы запроса
+}
+
+func (e *requestError) Error() string { return e.err.Error() }
+func (e *requestError) Unwrap() error { return e.err }
+
+func badRequest(reason string, format string, args ...any) error {
+	return &requestError{status: http.StatusBadRequest, reason: reason, err: fmt.Errorf(format, args...)}
+}
+
+// invalidRequest сообщает о нарушениях схемы, перечисляя все неверные поля
+func invalidRequest(what string, problems []jsonschema.Problem) error {
+	msgs := make([]string, len(problems))
+	for i, p := range problems {
+		msgs[i] = p.String()
+	}
+	return &requestError{
+		status: http.StatusBadRequest,
+		reason: "invalid_request",
+		err:    fmt.Errorf("invalid %s: %s", what, strings.Join(msgs, "; ")),
+		fields: problems,
+	}
+}
+
+// decode проверяет разобранные параметры по схеме и переносит их в Request
+func (h *Handler) decode(args map[string]any, what string) (Request, error) {
+	var req Request
+	if problems := h.schema.Validate(args); len(problems) > 0 {
+		return req, invalidRequest(what, problems)
+	}
+	data, err := json.Marshal(args)
+	if err == nil {
+		err = json.Unmarshal(data, &req)
+	}
+	if err != nil {
+		return req, badRequest("bad_request", "invalid %s: %v", what, err)
+	}
+	return req, nil
+}
+
+// parsePost читает multipart-форму: файл "image" и необязательный JSON "options"
+func (h *Handler) parsePost(w http.ResponseWriter, r *http.Request) (Request, io.ReadCloser, error) {
+	var req Request
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mt != "multipart/form-data" {
+		return req, nil, &requestError{status: http.StatusUnsupportedMediaType, reason: "unsupported_media_type", err: errors.New("expected multipart/form-data")}
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, h.opts.MaxBytes+maxOptionsBytes)
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return req, nil, badRequest("bad_request", "reading form: %v", err)
+	}
+
+	// Части читаются потоково: options должны идти раньше image,
+	// иначе они не будут учтены
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			return req, nil, badRequest("bad_request", "missing image part")
+		}
+		if err != nil {
+			return req, nil, readError(err)
+		}
+		switch part.FormName() {
+		case "options":
+			data, err := io.ReadAll(io.LimitReader(part, maxOptionsBytes))
+			part.Close()
+			if err != nil {
+				return req, nil, readError(err)
+			}
+			var args map[string]any
+			if err := json.Unmarshal(data, &args); err != nil {
+				return req, nil, badRequest("bad_request", "invalid options: %v", err)
+			}
+			if req, err = h.decode(args, "options"); err != nil {
+				return req, nil, err
+			}
+		case "image":
+			return req, part, nil
+		}
+		part.Close()
+	}
+}
+
+// parseGet берет параметры из строки запроса и загружает изображение по url
+func (h *Handler) parseGet(r *http.Request) (Request, io.ReadCloser, error) {
+	q := r.URL.Query()
+	// Подпись проверяем до любой работы, включая загрузку по url
+	if len(h.opts.SigningKey) > 0 {
+		if err := verify(h.opts.SigningKey, q, time.Now()); err != nil {
+			return Request{}, nil, err
+		}
+	}
+	// Параметры приводятся к типам схемы и проверяются так же, как JSON в POST
+	args := map[string]any{}
+	for name, prop := range h.schema.Properties {
+		if q.Has(name) {
+			args[name] = prop.Coerce(q.Get(name))
+		}
+	}
+	req, err := h.decode(args, "parameters")
+	if err != nil {
+		return req, nil, err
+	}
+	if key := q.Get("key"); key != "" {
+		body, err := h.open(r.Context(), key)
+		return req, body, err
+	}
+	if h.opts.DisableURL {
+		return req, nil, &requestError{status: http.StatusForbidden, reason: "url_disabled", err: errors.New("fetching by url is disabled")}
+	}
+	raw := q.Get("url")
+	if raw == "" {
+		return req, nil, badRequest("bad_request", "missing url parameter")
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return req, nil, badRequest("bad_request", "url must be an absolute http or https url")
+	}
+	body, err := h.opts.Fetch(r.Context(), u.String())
+	if err != nil && meme.ErrorReason(err) != "other" {
+		// Запрещенный адрес, слишком большой файл и т.п. - ошибка клиента
+		return req, nil, fmt.Errorf("fetching image: %w", err)
+	}
+	if err != nil {
+		return req, nil, &requestError{status: http.StatusBadGateway, reason: "fetch_failed", err: fmt.Errorf("fetching image: %w", err)}
+	}
+	// Поток ограничен, чтобы не читать больше MaxBytes
+	return req, struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(body, h.opts.MaxBytes+1), body}, nil
+}
+
+// open читает изображение из Options.Source
+func (h *Handler) open(ctx context.Context, key string) (io.ReadCloser, error) {
+	if h.opts.Source == nil {
+		return nil, badRequest("bad_request", "key parameter is not supported")
+	}
+	body, err := h.opts.Source.Open(ctx, key)
+	switch {
+	case errors.Is(err, storage.ErrInvalidKey):
+		return nil, badRequest("bad_request", "invalid key %q", key)
+	case errors.Is(err, fs.ErrNotExist):
+		return nil, &requestError{status: http.StatusNotFound, reason: "not_found", err: fmt.Errorf("image %q not found", key)}
+	case err != nil:
+		return nil, &requestError{status: http.StatusBadGateway, reason: "fetch_failed", err: fmt.Errorf("opening image: %w", err)}
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(body, h.opts.MaxBytes+1), body}, nil
+}
+
+// readError превращает ошибку чтения тела запроса в ответ
+func readError(err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return &requestError{status: http.StatusRequestEntityTooLarge, reason: "input_too_large", err: errors.New("request body is too large")}
+	}
+	return badRequest("bad_request", "reading form: %v", err)
+}
+
+// negotiate выбирает формат ответа: явный параметр или лучший тип из Accept
+func negotiate(format, accept string) (meme.Format, error) {
+	if format != "" {
+		return meme.ParseFormat(format)
+	}
+	best, bestQ := meme.FormatPNG, 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if s, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(s, 64); err != nil {
+				continue
+			}
+		}
+		for _, f := range meme.Formats() {
+			if meme.MIMEType(f) == mt && q > bestQ {
+				best, bestQ = f, q
+			}
+		}
+	}
+	return best, nil
+}
+
+// statusFor сопоставляет ошибку генерации HTTP-статусу
+func statusFor(err error) (int, string) {
+	var re *requestError
+	if errors.As(err, &re) {
+		return re.status, re.reason
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusServiceUnavailable, "timeout"
+	}
+	reason := meme.ErrorReason(err)
+	switch reason {
+	case "input_too_large", "image_too_large":
+		return http.StatusRequestEntityTooLarge, reason
+	case "url_not_allowed":
+		return http.StatusForbidden, reason
+	case "busy":
+		return http.StatusServiceUnavailable, reason
+	case "unknown_format", "format_not_allowed", "heif_unsupported":
+		return http.StatusUnsupportedMediaType, reason
+	case "nil_image", "empty_image", "decode_failed", "text_too_long", "invalid_config", "font_not_found":
+		return http.StatusUnprocessableEntity, reason
+	}
+	return http.StatusInternalServerError, reason
+}
+
+func writeRequestError(w http.ResponseWriter, err error) {
+	status, reason := statusFor(err)
+	if reason == "busy" {
+		w.Header().Set("Retry-After", "1")
+	}
+	body := errorBody{Error: err.Error(), Reason: reason}
+	var re *requestError
+	if errors.As(err, &re) {
+		body.Fields = re.fields
+	}
+	writeBody(w, status, body)
+}
+
+// errorBody - ответ с ошибкой
+type errorBody struct {
+	Error  string               `json:"error" required:"true" doc:"Error message."`
+	Reason string               `json:"reason,omitempty" doc:"Machine-readable error code, see meme.ErrorReason."`
+	Fields []jsonschema.Problem `json:"fields,omitempty" doc:"Invalid request fields (reason invalid_request)."`
+}
+
+// writeError отвечает ошибкой в JSON
+func writeError(w http.ResponseWriter, status int, msg, reason string) {
+	writeBody(w, status, errorBody{Error: msg, Reason: reason})
+}
+
+func writeBody(w http.ResponseWriter, status int, body errorBody) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,163 @@
+package httpmeme
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+
+	"github.com/go-goblin/meme"
+	"github.com/go-goblin/meme/internal/jsonschema"
+)
+
+// colorPattern - цвета, которые принимает meme.ParseColor
+const colorPattern = `^\s*#?([0-9a-fA-F]{3,4}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})\s*$`
+
+// requestSchema строит схему Request; перечисления тем и шрифтов берутся
+// из реестров, длина подписей - из конфигурации сервера
+func requestSchema(maxTextLength int) *jsonschema.Schema {
+	fonts := make([]string, 0, len(meme.GetAvailableFonts()))
+	for name := range meme.GetAvailableFonts() {
+		fonts = append(fonts, name)
+	}
+	slices.Sort(fonts)
+	s := jsonschema.Object(Request{})
+	s.Properties["top"].MaxLength = maxTextLength
+	s.Properties["bottom"].MaxLength = maxTextLength
+	s.Properties["theme"].Enum = meme.Themes()
+	s.Properties["font"].Enum = fonts
+	s.Properties["format"].Enum = formatNames()
+	for _, p := range s.Properties {
+		if p.Format == "color" {
+			p.Pattern = colorPattern
+		}
+	}
+	return s
+}
+
+// formatNames возвращает имена зарегистрированных выходных форматов
+func formatNames() []string {
+	var names []string
+	for _, f := range meme.Formats() {
+		names = append(names, string(f))
+	}
+	return names
+}
+
+// OpenAPI возвращает обработчик, отдающий описание API в формате
+// OpenAPI 3.1 для обработчика, смонтированного по пути path. Описание
+// строится из тех же схем, по которым проверяются запросы, и учитывает
+// Options: ключи хранилища, подпись, кеш.
+//
+//	h := httpmeme.New(opts)
+//	http.Handle("/generate", h)
+//	http.Handle("GET /openapi.json", h.OpenAPI("/generate"))
+func (h *Handler) OpenAPI(path string) http.Handler {
+	data, err := json.MarshalIndent(h.document(path), "", "  ")
+	if err != nil {
+		panic(err) // документ собирается из фиксированных типов
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	})
+}
+
+func (h *Handler) document(path string) map[string]any {
+	ref := func(name string) *jsonschema.Schema {
+		return &jsonschema.Schema{Ref: "#/components/schemas/" + name}
+	}
+	binary := &jsonschema.Schema{Type: "string", Format: "binary"}
+	errorResponse := func(desc string) map[string]any {
+		return map[string]any{
+			"description": desc,
+			"content":     map[string]any{"application/json": map[string]any{"schema": ref("Error")}},
+		}
+	}
+	images := map[string]any{}
+	for _, f := range meme.Formats() {
+		images[meme.MIMEType(f)] = map[string]any{"schema": binary}
+	}
+	responses := map[string]any{
+		"200": map[string]any{"description": "Generated meme.", "content": images},
+		"400": errorResponse("Invalid request; fields lists every invalid parameter."),
+		"403": errorResponse("Source url is not allowed or the link signature is invalid."),
+		"406": errorResponse("Requested format is not supported."),
+		"413": errorResponse("Image is too large."),
+		"415": errorResponse("Image format is not supported."),
+		"422": errorResponse("Image or captions can not be rendered."),
+		"503": errorResponse("Request timed out or the server is busy."),
+	}
+	if h.opts.Limiter != nil {
+		responses["429"] = errorResponse("Rate limit exceeded, see Retry-After.")
+	}
+	if h.opts.Cache != nil {
+		responses["304"] = map[string]any{"description": "Not modified, see ETag."}
+	}
+
+	names := make([]string, 0, len(h.schema.Properties))
+	for name := range h.schema.Properties {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	param := func(name, desc string, s *jsonschema.Schema) map[string]any {
+		return map[string]any{"name": name, "in": "query", "description": desc, "schema": s}
+	}
+	var params []map[string]any
+	if !h.opts.DisableURL {
+		params = append(params, param("url", "Public http(s) URL of the source image.", &jsonschema.Schema{Type: "string", Format: "uri"}))
+		responses["502"] = errorResponse("Source image could not be fetched.")
+	}
+	if h.opts.Source != nil {
+		params = append(params, param("key", "Key of the source image in the server storage.", &jsonschema.Schema{Type: "string"}))
+		responses["404"] = errorResponse("Source image not found.")
+	}
+	for _, name := range names {
+		p := h.schema.Properties[name]
+		params = append(params, param(name, p.Description, p))
+	}
+	get := map[string]any{
+		"summary":    "Generate a meme from an image fetched by url or read by key",
+		"parameters": params,
+		"responses":  responses,
+	}
+	ops := map[string]any{"get": get}
+	if len(h.opts.SigningKey) > 0 {
+		get["parameters"] = append(params,
+			param(SignatureParam, "Signature of the other parameters, see httpmeme.Sign.", &jsonschema.Schema{Type: "string"}),
+			param(ExpiresParam, "Expiry of the signed link, Unix time.", &jsonschema.Schema{Type: "integer"}),
+		)
+	}
+	if len(h.opts.SigningKey) == 0 || h.opts.AllowUnsignedUpload {
+		ops["post"] = map[string]any{
+			"summary": "Generate a meme from an uploaded image",
+			"requestBody": map[string]any{
+				"required": true,
+				"content": map[string]any{
+					"multipart/form-data": map[string]any{
+						"schema": &jsonschema.Schema{
+							Type: "object",
+							Properties: map[string]*jsonschema.Schema{
+								"options": ref("Request"),
+								"image":   binary,
+							},
+							Required: []string{"image"},
+						},
+						"encoding": map[string]any{"options": map[string]any{"contentType": "application/json"}},
+					},
+				},
+			},
+			"responses": responses,
+		}
+	}
+	return map[string]any{
+		"openapi": "3.1.0",
+		"info":    map[string]any{"title": "meme", "version": "1"},
+		"paths":   map[string]any{path: ops},
+		"components": map[string]any{
+			"schemas": map[string]any{
+				"Request": h.schema,
+				"Error":   jsonschema.Object(errorBody{}),
+			},
+		},
+	}
+}
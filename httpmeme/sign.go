@@ -0,0 +1,77 @@
+package httpmeme
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Параметры подписанной ссылки
+const (
+	SignatureParam = "sig"     // подпись HMAC-SHA256 в base64url без выравнивания
+	ExpiresParam   = "expires" // необязательный срок действия, Unix-время в секундах
+)
+
+// Sign подписывает параметры запроса ключом key и возвращает строку
+// запроса с добавленным sig. Подписываются все параметры, включая url,
+// подписи и expires, поэтому изменить любой из них, не зная ключа, нельзя.
+// Нулевой expires - ссылка без срока действия.
+//
+//	q := url.Values{"url": {src}, "top": {"Hello"}}
+//	link := "https://memes.example.com/generate?" + httpmeme.Sign(key, q, time.Now().Add(time.Hour))
+func Sign(key []byte, params url.Values, expires time.Time) string {
+	q := url.Values{}
+	for k, v := range params {
+		if k != SignatureParam {
+			q[k] = v
+		}
+	}
+	if !expires.IsZero() {
+		q.Set(ExpiresParam, strconv.FormatInt(expires.Unix(), 10))
+	}
+	q.Set(SignatureParam, signature(key, q))
+	return q.Encode()
+}
+
+// signature вычисляет подпись канонической строки: параметры без sig,
+// отсортированные по имени, как их кодирует url.Values.Encode
+func signature(key []byte, q url.Values) string {
+	canon := url.Values{}
+	for k, v := range q {
+		if k != SignatureParam {
+			canon[k] = v
+		}
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(canon.Encode()))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verify проверяет подпись и срок действия параметров запроса
+func verify(key []byte, q url.Values, now time.Time) error {
+	forbidden := func(reason, msg string) error {
+		return &requestError{status: http.StatusForbidden, reason: reason, err: errors.New(msg)}
+	}
+	sig := q.Get(SignatureParam)
+	if sig == "" {
+		return forbidden("invalid_signature", "missing signature")
+	}
+	if len(q[SignatureParam]) > 1 || !hmac.Equal([]byte(sig), []byte(signature(key, q))) {
+		return forbidden("invalid_signature", "invalid signature")
+	}
+	if s := q.Get(ExpiresParam); s != "" {
+		exp, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return forbidden("invalid_signature", "invalid expires")
+		}
+		if now.Unix() > exp {
+			return forbidden("expired", "signed url has expired")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package httpmeme
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+var (
+	testKey  = []byte("test signing key")
+	testNow  = time.Unix(1_700_000_000, 0)
+	testLink = url.Values{"url": {"https://example.com/cat.png"}, "top": {"Hello"}, "bottom": {"World"}}
+)
+
+// signed подписывает testLink и возвращает разобранные параметры
+func signed(t *testing.T, key []byte, expires time.Time) url.Values {
+	t.Helper()
+	q, err := url.ParseQuery(Sign(key, testLink, expires))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+func TestVerify(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		query  func() url.Values
+		reason string // "" - подпись верна
+	}{
+		{"valid", func() url.Values { return signed(t, testKey, time.Time{}) }, ""},
+		{"valid until", func() url.Values { return signed(t, testKey, testNow.Add(time.Minute)) }, ""},
+		{"expired", func() url.Values { return signed(t, testKey, testNow.Add(-time.Second)) }, "expired"},
+		{"missing", func() url.Values { return testLink }, "invalid_signature"},
+		{"wrong key", func() url.Values { return signed(t, []byte("other key"), time.Time{}) }, "invalid_signature"},
+		{"tampered url", func() url.Values {
+			q := signed(t, testKey, time.Time{})
+			q.Set("url", "http://169.254.169.254/latest/meta-data")
+			return q
+		}, "invalid_signature"},
+		{"tampered caption", func() url.Values {
+			q := signed(t, testKey, time.Time{})
+			q.Set("top", "Goodbye")
+			return q
+		}, "invalid_signature"},
+		{"added parameter", func() url.Values {
+			q := signed(t, testKey, time.Time{})
+			q.Set("width", "10000")
+			return q
+		}, "invalid_signature"},
+		{"removed parameter", func() url.Values {
+			q := signed(t, testKey, time.Time{})
+			q.Del("bottom")
+			return q
+		}, "invalid_signature"},
+		{"extended expiry", func() url.Values {
+			q := signed(t, testKey, testNow.Add(-time.Second))
+			q.Set(ExpiresParam, "99999999999")
+			return q
+		}, "invalid_signature"},
+		{"removed expiry", func() url.Values {
+			q := signed(t, testKey, testNow.Add(-time.Second))
+			q.Del(ExpiresParam)
+			return q
+		}, "invalid_signature"},
+		{"repeated signature", func() url.Values {
+			q := signed(t, testKey, time.Time{})
+			q.Add(SignatureParam, "x")
+			return q
+		}, "invalid_signature"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := verify(testKey, tc.query(), testNow)
+			if tc.reason == "" {
+				if err != nil {
+					t.Fatalf("verify: %v", err)
+				}
+				return
+			}
+			var re *requestError
+			if !errors.As(err, &re) {
+				t.Fatalf("verify = %v, want *requestError", err)
+			}
+			if re.status != http.StatusForbidden || re.reason != tc.reason {
+				t.Errorf("verify = %d %s, want 403 %s", re.status, re.reason, tc.reason)
+			}
+		})
+	}
+}
+
+func TestSignedHandler(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 64, 64))); err != nil {
+		t.Fatal(err)
+	}
+	fetched := 0
+	h := New(Options{
+		SigningKey: testKey,
+		Fetch: func(ctx context.Context, url string) (io.ReadCloser, error) {
+			fetched++
+			return io.NopCloser(bytes.NewReader(buf.Bytes())), nil
+		},
+	})
+	do := func(method, target string) int {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+		return w.Code
+	}
+
+	q := signed(t, testKey, time.Now().Add(time.Hour))
+	q.Set("top", "Goodbye")
+	if code := do(http.MethodGet, "/?"+q.Encode()); code != http.StatusForbidden {
+		t.Errorf("tampered link: status %d", code)
+	}
+	if code := do(http.MethodGet, "/?"+testLink.Encode()); code != http.StatusForbidden {
+		t.Errorf("unsigned link: status %d", code)
+	}
+	if code := do(http.MethodPost, "/"); code != http.StatusForbidden {
+		t.Errorf("upload without AllowUnsignedUpload: status %d", code)
+	}
+	if fetched != 0 {
+		t.Fatalf("rejected requests fetched %d images", fetched)
+	}
+
+	if code := do(http.MethodGet, "/?"+Sign(testKey, testLink, time.Now().Add(time.Hour))); code != http.StatusOK {
+		t.Errorf("signed link: status %d", code)
+	}
+	if fetched != 1 {
+		t.Errorf("signed link fetched %d images", fetched)
+	}
+}
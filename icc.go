@@ -0,0 +1,362 @@
+package meme
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"image"
+	"image/draw"
+	"io"
+	"math"
+)
+
+// Поддерживаются матричные RGB-профили ICC (Display P3, Adobe RGB, ProPhoto
+// и им подобные): колоранты rXYZ/gXYZ/bXYZ и кривые rTRC/gTRC/bTRC.
+// LUT-профили (A2B0) встречаются в фото редко и остаются без преобразования.
+
+// iccCurve - тональная кривая канала
+type iccCurve struct {
+	gamma    float64   // степенная функция, если table == nil и param == nil
+	table    []float64 // табличная кривая curv
+	param    []float64 // параметрическая кривая para: g, a, b, c, d, e, f
+	function int
+}
+
+// linear переводит закодированное значение [0,1] в линейное
+func (c *iccCurve) linear(v float64) float64 {
+	switch {
+	case c.table != nil:
+		pos := v * float64(len(c.table)-1)
+		i := int(pos)
+		if i >= len(c.table)-1 {
+			return c.table[len(c.table)-1]
+		}
+		frac := pos - float64(i)
+		return c.table[i]*(1-frac) + c.table[i+1]*frac
+
+	case c.param != nil:
+		p := c.param
+		g := p[0]
+		switch c.function {
+		case 0:
+			return math.Pow(v, g)
+		case 1:
+			if v >= -p[2]/p[1] {
+				return math.Pow(p[1]*v+p[2], g)
+			}
+			return 0
+		case 2:
+			if v >= -p[2]/p[1] {
+				return math.Pow(p[1]*v+p[2], g) + p[3]
+			}
+			return p[3]
+		case 3:
+			if v >= p[4] {
+				return math.Pow(p[1]*v+p[2], g)
+			}
+			return p[3] * v
+		default:
+			if v >= p[4] {
+				return math.Pow(p[1]*v+p[2], g) + p[5]
+			}
+			return p[3]*v + p[6]
+		}
+	}
+	return math.Pow(v, c.gamma)
+}
+
+// iccProfile - разобранный матричный RGB-профиль
+type iccProfile struct {
+	toXYZ  [3][3]float64 // RGB -> XYZ (D50), столбцы - колоранты
+	curves [3]iccCurve
+}
+
+// Матрица XYZ (D50) -> линейный sRGB, адаптированная по Брэдфорду
+var xyzD50ToSRGB = [3][3]float64{
+	{3.1338561, -1.6168667, -0.4906146},
+	{-0.9787684, 1.9161415, 0.0334540},
+	{0.0719453, -0.2289914, 1.4052427},
+}
+
+// Колоранты sRGB в D50 для распознавания профилей, уже являющихся sRGB
+var srgbToXYZD50 = [3][3]float64{
+	{0.4360747, 0.3850649, 0.1430804},
+	{0.2225045, 0.7168786, 0.0606169},
+	{0.0139322, 0.0971045, 0.7141733},
+}
+
+// findICCProfile извлекает встроенный ICC-профиль из JPEG, PNG или WebP
+func findICCProfile(data []byte) []byte {
+	switch {
+	case len(data) > 2 && data[0] == 0xff && data[1] == 0xd8:
+		return findJPEGICC(data)
+
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		chunk := findPNGChunk(data, "iCCP")
+		// Имя профиля, нулевой байт, метод сжатия, zlib-данные
+		i := bytes.IndexByte(chunk, 0)
+		if i < 0 || i+2 > len(chunk) {
+			return nil
+		}
+		zr, err := zlib.NewReader(bytes.NewReader(chunk[i+2:]))
+		if err != nil {
+			return nil
+		}
+		defer zr.Close()
+		profile, err := io.ReadAll(io.LimitReader(zr, 4<<20))
+		if err != nil {
+			return nil
+		}
+		return profile
+
+	case len(data) > 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return findRIFFChunk(data, "ICCP")
+	}
+	return nil
+}
+
+// findJPEGICC собирает профиль из сегментов APP2 "ICC_PROFILE"
+func findJPEGICC(data []byte) []byte {
+	const prefix = "ICC_PROFILE\x00"
+	chunks := map[int][]byte{}
+	total := 0
+	for p := 2; p+4 <= len(data); {
+		if data[p] != 0xff {
+			break
+		}
+		marker := data[p+1]
+		if marker == 0xda || marker == 0xd9 {
+			break
+		}
+		size := int(binary.BigEndian.Uint16(data[p+2:]))
+		if size < 2 || p+2+size > len(data) {
+			break
+		}
+		segment := data[p+4 : p+2+size]
+		if marker == 0xe2 && len(segment) > len(prefix)+2 && string(segment[:len(prefix)]) == prefix {
+			seq := int(segment[len(prefix)])
+			total = int(segment[len(prefix)+1])
+			chunks[seq] = segment[len(prefix)+2:]
+		}
+		p += 2 + size
+	}
+	if total == 0 || len(chunks) != total {
+		return nil
+	}
+	var profile []byte
+	for i := 1; i <= total; i++ {
+		chunk, ok := chunks[i]
+		if !ok {
+			return nil
+		}
+		profile = append(profile, chunk...)
+	}
+	return profile
+}
+
+// parseICCProfile разбирает матричный RGB-профиль. Возвращает nil, если
+// профиль не RGB, не матричный или уже эквивалентен sRGB.
+func parseICCProfile(data []byte) *iccProfile {
+	if len(data) < 132 || string(data[16:20]) != "RGB " || string(data[36:40]) != "acsp" {
+		return nil
+	}
+	tags := map[string][]byte{}
+	count := int(binary.BigEndian.Uint32(data[128:]))
+	for i := 0; i < count; i++ {
+		entry := 132 + i*12
+		if entry+12 > len(data) {
+			break
+		}
+		offset := int(binary.BigEndian.Uint32(data[entry+4:]))
+		size := int(binary.BigEndian.Uint32(data[entry+8:]))
+		if offset < 0 || size < 0 || offset+size > len(data) {
+			continue
+		}
+		tags[string(data[entry:entry+4])] = data[offset : offset+size]
+	}
+
+	p := &iccProfile{}
+	for col, name := range []string{"rXYZ", "gXYZ", "bXYZ"} {
+		xyz, ok := parseXYZ(tags[name])
+		if !ok {
+			return nil
+		}
+		for row := 0; row < 3; row++ {
+			p.toXYZ[row][col] = xyz[row]
+		}
+	}
+	for i, name := range []string{"rTRC", "gTRC", "bTRC"} {
+		c, ok := parseCurve(tags[name])
+		if !ok {
+			return nil
+		}
+		p.curves[i] = c
+	}
+
+	if p.isSRGB() {
+		return nil
+	}
+	return p
+}
+
+func s15Fixed16(b []byte) float64 {
+	return float64(int32(binary.BigEndian.Uint32(b))) / 65536
+}
+
+func parseXYZ(tag []byte) ([3]float64, bool) {
+	if len(tag) < 20 || string(tag[:4]) != "XYZ " {
+		return [3]float64{}, false
+	}
+	return [3]float64{s15Fixed16(tag[8:]), s15Fixed16(tag[12:]), s15Fixed16(tag[16:])}, true
+}
+
+func parseCurve(tag []byte) (iccCurve, bool) {
+	if len(tag) < 12 {
+		return iccCurve{}, false
+	}
+	switch string(tag[:4]) {
+	case "curv":
+		n := int(binary.BigEndian.Uint32(tag[8:]))
+		switch {
+		case n == 0:
+			return iccCurve{gamma: 1}, true
+		case n == 1 && len(tag) >= 14:
+			return iccCurve{gamma: float64(binary.BigEndian.Uint16(tag[12:])) / 256}, true
+		case len(tag) >= 12+2*n:
+			table := make([]float64, n)
+			for i := range table {
+				table[i] = float64(binary.BigEndian.Uint16(tag[12+2*i:])) / 65535
+			}
+			return iccCurve{table: table}, true
+		}
+
+	case "para":
+		function := int(binary.BigEndian.Uint16(tag[8:]))
+		counts := [5]int{1, 3, 4, 5, 7}
+		if function < 0 || function >= len(counts) || len(tag) < 12+4*counts[function] {
+			return iccCurve{}, false
+		}
+		param := make([]float64, 7)
+		for i := 0; i < counts[function]; i++ {
+			param[i] = s15Fixed16(tag[12+4*i:])
+		}
+		if function > 0 && param[1] == 0 {
+			return iccCurve{}, false
+		}
+		return iccCurve{param: param, function: function}, true
+	}
+	return iccCurve{}, false
+}
+
+func srgbLinear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func srgbEncode(v float64) float64 {
+	switch {
+	case v <= 0:
+		return 0
+	case v >= 1:
+		return 1
+	case v <= 0.0031308:
+		return v * 12.92
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+// isSRGB проверяет, совпадает ли профиль с sRGB с точностью до округления
+func (p *iccProfile) isSRGB() bool {
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 3; col++ {
+			if math.Abs(p.toXYZ[row][col]-srgbToXYZD50[row][col]) > 0.002 {
+				return false
+			}
+		}
+	}
+	for i := range p.curves {
+		for v := 0.0; v <= 1; v += 0.125 {
+			if math.Abs(p.curves[i].linear(v)-srgbLinear(v)) > 0.005 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// transform переводит колориметрию профиля в sRGB.
+// Результат - *image.NRGBA64 для 16-битных исходников и *image.NRGBA для остальных.
+func (p *iccProfile) transform(img image.Image) image.Image {
+	var m [3][3]float64
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				m[i][j] += xyzD50ToSRGB[i][k] * p.toXYZ[k][j]
+			}
+		}
+	}
+
+	convert := func(r, g, b float64) (float64, float64, float64) {
+		lr, lg, lb := p.curves[0].linear(r), p.curves[1].linear(g), p.curves[2].linear(b)
+		return srgbEncode(m[0][0]*lr + m[0][1]*lg + m[0][2]*lb),
+			srgbEncode(m[1][0]*lr + m[1][1]*lg + m[1][2]*lb),
+			srgbEncode(m[2][0]*lr + m[2][1]*lg + m[2][2]*lb)
+	}
+
+	b := img.Bounds()
+	if is16Bit(img) {
+		out := image.NewNRGBA64(image.Rect(0, 0, b.Dx(), b.Dy()))
+		draw.Draw(out, out.Bounds(), img, b.Min, draw.Src)
+		for i := 0; i < len(out.Pix); i += 8 {
+			r := float64(binary.BigEndian.Uint16(out.Pix[i:])) / 65535
+			g := float64(binary.BigEndian.Uint16(out.Pix[i+2:])) / 65535
+			bl := float64(binary.BigEndian.Uint16(out.Pix[i+4:])) / 65535
+			r, g, bl = convert(r, g, bl)
+			binary.BigEndian.PutUint16(out.Pix[i:], uint16(r*65535+0.5))
+			binary.BigEndian.PutUint16(out.Pix[i+2:], uint16(g*65535+0.5))
+			binary.BigEndian.PutUint16(out.Pix[i+4:], uint16(bl*65535+0.5))
+		}
+		return out
+	}
+
+	// Для 8 бит кривые заранее сводятся в таблицы
+	var lin [3][256]float64
+	for c := 0; c < 3; c++ {
+		for v := 0; v < 256; v++ {
+			lin[c][v] = p.curves[c].linear(float64(v) / 255)
+		}
+	}
+	out := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	if rgba := toRGBA(img); rgba.Opaque() {
+		copy(out.Pix, rgba.Pix)
+	} else {
+		draw.Draw(out, out.Bounds(), img, b.Min, draw.Src)
+	}
+	for i := 0; i < len(out.Pix); i += 4 {
+		lr, lg, lb := lin[0][out.Pix[i]], lin[1][out.Pix[i+1]], lin[2][out.Pix[i+2]]
+		out.Pix[i] = uint8(srgbEncode(m[0][0]*lr+m[0][1]*lg+m[0][2]*lb)*255 + 0.5)
+		out.Pix[i+1] = uint8(srgbEncode(m[1][0]*lr+m[1][1]*lg+m[1][2]*lb)*255 + 0.5)
+		out.Pix[i+2] = uint8(srgbEncode(m[2][0]*lr+m[2][1]*lg+m[2][2]*lb)*255 + 0.5)
+	}
+	return out
+}
+
+// is16Bit сообщает, хранит ли изображение больше 8 бит на канал
+func is16Bit(img image.Image) bool {
+	switch img.(type) {
+	case *image.RGBA64, *image.NRGBA64, *image.Gray16:
+		return true
+	}
+	return false
+}
+
+// convertToSRGB применяет встроенный ICC-профиль файла, если он отличается от sRGB
+func convertToSRGB(img image.Image, data []byte) image.Image {
+	profile := parseICCProfile(findICCProfile(data))
+	if profile == nil {
+		return img
+	}
+	return profile.transform(img)
+}
@@ -0,0 +1,375 @@
+package meme
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"testing"
+)
+
+// iccTag собирает тег профиля: сигнатура типа, 4 резервных байта и данные
+func iccTag(typ string, data ...any) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(typ)
+	buf.Write(make([]byte, 4))
+	for _, v := range data {
+		binary.Write(&buf, binary.BigEndian, v)
+	}
+	return buf.Bytes()
+}
+
+func iccFixed(v float64) int32 { return int32(math.Round(v * 65536)) }
+
+// iccXYZ - тег XYZ с одной точкой
+func iccXYZ(x, y, z float64) []byte {
+	return iccTag("XYZ ", iccFixed(x), iccFixed(y), iccFixed(z))
+}
+
+// iccGamma - тег curv со степенной функцией
+func iccGamma(g float64) []byte {
+	return iccTag("curv", uint32(1), uint16(math.Round(g*256)))
+}
+
+// iccPara - параметрический тег para
+func iccPara(function uint16, params ...float64) []byte {
+	data := []any{function, uint16(0)}
+	for _, p := range params {
+		data = append(data, iccFixed(p))
+	}
+	return iccTag("para", data...)
+}
+
+// iccSRGBCurve - кривая sRGB в виде para функции 3
+func iccSRGBCurve() []byte {
+	return iccPara(3, 2.4, 1/1.055, 0.055/1.055, 1/12.92, 0.04045)
+}
+
+// iccTestProfile собирает матричный RGB-профиль с колорантами sRGB
+// (если toXYZ == nil) и одной кривой trc для всех каналов
+func iccTestProfile(toXYZ *[3][3]float64, trc []byte) []byte {
+	m := srgbToXYZD50
+	if toXYZ != nil {
+		m = *toXYZ
+	}
+	tags := []struct {
+		name string
+		data []byte
+	}{
+		{"rXYZ", iccXYZ(m[0][0], m[1][0], m[2][0])},
+		{"gXYZ", iccXYZ(m[0][1], m[1][1], m[2][1])},
+		{"bXYZ", iccXYZ(m[0][2], m[1][2], m[2][2])},
+		{"rTRC", trc},
+		{"gTRC", trc},
+		{"bTRC", trc},
+	}
+
+	header := make([]byte, 128)
+	copy(header[12:], "mntr")
+	copy(header[16:], "RGB ")
+	copy(header[20:], "XYZ ")
+	copy(header[36:], "acsp")
+
+	var table, data bytes.Buffer
+	binary.Write(&table, binary.BigEndian, uint32(len(tags)))
+	offset := 128 + 4 + 12*len(tags)
+	for _, tag := range tags {
+		table.WriteString(tag.name)
+		binary.Write(&table, binary.BigEndian, uint32(offset+data.Len()))
+		binary.Write(&table, binary.BigEndian, uint32(len(tag.data)))
+		data.Write(tag.data)
+		for data.Len()%4 != 0 {
+			data.WriteByte(0)
+		}
+	}
+	profile := append(header, table.Bytes()...)
+	profile = append(profile, data.Bytes()...)
+	binary.BigEndian.PutUint32(profile, uint32(len(profile)))
+	return profile
+}
+
+func TestParseCurve(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		tag    []byte
+		in     float64
+		linear float64
+	}{
+		{"curv identity", iccTag("curv", uint32(0)), 0.5, 0.5},
+		{"curv gamma", iccGamma(2), 0.5, 0.25},
+		{"curv table", iccTag("curv", uint32(3), uint16(0), uint16(16384), uint16(65535)), 0.25, 0.125},
+		{"curv table end", iccTag("curv", uint32(2), uint16(0), uint16(65535)), 1, 1},
+		{"para 0", iccPara(0, 2), 0.5, 0.25},
+		{"para 1", iccPara(1, 1, 2, -0.5), 0.75, 1},
+		{"para 1 below", iccPara(1, 1, 2, -0.5), 0.2, 0},
+		{"para 2", iccPara(2, 1, 2, -0.5, 0.25), 0.2, 0.25},
+		{"para 3", iccSRGBCurve(), 0.5, srgbLinear(0.5)},
+		{"para 3 toe", iccSRGBCurve(), 0.02, srgbLinear(0.02)},
+		{"para 4", iccPara(4, 1, 1, 0, 0.5, 0.5, 0.25, 0.125), 0.25, 0.25},
+	} {
+		c, ok := parseCurve(tc.tag)
+		if !ok {
+			t.Errorf("%s: not parsed", tc.name)
+			continue
+		}
+		if got := c.linear(tc.in); math.Abs(got-tc.linear) > 1e-3 {
+			t.Errorf("%s: linear(%v) = %v, want %v", tc.name, tc.in, got, tc.linear)
+		}
+	}
+
+	for _, tc := range []struct {
+		name string
+		tag  []byte
+	}{
+		{"empty", nil},
+		{"short", []byte("curv\x00\x00\x00\x00")},
+		{"unknown type", iccTag("sf32", uint32(0))},
+		{"curv gamma truncated", iccTag("curv", uint32(1))},
+		{"curv table truncated", iccTag("curv", uint32(3), uint16(0), uint16(1))},
+		{"curv huge count", iccTag("curv", uint32(math.MaxUint32))},
+		{"para unknown function", iccPara(5, 1, 1, 0, 0, 0, 0, 0)},
+		{"para truncated", iccPara(3, 2.4, 1)},
+		{"para zero slope", iccPara(1, 2.2, 0, 0.5)},
+	} {
+		if _, ok := parseCurve(tc.tag); ok {
+			t.Errorf("%s: parsed", tc.name)
+		}
+	}
+}
+
+func TestParseICCProfile(t *testing.T) {
+	wide := [3][3]float64{
+		{0.6097559, 0.2052401, 0.1492240},
+		{0.3111242, 0.6256560, 0.0632197},
+		{0.0194811, 0.0608902, 0.7448387},
+	}
+	p := parseICCProfile(iccTestProfile(&wide, iccGamma(2.2)))
+	if p == nil {
+		t.Fatal("matrix profile not parsed")
+	}
+	for row := range 3 {
+		for col := range 3 {
+			if math.Abs(p.toXYZ[row][col]-wide[row][col]) > 1e-4 {
+				t.Errorf("toXYZ[%d][%d] = %v, want %v", row, col, p.toXYZ[row][col], wide[row][col])
+			}
+		}
+	}
+	if got := p.curves[1].linear(0.5); math.Abs(got-math.Pow(0.5, 2.2)) > 1e-3 {
+		t.Errorf("gTRC linear(0.5) = %v", got)
+	}
+
+	// Профиль, эквивалентный sRGB, преобразования не требует
+	if p := parseICCProfile(iccTestProfile(nil, iccSRGBCurve())); p != nil {
+		t.Error("sRGB profile not recognized")
+	}
+
+	valid := iccTestProfile(&wide, iccGamma(2.2))
+	corrupt := func(f func(b []byte) []byte) []byte {
+		return f(bytes.Clone(valid))
+	}
+	for _, tc := range []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"header only", valid[:128]},
+		{"gray", corrupt(func(b []byte) []byte { copy(b[16:], "GRAY"); return b })},
+		{"no acsp", corrupt(func(b []byte) []byte { copy(b[36:], "xxxx"); return b })},
+		{"tag offset past end", corrupt(func(b []byte) []byte {
+			binary.BigEndian.PutUint32(b[132+4:], uint32(len(b)))
+			return b
+		})},
+		{"tag size past end", corrupt(func(b []byte) []byte {
+			binary.BigEndian.PutUint32(b[132+8:], math.MaxUint32)
+			return b
+		})},
+		{"tag count past end", corrupt(func(b []byte) []byte {
+			binary.BigEndian.PutUint32(b[128:], math.MaxUint32)
+			return b[:132+12*3]
+		})},
+		{"missing curve", corrupt(func(b []byte) []byte { copy(b[132+12*5:], "xTRC"); return b })},
+		{"bad colorant type", corrupt(func(b []byte) []byte {
+			off := binary.BigEndian.Uint32(b[132+4:])
+			copy(b[off:], "sf32")
+			return b
+		})},
+	} {
+		if p := parseICCProfile(tc.data); p != nil {
+			t.Errorf("%s: parsed", tc.name)
+		}
+	}
+
+	// Любой обрезанный профиль разбирается без паники и, пока обрезан
+	// последний тег, не принимается
+	last := 132 + 12*5
+	end := int(binary.BigEndian.Uint32(valid[last+4:]) + binary.BigEndian.Uint32(valid[last+8:]))
+	for n := range len(valid) {
+		if p := parseICCProfile(valid[:n]); p != nil && n < end {
+			t.Errorf("truncated to %d bytes: parsed", n)
+		}
+	}
+}
+
+// iccAPP2 - сегмент APP2 с куском профиля seq из total
+func iccAPP2(seq, total byte, chunk []byte) []byte {
+	return append([]byte{'I', 'C', 'C', '_', 'P', 'R', 'O', 'F', 'I', 'L', 'E', 0, seq, total}, chunk...)
+}
+
+func TestFindICCProfile(t *testing.T) {
+	profile := iccTestProfile(nil, iccGamma(2.2))
+	half := len(profile) / 2
+	first, second := profile[:half], profile[half:]
+
+	var jpg, pngData bytes.Buffer
+	if err := jpeg.Encode(&jpg, image.NewGray(image.Rect(0, 0, 8, 8)), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(&pngData, image.NewGray(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatal(err)
+	}
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write(profile)
+	zw.Close()
+	iccp := append([]byte("test\x00\x00"), compressed.Bytes()...)
+
+	// withJPEGSegment вставляет сегмент сразу после SOI, поэтому
+	// последний добавленный сегмент идет в файле первым
+	app2 := func(segments ...[]byte) []byte {
+		data := jpg.Bytes()
+		for i := len(segments) - 1; i >= 0; i-- {
+			data = withJPEGSegment(t, data, 0xe2, segments[i])
+		}
+		return data
+	}
+
+	for _, tc := range []struct {
+		name string
+		data []byte
+		want []byte
+	}{
+		{"jpeg single", app2(iccAPP2(1, 1, profile)), profile},
+		{"jpeg chunks", app2(iccAPP2(1, 2, first), iccAPP2(2, 2, second)), profile},
+		{"jpeg chunks out of order", app2(iccAPP2(2, 2, second), iccAPP2(1, 2, first)), profile},
+		{"png", withPNGChunk(t, pngData.Bytes(), "iCCP", iccp), profile},
+		{"webp", riffWebP(riffChunk("ICCP", profile), riffChunk("VP8L", []byte{1, 2, 3})), profile},
+		{"no profile", jpg.Bytes(), nil},
+
+		// Испорченные данные дают отсутствие профиля, а не панику
+		{"jpeg missing chunk", app2(iccAPP2(1, 3, first), iccAPP2(2, 3, second)), nil},
+		{"jpeg duplicate chunk", app2(iccAPP2(1, 2, first), iccAPP2(1, 2, second)), nil},
+		{"jpeg zero sequence", app2(iccAPP2(0, 2, first), iccAPP2(1, 2, second)), nil},
+		{"jpeg zero total", app2(iccAPP2(1, 0, profile)), nil},
+		{"app2 without prefix", app2(append([]byte("ICC_PROFILE"), profile...)), nil},
+		{"app2 header only", app2([]byte("ICC_PROFILE\x00\x01")), nil},
+		{"app2 size past end", append([]byte{0xff, 0xd8, 0xff, 0xe2, 0xff, 0xff}, iccAPP2(1, 1, profile)...), nil},
+		{"app2 size below 2", []byte{0xff, 0xd8, 0xff, 0xe2, 0x00, 0x01, 0x00, 0x00}, nil},
+		{"app2 after scan", append(append(bytes.Clone(jpg.Bytes()[:2]), 0xff, 0xda, 0x00, 0x02), app2(iccAPP2(1, 1, profile))[2:]...), nil},
+		{"png corrupt zlib", withPNGChunk(t, pngData.Bytes(), "iCCP", append([]byte("test\x00\x00"), 0x78, 0x9c, 0xff, 0xff)), nil},
+		{"png truncated zlib", withPNGChunk(t, pngData.Bytes(), "iCCP", iccp[:len(iccp)-8]), nil},
+		{"png no name terminator", withPNGChunk(t, pngData.Bytes(), "iCCP", []byte("test")), nil},
+		{"webp chunk past end", riffWebP([]byte("ICCP\xff\xff\xff\x7f")), nil},
+	} {
+		if got := findICCProfile(tc.data); !bytes.Equal(got, tc.want) {
+			t.Errorf("%s: got %d bytes, want %d", tc.name, len(got), len(tc.want))
+		}
+	}
+}
+
+func TestFindICCProfileTruncated(t *testing.T) {
+	var jpg bytes.Buffer
+	if err := jpeg.Encode(&jpg, image.NewGray(image.Rect(0, 0, 8, 8)), nil); err != nil {
+		t.Fatal(err)
+	}
+	profile := iccTestProfile(nil, iccGamma(2.2))
+	data := withJPEGSegment(t, jpg.Bytes(), 0xe2, iccAPP2(2, 2, profile[100:]))
+	data = withJPEGSegment(t, data, 0xe2, iccAPP2(1, 2, profile[:100]))
+	// Любой обрезанный файл разбирается без паники
+	for n := range len(data) {
+		convertToSRGB(image.NewRGBA(image.Rect(0, 0, 1, 1)), data[:n])
+	}
+}
+
+func TestICCTransform(t *testing.T) {
+	// Линейный профиль с колорантами sRGB: серый v переходит в srgbEncode(v)
+	p := parseICCProfile(iccTestProfile(nil, iccTag("curv", uint32(0))))
+	if p == nil {
+		t.Fatal("linear profile not parsed")
+	}
+
+	src := image.NewNRGBA(image.Rect(2, 3, 4, 4))
+	src.SetNRGBA(2, 3, color.NRGBA{128, 128, 128, 255})
+	src.SetNRGBA(3, 3, color.NRGBA{0, 0, 0, 255})
+	out, ok := p.transform(src).(*image.NRGBA)
+	if !ok {
+		t.Fatalf("8-bit result is %T, want *image.NRGBA", p.transform(src))
+	}
+	if out.Bounds() != image.Rect(0, 0, 2, 1) {
+		t.Errorf("bounds %v", out.Bounds())
+	}
+	want := uint8(srgbEncode(128.0/255)*255 + 0.5)
+	for i, v := range out.Pix[:3] {
+		if d := int(v) - int(want); d < -1 || d > 1 {
+			t.Errorf("channel %d = %d, want %d", i, v, want)
+		}
+	}
+	if c := out.NRGBAAt(1, 0); c != (color.NRGBA{0, 0, 0, 255}) {
+		t.Errorf("black became %v", c)
+	}
+
+	// 16-битный исходник сохраняет точность: соседние значения,
+	// неразличимые в 8 битах, остаются разными
+	src16 := image.NewNRGBA64(image.Rect(0, 0, 2, 1))
+	src16.SetNRGBA64(0, 0, color.NRGBA64{0x8000, 0x8000, 0x8000, 0xffff})
+	src16.SetNRGBA64(1, 0, color.NRGBA64{0x8010, 0x8010, 0x8010, 0xffff})
+	out16, ok := p.transform(src16).(*image.NRGBA64)
+	if !ok {
+		t.Fatalf("16-bit result is %T, want *image.NRGBA64", p.transform(src16))
+	}
+	a, b := out16.NRGBA64At(0, 0), out16.NRGBA64At(1, 0)
+	if a.R == b.R || a.R>>8 != b.R>>8 {
+		t.Errorf("16-bit precision lost: %#04x and %#04x", a.R, b.R)
+	}
+	if want := srgbEncode(float64(0x8000)/65535) * 65535; math.Abs(float64(a.R)-want) > 0x100 {
+		t.Errorf("16-bit value %#04x, want about %#04x", a.R, uint16(want))
+	}
+}
+
+func TestDecodeColorManagement(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	for i := range src.Pix {
+		src.Pix[i] = 128
+		if i%4 == 3 {
+			src.Pix[i] = 255
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write(iccTestProfile(nil, iccTag("curv", uint32(0))))
+	zw.Close()
+	data := withPNGChunk(t, buf.Bytes(), "iCCP", append([]byte("linear\x00\x00"), compressed.Bytes()...))
+
+	red := func(opts *DecodeOptions) uint8 {
+		t.Helper()
+		img, _, err := DecodeImage(bytes.NewReader(data), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, _, _, _ := img.At(0, 0).RGBA()
+		return uint8(r >> 8)
+	}
+	if got := red(&DecodeOptions{}); got != 128 {
+		t.Errorf("without color management: %d, want 128", got)
+	}
+	if got, want := red(&DecodeOptions{ColorManagement: true}), uint8(srgbEncode(128.0/255)*255+0.5); got < want-1 || got > want+1 {
+		t.Errorf("with color management: %d, want %d", got, want)
+	}
+}
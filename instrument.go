@@ -0,0 +1,80 @@
+package meme
+
+import (
+	"runtime/metrics"
+	"time"
+)
+
+// Stage - этап генерации, о котором сообщает Instrumenter
+type Stage string
+
+const (
+	StageDecode    Stage = "decode"    // чтение и декодирование входа
+	StageLayout    Stage = "layout"    // расчёт геометрии
+	StageFontLoad  Stage = "font_load" // загрузка и парсинг шрифта
+	StageOutline   Stage = "outline"   // отрисовка подписей вместе с обводкой
+	StageComposite Stage = "composite" // фон, рамка и вставка фото
+	StageEncode    Stage = "encode"    // кодирование результата
+)
+
+// StageStats - измерения одного этапа. Счётчики выделений берутся из
+// runtime/metrics: они общие для процесса и обновляются порциями, поэтому
+// мелкие выделения и параллельная генерация дают приблизительные значения.
+type StageStats struct {
+	Stage      Stage
+	Duration   time.Duration
+	Allocs     uint64 // число выделенных объектов
+	AllocBytes uint64 // объём выделенной памяти
+}
+
+// Instrumenter получает измерения каждого этапа генерации
+type Instrumenter interface {
+	ObserveStage(StageStats)
+}
+
+// InstrumenterFunc позволяет использовать функцию как Instrumenter
+type InstrumenterFunc func(StageStats)
+
+// ObserveStage вызывает f(s)
+func (f InstrumenterFunc) ObserveStage(s StageStats) {
+	f(s)
+}
+
+// SetInstrumenter подключает сбор измерений по этапам (nil - отключить)
+func (g *Generator) SetInstrumenter(i Instrumenter) {
+	g.instrumenter = i
+}
+
+var allocMetrics = []string{"/gc/heap/allocs:objects", "/gc/heap/allocs:bytes"}
+
+func readAllocs() (objects, bytes uint64) {
+	samples := []metrics.Sample{{Name: allocMetrics[0]}, {Name: allocMetrics[1]}}
+	metrics.Read(samples)
+	if samples[0].Value.Kind() == metrics.KindUint64 {
+		objects = samples[0].Value.Uint64()
+	}
+	if samples[1].Value.Kind() == metrics.KindUint64 {
+		bytes = samples[1].Value.Uint64()
+	}
+	return objects, bytes
+}
+
+// stage начинает измерение этапа; возвращённую функцию нужно вызвать по его окончании
+func (g *Generator) stage(s Stage) func() {
+	inst := g.instrumenter
+	if inst == nil {
+		return func() {}
+	}
+	objects, bytes := readAllocs()
+	start := time.Now()
+	return func() {
+		elapsed := time.Since(start)
+		objects2, bytes2 := readAllocs()
+		inst.ObserveStage(StageStats{
+			Stage:      s,
+			Duration:   elapsed,
+			Allocs:     objects2 - objects,
+			AllocBytes: bytes2 - bytes,
+		})
+	}
+}
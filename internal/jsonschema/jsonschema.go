@@ -0,0 +1,259 @@
+// Package jsonschema строит подмножество JSON Schema по Go-структурам и
+// проверяет по нему разобранный JSON. Используется для описания API
+// (httpmeme, mcpmeme) и проверки запросов до генерации.
+//
+// Схема поля берется из тегов:
+//
+//	Top     string `json:"top" doc:"Подпись"`
+//	Quality int    `json:"quality" minimum:"1" maximum:"100"`
+//	Format  string `json:"format" enum:"png,jpeg,webp"`
+//	Color   string `json:"color" pattern:"^#[0-9a-f]{6}$"`
+//	URL     string `json:"url" format:"uri" required:"true"`
+//
+// Перечисления, зависящие от реестров (темы, шрифты), задаются после
+// построения схемы.
+package jsonschema
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Schema - описание значения; Validate проверяет ровно то, что объявлено
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	MaxLength            int                `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+}
+
+// Problem - одно нарушение схемы
+type Problem struct {
+	Field   string `json:"field,omitempty" doc:"Field name, empty for object-level problems."`
+	Message string `json:"message" required:"true"`
+}
+
+func (p Problem) String() string {
+	if p.Field == "" {
+		return p.Message
+	}
+	return p.Field + ": " + p.Message
+}
+
+// Object строит схему объекта по полям структуры v (значение или
+// указатель). Поля без тега json и с тегом "-" пропускаются; неизвестные
+// свойства запрещены. Вложенные структуры и срезы описываются так же.
+func Object(v any) *Schema {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("jsonschema: %s is not a struct", t))
+	}
+	return object(t)
+}
+
+func object(t reflect.Type) *Schema {
+	no := false
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}, AdditionalProperties: &no}
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if !f.IsExported() || name == "" || name == "-" {
+			continue
+		}
+		s.Properties[name] = field(f)
+		if f.Tag.Get("required") == "true" {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return s
+}
+
+// field строит схему поля по его типу и тегам
+func field(f reflect.StructField) *Schema {
+	s := typeSchema(f.Type)
+	s.Description, s.Pattern, s.Format = f.Tag.Get("doc"), f.Tag.Get("pattern"), f.Tag.Get("format")
+	if e := f.Tag.Get("enum"); e != "" {
+		s.Enum = strings.Split(e, ",")
+	}
+	s.Minimum = bound(f, "minimum")
+	s.Maximum = bound(f, "maximum")
+	return s
+}
+
+func typeSchema(t reflect.Type) *Schema {
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice:
+		return &Schema{Type: "array", Items: typeSchema(t.Elem())}
+	case reflect.Struct:
+		return object(t)
+	}
+	panic(fmt.Sprintf("jsonschema: unsupported type %s", t))
+}
+
+func bound(f reflect.StructField, tag string) *float64 {
+	v, ok := f.Tag.Lookup(tag)
+	if !ok {
+		return nil
+	}
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		panic(fmt.Sprintf("jsonschema: invalid %s %q of field %s", tag, v, f.Name))
+	}
+	return &n
+}
+
+// Validate возвращает все нарушения схемы в объекте args, упорядоченные по
+// именам полей
+func (s *Schema) Validate(args map[string]any) []Problem {
+	var problems []Problem
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		prop, ok := s.Properties[name]
+		if !ok {
+			if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				problems = append(problems, Problem{name, "unknown field"})
+			}
+			continue
+		}
+		if msg := prop.Check(args[name]); msg != "" {
+			problems = append(problems, Problem{name, msg})
+		}
+	}
+	for _, name := range s.Required {
+		if _, ok := args[name]; !ok {
+			problems = append(problems, Problem{name, "required"})
+		}
+	}
+	if len(s.OneOf) > 0 {
+		matched := 0
+		for _, alt := range s.OneOf {
+			if len(alt.Validate(args)) == 0 && alt.hasRequired(args) {
+				matched++
+			}
+		}
+		if matched != 1 {
+			var alts []string
+			for _, alt := range s.OneOf {
+				alts = append(alts, alt.Required...)
+			}
+			problems = append(problems, Problem{Message: fmt.Sprintf("exactly one of %v is required", alts)})
+		}
+	}
+	return problems
+}
+
+// hasRequired сообщает, что все обязательные поля присутствуют
+func (s *Schema) hasRequired(args map[string]any) bool {
+	for _, name := range s.Required {
+		if _, ok := args[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Check проверяет одно значение, разобранное encoding/json, и возвращает
+// описание нарушения или пустую строку
+func (s *Schema) Check(v any) string {
+	switch s.Type {
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return "must be a string"
+		}
+		if s.MaxLength > 0 && utf8.RuneCountInString(str) > s.MaxLength {
+			return fmt.Sprintf("must be at most %d characters", s.MaxLength)
+		}
+		if len(s.Enum) > 0 && !slices.Contains(s.Enum, str) {
+			return fmt.Sprintf("must be one of %v", s.Enum)
+		}
+		if s.Pattern != "" && !compile(s.Pattern).MatchString(str) {
+			if s.Format != "" {
+				return "must be a valid " + s.Format
+			}
+			return fmt.Sprintf("must match %s", s.Pattern)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return "must be a boolean"
+		}
+	case "integer", "number":
+		n, ok := v.(float64)
+		if !ok {
+			return "must be a " + s.Type
+		}
+		if s.Type == "integer" && n != math.Trunc(n) {
+			return "must be an integer"
+		}
+		if s.Minimum != nil && n < *s.Minimum {
+			return fmt.Sprintf("must be at least %v", *s.Minimum)
+		}
+		if s.Maximum != nil && n > *s.Maximum {
+			return fmt.Sprintf("must be at most %v", *s.Maximum)
+		}
+	}
+	return ""
+}
+
+// Coerce переводит строковое значение (параметр строки запроса) в тип
+// схемы; неподходящее значение возвращается как есть, чтобы Check сообщил
+// о нем
+func (s *Schema) Coerce(v string) any {
+	switch s.Type {
+	case "boolean":
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	case "integer", "number":
+		if n, err := strconv.ParseFloat(v, 64); err == nil {
+			return n
+		}
+	}
+	return v
+}
+
+// Шаблоны задаются в тегах и константах, поэтому компилируются один раз
+var patterns sync.Map
+
+func compile(pattern string) *regexp.Regexp {
+	if re, ok := patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp)
+	}
+	re := regexp.MustCompile(pattern)
+	patterns.Store(pattern, re)
+	return re
+}
@@ -0,0 +1,51 @@
+// Package options разбирает параметры генерации в JSON для привязок к
+// другим языкам (cmd/memewasm, cmd/memecshared). Ключи - поля файла
+// конфигурации (см. meme.LoadConfig) и параметры кодирования:
+//
+//	{"top_text": "Текст", "theme": "vaporwave", "format": "jpeg", "quality": 85}
+package options
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-goblin/meme"
+)
+
+// encoding - ключи, не входящие в конфигурацию
+type encoding struct {
+	Format         string `json:"format"`           // png, jpeg, webp; пусто - PNG
+	Quality        int    `json:"quality"`          // качество JPEG 1-100
+	MaxOutputBytes int64  `json:"max_output_bytes"` // предел размера файла, 0 - без предела
+	FontData       []byte `json:"font_data"`        // файл шрифта в base64
+}
+
+// Parse возвращает конфигурацию и параметры кодирования; пустые data -
+// значения по умолчанию
+func Parse(data []byte) (*meme.Config, *meme.EncodeOptions, error) {
+	cfg := meme.DefaultConfig()
+	opts := &meme.EncodeOptions{}
+	if len(data) == 0 {
+		return cfg, opts, nil
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, nil, fmt.Errorf("%w: parsing options: %w", meme.ErrInvalidConfig, err)
+	}
+	var enc encoding
+	if err := json.Unmarshal(data, &enc); err != nil {
+		return nil, nil, fmt.Errorf("%w: parsing options: %w", meme.ErrInvalidConfig, err)
+	}
+	if enc.Format != "" {
+		f, err := meme.ParseFormat(enc.Format)
+		if err != nil {
+			return nil, nil, err
+		}
+		opts.Format = f
+	}
+	opts.Quality = enc.Quality
+	opts.MaxOutputBytes = enc.MaxOutputBytes
+	if len(enc.FontData) > 0 {
+		cfg.FontPath, cfg.FontData = "", enc.FontData
+	}
+	return cfg, opts, nil
+}
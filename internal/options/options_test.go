@@ -0,0 +1,36 @@
+package options
+
+import (
+	"testing"
+
+	"github.com/go-goblin/meme"
+)
+
+func TestParseTheme(t *testing.T) {
+	cfg, opts, err := Parse([]byte(`{"top_text": "Текст", "theme": "vaporwave", "format": "jpeg", "quality": 85}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.Format != meme.FormatJPEG || opts.Quality != 85 {
+		t.Errorf("encode options = %+v", opts)
+	}
+	if cfg.TopText != "Текст" {
+		t.Errorf("TopText = %q", cfg.TopText)
+	}
+	got := meme.NewGenerator(cfg).Config()
+	for _, c := range []struct {
+		field     string
+		got, want string
+	}{
+		{"BackgroundColor", meme.FormatColor(got.BackgroundColor), "#1a0b2e"},
+		{"BorderColor", meme.FormatColor(got.BorderColor), "#ff71ce"},
+		{"TextColor", meme.FormatColor(got.TextColor), "#01cdfe"},
+	} {
+		if c.got != c.want {
+			t.Errorf("%s = %s, want %s", c.field, c.got, c.want)
+		}
+	}
+	if got.Border != 8 {
+		t.Errorf("Border = %d, want 8", got.Border)
+	}
+}
@@ -0,0 +1,89 @@
+package meme
+
+import (
+	"image"
+	"math"
+	"math/rand/v2"
+
+	"golang.org/x/image/font"
+)
+
+// Jitter - "рукописные" или "проклятые" подписи: каждый глиф случайно
+// сдвигается и поворачивается. Случайность берется из Generator.newRand,
+// поэтому с Config.Deterministic узор задается Config.Seed.
+type Jitter struct {
+	Offset   float64 // наибольший сдвиг глифа по каждой оси в пикселях
+	Rotation float64 // наибольший поворот глифа в градусах
+}
+
+// Пределы: сильнее буквы разлетаются и перестают читаться
+const (
+	maxJitterOffset   = 64
+	maxJitterRotation = 45
+)
+
+// active сообщает, что эффект включен
+func (j Jitter) active() bool {
+	return j.Offset > 0 || j.Rotation > 0
+}
+
+// pad - на сколько пикселей глифы могут выйти за границы строки
+func (j Jitter) pad(face font.Face) int {
+	h := face.Metrics().Height.Ceil()
+	return int(math.Ceil(min(j.Offset, maxJitterOffset)+float64(h)*math.Sin(min(j.Rotation, maxJitterRotation)*math.Pi/180))) + 1
+}
+
+// newRand возвращает источник узора для зерна строки seed
+func (j Jitter) newRand(seed uint64) *rand.Rand {
+	return rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+}
+
+// next возвращает сдвиг и поворот (в радианах) очередного глифа
+func (j Jitter) next(rnd *rand.Rand) (dx, dy, angle float64) {
+	offset, rotation := min(j.Offset, maxJitterOffset), min(j.Rotation, maxJitterRotation)
+	dx = offset * (2*rnd.Float64() - 1)
+	dy = offset * (2*rnd.Float64() - 1)
+	angle = rotation * (2*rnd.Float64() - 1) * math.Pi / 180
+	return dx, dy, angle
+}
+
+// rotateGlyph накладывает маску глифа, повернутую на angle радиан вокруг
+// центра dr, на dst. Пиксели берутся билинейной интерполяцией.
+func rotateGlyph(dst *image.Alpha, dr image.Rectangle, mask image.Image, mp image.Point, angle float64) {
+	sin, cos := math.Sincos(angle)
+	cx, cy := float64(dr.Min.X+dr.Max.X)/2, float64(dr.Min.Y+dr.Max.Y)/2
+	w, h := float64(dr.Dx()), float64(dr.Dy())
+	hw := (w*math.Abs(cos) + h*math.Abs(sin)) / 2
+	hh := (w*math.Abs(sin) + h*math.Abs(cos)) / 2
+	out := image.Rect(int(math.Floor(cx-hw)), int(math.Floor(cy-hh)), int(math.Ceil(cx+hw)), int(math.Ceil(cy+hh))).Intersect(dst.Rect)
+
+	alpha, fast := mask.(*image.Alpha)
+	// at - альфа маски в точке (x, y) координат dr, вне глифа - 0
+	at := func(x, y int) float64 {
+		if x < 0 || y < 0 || x >= dr.Dx() || y >= dr.Dy() {
+			return 0
+		}
+		if fast {
+			return float64(alpha.AlphaAt(mp.X+x, mp.Y+y).A)
+		}
+		_, _, _, a := mask.At(mp.X+x, mp.Y+y).RGBA()
+		return float64(a >> 8)
+	}
+	for y := out.Min.Y; y < out.Max.Y; y++ {
+		for x := out.Min.X; x < out.Max.X; x++ {
+			// Обратный поворот центра пикселя в координаты исходной маски
+			px, py := float64(x)+0.5-cx, float64(y)+0.5-cy
+			sx := px*cos + py*sin + w/2 - 0.5
+			sy := -px*sin + py*cos + h/2 - 0.5
+			x0, y0 := int(math.Floor(sx)), int(math.Floor(sy))
+			fx, fy := sx-float64(x0), sy-float64(y0)
+			a := (at(x0, y0)*(1-fx)+at(x0+1, y0)*fx)*(1-fy) + (at(x0, y0+1)*(1-fx)+at(x0+1, y0+1)*fx)*fy
+			if a <= 0 {
+				continue
+			}
+			i := dst.PixOffset(x, y)
+			d := float64(dst.Pix[i])
+			dst.Pix[i] = uint8(math.Round(min(a+d*(255-a)/255, 255)))
+		}
+	}
+}
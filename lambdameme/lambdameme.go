@@ -0,0 +1,220 @@
+// Package lambdameme запускает HTTP-обработчик генератора в AWS Lambda
+// за API Gateway (REST API и HTTP API, форматы событий 1.0 и 2.0).
+//
+//	func main() {
+//		lambda.Start(lambdameme.New(httpmeme.Options{}).Handle)
+//	}
+//
+// Пакет не зависит от aws-lambda-go: Request и Response совпадают с
+// событиями API Gateway по JSON, и lambda.Start принимает Handle как есть.
+package lambdameme
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-goblin/meme"
+	"github.com/go-goblin/meme/httpmeme"
+)
+
+// Request - событие прокси-интеграции API Gateway. Поля форматов 1.0
+// (REST API) и 2.0 (HTTP API) объединены; формат определяется по Version.
+type Request struct {
+	Version string `json:"version"`
+
+	// Формат 1.0
+	HTTPMethod                      string              `json:"httpMethod"`
+	Path                            string              `json:"path"`
+	MultiValueHeaders               map[string][]string `json:"multiValueHeaders"`
+	QueryStringParameters           map[string]string   `json:"queryStringParameters"`
+	MultiValueQueryStringParameters map[string][]string `json:"multiValueQueryStringParameters"`
+
+	// Формат 2.0
+	RawPath        string   `json:"rawPath"`
+	RawQueryString string   `json:"rawQueryString"`
+	Cookies        []string `json:"cookies"`
+	RequestContext struct {
+		HTTP struct {
+			Method   string `json:"method"`
+			SourceIP string `json:"sourceIp"`
+		} `json:"http"`
+		Identity struct {
+			SourceIP string `json:"sourceIp"`
+		} `json:"identity"`
+	} `json:"requestContext"`
+
+	Headers         map[string]string `json:"headers"`
+	Body            string            `json:"body"`
+	IsBase64Encoded bool              `json:"isBase64Encoded"`
+}
+
+// Response - ответ прокси-интеграции API Gateway
+type Response struct {
+	StatusCode        int                 `json:"statusCode"`
+	Headers           map[string]string   `json:"headers,omitempty"`
+	MultiValueHeaders map[string][]string `json:"multiValueHeaders,omitempty"`
+	Body              string              `json:"body"`
+	IsBase64Encoded   bool                `json:"isBase64Encoded"`
+}
+
+// Handler переводит события API Gateway в вызовы http.Handler
+type Handler struct {
+	h http.Handler
+}
+
+// New создает обработчик httpmeme и прогревает его. Холодный старт
+// Lambda оплачивается первым запросом, поэтому шрифт из FontPath читается
+// в память заранее (следующие запросы не обращаются к диску), а тестовая
+// отрисовка разбирает шрифт и загружает код до прихода запроса.
+func New(opts httpmeme.Options) *Handler {
+	cfg := meme.DefaultConfig()
+	if opts.Config != nil {
+		c := *opts.Config
+		cfg = &c
+	}
+	if cfg.FontPath != "" && len(cfg.FontData) == 0 {
+		if data, err := os.ReadFile(cfg.FontPath); err == nil {
+			cfg.FontPath, cfg.FontData = "", data
+		}
+	}
+	opts.Config = cfg
+	warmUp(cfg)
+	return Wrap(httpmeme.New(opts))
+}
+
+// Wrap оборачивает произвольный http.Handler без прогрева
+func Wrap(h http.Handler) *Handler {
+	return &Handler{h: h}
+}
+
+// warmUp рисует маленький мем, чтобы разобрать шрифт при инициализации.
+// Ошибки игнорируются: те же ошибки получит первый запрос.
+func warmUp(cfg *meme.Config) {
+	c := *cfg
+	c.TopText, c.BottomText = "warm up", ""
+	meme.NewGenerator(&c).Generate(image.NewRGBA(image.Rect(0, 0, 64, 64)))
+}
+
+// Handle обрабатывает одно событие API Gateway
+func (h *Handler) Handle(ctx context.Context, req Request) (Response, error) {
+	r, err := req.httpRequest(ctx)
+	if err != nil {
+		return Response{StatusCode: http.StatusBadRequest, Body: err.Error()}, nil
+	}
+	w := &responseWriter{header: http.Header{}}
+	h.h.ServeHTTP(w, r)
+	return w.response(req.Version == "2.0"), nil
+}
+
+// httpRequest собирает http.Request из события
+func (req *Request) httpRequest(ctx context.Context) (*http.Request, error) {
+	body := []byte(req.Body)
+	if req.IsBase64Encoded {
+		var err error
+		if body, err = base64.StdEncoding.DecodeString(req.Body); err != nil {
+			return nil, fmt.Errorf("decoding body: %w", err)
+		}
+	}
+
+	method, path, query, ip := req.HTTPMethod, req.Path, url.Values{}, req.RequestContext.Identity.SourceIP
+	if req.Version == "2.0" {
+		method, path, ip = req.RequestContext.HTTP.Method, req.RawPath, req.RequestContext.HTTP.SourceIP
+		q, err := url.ParseQuery(req.RawQueryString)
+		if err != nil {
+			return nil, fmt.Errorf("parsing query: %w", err)
+		}
+		query = q
+	} else {
+		for k, v := range req.QueryStringParameters {
+			query.Set(k, v)
+		}
+		for k, vs := range req.MultiValueQueryStringParameters {
+			query[k] = vs
+		}
+	}
+	u := &url.URL{Path: path, RawQuery: query.Encode()}
+
+	r, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range req.Headers {
+		r.Header.Set(k, v)
+	}
+	for k, vs := range req.MultiValueHeaders {
+		r.Header.Del(k)
+		for _, v := range vs {
+			r.Header.Add(k, v)
+		}
+	}
+	for _, c := range req.Cookies {
+		r.Header.Add("Cookie", c)
+	}
+	r.Host = r.Header.Get("Host")
+	r.RemoteAddr = ip
+	r.ContentLength = int64(len(body))
+	return r, nil
+}
+
+// responseWriter накапливает ответ обработчика в памяти
+type responseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *responseWriter) Header() http.Header { return w.header }
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(p)
+}
+
+var _ http.ResponseWriter = (*responseWriter)(nil)
+
+// response превращает накопленный ответ в ответ API Gateway. Двоичное
+// содержимое передается в base64, для чего в REST API нужно разрешить
+// binaryMediaTypes (например */*).
+func (w *responseWriter) response(v2 bool) Response {
+	resp := Response{StatusCode: w.status}
+	if resp.StatusCode == 0 {
+		resp.StatusCode = http.StatusOK
+	}
+	if v2 {
+		// Формат 2.0 не поддерживает multiValueHeaders: значения склеиваются
+		resp.Headers = make(map[string]string, len(w.header))
+		for k, vs := range w.header {
+			resp.Headers[k] = strings.Join(vs, ",")
+		}
+	} else {
+		resp.MultiValueHeaders = w.header
+	}
+
+	data := w.body.Bytes()
+	if isText(w.header.Get("Content-Type")) && utf8.Valid(data) {
+		resp.Body = string(data)
+	} else {
+		resp.Body = base64.StdEncoding.EncodeToString(data)
+		resp.IsBase64Encoded = true
+	}
+	return resp
+}
+
+// isText сообщает, можно ли передать тело без base64
+func isText(contentType string) bool {
+	return strings.HasPrefix(contentType, "text/") || strings.HasPrefix(contentType, "application/json")
+}
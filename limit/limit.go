@@ -0,0 +1,103 @@
+// Package limit ограничивает нагрузку на серверы генерации (httpmeme,
+// grpcmeme): частоту запросов каждого клиента и число одновременных
+// генераций, чтобы один клиент не занял весь процессор большими фото.
+//
+//	opts := httpmeme.Options{
+//		Limiter:     limit.NewTokenBucket(2, 10), // 2 запроса в секунду, до 10 подряд
+//		Concurrency: limit.NewSemaphore(runtime.NumCPU()),
+//	}
+package limit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter решает, можно ли выполнить запрос клиента. Ключ клиента
+// выбирает сервер: IP-адрес, API-ключ и т.п. Реализация должна быть
+// безопасна для одновременного использования.
+type Limiter interface {
+	Allow(key string) bool
+}
+
+// TokenBucket - "ведро с токенами" на каждого клиента: rate запросов в
+// секунду в среднем и до burst подряд
+type TokenBucket struct {
+	rate  float64
+	burst float64
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	sweep   time.Time
+}
+
+var _ Limiter = (*TokenBucket)(nil)
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket создает ограничитель; burst меньше 1 считается равным 1
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	return &TokenBucket{rate: rate, burst: float64(max(burst, 1)), buckets: map[string]*bucket{}, sweep: time.Now()}
+}
+
+// Allow расходует токен клиента key, если он есть
+func (l *TokenBucket) Allow(key string) bool {
+	return l.allow(key, time.Now())
+}
+
+func (l *TokenBucket) allow(key string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// Полные ведра ничем не отличаются от новых: периодически их выбрасываем
+	if now.Sub(l.sweep) > time.Minute {
+		for k, b := range l.buckets {
+			if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+				delete(l.buckets, k)
+			}
+		}
+		l.sweep = now
+	}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = min(b.tokens+now.Sub(b.last).Seconds()*l.rate, l.burst)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Semaphore ограничивает число одновременных генераций. Один семафор
+// можно передать нескольким серверам, чтобы предел был общим.
+type Semaphore struct {
+	slots chan struct{}
+}
+
+// NewSemaphore создает семафор на n мест; n меньше 1 считается равным 1
+func NewSemaphore(n int) *Semaphore {
+	return &Semaphore{slots: make(chan struct{}, max(n, 1))}
+}
+
+// Acquire ждет свободного места до отмены ctx. После успешного вызова
+// место нужно вернуть через Release.
+func (s *Semaphore) Acquire(ctx context.Context) error {
+	select {
+	case s.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release возвращает место, занятое Acquire
+func (s *Semaphore) Release() {
+	<-s.slots
+}
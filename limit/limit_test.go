@@ -0,0 +1,112 @@
+package limit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	l := NewTokenBucket(2, 3)
+	now := time.Unix(1_700_000_000, 0)
+
+	for i := range 3 {
+		if !l.allow("a", now) {
+			t.Fatalf("request %d within burst rejected", i)
+		}
+	}
+	if l.allow("a", now) {
+		t.Fatal("request over burst allowed")
+	}
+	// Клиенты не делят ведро
+	if !l.allow("b", now) {
+		t.Fatal("other client rejected")
+	}
+
+	// 2 токена в секунду: через 0.5 с появляется ровно один
+	now = now.Add(500 * time.Millisecond)
+	if !l.allow("a", now) {
+		t.Fatal("refilled token rejected")
+	}
+	if l.allow("a", now) {
+		t.Fatal("second token after 0.5s allowed")
+	}
+
+	// Ведро не наполняется выше burst
+	now = now.Add(time.Hour)
+	for i := range 3 {
+		if !l.allow("a", now) {
+			t.Fatalf("request %d after idle rejected", i)
+		}
+	}
+	if l.allow("a", now) {
+		t.Fatal("idle client got more than burst")
+	}
+}
+
+func TestTokenBucketSweep(t *testing.T) {
+	l := NewTokenBucket(1, 2)
+	now := time.Unix(1_700_000_000, 0)
+	l.sweep = now
+	l.allow("idle", now)
+	l.allow("busy", now)
+	l.allow("busy", now)
+
+	// За две минуты "idle" наполнилось, а "busy" расходует токены
+	// каждую секунду и остается пустым
+	for range 120 {
+		now = now.Add(time.Second)
+		l.allow("busy", now)
+	}
+	l.mu.Lock()
+	_, idle := l.buckets["idle"]
+	_, busy := l.buckets["busy"]
+	l.mu.Unlock()
+	if idle || !busy {
+		t.Errorf("after sweep: idle kept %v, busy kept %v", idle, busy)
+	}
+}
+
+func TestTokenBucketBurst(t *testing.T) {
+	l := NewTokenBucket(0, 0)
+	now := time.Now()
+	if !l.allow("a", now) || l.allow("a", now) {
+		t.Error("burst below 1 is not treated as 1")
+	}
+}
+
+func TestSemaphore(t *testing.T) {
+	s := NewSemaphore(2)
+	ctx := context.Background()
+	for range 2 {
+		if err := s.Acquire(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := s.Acquire(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Acquire on full semaphore = %v", err)
+	}
+
+	acquired := make(chan error)
+	go func() { acquired <- s.Acquire(ctx) }()
+	select {
+	case <-acquired:
+		t.Fatal("Acquire did not wait for Release")
+	case <-time.After(10 * time.Millisecond):
+	}
+	s.Release()
+	if err := <-acquired; err != nil {
+		t.Fatal(err)
+	}
+	if n := len(s.slots); n != 2 {
+		t.Errorf("%d slots taken, want 2", n)
+	}
+
+	if cap(NewSemaphore(0).slots) != 1 {
+		t.Error("n below 1 is not treated as 1")
+	}
+}
@@ -0,0 +1,90 @@
+package meme
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sync"
+)
+
+// Таблицы перевода между sRGB и линейным светом: 8 бит sRGB -> 16 бит
+// линейного значения и обратно с шагом 16
+var linearTables = sync.OnceValues(func() (*[256]uint16, *[4097]uint8) {
+	var toLinear [256]uint16
+	for i := range toLinear {
+		v := float64(i) / 255
+		if v <= 0.04045 {
+			v /= 12.92
+		} else {
+			v = math.Pow((v+0.055)/1.055, 2.4)
+		}
+		toLinear[i] = uint16(math.Round(v * 0xffff))
+	}
+	var toSRGB [4097]uint8
+	for i := range toSRGB {
+		v := min(float64(i)*16/0xffff, 1)
+		if v <= 0.0031308 {
+			v *= 12.92
+		} else {
+			v = 1.055*math.Pow(v, 1/2.4) - 0.055
+		}
+		toSRGB[i] = uint8(math.Round(v * 255))
+	}
+	return &toLinear, &toSRGB
+})
+
+// drawMaskLinear - draw.DrawMask однотонным цветом c по правилу Over, но
+// со смешиванием в линейном свете: полупрозрачные края глифов не темнеют,
+// как при смешивании значений sRGB. Быстрый путь - для *image.RGBA.
+func drawMaskLinear(dst draw.Image, r image.Rectangle, c color.Color, mask *image.Alpha, mp image.Point) {
+	clipped := r.Intersect(dst.Bounds())
+	if clipped.Empty() {
+		return
+	}
+	mp = mp.Add(clipped.Min.Sub(r.Min))
+	r = clipped
+	toLinear, toSRGB := linearTables()
+	src := color.NRGBAModel.Convert(c).(color.NRGBA)
+	sr, sg, sb := uint32(toLinear[src.R]), uint32(toLinear[src.G]), uint32(toLinear[src.B])
+
+	// blend смешивает один канал: d - значение dst в sRGB без
+	// предумножения, da - альфа dst, a - альфа источника (0..0xffff)
+	blend := func(s, d, da, a, outA uint32) uint8 {
+		dl := uint32(toLinear[d]) * da / 0xff
+		l := (s*a + dl*(0xffff-a)) / outA
+		return toSRGB[min(l, 0xffff)>>4]
+	}
+	rgba, fast := dst.(*image.RGBA)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			m := uint32(mask.AlphaAt(x-r.Min.X+mp.X, y-r.Min.Y+mp.Y).A)
+			if m == 0 {
+				continue
+			}
+			a := m * uint32(src.A) * 0xffff / (0xff * 0xff)
+			var d color.NRGBA
+			if fast {
+				d = color.NRGBAModel.Convert(rgba.RGBAAt(x, y)).(color.NRGBA)
+			} else {
+				d = color.NRGBAModel.Convert(dst.At(x, y)).(color.NRGBA)
+			}
+			da := uint32(d.A)
+			outA := a + da*0x101*(0xffff-a)/0xffff
+			if outA == 0 {
+				continue
+			}
+			out := color.NRGBA{
+				R: blend(sr, uint32(d.R), da, a, outA),
+				G: blend(sg, uint32(d.G), da, a, outA),
+				B: blend(sb, uint32(d.B), da, a, outA),
+				A: uint8(outA >> 8),
+			}
+			if fast {
+				rgba.SetRGBA(x, y, color.RGBAModel.Convert(out).(color.RGBA))
+			} else {
+				dst.Set(x, y, out)
+			}
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package meme
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SetLogger подключает журнал отладки (nil - молчать).
+// Генератор пишет на уровне Debug промахи кеша шрифтов, автоподбор размера
+// шрифта, использование запасных вариантов и предупреждения о конфигурации.
+func (g *Generator) SetLogger(l *slog.Logger) {
+	g.logger = l
+}
+
+// debug пишет сообщение в журнал, если он подключен
+func (g *Generator) debug(msg string, args ...any) {
+	logDebug(g.logger, msg, args...)
+}
+
+func logDebug(l *slog.Logger, msg string, args ...any) {
+	if l != nil && l.Enabled(context.Background(), slog.LevelDebug) {
+		l.Debug(msg, args...)
+	}
+}
+
+// warnConfig сообщает о настройках, которые допустимы, но вряд ли задуманы
+func (g *Generator) warnConfig() {
+	if g.logger == nil {
+		return
+	}
+	cfg := g.config
+	if len(cfg.FontData) > 0 && cfg.FontPath != "" {
+		g.debug("both FontData and FontPath are set, using FontData", "font_path", cfg.FontPath)
+	}
+	if cfg.Border > cfg.Padding {
+		g.debug("border is wider than padding and will be clipped by the canvas edge", "border", cfg.Border, "padding", cfg.Padding)
+	}
+}
@@ -1,18 +1,22 @@
 package meme
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"image"
 	"image/color"
 	"image/draw"
-	"os"
+	"log/slog"
+	"maps"
+	"math"
+	"slices"
 	"strings"
-	"sync"
+	"time"
 
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/gofont/gobold"
 	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/gofont/gosmallcaps"
 	"golang.org/x/image/font/opentype"
 	"golang.org/x/image/math/fixed"
 )
@@ -23,6 +27,9 @@ type Config struct {
 	TopText    string
 	BottomText string
 
+	// Тема оформления (см. RegisterTheme); заполняет незаданные поля ниже
+	Theme string
+
 	// Настройки шрифта
 	FontSize float64
 	FontPath string // путь к файлу .ttf шрифта (опционально)
@@ -41,7 +48,62 @@ type Config struct {
 
 	// Настройки текста
 	TextUppercase bool // Автоматически преобразовывать текст в верхний регистр
-	AutoFontSize  bool // Автоматически подбирать размер шрифта под ширину изображения
+	// Смешивать края подписей и обводки с фоном в линейном свете: на крупных
+	// подписях края не темнеют, но отрисовка текста медленнее
+	LinearBlending bool
+	// Рисовать подписи одним контуром цвета TextColor без заливки; ширина
+	// контура - TextOutlineWidth, 0 - по размеру шрифта. Контур обводит
+	// векторные контуры глифов со скругленными углами
+	TextHollow bool
+	// Объемные буквы (Depth 0 - без объема)
+	TextExtrude Extrude
+	// Неоновое свечение вокруг подписей (Radius 0 - без свечения)
+	TextGlow Glow
+	// "Рукописное" дрожание глифов (нулевое - ровные строки)
+	TextJitter Jitter
+	// Волна вдоль подписей (Amplitude 0 - ровные строки)
+	TextWave Wave
+	// Ставить подписи с точностью 1/64 пикселя, а не округлять начало строки
+	// до целого: ровнее центрирование и мелкий текст
+	SubpixelText bool
+
+	// Регистр отдельных подписей; CaseDefault - по TextUppercase
+	TopCase      TextCase
+	BottomCase   TextCase
+	AutoFontSize bool // Автоматически подбирать размер шрифта под ширину изображения
+
+	// Наибольшая ширина подписи в долях ширины холста (например, 0.8):
+	// длинная подпись переносится по словам на несколько строк. 0 - одна
+	// строка во всю ширину холста.
+	TopMaxWidthRatio    float64
+	BottomMaxWidthRatio float64
+
+	// Поля холста под интерфейсом платформы, куда не попадут подписи
+	// (см. LookupSafeArea); нулевое - без полей
+	SafeArea SafeArea
+
+	// Настройки входного изображения
+	AutoOrient      bool // Поворачивать фото по тегу EXIF Orientation (для GenerateFrom)
+	ColorManagement bool // Переводить фото со встроенным ICC-профилем (Display P3, Adobe RGB) в sRGB
+
+	// Эффекты над фото по порядку до расчета геометрии (см. RegisterFilter)
+	Filters []FilterSpec
+
+	// Ограничения для недоверенных входных данных (0 - без ограничения)
+	MaxPixels int   // максимум пикселей исходного изображения (ширина*высота)
+	MaxBytes  int64 // максимальный размер входного файла для GenerateFrom
+
+	MaxTextLength int // максимум символов в одной подписи
+
+	// Производительность
+	ParallelRender bool // Делить заливку и вставку фото на полосы по числу ядер (для больших холстов)
+
+	// Воспроизводимость
+	Deterministic bool  // Одинаковый результат байт в байт при одинаковом входе (для кеша и эталонных тестов)
+	Seed          int64 // Зерно случайных эффектов при Deterministic
+
+	// Отладка
+	Debug bool // Рисовать поверх результата направляющие разметки (рамки подписей, базовые линии, поля)
 }
 
 // DefaultConfig возвращает конфигурацию по умолчанию
@@ -57,51 +119,208 @@ func DefaultConfig() *Config {
 		TextOutlineWidth: 0,
 		TextUppercase:    true,
 		AutoFontSize:     true,
+		AutoOrient:       true,
+		ColorManagement:  true,
+		MaxPixels:        DefaultMaxPixels,
+		MaxBytes:         DefaultMaxBytes,
+		MaxTextLength:    DefaultMaxTextLength,
 	}
 }
 
 // Generator - генератор мемов
 type Generator struct {
 	config *Config
-	// Кеш загруженных шрифтов: путь -> *opentype.Font
-	fontCache   map[string]*opentype.Font
-	fontCacheMu sync.RWMutex
+	fonts  *fontCache // общий с производными генераторами и Clone
+
+	cache        Cache           // кеш результатов GenerateBytes (nil - без кеша)
+	instrumenter Instrumenter    // сбор измерений по этапам (nil - без измерений)
+	metrics      Metrics         // метрики для мониторинга (nil - без метрик)
+	logger       *slog.Logger    // журнал отладки (nil - без журнала)
+	hooks        []hookEntry     // см. AddHook
+	text         TextRenderer    // отрисовка подписей (nil - DrawerTextRenderer)
+	frame        FrameRenderer   // фон и рамка (nil - BorderFrameRenderer)
+	translator   Translator      // перевод подписей (nil - NopTranslator)
+	detector     CaptionDetector // поиск старых подписей (nil - HeuristicCaptionDetector)
 }
 
-// NewGenerator создает новый генератор с конфигурацией
+// NewGenerator создает новый генератор с копией конфигурации config (с
+// подставленной темой): последующие изменения config на генератор не
+// влияют.
 func NewGenerator(config *Config) *Generator {
 	if config == nil {
 		config = DefaultConfig()
 	}
 	return &Generator{
-		config:    config,
-		fontCache: make(map[string]*opentype.Font),
+		config: cloneConfig(withTheme(config)),
+		fonts:  newFontCache(),
+	}
+}
+
+// derive создает генератор с другой конфигурацией, сохраняя подключенные
+// журнал, метрики и инструментацию и общий кеш шрифтов
+func (g *Generator) derive(cfg *Config) *Generator {
+	return &Generator{
+		config:       cfg,
+		fonts:        g.fonts,
+		cache:        g.cache,
+		instrumenter: g.instrumenter,
+		metrics:      g.metrics,
+		logger:       g.logger,
+		hooks:        slices.Clone(g.hooks),
+		text:         g.text,
+		frame:        g.frame,
+		translator:   g.translator,
+		detector:     g.detector,
 	}
 }
 
-// Config возвращает текущую конфигурацию (можно изменять)
+// Config возвращает копию конфигурации генератора с подставленной темой.
+// Изменения копии на генератор не влияют: генератор с другой
+// конфигурацией создает WithConfig, а подписи отдельного вызова
+// передаются через GenerateContent.
+//
+// Раньше Config возвращал саму конфигурацию, и код вида
+// g.Config().TopText = "..." менял генератор. Такой код по-прежнему
+// компилируется, но больше ничего не делает (см. CHANGELOG.md).
 func (g *Generator) Config() *Config {
-	return g.config
+	return cloneConfig(g.config)
 }
 
 // Generate создает демотиватор из изображения
 func (g *Generator) Generate(img image.Image) (*image.RGBA, error) {
+	return g.GenerateInto(nil, img)
+}
+
+// GenerateInto создает демотиватор, переиспользуя буфер пикселей dst.
+// Если dst равен nil или ёмкости dst.Pix не хватает, выделяется новый буфер.
+// Возвращаемое изображение имеет точный размер результата и при
+// переиспользовании разделяет память с dst, поэтому dst нельзя
+// использовать повторно, пока нужен результат.
+func (g *Generator) GenerateInto(dst *image.RGBA, img image.Image) (out *image.RGBA, err error) {
+	defer g.observeGeneration(time.Now(), &err)
+	return g.generateInto(dst, img)
+}
+
+// GenerateContext - Generate с отменой через ctx: генерация прерывается
+// между этапами (фильтры, раскладка, отрисовка) и возвращает ошибку ctx
+func (g *Generator) GenerateContext(ctx context.Context, img image.Image) (out *image.RGBA, err error) {
+	defer g.observeGeneration(time.Now(), &err)
+	return g.generateContext(ctx, nil, img)
+}
+
+// generateInto - GenerateInto без учёта в метриках.
+// Паника в любом этапе возвращается как *PanicError.
+func (g *Generator) generateInto(dst *image.RGBA, img image.Image) (*image.RGBA, error) {
+	return g.generateContext(context.Background(), dst, img)
+}
+
+// generateContext - generateInto, который проверяет ctx между этапами
+func (g *Generator) generateContext(ctx context.Context, dst *image.RGBA, img image.Image) (_ *image.RGBA, err error) {
+	defer recoverPanic(&err)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := g.validateInput(img); err != nil {
+		return nil, err
+	}
+	if img, err = g.beforeLayout(ctx, img); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	done := g.stage(StageLayout)
+	l := g.layout(img)
+	done()
+	if err := checkCanvas(l.canvas); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	out := reuseRGBA(dst, l.canvas)
+	if err := g.render(out, img, l); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// OutputBounds возвращает размер результата Generate для img,
+// например чтобы заранее подготовить буфер для GenerateInto
+func (g *Generator) OutputBounds(img image.Image) (image.Rectangle, error) {
+	if err := g.validateInput(img); err != nil {
+		return image.Rectangle{}, err
+	}
+	img, err := g.beforeLayout(context.Background(), img)
+	if err != nil {
+		return image.Rectangle{}, err
+	}
+	return g.layout(img).canvas, nil
+}
+
+// reuseRGBA возвращает изображение размера r поверх памяти dst, если её хватает.
+// Содержимое не очищается: render целиком заливает холст фоном.
+func reuseRGBA(dst *image.RGBA, r image.Rectangle) *image.RGBA {
+	n := 4 * r.Dx() * r.Dy()
+	if dst == nil || cap(dst.Pix) < n {
+		return image.NewRGBA(r)
+	}
+	return &image.RGBA{Pix: dst.Pix[:n], Stride: 4 * r.Dx(), Rect: r}
+}
+
+// Generate64 создает демотиватор с 16 битами на канал.
+// Полезно для 16-битных исходников: цвета не огрубляются до 8 бит
+// до этапа кодирования.
+func (g *Generator) Generate64(img image.Image) (out *image.NRGBA64, err error) {
+	defer g.observeGeneration(time.Now(), &err)
+	return g.generate64(img)
+}
+
+func (g *Generator) generate64(img image.Image) (*image.NRGBA64, error) {
+	out, err := g.generateCanvas(img, func(r image.Rectangle) draw.Image { return image.NewNRGBA64(r) })
+	if err != nil {
+		return nil, err
+	}
+	return out.(*image.NRGBA64), nil
+}
+
+// layout содержит рассчитанную геометрию демотиватора
+type layout struct {
+	topText    string
+	bottomText string
+	fontSize   float64
+
+	// Строки подписей после переноса и шаг между ними
+	topLines    []string
+	bottomLines []string
+	lineStep    int
+
+	canvas image.Rectangle // размер результата
+	photo  image.Rectangle // область исходного изображения на холсте
+	safe   image.Rectangle // область холста вне Config.SafeArea
+
+	// Горизонтальный отрезок, по центру которого стоят подписи
+	textLeft, textRight int
+
+	topBaseline    int
+	bottomBaseline int
+}
+
+// layout рассчитывает размеры холста и позиции элементов
+func (g *Generator) layout(img image.Image) layout {
 	cfg := g.config
+	var l layout
 
 	// Применяем преобразование регистра если нужно
-	topText := cfg.TopText
-	bottomText := cfg.BottomText
-	if cfg.TextUppercase {
-		topText = toUpperSafe(topText)
-		bottomText = toUpperSafe(bottomText)
-	}
+	l.topText = clampCombining(cfg.TopCase.resolve(cfg.TextUppercase).Apply(cfg.TopText))
+	l.bottomText = clampCombining(cfg.BottomCase.resolve(cfg.TextUppercase).Apply(cfg.BottomText))
 
 	srcBounds := img.Bounds()
 	imgWidth := srcBounds.Dx()
 	imgHeight := srcBounds.Dy()
 
 	// Автоматически подбираем размер шрифта если включено
-	fontSize := cfg.FontSize
+	l.fontSize = cfg.FontSize
 	if cfg.AutoFontSize {
 		// Базовый размер + корректировка под ширину
 		baseSize := 48.0
@@ -111,174 +330,212 @@ func (g *Generator) Generate(img image.Image) (*image.RGBA, error) {
 		} else if scaleFactor > 2.0 {
 			scaleFactor = 2.0
 		}
-		fontSize = baseSize * scaleFactor
+		l.fontSize = baseSize * scaleFactor
+		g.debug("auto font size", "width", imgWidth, "scale", scaleFactor, "font_size", l.fontSize)
 	}
 
-	// Рассчитываем размеры результата
+	resultWidth := imgWidth + cfg.Padding*2
+	l.lineStep = int(l.fontSize * 1.2)
+	l.topLines, l.bottomLines = g.wrapCaptions(l, resultWidth)
+
+	// Рассчитываем размеры результата; каждая строка переноса добавляет
+	// межстрочный шаг
 	textHeight := 0
-	if topText != "" {
-		textHeight += int(fontSize * 1.5)
+	if l.topText != "" {
+		textHeight += int(l.fontSize*1.5) + (len(l.topLines)-1)*l.lineStep
 	}
-	if bottomText != "" {
-		textHeight += int(fontSize * 1.5)
+	if l.bottomText != "" {
+		textHeight += int(l.fontSize*1.5) + (len(l.bottomLines)-1)*l.lineStep
 	}
 
-	resultWidth := imgWidth + cfg.Padding*2
 	resultHeight := imgHeight + cfg.Padding*2 + textHeight
+	l.photo = image.Rect(cfg.Padding, cfg.Padding, cfg.Padding+imgWidth, cfg.Padding+imgHeight)
+
+	// Позиционируем текст
+	currentY := cfg.Padding + imgHeight + int(l.fontSize*0.8) + 40
+	if l.topText != "" {
+		l.topBaseline = currentY
+		currentY += len(l.topLines) * l.lineStep
+	}
+	if l.bottomText != "" {
+		l.bottomBaseline = currentY
+		currentY += len(l.bottomLines) * l.lineStep
+	}
 
-	// Создаем изображение для результата
-	out := image.NewRGBA(image.Rect(0, 0, resultWidth, resultHeight))
+	// Нижние строки не должны попасть в нижнее поле SafeArea: холст
+	// удлиняется так, чтобы поле началось ниже свисающих элементов букв
+	if safe := cfg.SafeArea; safe.Bottom > 0 && textHeight > 0 {
+		textBottom := currentY - l.lineStep + int(l.fontSize*0.3)
+		resultHeight = max(resultHeight, int(math.Ceil(float64(textBottom)/(1-safe.Bottom))))
+	}
+	l.canvas = image.Rect(0, 0, resultWidth, resultHeight)
+	l.safe = cfg.SafeArea.rect(l.canvas)
+	l.textLeft, l.textRight = cfg.SafeArea.span(resultWidth)
 
-	// Заливаем фон
-	draw.Draw(out, out.Bounds(), &image.Uniform{cfg.BackgroundColor}, image.Point{}, draw.Src)
+	return l
+}
 
-	// Рисуем рамку
-	for i := 0; i < cfg.Border; i++ {
-		rect := image.Rect(
-			cfg.Padding-cfg.Border+i,
-			cfg.Padding-cfg.Border+i,
-			cfg.Padding+imgWidth+cfg.Border-i,
-			cfg.Padding+imgHeight+cfg.Border-i,
-		)
-		draw.Draw(out, rect, &image.Uniform{cfg.BorderColor}, image.Point{}, draw.Src)
+// wrapCaptions переносит подписи по TopMaxWidthRatio и BottomMaxWidthRatio
+// холста шириной width и по ширине между боковыми полями SafeArea. Если
+// шрифт не загружается, подписи остаются в одну строку: ту же ошибку
+// вернет render.
+func (g *Generator) wrapCaptions(l layout, width int) (top, bottom []string) {
+	cfg := g.config
+	top, bottom = []string{l.topText}, []string{l.bottomText}
+	sides := cfg.SafeArea.sides()
+	if cfg.TopMaxWidthRatio <= 0 && cfg.BottomMaxWidthRatio <= 0 && !sides {
+		return top, bottom
+	}
+	face, err := g.loadFont(l.fontSize)
+	if err != nil {
+		return top, bottom
+	}
+	defer face.Close()
+	tr := g.textRenderer()
+	limit := func(ratio float64) int {
+		w := width
+		if ratio > 0 {
+			w = int(float64(width) * ratio)
+		}
+		if sides {
+			left, right := cfg.SafeArea.span(width)
+			w = min(w, right-left)
+		}
+		return w
+	}
+	if (cfg.TopMaxWidthRatio > 0 || sides) && l.topText != "" {
+		top = wrapText(tr, face, l.topText, limit(cfg.TopMaxWidthRatio))
+	}
+	if (cfg.BottomMaxWidthRatio > 0 || sides) && l.bottomText != "" {
+		bottom = wrapText(tr, face, l.bottomText, limit(cfg.BottomMaxWidthRatio))
 	}
+	return top, bottom
+}
+
+// render рисует демотиватор на подготовленном холсте размера l.canvas
+func (g *Generator) render(out draw.Image, img image.Image, l layout) error {
+	cfg := g.config
 
-	// Вставляем оригинальное изображение
-	draw.Draw(
-		out,
-		image.Rect(cfg.Padding, cfg.Padding, cfg.Padding+imgWidth, cfg.Padding+imgHeight),
-		img,
-		srcBounds.Min,
-		draw.Over,
-	)
+	// Фон, рамка и фото не перекрываются между полосами холста,
+	// поэтому их можно рисовать независимо для каждой полосы
+	frame, exported := g.frameRenderer(), l.exported()
+	composite := func(band image.Rectangle) {
+		// Фон и рамка
+		frame.Draw(out, band, exported, cfg)
+
+		// Вставляем оригинальное изображение
+		photo := l.photo.Intersect(band)
+		drawSource(out, photo, img, img.Bounds().Min.Add(photo.Min.Sub(l.photo.Min)))
+	}
+	done := g.stage(StageComposite)
+	if cfg.ParallelRender {
+		forEachBand(out.Bounds(), composite)
+	} else {
+		composite(out.Bounds())
+	}
+	done()
+	var dc *DrawContext
+	if len(g.hooks) > 0 {
+		dc = &DrawContext{Hook: HookAfterCompose, Config: cfg, Source: img, Canvas: out, Layout: exported}
+		if err := g.runHooks(dc); err != nil {
+			return err
+		}
+	}
 
 	// Загружаем шрифт с указанным размером
-	fontFace, err := g.loadFont(fontSize)
+	done = g.stage(StageFontLoad)
+	fontFace, err := g.loadFont(l.fontSize)
+	done()
 	if err != nil {
-		return nil, fmt.Errorf("не удалось загрузить шрифт: %w", err)
+		return fmt.Errorf("loading font: %w", err)
 	}
 	defer fontFace.Close()
 
-	// Позиционируем текст
-	currentY := cfg.Padding + imgHeight + int(fontSize*0.8) + 40
+	defer g.stage(StageOutline)()
 
 	// Добавляем верхний текст
-	if topText != "" {
-		g.drawCenteredText(out, fontFace, topText, currentY)
-		currentY += int(fontSize * 1.2)
+	if l.topText != "" {
+		for i, line := range l.topLines {
+			g.drawCenteredText(out, fontFace, line, l.textLeft, l.textRight, l.topBaseline+i*l.lineStep)
+		}
 	}
 
 	// Добавляем нижний текст
-	if bottomText != "" {
-		g.drawCenteredText(out, fontFace, bottomText, currentY)
+	if l.bottomText != "" {
+		for i, line := range l.bottomLines {
+			g.drawCenteredText(out, fontFace, line, l.textLeft, l.textRight, l.bottomBaseline+i*l.lineStep)
+		}
 	}
 
-	return out, nil
+	if cfg.Debug {
+		g.drawDebug(out, fontFace, l)
+	}
+
+	if dc != nil {
+		dc.Hook = HookBeforeEncode
+		return g.runHooks(dc)
+	}
+	return nil
 }
 
 // loadFont загружает шрифт в зависимости от конфигурации
 func (g *Generator) loadFont(size float64) (font.Face, error) {
-	cfg := g.config
+	return g.loadFontFrom(g.config.FontPath, g.config.FontData, size)
+}
 
-	var fontBytes []byte
+// loadFontFrom загружает шрифт из данных fontData или файла fontPath;
+// если не задано ни то, ни другое - встроенный Go Bold. Разобранные шрифты
+// кешируются: файлы - по пути, данные - по содержимому (см. fontDataKey),
+// поэтому одинаковый FontData в каждом запросе разбирается один раз.
+func (g *Generator) loadFontFrom(fontPath string, fontData []byte, size float64) (font.Face, error) {
 	var cacheKey string
-
-	// Определяем источник данных шрифта
 	switch {
-	case len(cfg.FontData) > 0:
-		fontBytes = cfg.FontData
-		cacheKey = "embedded_font_data"
-
-	case cfg.FontPath != "":
-		// Загружаем из файла с кешированием
-		cacheKey = cfg.FontPath
-
-		// Проверяем кеш
-		g.fontCacheMu.RLock()
-		cachedFont, ok := g.fontCache[cacheKey]
-		g.fontCacheMu.RUnlock()
-
-		if ok && cachedFont != nil {
-			// Используем кешированный шрифт
-			face, err := opentype.NewFace(cachedFont, &opentype.FaceOptions{
-				Size:    size,
-				DPI:     72,
-				Hinting: font.HintingFull,
-			})
-			if err != nil {
-				return nil, fmt.Errorf("ошибка создания face из кеша: %w", err)
+	case len(fontData) > 0:
+		cacheKey = fontDataKey(fontData)
+	case fontPath != "":
+		cacheKey = fontFileKey(fontPath)
+	default:
+		g.debug("no font configured, using embedded Go Bold")
+		fontData = gobold.TTF
+		cacheKey = fontDataKey(fontData)
+	}
+
+	parsedFont, ok := g.fonts.get(cacheKey)
+	g.observeFontCache(ok)
+	if !ok {
+		if len(fontData) == 0 {
+			g.debug("font cache miss, loading from disk", "path", fontPath)
+			var err error
+			if fontData, err = g.loadFontFromFile(fontPath); err != nil {
+				return nil, err
 			}
-			return face, nil
 		}
-
-		// Загружаем из файла
 		var err error
-		fontBytes, err = g.loadFontFromFile(cfg.FontPath)
-		if err != nil {
-			return nil, err
+		if parsedFont, err = opentype.Parse(fontData); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidFont, err)
 		}
-
-	default:
-		// Используем встроенный жирный шрифт Go по умолчанию
-		fontBytes = gobold.TTF
-		cacheKey = "gobold_embedded"
+		g.fonts.put(cacheKey, parsedFont)
 	}
 
-	// Парсим шрифт
-	parsedFont, err := opentype.Parse(fontBytes)
+	face, err := newFace(parsedFont, size)
 	if err != nil {
-		return nil, fmt.Errorf("ошибка парсинга шрифта: %w", err)
+		return nil, fmt.Errorf("creating font face: %w", err)
 	}
-
-	// Кешируем если это файловый шрифт
-	if cfg.FontPath != "" {
-		g.fontCacheMu.Lock()
-		g.fontCache[cacheKey] = parsedFont
-		g.fontCacheMu.Unlock()
-	}
-
-	// Создаем face
-	face, err := opentype.NewFace(parsedFont, &opentype.FaceOptions{
-		Size:    size,
-		DPI:     72,
-		Hinting: font.HintingFull,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("ошибка создания font face: %w", err)
-	}
-
 	return face, nil
 }
 
 // loadFontFromFile загружает шрифт из файла с валидацией
 func (g *Generator) loadFontFromFile(path string) ([]byte, error) {
-	// Проверяем существование файла
-	fileInfo, err := os.Stat(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("файл шрифта не найден: %s", path)
-		}
-		return nil, fmt.Errorf("ошибка доступа к файлу шрифта: %w", err)
-	}
-
-	// Проверяем размер файла (не должен быть слишком большим или маленьким)
-	if fileInfo.Size() == 0 {
-		return nil, errors.New("файл шрифта пустой")
-	}
-	if fileInfo.Size() > 10*1024*1024 { // 10MB максимум
-		return nil, fmt.Errorf("файл шрифта слишком большой: %d байт", fileInfo.Size())
-	}
-
-	// Читаем файл
-	data, err := os.ReadFile(path)
+	// Чтение файла и проверки размера зависят от платформы (см. font_file.go)
+	data, err := readFontFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("ошибка чтения файла шрифта: %w", err)
+		return nil, err
 	}
 
 	// Базовая валидация что это TTF/OTF файл
 	// TTF/OTF файлы начинаются с определённых сигнатур
 	if len(data) < 4 {
-		return nil, errors.New("файл слишком маленький для шрифта")
+		return nil, fmt.Errorf("%w: file is too small to be a font", ErrInvalidFont)
 	}
 
 	// Проверяем сигнатуры TTF/OTF
@@ -295,8 +552,25 @@ func (g *Generator) loadFontFromFile(path string) ([]byte, error) {
 	return data, nil
 }
 
-// LoadFontFile загружает шрифт из файла и устанавливает его в конфигурацию
-// Удобный метод для динамической загрузки шрифтов
+// WithFont возвращает генератор со шрифтом из файла path. Как и
+// WithConfig, он делит с g кеш шрифтов, хуки и рендереры, а сам g не
+// меняется, поэтому вызов безопасен во время генерации на g.
+func (g *Generator) WithFont(path string) (*Generator, error) {
+	data, err := g.loadFontFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := cloneConfig(g.config)
+	cfg.FontData = data
+	cfg.FontPath = path // сохраняем путь для кеширования
+	return g.derive(cfg), nil
+}
+
+// LoadFontFile загружает шрифт из файла и устанавливает его в конфигурацию g.
+//
+// Deprecated: LoadFontFile меняет конфигурацию на месте и гоняется с
+// одновременными Generate и GenerateContent на том же генераторе.
+// Используйте WithFont.
 func (g *Generator) LoadFontFile(path string) error {
 	data, err := g.loadFontFromFile(path)
 	if err != nil {
@@ -309,11 +583,10 @@ func (g *Generator) LoadFontFile(path string) error {
 	return nil
 }
 
-// ClearFontCache очищает кеш шрифтов
+// ClearFontCache очищает кеш шрифтов, общий для генератора и его копий
+// из Clone
 func (g *Generator) ClearFontCache() {
-	g.fontCacheMu.Lock()
-	g.fontCache = make(map[string]*opentype.Font)
-	g.fontCacheMu.Unlock()
+	g.fonts.reset()
 }
 
 // PreloadFont предзагружает шрифт в кеш
@@ -325,55 +598,47 @@ func (g *Generator) PreloadFont(path string) error {
 
 	parsedFont, err := opentype.Parse(data)
 	if err != nil {
-		return fmt.Errorf("ошибка парсинга предзагружаемого шрифта: %w", err)
+		return fmt.Errorf("%w: %w", ErrInvalidFont, err)
 	}
 
-	g.fontCacheMu.Lock()
-	g.fontCache[path] = parsedFont
-	g.fontCacheMu.Unlock()
+	g.fonts.put(fontFileKey(path), parsedFont)
 
 	return nil
 }
 
-// drawCenteredText рисует текст по центру
-func (g *Generator) drawCenteredText(img *image.RGBA, face font.Face, text string, y int) {
+// drawCenteredText рисует текст по центру отрезка от left до right
+func (g *Generator) drawCenteredText(img draw.Image, face font.Face, text string, left, right, y int) {
 	if text == "" {
 		return
 	}
 
 	cfg := g.config
 
-	// Создаем drawer для измерения
-	d := &font.Drawer{
-		Dst:  img,
-		Face: face,
+	// Измеряем ширину текста
+	r := g.textRenderer()
+	textWidth := r.Measure(face, text)
+	dot := fixed.P(left+(right-left-textWidth.Ceil())/2, y)
+	if cfg.SubpixelText {
+		// Без округления до пикселя: подписи разной ширины не "дрожат"
+		dot.X = fixed.I(left) + (fixed.I(right-left)-textWidth)/2
+	}
+	if dot.X < 0 {
+		g.debug("caption is wider than the canvas and will be clipped", "text", text, "text_width", textWidth.Ceil(), "canvas_width", img.Bounds().Dx())
 	}
 
-	// Измеряем ширину текста
-	textWidth := d.MeasureString(text).Ceil()
-	x := (img.Bounds().Dx() - textWidth) / 2
-
-	// Рисуем обводку если нужно
-	if cfg.TextOutlineWidth > 0 {
-		d.Src = image.NewUniform(cfg.TextOutlineColor)
-
-		// Рисуем обводку по кругу
-		for dx := -cfg.TextOutlineWidth; dx <= cfg.TextOutlineWidth; dx++ {
-			for dy := -cfg.TextOutlineWidth; dy <= cfg.TextOutlineWidth; dy++ {
-				// Пропускаем центр чтобы не перекрывать
-				if dx == 0 && dy == 0 {
-					continue
-				}
-				d.Dot = fixed.P(x+dx, y+dy)
-				d.DrawString(text)
-			}
+	style := TextStyle{Color: cfg.TextColor, OutlineColor: cfg.TextOutlineColor, OutlineWidth: max(cfg.TextOutlineWidth, 0), Extrude: cfg.TextExtrude, Glow: cfg.TextGlow, Wave: cfg.TextWave, Linear: cfg.LinearBlending}
+	if cfg.TextJitter.active() {
+		// Строки различаются базовой линией, иначе в режиме Deterministic
+		// верхняя и нижняя подписи дрожали бы одинаково
+		style.Jitter, style.JitterSeed = cfg.TextJitter, g.newRand().Uint64()^uint64(y)
+	}
+	if cfg.TextHollow {
+		style.Hollow = true
+		if style.OutlineWidth == 0 {
+			style.OutlineWidth = max(face.Metrics().Ascent.Ceil()/16, 1)
 		}
 	}
-
-	// Рисуем основной текст
-	d.Src = image.NewUniform(cfg.TextColor)
-	d.Dot = fixed.P(x, y)
-	d.DrawString(text)
+	r.Draw(img, face, text, dot, style)
 }
 
 // Helper function for safe uppercase conversion
@@ -404,29 +669,32 @@ func GenerateWithCustomFont(img image.Image, topText, bottomText, fontPath strin
 
 // ValidateFontFile проверяет что файл является валидным шрифтом
 func ValidateFontFile(path string) error {
-	// Простая проверка существования и чтения
-	data, err := os.ReadFile(path)
+	data, err := readFontFile(path)
 	if err != nil {
-		return fmt.Errorf("ошибка чтения файла: %w", err)
+		return err
 	}
 
 	if len(data) < 4 {
-		return errors.New("файл слишком мал для шрифта")
+		return fmt.Errorf("%w: file is too small to be a font", ErrInvalidFont)
 	}
 
 	// Пытаемся распарсить чтобы убедиться что это валидный шрифт
 	_, err = opentype.Parse(data)
 	if err != nil {
-		return fmt.Errorf("невалидный файл шрифта: %w", err)
+		return fmt.Errorf("%w: %w", ErrInvalidFont, err)
 	}
 
 	return nil
 }
 
+// builtinFonts - встроенные шрифты по именам
+var builtinFonts = map[string][]byte{
+	"regular":   goregular.TTF,
+	"bold":      gobold.TTF,
+	"smallcaps": gosmallcaps.TTF,
+}
+
 // GetAvailableFonts возвращает список доступных встроенных шрифтов
 func GetAvailableFonts() map[string][]byte {
-	return map[string][]byte{
-		"regular": goregular.TTF,
-		"bold":    gobold.TTF,
-	}
+	return maps.Clone(builtinFonts)
 }
@@ -0,0 +1,302 @@
+// Package mcpmeme предоставляет генератор как инструмент Model Context
+// Protocol, чтобы LLM-агенты могли создавать мемы по запросу.
+//
+//	srv := mcpmeme.New(mcpmeme.Options{})
+//	err := srv.Serve(ctx, os.Stdin, os.Stdout)
+//
+// Транспорт - stdio: JSON-RPC 2.0, по одному сообщению в строке. Сервер
+// объявляет инструмент generate_meme; аргументы проверяются по его
+// JSON Schema до генерации, ошибки проверки перечисляют все неверные поля.
+// Фото передается ссылкой (загружается через meme.FetchImage) или в base64.
+package mcpmeme
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/go-goblin/meme"
+)
+
+// Значения Options по умолчанию
+const (
+	DefaultMaxBytes = 20 << 20
+
+	// ProtocolVersion - версия MCP, которую сервер предлагает по умолчанию
+	ProtocolVersion = "2025-06-18"
+
+	maxMessageBytes = 64 << 20 // base64 увеличивает фото на треть
+)
+
+// Поддерживаемые версии протокола: клиент получает запрошенную, если она
+// есть в списке
+var protocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// Options задаёт поведение сервера
+type Options struct {
+	// Config - базовая конфигурация; подписи и оформление берутся из
+	// аргументов инструмента. nil - meme.DefaultConfig().
+	Config *meme.Config
+
+	// Setup вызывается для генератора каждого вызова
+	Setup func(*meme.Generator)
+
+	MaxBytes int64 // предел размера фото, 0 - 20 МиБ
+
+	// Fetch загружает фото по image_url. nil - meme.FetchImage с пределом
+	// MaxBytes и защитой от SSRF. Вызывающий закрывает результат.
+	Fetch func(ctx context.Context, url string) (io.ReadCloser, error)
+
+	Name    string // имя сервера в ответе initialize, пусто - "meme"
+	Version string // версия сервера, пусто - "dev"
+}
+
+// Server обслуживает одну сессию MCP
+type Server struct {
+	opts Options
+	tool tool
+}
+
+// New создает сервер с настройками opts
+func New(opts Options) *Server {
+	if opts.Config == nil {
+		opts.Config = meme.DefaultConfig()
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultMaxBytes
+	}
+	if opts.Fetch == nil {
+		fo := &meme.FetchOptions{MaxBytes: opts.MaxBytes}
+		opts.Fetch = func(ctx context.Context, url string) (io.ReadCloser, error) {
+			return meme.FetchImage(ctx, url, fo)
+		}
+	}
+	if opts.Name == "" {
+		opts.Name = "meme"
+	}
+	if opts.Version == "" {
+		opts.Version = "dev"
+	}
+	return &Server{opts: opts, tool: generateTool(opts.Config.MaxTextLength)}
+}
+
+// Коды ошибок JSON-RPC
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// request - запрос или уведомление JSON-RPC (уведомление без id)
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+func (e *rpcError) Error() string { return e.Message }
+
+// Serve читает запросы из r и пишет ответы в w до конца ввода или отмены
+// ctx. Вызовы инструмента выполняются параллельно, ответы пишутся по мере
+// готовности.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		mu sync.Mutex // один ответ - одна строка, без перемешивания
+		wg sync.WaitGroup
+	)
+	defer wg.Wait()
+	send := func(resp response) {
+		data, err := json.Marshal(resp)
+		if err != nil {
+			data, _ = json.Marshal(response{JSONRPC: "2.0", ID: resp.ID, Error: &rpcError{Code: codeInvalidRequest, Message: err.Error()}})
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		w.Write(append(data, '\n'))
+	}
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64<<10), maxMessageBytes)
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var req request
+		if err := json.Unmarshal(line, &req); err != nil || req.JSONRPC != "2.0" || req.Method == "" {
+			code, msg := codeInvalidRequest, "invalid request"
+			if err != nil {
+				code, msg = codeParseError, "parse error: "+err.Error()
+			}
+			send(response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: code, Message: msg}})
+			continue
+		}
+		if len(req.ID) == 0 {
+			// Уведомления (notifications/initialized и т.п.) ответа не требуют
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := s.handle(ctx, &req)
+			resp := response{JSONRPC: "2.0", ID: req.ID, Result: result}
+			if err != nil {
+				var re *rpcError
+				if !errors.As(err, &re) {
+					re = &rpcError{Code: codeInvalidRequest, Message: err.Error()}
+				}
+				resp.Result, resp.Error = nil, re
+			}
+			send(resp)
+		}()
+	}
+	return sc.Err()
+}
+
+// handle выполняет один метод
+func (s *Server) handle(ctx context.Context, req *request) (any, error) {
+	switch req.Method {
+	case "initialize":
+		var p struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		json.Unmarshal(req.Params, &p)
+		version := ProtocolVersion
+		for _, v := range protocolVersions {
+			if v == p.ProtocolVersion {
+				version = v
+			}
+		}
+		return map[string]any{
+			"protocolVersion": version,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]string{"name": s.opts.Name, "version": s.opts.Version},
+		}, nil
+	case "ping":
+		return struct{}{}, nil
+	case "tools/list":
+		return map[string]any{"tools": []tool{s.tool}}, nil
+	case "tools/call":
+		var p struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: "invalid params: " + err.Error()}
+		}
+		if p.Name != s.tool.Name {
+			return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("unknown tool %q", p.Name)}
+		}
+		return s.call(ctx, p.Arguments)
+	}
+	return nil, &rpcError{Code: codeMethodNotFound, Message: fmt.Sprintf("method %q not found", req.Method)}
+}
+
+// content - элемент результата инструмента
+type content struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	Data     string `json:"data,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+}
+
+type callResult struct {
+	Content []content `json:"content"`
+	IsError bool      `json:"isError,omitempty"`
+}
+
+// call проверяет аргументы и создает мем. Ошибки генерации возвращаются
+// результатом с isError, чтобы агент увидел их и мог исправить запрос.
+func (s *Server) call(ctx context.Context, raw json.RawMessage) (any, error) {
+	var args map[string]any
+	if len(raw) > 0 && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &args); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: "arguments must be an object"}
+		}
+	}
+	if problems := s.tool.InputSchema.Validate(args); len(problems) > 0 {
+		msgs := make([]string, len(problems))
+		for i, p := range problems {
+			msgs[i] = p.String()
+		}
+		return nil, &rpcError{Code: codeInvalidParams, Message: "invalid arguments", Data: map[string]any{"errors": msgs}}
+	}
+	var a arguments
+	if err := json.Unmarshal(raw, &a); err != nil {
+		return nil, &rpcError{Code: codeInvalidParams, Message: "invalid arguments: " + err.Error()}
+	}
+
+	data, mimeType, err := s.generate(ctx, &a)
+	if err != nil {
+		return callResult{IsError: true, Content: []content{{Type: "text", Text: fmt.Sprintf("%s: %v", meme.ErrorReason(err), err)}}}, nil
+	}
+	return callResult{Content: []content{
+		{Type: "image", Data: base64.StdEncoding.EncodeToString(data), MimeType: mimeType},
+	}}, nil
+}
+
+// generate загружает фото и создает мем
+func (s *Server) generate(ctx context.Context, a *arguments) ([]byte, string, error) {
+	cfg := *s.opts.Config
+	// Тема меняет оформление, но не ограничения сервера
+	if err := cfg.ApplyOverride(meme.StyleOverride{Theme: a.Theme, Font: a.Font, Uppercase: a.Uppercase}); err != nil {
+		return nil, "", err
+	}
+	cfg.TopText, cfg.BottomText = a.Top, a.Bottom
+	if cfg.MaxBytes == 0 || cfg.MaxBytes > s.opts.MaxBytes {
+		cfg.MaxBytes = s.opts.MaxBytes
+	}
+
+	var in io.Reader
+	if a.ImageURL != "" {
+		body, err := s.opts.Fetch(ctx, a.ImageURL)
+		if err != nil {
+			return nil, "", fmt.Errorf("fetching image: %w", err)
+		}
+		defer body.Close()
+		in = body
+	} else {
+		if int64(base64.StdEncoding.DecodedLen(len(a.ImageBase64))) > s.opts.MaxBytes+2 {
+			return nil, "", fmt.Errorf("%w: limit is %d bytes", meme.ErrInputTooLarge, s.opts.MaxBytes)
+		}
+		data, err := base64.StdEncoding.DecodeString(a.ImageBase64)
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: image_base64: %v", meme.ErrUnknownFormat, err)
+		}
+		in = bytes.NewReader(data)
+	}
+
+	format := meme.FormatPNG
+	if a.Format != "" {
+		format = meme.Format(a.Format)
+	}
+	g := meme.NewGenerator(&cfg)
+	if s.opts.Setup != nil {
+		s.opts.Setup(g)
+	}
+	data, err := g.GenerateBytes(in, &meme.EncodeOptions{Format: format})
+	return data, meme.MIMEType(format), err
+}
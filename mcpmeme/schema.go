@@ -0,0 +1,57 @@
+package mcpmeme
+
+import (
+	"slices"
+
+	"github.com/go-goblin/meme"
+	"github.com/go-goblin/meme/internal/jsonschema"
+)
+
+// tool - описание инструмента в ответе tools/list
+type tool struct {
+	Name        string             `json:"name"`
+	Title       string             `json:"title,omitempty"`
+	Description string             `json:"description"`
+	InputSchema *jsonschema.Schema `json:"inputSchema"`
+}
+
+// arguments - аргументы generate_meme; по ним строится схема инструмента
+type arguments struct {
+	ImageURL    string `json:"image_url" doc:"Public http(s) URL of the source image."`
+	ImageBase64 string `json:"image_base64" doc:"Source image file (PNG, JPEG, GIF, WebP) encoded as base64."`
+	Top         string `json:"top" doc:"Main caption, large text under the image."`
+	Bottom      string `json:"bottom" doc:"Secondary caption, smaller text below the main one."`
+	Theme       string `json:"theme" doc:"Visual theme."`
+	Font        string `json:"font" doc:"Built-in font."`
+	Uppercase   bool   `json:"uppercase" doc:"Render captions in upper case."`
+	Format      string `json:"format" doc:"Output format, png by default."`
+}
+
+// generateTool описывает generate_meme; темы и шрифты перечисляются из
+// зарегистрированных, чтобы агент не угадывал имена
+func generateTool(maxTextLength int) tool {
+	fonts := make([]string, 0, len(meme.GetAvailableFonts()))
+	for name := range meme.GetAvailableFonts() {
+		fonts = append(fonts, name)
+	}
+	slices.Sort(fonts)
+	s := jsonschema.Object(arguments{})
+	s.Properties["top"].MaxLength = maxTextLength
+	s.Properties["bottom"].MaxLength = maxTextLength
+	s.Properties["theme"].Enum = meme.Themes()
+	s.Properties["font"].Enum = fonts
+	for _, f := range meme.Formats() {
+		s.Properties["format"].Enum = append(s.Properties["format"].Enum, string(f))
+	}
+	s.OneOf = []*jsonschema.Schema{
+		{Required: []string{"image_url"}},
+		{Required: []string{"image_base64"}},
+	}
+	return tool{
+		Name:  "generate_meme",
+		Title: "Generate meme",
+		Description: "Create a demotivator-style meme: the image framed with a border and " +
+			"captions below it. Returns the image. Pass the photo as image_url or image_base64.",
+		InputSchema: s,
+	}
+}
@@ -0,0 +1,173 @@
+// Package memetest помогает писать регрессионные тесты для стилей мемов:
+// сравнение с эталонными изображениями с допуском по пикселям, сохранение
+// карты отличий и обновление эталонов флагом -update.
+//
+//	func TestStyle(t *testing.T) {
+//		g := memetest.NewGenerator(cfg)
+//		out, err := g.Generate(src)
+//		if err != nil {
+//			t.Fatal(err)
+//		}
+//		memetest.AssertGolden(t, "style", out, memetest.Options{Tolerance: 2})
+//	}
+//
+// Эталоны хранятся в testdata/<name>.png; go test -update перезаписывает их.
+package memetest
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-goblin/meme"
+)
+
+var update = flag.Bool("update", false, "rewrite golden images in testdata")
+
+// GoldenDir - каталог эталонов относительно пакета с тестами
+var GoldenDir = "testdata"
+
+// NewGenerator создает копию конфигурации в режиме Deterministic,
+// чтобы результат не менялся от запуска к запуску
+func NewGenerator(cfg *meme.Config) *meme.Generator {
+	if cfg == nil {
+		cfg = meme.DefaultConfig()
+	}
+	c := *cfg
+	c.Deterministic = true
+	return meme.NewGenerator(&c)
+}
+
+// Options задаёт допуски сравнения
+type Options struct {
+	// Tolerance - допустимая разница каждого канала (0-255)
+	Tolerance uint8
+	// MaxDiffPixels - сколько пикселей может превышать допуск
+	MaxDiffPixels int
+}
+
+// Result - итог сравнения двух изображений
+type Result struct {
+	DiffPixels int         // число пикселей, отличающихся больше допуска
+	MaxDelta   uint8       // наибольшая разница каналов
+	Diff       *image.RGBA // карта отличий: эталон серым, отличия красным
+}
+
+// Compare сравнивает изображения попиксельно. Разные размеры считаются
+// отличием всех пикселей объединённой области.
+func Compare(got, want image.Image, tolerance uint8) Result {
+	gb, wb := got.Bounds(), want.Bounds()
+	size := image.Rect(0, 0, max(gb.Dx(), wb.Dx()), max(gb.Dy(), wb.Dy()))
+	res := Result{Diff: image.NewRGBA(size)}
+	sameSize := gb.Size() == wb.Size()
+
+	for y := 0; y < size.Dy(); y++ {
+		for x := 0; x < size.Dx(); x++ {
+			wp := image.Pt(wb.Min.X+x, wb.Min.Y+y)
+			gp := image.Pt(gb.Min.X+x, gb.Min.Y+y)
+			if !sameSize || !wp.In(wb) || !gp.In(gb) {
+				res.DiffPixels++
+				res.MaxDelta = 255
+				res.Diff.SetRGBA(x, y, color.RGBA{255, 0, 0, 255})
+				continue
+			}
+			wc := color.NRGBAModel.Convert(want.At(wp.X, wp.Y)).(color.NRGBA)
+			gc := color.NRGBAModel.Convert(got.At(gp.X, gp.Y)).(color.NRGBA)
+			delta := max(absDiff(wc.R, gc.R), absDiff(wc.G, gc.G), absDiff(wc.B, gc.B), absDiff(wc.A, gc.A))
+			res.MaxDelta = max(res.MaxDelta, delta)
+			if delta > tolerance {
+				res.DiffPixels++
+				// Яркость отметки растёт с величиной отличия
+				res.Diff.SetRGBA(x, y, color.RGBA{uint8(128 + int(delta)/2), 0, 0, 255})
+				continue
+			}
+			// Совпадающие пиксели - приглушённая яркость эталона для ориентира
+			lum := uint8((299*int(wc.R) + 587*int(wc.G) + 114*int(wc.B)) / 1000 / 4)
+			res.Diff.SetRGBA(x, y, color.RGBA{lum, lum, lum, 255})
+		}
+	}
+	return res
+}
+
+func absDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// AssertGolden сравнивает img с эталоном testdata/<name>.png.
+// С флагом -update эталон перезаписывается. При отличии карта отличий
+// сохраняется в testdata/<name>.diff.png, а тест помечается проваленным.
+func AssertGolden(t testing.TB, name string, img image.Image, opts Options) {
+	t.Helper()
+	path := filepath.Join(GoldenDir, name+".png")
+	diffPath := filepath.Join(GoldenDir, name+".diff.png")
+
+	if *update {
+		if err := writePNG(path, img); err != nil {
+			t.Fatalf("memetest: updating golden %s: %v", path, err)
+		}
+		_ = os.Remove(diffPath)
+		return
+	}
+
+	want, err := readPNG(path)
+	if err != nil {
+		t.Fatalf("memetest: reading golden %s: %v (run go test -update to create it)", path, err)
+	}
+
+	res := Compare(img, want, opts.Tolerance)
+	if res.DiffPixels <= opts.MaxDiffPixels {
+		_ = os.Remove(diffPath)
+		return
+	}
+	if err := writePNG(diffPath, res.Diff); err != nil {
+		t.Logf("memetest: writing diff %s: %v", diffPath, err)
+	}
+	t.Errorf("memetest: %s differs from golden: %d pixels over tolerance %d (max delta %d), diff saved to %s",
+		name, res.DiffPixels, opts.Tolerance, res.MaxDelta, diffPath)
+}
+
+func readPNG(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return png.Decode(f)
+}
+
+func writePNG(path string, img image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	// Эталон всегда сохраняем как NRGBA, чтобы не зависеть от типа входа
+	nrgba := image.NewNRGBA(img.Bounds())
+	draw.Draw(nrgba, nrgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	if err := png.Encode(f, nrgba); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Update сообщает, запущены ли тесты с флагом -update
+func Update() bool {
+	return *update
+}
+
+// String форматирует результат сравнения для сообщений тестов
+func (r Result) String() string {
+	return fmt.Sprintf("%d pixels differ, max delta %d", r.DiffPixels, r.MaxDelta)
+}
@@ -0,0 +1,296 @@
+package meme
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"hash/crc32"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Metadata - метаданные, встраиваемые в результат при кодировании.
+// Для JPEG пишутся EXIF и XMP, для PNG - текстовые чанки, для WebP - чанки EXIF и XMP.
+type Metadata struct {
+	Software    string
+	Author      string
+	Copyright   string
+	Description string // обычно текст подписи
+	Created     time.Time
+}
+
+// DefaultSoftware - значение тега Software по умолчанию
+const DefaultSoftware = "go-goblin/meme"
+
+// Metadata возвращает метаданные для текущей конфигурации:
+// текст подписей в качестве описания и текущее время создания.
+// В режиме Deterministic время не заполняется, чтобы файл не зависел от запуска.
+func (g *Generator) Metadata() *Metadata {
+	var captions []string
+	for _, text := range []string{g.config.TopText, g.config.BottomText} {
+		if text != "" {
+			captions = append(captions, text)
+		}
+	}
+	md := &Metadata{
+		Software:    DefaultSoftware,
+		Description: strings.Join(captions, "\n"),
+	}
+	if !g.config.Deterministic {
+		md.Created = time.Now()
+	}
+	return md
+}
+
+// embedMetadata встраивает метаданные в уже закодированный файл
+func embedMetadata(data []byte, format Format, md *Metadata) ([]byte, error) {
+	switch format {
+	case FormatPNG, "":
+		return embedPNGText(data, md)
+	case FormatJPEG:
+		return embedJPEGMetadata(data, md)
+	case FormatWebP:
+		return embedWebPMetadata(data, md)
+	}
+	return nil, errors.New("metadata is not supported for format " + string(format))
+}
+
+// exifDateTime форматирует время так, как требует EXIF
+func exifDateTime(t time.Time) string {
+	return t.Format("2006:01:02 15:04:05")
+}
+
+// buildExif собирает TIFF-блок EXIF с тегами IFD0
+func buildExif(md *Metadata) []byte {
+	type entry struct {
+		tag   uint16
+		value string
+	}
+	var entries []entry
+	add := func(tag uint16, v string) {
+		if v != "" {
+			entries = append(entries, entry{tag, v})
+		}
+	}
+	add(0x010e, md.Description)
+	add(0x0131, md.Software)
+	if !md.Created.IsZero() {
+		add(0x0132, exifDateTime(md.Created))
+	}
+	add(0x013b, md.Author)
+	add(0x8298, md.Copyright)
+	if len(entries) == 0 {
+		return nil
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].tag < entries[j].tag })
+
+	le := binary.LittleEndian
+	ifdSize := 2 + len(entries)*12 + 4
+	valueOffset := 8 + ifdSize
+
+	var values []byte
+	tiff := make([]byte, 8, valueOffset)
+	copy(tiff, "II\x2a\x00")
+	le.PutUint32(tiff[4:], 8)
+	tiff = le.AppendUint16(tiff, uint16(len(entries)))
+	for _, e := range entries {
+		v := append([]byte(e.value), 0)
+		tiff = le.AppendUint16(tiff, e.tag)
+		tiff = le.AppendUint16(tiff, 2) // ASCII
+		tiff = le.AppendUint32(tiff, uint32(len(v)))
+		if len(v) <= 4 {
+			var inline [4]byte
+			copy(inline[:], v)
+			tiff = append(tiff, inline[:]...)
+			continue
+		}
+		tiff = le.AppendUint32(tiff, uint32(valueOffset+len(values)))
+		values = append(values, v...)
+		if len(values)%2 == 1 {
+			values = append(values, 0) // значения выравниваются по словам
+		}
+	}
+	tiff = le.AppendUint32(tiff, 0) // следующего IFD нет
+	return append(tiff, values...)
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// buildXMP собирает XMP-пакет с описанием, автором и правами
+func buildXMP(md *Metadata) []byte {
+	var b strings.Builder
+	b.WriteString(`<?xpacket begin="` + "\ufeff" + `" id="W5M0MpCehiHzreSzNTczkc9d"?>`)
+	b.WriteString(`<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">`)
+	b.WriteString(`<rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:xmp="http://ns.adobe.com/xap/1.0/">`)
+	if md.Software != "" {
+		b.WriteString(`<xmp:CreatorTool>` + xmlEscape(md.Software) + `</xmp:CreatorTool>`)
+	}
+	if !md.Created.IsZero() {
+		b.WriteString(`<xmp:CreateDate>` + md.Created.Format(time.RFC3339) + `</xmp:CreateDate>`)
+	}
+	if md.Author != "" {
+		b.WriteString(`<dc:creator><rdf:Seq><rdf:li>` + xmlEscape(md.Author) + `</rdf:li></rdf:Seq></dc:creator>`)
+	}
+	if md.Copyright != "" {
+		b.WriteString(`<dc:rights><rdf:Alt><rdf:li xml:lang="x-default">` + xmlEscape(md.Copyright) + `</rdf:li></rdf:Alt></dc:rights>`)
+	}
+	if md.Description != "" {
+		b.WriteString(`<dc:description><rdf:Alt><rdf:li xml:lang="x-default">` + xmlEscape(md.Description) + `</rdf:li></rdf:Alt></dc:description>`)
+	}
+	b.WriteString(`</rdf:Description></rdf:RDF></x:xmpmeta><?xpacket end="w"?>`)
+	return []byte(b.String())
+}
+
+const xmpNamespace = "http://ns.adobe.com/xap/1.0/\x00"
+
+// embedJPEGMetadata вставляет сегменты APP1 (EXIF и XMP) сразу после SOI
+func embedJPEGMetadata(data []byte, md *Metadata) ([]byte, error) {
+	if len(data) < 2 || data[0] != 0xff || data[1] != 0xd8 {
+		return nil, errors.New("invalid JPEG")
+	}
+	var segments []byte
+	appendSegment := func(marker byte, payload []byte) error {
+		if len(payload)+2 > 0xffff {
+			return errors.New("metadata does not fit into a JPEG segment")
+		}
+		segments = append(segments, 0xff, marker)
+		segments = binary.BigEndian.AppendUint16(segments, uint16(len(payload)+2))
+		segments = append(segments, payload...)
+		return nil
+	}
+	if exif := buildExif(md); exif != nil {
+		if err := appendSegment(0xe1, append([]byte("Exif\x00\x00"), exif...)); err != nil {
+			return nil, err
+		}
+	}
+	if err := appendSegment(0xe1, append([]byte(xmpNamespace), buildXMP(md)...)); err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, len(data)+len(segments))
+	out = append(out, data[:2]...)
+	out = append(out, segments...)
+	return append(out, data[2:]...), nil
+}
+
+// pngChunk собирает чанк PNG с контрольной суммой
+func pngChunk(typ string, payload []byte) []byte {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(payload)))
+	chunk = append(chunk, typ...)
+	chunk = append(chunk, payload...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+func isLatin1(s string) bool {
+	for _, r := range s {
+		if r > 0xff {
+			return false
+		}
+	}
+	return true
+}
+
+// pngTextChunk возвращает tEXt для Latin-1 и iTXt (UTF-8) для остального текста
+func pngTextChunk(keyword, text string) []byte {
+	if isLatin1(text) {
+		payload := []byte(keyword + "\x00")
+		for _, r := range text {
+			payload = append(payload, byte(r))
+		}
+		return pngChunk("tEXt", payload)
+	}
+	// Ключевое слово, без сжатия, пустые язык и перевод ключа
+	payload := append([]byte(keyword), 0, 0, 0, 0, 0)
+	return pngChunk("iTXt", append(payload, text...))
+}
+
+// embedPNGText вставляет текстовые чанки сразу после IHDR
+func embedPNGText(data []byte, md *Metadata) ([]byte, error) {
+	const ihdrEnd = 8 + 8 + 13 + 4
+	if len(data) < ihdrEnd || !bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")) || string(data[12:16]) != "IHDR" {
+		return nil, errors.New("invalid PNG")
+	}
+	var chunks []byte
+	add := func(keyword, text string) {
+		if text != "" {
+			chunks = append(chunks, pngTextChunk(keyword, text)...)
+		}
+	}
+	add("Software", md.Software)
+	add("Author", md.Author)
+	add("Copyright", md.Copyright)
+	add("Description", md.Description)
+	if !md.Created.IsZero() {
+		add("Creation Time", md.Created.Format(time.RFC1123Z))
+	}
+
+	out := make([]byte, 0, len(data)+len(chunks))
+	out = append(out, data[:ihdrEnd]...)
+	out = append(out, chunks...)
+	return append(out, data[ihdrEnd:]...), nil
+}
+
+// embedWebPMetadata переводит простой WebP в расширенный формат (VP8X)
+// и добавляет в конец чанки EXIF и XMP
+func embedWebPMetadata(data []byte, md *Metadata) ([]byte, error) {
+	if len(data) < 30 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, errors.New("invalid WebP")
+	}
+	fourCC := string(data[12:16])
+	if fourCC != "VP8L" {
+		return nil, errors.New("metadata is only supported for lossless WebP")
+	}
+	payload := data[20:]
+
+	// Размеры из заголовка VP8L. Флаг альфа-канала в VP8X не ставим:
+	// у VP8L он хранится в собственном заголовке, а golang.org/x/image/webp
+	// отвергает VP8L при выставленном флаге.
+	bits := binary.LittleEndian.Uint32(payload[1:5])
+	width := bits&0x3fff + 1
+	height := (bits>>14)&0x3fff + 1
+
+	exif := buildExif(md)
+	xmp := buildXMP(md)
+
+	var flags byte = 0x04 // XMP
+	if exif != nil {
+		flags |= 0x08
+	}
+	vp8x := make([]byte, 10)
+	vp8x[0] = flags
+	putUint24(vp8x[4:], width-1)
+	putUint24(vp8x[7:], height-1)
+
+	var body []byte
+	body = append(body, "WEBP"...)
+	body = appendRIFFChunk(body, "VP8X", vp8x)
+	body = append(body, data[12:]...) // исходный чанк VP8L вместе с выравниванием
+	if exif != nil {
+		body = appendRIFFChunk(body, "EXIF", exif)
+	}
+	body = appendRIFFChunk(body, "XMP ", xmp)
+
+	out := append([]byte("RIFF"), 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(body)))
+	return append(out, body...), nil
+}
+
+func putUint24(b []byte, v uint32) {
+	b[0], b[1], b[2] = byte(v), byte(v>>8), byte(v>>16)
+}
+
+func appendRIFFChunk(dst []byte, fourCC string, payload []byte) []byte {
+	dst = append(dst, fourCC...)
+	dst = binary.LittleEndian.AppendUint32(dst, uint32(len(payload)))
+	dst = append(dst, payload...)
+	if len(payload)%2 == 1 {
+		dst = append(dst, 0)
+	}
+	return dst
+}
@@ -0,0 +1,57 @@
+package meme
+
+import (
+	"io"
+	"time"
+)
+
+// Metrics получает события генерации для систем мониторинга.
+// Адаптер для Prometheus находится в пакете github.com/go-goblin/meme/prom,
+// поэтому основной пакет от него не зависит.
+type Metrics interface {
+	// ObserveGeneration вызывается после каждой генерации; err равен nil при успехе
+	ObserveGeneration(d time.Duration, err error)
+	// ObserveOutput сообщает размер закодированного результата
+	ObserveOutput(format Format, size int)
+	// ObserveFontCache сообщает о попадании или промахе кеша шрифтов
+	ObserveFontCache(hit bool)
+}
+
+// SetMetrics подключает сбор метрик (nil - отключить)
+func (g *Generator) SetMetrics(m Metrics) {
+	g.metrics = m
+}
+
+// observeGeneration сообщает о завершении генерации, начатой в start
+func (g *Generator) observeGeneration(start time.Time, err *error) {
+	if g.metrics != nil {
+		g.metrics.ObserveGeneration(time.Since(start), *err)
+	}
+}
+
+func (g *Generator) observeOutput(format Format, size int) {
+	if g.metrics != nil {
+		if format == "" {
+			format = FormatPNG
+		}
+		g.metrics.ObserveOutput(format, size)
+	}
+}
+
+func (g *Generator) observeFontCache(hit bool) {
+	if g.metrics != nil {
+		g.metrics.ObserveFontCache(hit)
+	}
+}
+
+// countingWriter подсчитывает записанные байты
+type countingWriter struct {
+	w io.Writer
+	n int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += n
+	return n, err
+}
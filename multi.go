@@ -0,0 +1,115 @@
+package meme
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"time"
+)
+
+// MultiLayout - раскладка фото совместного демотиватора
+type MultiLayout int
+
+const (
+	MultiAuto MultiLayout = iota // в ряд для 2-3 фото, сеткой 2x2 для 4
+	MultiRow                     // в один ряд, фото приводятся к общей высоте
+	MultiGrid                    // сеткой по два в ряд с обрезкой до общего размера
+)
+
+// MultiOptions задает раскладку GenerateMulti
+type MultiOptions struct {
+	Layout MultiLayout
+	Gap    int // промежуток между фото цвета рамки (BorderColor), 0 - вплотную
+}
+
+// Сколько фото помещается в одну рамку
+const (
+	minMultiImages = 2
+	maxMultiImages = 4
+)
+
+// GenerateMulti создает "совместный" демотиватор: 2-4 фото в одной рамке
+// и общие подписи под ними. Фото не увеличиваются: общая высота ряда и
+// размер ячейки сетки берутся по самому маленькому. Config.MaxPixels
+// ограничивает и каждое фото, и собранное из них изображение.
+func (g *Generator) GenerateMulti(imgs []image.Image, opts *MultiOptions) (out *image.RGBA, err error) {
+	defer g.observeGeneration(time.Now(), &err)
+	defer recoverPanic(&err)
+	if opts == nil {
+		opts = &MultiOptions{}
+	}
+	photo, err := g.joinPhotos(imgs, *opts)
+	if err != nil {
+		return nil, err
+	}
+	return g.generateInto(nil, photo)
+}
+
+// joinPhotos собирает фото в одно изображение по раскладке opts
+func (g *Generator) joinPhotos(imgs []image.Image, opts MultiOptions) (*image.RGBA, error) {
+	if len(imgs) < minMultiImages || len(imgs) > maxMultiImages {
+		return nil, &ConfigError{Field: "images", Reason: fmt.Sprintf("must contain %d to %d images", minMultiImages, maxMultiImages)}
+	}
+	if opts.Gap < 0 {
+		return nil, &ConfigError{Field: "MultiOptions.Gap", Reason: "must not be negative"}
+	}
+	for _, img := range imgs {
+		if err := g.validateInput(img); err != nil {
+			return nil, err
+		}
+	}
+	layout := opts.Layout
+	if layout == MultiAuto {
+		layout = MultiRow
+		if len(imgs) == maxMultiImages {
+			layout = MultiGrid
+		}
+	}
+
+	gap := opts.Gap
+	var cells []image.Rectangle
+	switch layout {
+	case MultiRow:
+		h := imgs[0].Bounds().Dy()
+		for _, img := range imgs[1:] {
+			h = min(h, img.Bounds().Dy())
+		}
+		x := 0
+		for _, img := range imgs {
+			b := img.Bounds()
+			w := max(b.Dx()*h/b.Dy(), 1)
+			cells = append(cells, image.Rect(x, 0, x+w, h))
+			x += w + gap
+		}
+	case MultiGrid:
+		w, h := imgs[0].Bounds().Dx(), imgs[0].Bounds().Dy()
+		for _, img := range imgs[1:] {
+			w, h = min(w, img.Bounds().Dx()), min(h, img.Bounds().Dy())
+		}
+		for i := range imgs {
+			col, row := i%2, i/2
+			cell := image.Rect(0, 0, w, h).Add(image.Pt(col*(w+gap), row*(h+gap)))
+			if i == len(imgs)-1 && col == 0 {
+				// Нечетное фото занимает всю ширину последнего ряда
+				cell.Max.X += w + gap
+			}
+			cells = append(cells, cell)
+		}
+	default:
+		return nil, &ConfigError{Field: "MultiOptions.Layout", Reason: fmt.Sprintf("unknown layout %d", layout)}
+	}
+
+	var bounds image.Rectangle
+	for _, c := range cells {
+		bounds = bounds.Union(c)
+	}
+	if err := checkCanvas(bounds); err != nil {
+		return nil, err
+	}
+	out := image.NewRGBA(bounds)
+	draw.Draw(out, bounds, image.NewUniform(g.config.BorderColor), image.Point{}, draw.Src)
+	for i, img := range imgs {
+		drawCover(out, cells[i], img)
+	}
+	return out, nil
+}
@@ -0,0 +1,48 @@
+package meme
+
+import (
+	"image"
+	"math"
+	"time"
+)
+
+// NestCaption - подписи одного уровня вложенного демотиватора
+type NestCaption struct {
+	TopText    string
+	BottomText string
+}
+
+// Во сколько раз уменьшается шрифт при переходе на уровень глубже
+const nestFontShrink = 0.8
+
+// Nest строит "демотиватор демотиватора": результат снова и снова
+// оборачивается в рамку levels раз. captions[0] - подписи самого внутреннего
+// уровня; уровни без своих подписей берут TopText/BottomText конфигурации.
+// Шрифт внешнего уровня равен обычному, а каждый внутренний в 0.8 раза меньше.
+func (g *Generator) Nest(img image.Image, levels int, captions ...NestCaption) (out *image.RGBA, err error) {
+	defer g.observeGeneration(time.Now(), &err)
+	if levels < 1 {
+		return nil, &ConfigError{Field: "levels", Reason: "must be at least 1"}
+	}
+	if err := g.validateInput(img); err != nil {
+		return nil, err
+	}
+
+	baseSize := g.layout(img).fontSize
+	current := img
+	for i := range levels {
+		cfg := *g.config
+		if i < len(captions) {
+			cfg.TopText, cfg.BottomText = captions[i].TopText, captions[i].BottomText
+		}
+		cfg.AutoFontSize = false
+		cfg.FontSize = baseSize * math.Pow(nestFontShrink, float64(levels-1-i))
+
+		out, err = g.derive(&cfg).generateInto(nil, current)
+		if err != nil {
+			return nil, err
+		}
+		current = out
+	}
+	return out, nil
+}
@@ -0,0 +1,150 @@
+package meme
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"time"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// NewsOptions задаёт плашку "срочных новостей" в нижней трети кадра
+type NewsOptions struct {
+	Label    string // текст красной вкладки, пусто - "BREAKING"
+	Headline string // заголовок на белой плашке
+	Ticker   string // бегущая строка внизу; не помещающийся текст обрезается
+	Clock    string // время в углу бегущей строки, например "12:45"
+
+	Logo image.Image // логотип канала справа на плашке заголовка; nil - без логотипа
+
+	LabelColor        color.Color // nil - красный
+	BarColor          color.Color // nil - белый
+	TextColor         color.Color // nil - почти черный, для заголовка
+	TickerColor       color.Color // nil - темно-синий фон бегущей строки
+	TickerTextColor   color.Color // nil - белый
+	HeadlineSizeRatio float64     // высота плашки заголовка от высоты кадра, 0 - 0.12
+}
+
+// Значения NewsOptions по умолчанию
+const (
+	defaultNewsLabel        = "BREAKING"
+	defaultNewsHeadlineSize = 0.12
+	newsTickerRatio         = 0.5 // высота бегущей строки от высоты плашки
+	newsLabelRatio          = 0.6 // высота вкладки от высоты плашки
+)
+
+var (
+	newsRed    = color.RGBA{0xc8, 0x10, 0x2e, 0xff}
+	newsInk    = color.RGBA{0x11, 0x11, 0x11, 0xff}
+	newsTicker = color.RGBA{0x0b, 0x1f, 0x4b, 0xff}
+)
+
+// BreakingNews рисует поверх фото плашку новостного канала: красную
+// вкладку "BREAKING", белую полосу с заголовком, бегущую строку и часы.
+// Размер результата равен размеру фото; шрифт берется из конфигурации.
+func (g *Generator) BreakingNews(img image.Image, opts NewsOptions) (out *image.RGBA, err error) {
+	defer g.observeGeneration(time.Now(), &err)
+	defer recoverPanic(&err)
+
+	// Заголовок и бегущую строку проверяем ограничениями подписей
+	cfg := *g.config
+	cfg.TopText, cfg.BottomText = opts.Headline, opts.Ticker
+	dg := g.derive(&cfg)
+	if err := dg.validateInput(img); err != nil {
+		return nil, err
+	}
+	if opts.HeadlineSizeRatio < 0 || opts.HeadlineSizeRatio > 0.5 {
+		return nil, &ConfigError{Field: "HeadlineSizeRatio", Reason: "must be between 0 and 0.5"}
+	}
+
+	label := opts.Label
+	if label == "" {
+		label = defaultNewsLabel
+	}
+	ratio := opts.HeadlineSizeRatio
+	if ratio == 0 {
+		ratio = defaultNewsHeadlineSize
+	}
+	labelColor := colorOr(opts.LabelColor, newsRed)
+	barColor := colorOr(opts.BarColor, color.White)
+	textColor := colorOr(opts.TextColor, newsInk)
+	tickerColor := colorOr(opts.TickerColor, newsTicker)
+	tickerText := colorOr(opts.TickerTextColor, color.White)
+
+	b := img.Bounds()
+	canvas := image.Rect(0, 0, b.Dx(), b.Dy())
+	out = image.NewRGBA(canvas)
+	draw.Draw(out, canvas, image.NewUniform(cfg.BackgroundColor), image.Point{}, draw.Src)
+	drawSource(out, canvas, img, b.Min)
+
+	defer dg.stage(StageOutline)()
+	w, h := canvas.Dx(), canvas.Dy()
+	barH := max(int(float64(h)*ratio), 8)
+	tickerH := int(float64(barH) * newsTickerRatio)
+	labelH := int(float64(barH) * newsLabelRatio)
+	left := w / 25
+
+	ticker := image.Rect(0, h-tickerH, w, h)
+	bar := image.Rect(left, ticker.Min.Y-barH, w, ticker.Min.Y)
+	pad := barH / 5
+
+	tr := dg.textRenderer()
+	// Вкладка над левым краем плашки по ширине текста
+	labelFace, err := dg.loadFont(float64(labelH) * 0.6)
+	if err != nil {
+		return nil, err
+	}
+	labelW := tr.Measure(labelFace, toUpperSafe(label)).Ceil() + 2*pad
+	labelFace.Close()
+	tab := image.Rect(left, bar.Min.Y-labelH, left+labelW, bar.Min.Y)
+	draw.Draw(out, tab, image.NewUniform(labelColor), image.Point{}, draw.Src)
+	if err := dg.drawSlotText(out, TextSlot{Name: "label", Rect: tab, FontSize: float64(labelH) * 0.6, Color: color.White, Uppercase: true}, label); err != nil {
+		return nil, err
+	}
+
+	draw.Draw(out, bar, image.NewUniform(barColor), image.Point{}, draw.Src)
+	headline := image.Rect(bar.Min.X+pad, bar.Min.Y, bar.Max.X-pad, bar.Max.Y)
+	if opts.Logo != nil {
+		logo := image.Rect(bar.Max.X-barH, bar.Min.Y, bar.Max.X, bar.Max.Y).Inset(pad / 2)
+		drawContain(out, logo, opts.Logo)
+		headline.Max.X = logo.Min.X - pad
+	}
+	if opts.Headline != "" {
+		slot := TextSlot{Name: "headline", Rect: headline, FontSize: float64(barH) * 0.55, Color: textColor, Align: AlignLeft, Uppercase: true}
+		if err := dg.drawSlotText(out, slot, opts.Headline); err != nil {
+			return nil, err
+		}
+	}
+
+	draw.Draw(out, ticker, image.NewUniform(tickerColor), image.Point{}, draw.Src)
+	tickerFace, err := dg.loadFont(float64(tickerH) * 0.6)
+	if err != nil {
+		return nil, err
+	}
+	defer tickerFace.Close()
+	m := tickerFace.Metrics()
+	baseline := ticker.Min.Y + (tickerH+m.Ascent.Ceil()-m.Descent.Ceil())/2
+	textArea := image.Rect(left, ticker.Min.Y, w, h)
+	if opts.Clock != "" {
+		clockW := tr.Measure(tickerFace, opts.Clock).Ceil() + 2*pad
+		clock := image.Rect(0, ticker.Min.Y, clockW, h)
+		draw.Draw(out, clock, image.NewUniform(labelColor), image.Point{}, draw.Src)
+		tr.Draw(out, tickerFace, opts.Clock, fixed.P(pad, baseline), TextStyle{Color: color.White, Linear: dg.config.LinearBlending})
+		textArea.Min.X = max(textArea.Min.X, clock.Max.X+pad)
+	}
+	if opts.Ticker != "" {
+		// Рисуем в подизображение, чтобы текст обрезался по краю полосы
+		dst := out.SubImage(textArea).(*image.RGBA)
+		tr.Draw(dst, tickerFace, opts.Ticker, fixed.P(textArea.Min.X, baseline), TextStyle{Color: tickerText, Linear: dg.config.LinearBlending})
+	}
+	return out, nil
+}
+
+// colorOr возвращает c или def, если c не задан
+func colorOr(c, def color.Color) color.Color {
+	if c == nil {
+		return def
+	}
+	return c
+}
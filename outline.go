@@ -0,0 +1,196 @@
+package meme
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// drawOutlinedText рисует строку с обводкой шириной style.OutlineWidth.
+// Глифы растеризуются один раз в альфа-маску, обводка получается
+// расширением маски по квадрату (width, width) - тот же результат, что
+// и отрисовка строки со всеми сдвигами, но за O(width) на пиксель.
+// Для style.Hollow обводка строится по векторным контурам глифов (см.
+// strokeText) со скругленными углами, из нее вычитается сама маска, и
+// остается только контур цвета style.Color; расширение маски остается
+// запасным путем для face без контуров. Свечение style.Glow и объем style.Extrude
+// рисуются силуэтом с обводкой позади всего остального. С style.Jitter
+// и style.Wave глифы попадают в маску по одному, и все эффекты следуют
+// за ними.
+func drawOutlinedText(dst draw.Image, face font.Face, text string, dot fixed.Point26_6, style TextStyle) {
+	width := max(style.OutlineWidth, 0)
+	if style.Hollow {
+		width = max(width, 1)
+	}
+	d := &font.Drawer{Face: face, Src: image.Opaque, Dot: dot}
+	bounds, _ := d.BoundString(text)
+	r := image.Rect(bounds.Min.X.Floor(), bounds.Min.Y.Floor(), bounds.Max.X.Ceil(), bounds.Max.Y.Ceil())
+	if hasCombining(text) {
+		// Стопки диакритики ("zalgo") не выходят за строку
+		m := face.Metrics()
+		r.Min.Y = max(r.Min.Y, (dot.Y - m.Ascent).Floor())
+		r.Max.Y = min(r.Max.Y, (dot.Y + m.Descent).Ceil())
+	}
+	glyphwise := style.Jitter.active() || style.Wave.active()
+	if glyphwise {
+		r = r.Inset(-style.Jitter.pad(face) - style.Wave.pad())
+	}
+	r = r.Inset(-width)
+	if r.Empty() {
+		return
+	}
+
+	mask := getAlpha(r)
+	defer putAlpha(mask)
+	if glyphwise {
+		drawGlyphs(mask, face, text, dot, style)
+	} else {
+		d.Dst = mask
+		d.DrawString(text)
+	}
+
+	// fill рисует маску m, сдвинутую на off
+	fill := func(c color.Color, m *image.Alpha, off image.Point) {
+		dr := r.Add(off)
+		if style.Linear {
+			drawMaskLinear(dst, dr, c, m, r.Min)
+			return
+		}
+		draw.DrawMask(dst, dr, image.NewUniform(c), image.Point{}, m, r.Min, draw.Over)
+	}
+	var outline *image.Alpha
+	if vf, ok := face.(*vectorFace); ok && style.Hollow {
+		outline = getAlpha(r)
+		defer putAlpha(outline)
+		strokeText(outline, vf, text, dot, style, float64(width))
+		for i, a := range mask.Pix {
+			outline.Pix[i] = max(outline.Pix[i], a)
+		}
+	} else if width > 0 {
+		outline = dilate(mask, width)
+		defer putAlpha(outline)
+	}
+	silhouette := mask
+	if outline != nil {
+		silhouette = outline
+	}
+	if style.Glow.Radius > 0 {
+		style.Glow.draw(dst, silhouette, style.Color)
+	}
+	if style.Extrude.Depth > 0 {
+		near := color.Color(color.Black)
+		if style.OutlineColor != nil && !style.Hollow {
+			near = style.OutlineColor
+		}
+		style.Extrude.draw(silhouette, near, fill)
+	}
+	if style.Hollow {
+		// Контур - обводка без заливки; сглаженный внутренний край
+		// получается из полупрозрачных пикселей маски
+		for i, a := range mask.Pix {
+			outline.Pix[i] = uint8(uint32(outline.Pix[i]) * uint32(255-a) / 255)
+		}
+		fill(style.Color, outline, image.Point{})
+		return
+	}
+	if outline != nil {
+		fill(style.OutlineColor, outline, image.Point{})
+	}
+	fill(style.Color, mask, image.Point{})
+}
+
+// drawGlyphs рисует строку в маску dst как font.Drawer, но сдвигает
+// каждый глиф по style.Wave и сдвигает и поворачивает по style.Jitter.
+// Для одного style.JitterSeed узор всегда один и тот же.
+func drawGlyphs(dst *image.Alpha, face font.Face, text string, dot fixed.Point26_6, style TextStyle) {
+	forEachGlyph(face, text, dot, style, func(c rune, at fixed.Point26_6, angle float64) (fixed.Int26_6, bool) {
+		dr, mask, mp, advance, ok := face.Glyph(at, c)
+		if !ok {
+			return 0, false
+		}
+		if angle == 0 {
+			draw.DrawMask(dst, dr, image.Opaque, image.Point{}, mask, mp, draw.Over)
+		} else {
+			rotateGlyph(dst, dr, mask, mp, angle)
+		}
+		return advance, true
+	})
+}
+
+// forEachGlyph расставляет глифы строки как font.Drawer, добавляя сдвиги
+// style.Wave и style.Jitter. fn рисует глиф c с началом в at, повернутый
+// на angle вокруг своего центра, и возвращает advance; ok == false -
+// глифа нет, и строка не сдвигается.
+func forEachGlyph(face font.Face, text string, dot fixed.Point26_6, style TextStyle, fn func(c rune, at fixed.Point26_6, angle float64) (advance fixed.Int26_6, ok bool)) {
+	rnd := style.Jitter.newRand(style.JitterSeed)
+	var width float64
+	if style.Wave.active() {
+		width = float64(font.MeasureString(face, text)) / 64
+	}
+	start := dot.X
+	prev := rune(-1)
+	for _, c := range text {
+		if prev >= 0 {
+			dot.X += face.Kern(prev, c)
+		}
+		prev = c
+		// Числа берутся всегда в одном порядке, даже для пропущенных глифов
+		var dx, dy, angle float64
+		if style.Jitter.active() {
+			dx, dy, angle = style.Jitter.next(rnd)
+		}
+		if style.Wave.active() {
+			advance, _ := face.GlyphAdvance(c)
+			dy += style.Wave.offset(float64(dot.X-start+advance/2)/64, width)
+		}
+
+		at := fixed.Point26_6{X: dot.X + fixed.Int26_6(dx*64), Y: dot.Y + fixed.Int26_6(dy*64)}
+		advance, ok := fn(c, at, angle)
+		if !ok {
+			continue
+		}
+		dot.X += advance
+	}
+}
+
+// dilate возвращает маску, где каждый пиксель - максимум src в квадрате
+// радиуса radius. Фильтр раздельный: сначала по строкам, затем по столбцам.
+func dilate(src *image.Alpha, radius int) *image.Alpha {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	tmp := getAlpha(b)
+	defer putAlpha(tmp)
+	out := getAlpha(b)
+
+	for y := 0; y < h; y++ {
+		row := src.Pix[y*src.Stride : y*src.Stride+w]
+		dst := tmp.Pix[y*tmp.Stride : y*tmp.Stride+w]
+		for x := range dst {
+			dst[x] = maxAlpha(row, max(x-radius, 0), min(x+radius, w-1), 1)
+		}
+	}
+	for x := 0; x < w; x++ {
+		col := tmp.Pix[x:]
+		for y := 0; y < h; y++ {
+			out.Pix[y*out.Stride+x] = maxAlpha(col, max(y-radius, 0)*tmp.Stride, min(y+radius, h-1)*tmp.Stride, tmp.Stride)
+		}
+	}
+	return out
+}
+
+// maxAlpha - максимум pix[from], pix[from+step], ..., pix[to]
+func maxAlpha(pix []uint8, from, to, step int) uint8 {
+	var m uint8
+	for i := from; i <= to; i += step {
+		if pix[i] > m {
+			m = pix[i]
+			if m == 0xff {
+				break
+			}
+		}
+	}
+	return m
+}
@@ -0,0 +1,84 @@
+package meme
+
+import (
+	"image"
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestStrokeTextRoundCorners(t *testing.T) {
+	face, err := NewGenerator(nil).loadFontFrom("", nil, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer face.Close()
+	vf, ok := face.(*vectorFace)
+	if !ok {
+		t.Fatalf("face is %T, want *vectorFace", face)
+	}
+
+	// Дефис в Go Bold - прямоугольник: у скругленной обводки углы срезаны,
+	// а середина остается пустой
+	const radius = 4
+	dot := fixed.P(50, 250)
+	bounds, _ := font.BoundString(face, "-")
+	glyph := image.Rect(bounds.Min.X.Round(), bounds.Min.Y.Round(), bounds.Max.X.Round(), bounds.Max.Y.Round()).Add(image.Pt(50, 250))
+	if glyph.Dy() <= 2*radius+2 {
+		t.Fatalf("hyphen %v is too thin for the test", glyph)
+	}
+	stroke := image.NewAlpha(image.Rect(0, 0, 300, 300))
+	strokeText(stroke, vf, "-", dot, TextStyle{}, radius)
+
+	mid := image.Pt((glyph.Min.X+glyph.Max.X)/2, (glyph.Min.Y+glyph.Max.Y)/2)
+	for _, tc := range []struct {
+		name string
+		p    image.Point
+		want bool
+	}{
+		{"left side", image.Pt(glyph.Min.X-radius/2, mid.Y), true},
+		{"above top", image.Pt(mid.X, glyph.Min.Y-radius/2), true},
+		{"inner edge", image.Pt(mid.X, glyph.Min.Y+radius/2), true},
+		{"beyond radius", image.Pt(glyph.Min.X-radius-2, mid.Y), false},
+		{"corner diagonal", image.Pt(glyph.Min.X-radius, glyph.Min.Y-radius), false},
+		{"middle", mid, false},
+	} {
+		a := stroke.AlphaAt(tc.p.X, tc.p.Y).A
+		if got := a > 0x80; got != tc.want {
+			t.Errorf("%s %v: alpha %d", tc.name, tc.p, a)
+		}
+	}
+}
+
+func TestHollowTextVector(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.TextHollow = true
+	cfg.TextOutlineWidth = 8
+	cfg.TopText = "Hollow"
+	for _, effects := range []func(*Config){
+		func(*Config) {},
+		func(c *Config) { c.TextJitter = Jitter{Offset: 3, Rotation: 10} },
+		func(c *Config) { c.TextWave = Wave{Amplitude: 6} },
+	} {
+		c := *cfg
+		effects(&c)
+		out, err := NewGenerator(&c).Generate(image.NewRGBA(image.Rect(0, 0, 400, 200)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Под фото рисуется контур подписи
+		var lit int
+		b := out.Bounds()
+		for y := b.Max.Y - b.Dy()/4; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if out.RGBAAt(x, y).R > 0x80 {
+					lit++
+				}
+			}
+		}
+		if lit == 0 {
+			t.Errorf("jitter %v, wave %v: no outline drawn", c.TextJitter, c.TextWave)
+		}
+	}
+}
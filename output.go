@@ -0,0 +1,95 @@
+package meme
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"time"
+)
+
+// OutputOptions выбирает модель пикселей результата GenerateOutput
+type OutputOptions struct {
+	// Model - color.RGBAModel (по умолчанию), color.NRGBAModel,
+	// color.RGBA64Model, color.NRGBA64Model, color.GrayModel,
+	// color.Gray16Model или color.Palette для GIF. Результат - изображение
+	// соответствующего типа: *image.NRGBA, *image.Gray, *image.Paletted...
+	Model color.Model
+
+	// Dither - для палитры: рассеивать ошибку по Флойду-Стейнбергу, а не
+	// брать ближайший цвет палитры
+	Dither bool
+}
+
+// GenerateOutput создает демотиватор сразу в нужной модели пикселей, без
+// отдельного прохода преобразования у вызывающего: NRGBA и серые холсты
+// рисуются напрямую, палитра получается одним проходом из RGBA.
+func (g *Generator) GenerateOutput(img image.Image, opts *OutputOptions) (out image.Image, err error) {
+	defer g.observeGeneration(time.Now(), &err)
+	if opts == nil {
+		opts = &OutputOptions{}
+	}
+	var canvas func(image.Rectangle) draw.Image
+	switch m := opts.Model; m {
+	case nil, color.RGBAModel:
+		canvas = func(r image.Rectangle) draw.Image { return image.NewRGBA(r) }
+	case color.NRGBAModel:
+		canvas = func(r image.Rectangle) draw.Image { return image.NewNRGBA(r) }
+	case color.RGBA64Model:
+		canvas = func(r image.Rectangle) draw.Image { return image.NewRGBA64(r) }
+	case color.NRGBA64Model:
+		canvas = func(r image.Rectangle) draw.Image { return image.NewNRGBA64(r) }
+	case color.GrayModel:
+		canvas = func(r image.Rectangle) draw.Image { return image.NewGray(r) }
+	case color.Gray16Model:
+		canvas = func(r image.Rectangle) draw.Image { return image.NewGray16(r) }
+	default:
+		p, ok := m.(color.Palette)
+		if !ok {
+			return nil, &ConfigError{Field: "OutputOptions.Model", Reason: "unsupported color model"}
+		}
+		if len(p) == 0 || len(p) > 256 {
+			return nil, &ConfigError{Field: "OutputOptions.Model", Reason: "palette must have 1 to 256 colors"}
+		}
+		// Сглаженные края подписей требуют промежуточных цветов, поэтому
+		// палитра применяется к готовому изображению
+		rgba, err := g.generateInto(nil, img)
+		if err != nil {
+			return nil, err
+		}
+		pm := image.NewPaletted(rgba.Bounds(), p)
+		var d draw.Drawer = draw.Src
+		if opts.Dither {
+			d = draw.FloydSteinberg
+		}
+		d.Draw(pm, pm.Rect, rgba, rgba.Rect.Min)
+		return pm, nil
+	}
+	dst, err := g.generateCanvas(img, canvas)
+	if err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+// generateCanvas рисует демотиватор на холсте, который создает newCanvas
+func (g *Generator) generateCanvas(img image.Image, newCanvas func(image.Rectangle) draw.Image) (_ draw.Image, err error) {
+	defer recoverPanic(&err)
+	if err := g.validateInput(img); err != nil {
+		return nil, err
+	}
+	if img, err = g.beforeLayout(context.Background(), img); err != nil {
+		return nil, err
+	}
+	done := g.stage(StageLayout)
+	l := g.layout(img)
+	done()
+	if err := checkCanvas(l.canvas); err != nil {
+		return nil, err
+	}
+	out := newCanvas(l.canvas)
+	if err := g.render(out, img, l); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
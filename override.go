@@ -0,0 +1,66 @@
+package meme
+
+import (
+	"fmt"
+	"image/color"
+)
+
+// StyleOverride - оформление, которое клиент сервера (httpmeme, grpcmeme,
+// mcpmeme, worker) выбирает в запросе поверх конфигурации сервера.
+// Пустые поля не меняют конфигурацию.
+type StyleOverride struct {
+	Theme string
+
+	// Font - имя встроенного шрифта (см. GetAvailableFonts); пути к файлам
+	// не принимаются, чтобы клиент не читал файлы сервера
+	Font     string
+	FontSize float64 // > 0 - отключает AutoFontSize
+
+	// Цвета "#rrggbb"
+	BackgroundColor, BorderColor, TextColor string
+
+	Uppercase bool // включает TextUppercase, но не выключает
+}
+
+// ApplyOverride применяет выбор клиента o: тему через ApplyTheme (с
+// сохранением ограничений конфигурации), затем шрифт, размер и цвета.
+// Ошибка - *ConfigError или ErrFontNotFound для неизвестного шрифта.
+func (c *Config) ApplyOverride(o StyleOverride) error {
+	cfg := *c
+	if o.Theme != "" {
+		if err := cfg.ApplyTheme(o.Theme); err != nil {
+			return err
+		}
+	}
+	if o.Font != "" {
+		data, ok := GetAvailableFonts()[o.Font]
+		if !ok {
+			return fmt.Errorf("%w: unknown built-in font %q", ErrFontNotFound, o.Font)
+		}
+		cfg.FontPath, cfg.FontData = "", data
+	}
+	if o.FontSize > 0 {
+		cfg.FontSize, cfg.AutoFontSize = o.FontSize, false
+	}
+	for _, f := range []struct {
+		field string
+		value string
+		dst   *color.Color
+	}{
+		{"BackgroundColor", o.BackgroundColor, &cfg.BackgroundColor},
+		{"BorderColor", o.BorderColor, &cfg.BorderColor},
+		{"TextColor", o.TextColor, &cfg.TextColor},
+	} {
+		if f.value == "" {
+			continue
+		}
+		col, err := ParseColor(f.value)
+		if err != nil {
+			return &ConfigError{Field: f.field, Reason: err.Error()}
+		}
+		*f.dst = col
+	}
+	cfg.TextUppercase = cfg.TextUppercase || o.Uppercase
+	*c = cfg
+	return nil
+}
@@ -0,0 +1,48 @@
+package meme
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestApplyOverride(t *testing.T) {
+	base := DefaultConfig()
+	base.MaxPixels, base.MaxBytes, base.MaxTextLength = 100, 200, 30
+
+	cfg := *base
+	err := cfg.ApplyOverride(StyleOverride{Theme: "vaporwave", Font: "regular", FontSize: 20, TextColor: "#ffee00", Uppercase: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MaxPixels != 100 || cfg.MaxBytes != 200 || cfg.MaxTextLength != 30 {
+		t.Errorf("limits lost: %d %d %d", cfg.MaxPixels, cfg.MaxBytes, cfg.MaxTextLength)
+	}
+	got := withTheme(&cfg)
+	if c := FormatColor(got.TextColor); c != "#ffee00" {
+		t.Errorf("TextColor = %s, want #ffee00", c)
+	}
+	if c := FormatColor(got.BackgroundColor); c != "#1a0b2e" {
+		t.Errorf("BackgroundColor = %s, want theme #1a0b2e", c)
+	}
+	if got.FontSize != 20 || got.AutoFontSize || !got.TextUppercase || len(got.FontData) == 0 {
+		t.Errorf("FontSize %v, AutoFontSize %v, TextUppercase %v", got.FontSize, got.AutoFontSize, got.TextUppercase)
+	}
+
+	for _, tc := range []struct {
+		name string
+		o    StyleOverride
+		want error
+	}{
+		{"unknown theme", StyleOverride{Theme: "no-such-theme"}, ErrInvalidConfig},
+		{"font path", StyleOverride{Font: "/etc/passwd"}, ErrFontNotFound},
+		{"bad color", StyleOverride{BorderColor: "red-ish"}, ErrInvalidConfig},
+	} {
+		cfg := *base
+		if err := cfg.ApplyOverride(tc.o); !errors.Is(err, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.want)
+		}
+		if cfg.Theme != "" || cfg.FontPath != "" {
+			t.Errorf("%s: config changed on error", tc.name)
+		}
+	}
+}
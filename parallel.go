@@ -0,0 +1,34 @@
+package meme
+
+import (
+	"image"
+	"runtime"
+	"sync"
+)
+
+// Холсты меньше этого размера рисуются в одном потоке:
+// запуск горутин обходится дороже самой отрисовки
+const minParallelPixels = 1 << 20
+
+// forEachBand делит r на горизонтальные полосы по числу GOMAXPROCS
+// и вызывает fn для каждой полосы в отдельной горутине.
+// fn должна писать только в пиксели своей полосы.
+func forEachBand(r image.Rectangle, fn func(band image.Rectangle)) {
+	workers := min(runtime.GOMAXPROCS(0), r.Dy())
+	if workers <= 1 || r.Dx()*r.Dy() < minParallelPixels {
+		fn(r)
+		return
+	}
+
+	var wg sync.WaitGroup
+	height := (r.Dy() + workers - 1) / workers
+	for y := r.Min.Y; y < r.Max.Y; y += height {
+		band := image.Rect(r.Min.X, y, r.Max.X, min(y+height, r.Max.Y))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn(band)
+		}()
+	}
+	wg.Wait()
+}
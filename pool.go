@@ -0,0 +1,106 @@
+package meme
+
+import (
+	"bytes"
+	"image"
+	"sync"
+	"sync/atomic"
+)
+
+// Переиспользование временных буферов. Под постоянной нагрузкой
+// (боты, HTTP-сервисы) это заметно снижает давление на сборщик мусора.
+
+var poolingDisabled atomic.Bool
+
+// SetPooling включает или отключает переиспользование временных буферов
+// (по умолчанию включено). В средах с жёстким лимитом памяти пулы можно
+// отключить: тогда буферы освобождаются сразу после использования.
+func SetPooling(enabled bool) {
+	poolingDisabled.Store(!enabled)
+}
+
+// Слишком большие буферы в пул не возвращаем, чтобы один огромный
+// запрос не удерживал память навсегда
+const maxPooledBytes = 64 << 20
+
+var bufferPool = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+// getBuffer возвращает пустой буфер
+func getBuffer() *bytes.Buffer {
+	if poolingDisabled.Load() {
+		return new(bytes.Buffer)
+	}
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if poolingDisabled.Load() || buf.Cap() > maxPooledBytes {
+		return
+	}
+	bufferPool.Put(buf)
+}
+
+var uint32Pool sync.Pool // *[]uint32
+
+// getUint32s возвращает срез длины n с произвольным содержимым
+func getUint32s(n int) []uint32 {
+	if !poolingDisabled.Load() {
+		if p, ok := uint32Pool.Get().(*[]uint32); ok && cap(*p) >= n {
+			return (*p)[:n]
+		}
+	}
+	return make([]uint32, n)
+}
+
+func putUint32s(s []uint32) {
+	if poolingDisabled.Load() || cap(s)*4 > maxPooledBytes {
+		return
+	}
+	uint32Pool.Put(&s)
+}
+
+var nrgbaPool sync.Pool // *image.NRGBA
+
+// getNRGBA возвращает прозрачное изображение размера r
+func getNRGBA(r image.Rectangle) *image.NRGBA {
+	n := 4 * r.Dx() * r.Dy()
+	if !poolingDisabled.Load() {
+		if img, ok := nrgbaPool.Get().(*image.NRGBA); ok && cap(img.Pix) >= n {
+			pix := img.Pix[:n]
+			clear(pix)
+			return &image.NRGBA{Pix: pix, Stride: 4 * r.Dx(), Rect: r}
+		}
+	}
+	return image.NewNRGBA(r)
+}
+
+func putNRGBA(img *image.NRGBA) {
+	if poolingDisabled.Load() || cap(img.Pix) > maxPooledBytes {
+		return
+	}
+	nrgbaPool.Put(img)
+}
+
+var alphaPool sync.Pool // *image.Alpha
+
+// getAlpha возвращает пустую маску размера r
+func getAlpha(r image.Rectangle) *image.Alpha {
+	n := r.Dx() * r.Dy()
+	if !poolingDisabled.Load() {
+		if m, ok := alphaPool.Get().(*image.Alpha); ok && cap(m.Pix) >= n {
+			pix := m.Pix[:n]
+			clear(pix)
+			return &image.Alpha{Pix: pix, Stride: r.Dx(), Rect: r}
+		}
+	}
+	return image.NewAlpha(r)
+}
+
+func putAlpha(m *image.Alpha) {
+	if poolingDisabled.Load() || cap(m.Pix) > maxPooledBytes {
+		return
+	}
+	alphaPool.Put(m)
+}
@@ -0,0 +1,237 @@
+package meme
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/math/fixed"
+)
+
+// Post - публикация в соцсети для PostCard
+type Post struct {
+	DisplayName string
+	Handle      string      // без "@": "@" добавляется при отрисовке
+	Avatar      image.Image // nil - круг с первой буквой DisplayName
+	Verified    bool        // галочка после имени
+
+	Text      string
+	Image     image.Image // картинка под текстом; nil - без картинки
+	Timestamp string      // например "3:14 PM · Mar 14, 2026"; пусто - без строки времени
+
+	Replies, Reposts, Likes int
+}
+
+// PostOptions задает оформление карточки публикации
+type PostOptions struct {
+	Width    int     // ширина карточки, 0 - 720
+	FontSize float64 // размер текста публикации, 0 - 30
+	Dark     bool    // темная тема
+
+	// Framed оборачивает карточку в рамку демотиватора
+	// с подписями TopText и BottomText конфигурации
+	Framed bool
+}
+
+// Значения PostOptions по умолчанию
+const (
+	defaultPostWidth    = 720
+	defaultPostFontSize = 30
+
+	postSmallRatio = 0.8 // размер имени, времени и счетчиков от размера текста
+)
+
+// postPalette - цвета карточки
+type postPalette struct {
+	background, text, hint, line color.Color
+}
+
+var (
+	postLight    = postPalette{color.White, color.RGBA{0x0f, 0x14, 0x19, 0xff}, color.RGBA{0x53, 0x64, 0x71, 0xff}, color.RGBA{0xef, 0xf3, 0xf4, 0xff}}
+	postDark     = postPalette{color.Black, color.RGBA{0xe7, 0xe9, 0xea, 0xff}, color.RGBA{0x71, 0x76, 0x7b, 0xff}, color.RGBA{0x2f, 0x33, 0x36, 0xff}}
+	postVerified = color.RGBA{0x1d, 0x9b, 0xf0, 0xff}
+)
+
+// PostCard рисует карточку публикации: аватар, имя и @handle, текст,
+// картинку, время и счетчики ответов, репостов и лайков. Текст
+// переносится по ширине карточки. Шрифт берется из конфигурации; если он
+// не задан, используются Go Regular и Go Bold для имени.
+func (g *Generator) PostCard(p Post, opts PostOptions) (out *image.RGBA, err error) {
+	defer g.observeGeneration(time.Now(), &err)
+	defer recoverPanic(&err)
+	cfg := g.config
+	if opts.Width < 0 || opts.FontSize < 0 || p.Replies < 0 || p.Reposts < 0 || p.Likes < 0 {
+		return nil, &ConfigError{Field: "PostOptions", Reason: "sizes and counts must not be negative"}
+	}
+	if n := utf8.RuneCountInString(p.Text); cfg.MaxTextLength > 0 && n > cfg.MaxTextLength {
+		return nil, fmt.Errorf("%w: post has %d characters, limit is %d", ErrTextTooLong, n, cfg.MaxTextLength)
+	}
+	if p.Image != nil {
+		if err := g.validateInput(p.Image); err != nil {
+			return nil, err
+		}
+	}
+
+	width := opts.Width
+	if width == 0 {
+		width = defaultPostWidth
+	}
+	size := opts.FontSize
+	if size == 0 {
+		size = defaultPostFontSize
+	}
+	pal := postLight
+	if opts.Dark {
+		pal = postDark
+	}
+
+	done := g.stage(StageFontLoad)
+	fontPath, regular, bold := cfg.FontPath, cfg.FontData, cfg.FontData
+	if fontPath == "" && len(regular) == 0 {
+		regular, bold = goregular.TTF, gobold.TTF
+	}
+	var faces [3]font.Face
+	for i, f := range []struct {
+		data []byte
+		size float64
+	}{{regular, size}, {regular, size * postSmallRatio}, {bold, size * postSmallRatio}} {
+		if faces[i], err = g.loadFontFrom(fontPath, f.data, f.size); err != nil {
+			done()
+			return nil, err
+		}
+		defer faces[i].Close()
+	}
+	done()
+	face, small, name := faces[0], faces[1], faces[2]
+
+	// Геометрия: шапка с аватаром, текст, картинка, время, счетчики
+	done = g.stage(StageLayout)
+	tr := g.textRenderer()
+	unit := int(math.Round(size))
+	margin, gap := unit*4/5, unit/2
+	avatarD := unit * 2
+	lineH := face.Metrics().Height.Ceil()
+	smallH := small.Metrics().Height.Ceil()
+	inner := width - 2*margin
+	lines := wrapText(tr, face, p.Text, inner)
+	if p.Text == "" {
+		lines = nil
+	}
+
+	y := margin + avatarD + gap
+	textTop := y
+	y += len(lines) * lineH
+	var picture image.Rectangle
+	if p.Image != nil {
+		b := p.Image.Bounds()
+		h := min(max(b.Dy()*inner/max(b.Dx(), 1), 1), inner)
+		picture = image.Rect(margin, y+gap, margin+inner, y+gap+h)
+		y = picture.Max.Y
+	}
+	timeTop := y + gap
+	if p.Timestamp != "" {
+		y = timeTop + smallH
+	}
+	countsTop := y + gap
+	y = countsTop + gap + smallH
+	canvas := image.Rect(0, 0, width, y+margin)
+	done()
+	if err := checkCanvas(canvas); err != nil {
+		return nil, err
+	}
+
+	defer g.stage(StageOutline)()
+	out = image.NewRGBA(canvas)
+	draw.Draw(out, canvas, image.NewUniform(pal.background), image.Point{}, draw.Src)
+	style := func(c color.Color) TextStyle { return TextStyle{Color: c, Linear: cfg.LinearBlending} }
+
+	g.drawAvatar(out, image.Rect(margin, margin, margin+avatarD, margin+avatarD), ChatMessage{Sender: p.DisplayName, Avatar: p.Avatar}, name)
+	// Имя и @handle - двумя строками по центру аватара
+	x := margin + avatarD + gap
+	headTop := margin + (avatarD-2*smallH)/2
+	nameY := headTop + name.Metrics().Ascent.Ceil()
+	tr.Draw(out, name, p.DisplayName, fixed.P(x, nameY), style(pal.text))
+	if p.Verified {
+		d := smallH * 3 / 4
+		nx := x + tr.Measure(name, p.DisplayName).Ceil() + unit/5
+		badge := image.Rect(nx, headTop+(smallH-d)/2, nx+d, headTop+(smallH+d)/2)
+		fillRounded(out, badge, d/2, postVerified)
+		drawCheck(out, badge.Inset(d/4), max(d/10, 1))
+	}
+	if p.Handle != "" {
+		handle := "@" + strings.TrimPrefix(p.Handle, "@")
+		tr.Draw(out, small, handle, fixed.P(x, headTop+smallH+small.Metrics().Ascent.Ceil()), style(pal.hint))
+	}
+
+	for i, l := range lines {
+		tr.Draw(out, face, l, fixed.P(margin, textTop+i*lineH+face.Metrics().Ascent.Ceil()), style(pal.text))
+	}
+	if p.Image != nil {
+		tmp := image.NewRGBA(image.Rect(0, 0, picture.Dx(), picture.Dy()))
+		drawCover(tmp, tmp.Bounds(), p.Image)
+		draw.DrawMask(out, picture, tmp, image.Point{}, roundedMask(picture.Dx(), picture.Dy(), unit/2), image.Point{}, draw.Over)
+	}
+	if p.Timestamp != "" {
+		tr.Draw(out, small, p.Timestamp, fixed.P(margin, timeTop+small.Metrics().Ascent.Ceil()), style(pal.hint))
+	}
+
+	// Счетчики под разделителем: число жирным, подпись серым
+	draw.Draw(out, image.Rect(margin, countsTop, width-margin, countsTop+1), image.NewUniform(pal.line), image.Point{}, draw.Src)
+	cx := margin
+	baseline := countsTop + gap + small.Metrics().Ascent.Ceil()
+	for _, c := range []struct {
+		n     int
+		label string
+	}{{p.Replies, "Replies"}, {p.Reposts, "Reposts"}, {p.Likes, "Likes"}} {
+		s := formatCount(c.n)
+		tr.Draw(out, name, s, fixed.P(cx, baseline), style(pal.text))
+		cx += tr.Measure(name, s+" ").Ceil()
+		tr.Draw(out, small, c.label, fixed.P(cx, baseline), style(pal.hint))
+		cx += tr.Measure(small, c.label).Ceil() + unit
+	}
+
+	if opts.Framed {
+		return g.generateInto(nil, out)
+	}
+	return out, nil
+}
+
+// formatCount сокращает число как в соцсетях: 999, 1.2K, 3.4M
+func formatCount(n int) string {
+	short := func(v float64, suffix string) string {
+		s := strconv.FormatFloat(math.Floor(v*10)/10, 'f', 1, 64)
+		return strings.TrimSuffix(s, ".0") + suffix
+	}
+	switch {
+	case n < 1000:
+		return strconv.Itoa(n)
+	case n < 1000000:
+		return short(float64(n)/1e3, "K")
+	default:
+		return short(float64(n)/1e6, "M")
+	}
+}
+
+// drawCheck рисует белую галочку толщины t в квадрате r
+func drawCheck(dst draw.Image, r image.Rectangle, t int) {
+	white := image.NewUniform(color.White)
+	w := r.Dx()
+	// Короткий штрих вниз-вправо от левой трети, длинный - вверх-вправо
+	for i := range w / 3 {
+		p := image.Pt(r.Min.X+i, r.Min.Y+w/2+i)
+		draw.Draw(dst, image.Rectangle{Min: p, Max: p.Add(image.Pt(t, t))}, white, image.Point{}, draw.Over)
+	}
+	for i := range w - w/3 {
+		p := image.Pt(r.Min.X+w/3+i, r.Min.Y+w/2+w/3-i)
+		draw.Draw(dst, image.Rectangle{Min: p, Max: p.Add(image.Pt(t, t))}, white, image.Point{}, draw.Over)
+	}
+}
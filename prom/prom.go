@@ -0,0 +1,100 @@
+// Package prom экспортирует метрики генератора мемов в Prometheus.
+//
+//	m := prom.New("meme")
+//	prometheus.MustRegister(m)
+//	generator.SetMetrics(m)
+package prom
+
+import (
+	"time"
+
+	"github.com/go-goblin/meme"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics реализует meme.Metrics и prometheus.Collector
+type Metrics struct {
+	generations *prometheus.CounterVec
+	failures    *prometheus.CounterVec
+	duration    prometheus.Histogram
+	outputBytes *prometheus.HistogramVec
+	fontCache   *prometheus.CounterVec
+}
+
+var _ meme.Metrics = (*Metrics)(nil)
+
+// New создает набор метрик с префиксом namespace
+func New(namespace string) *Metrics {
+	return &Metrics{
+		generations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "generations_total",
+			Help:      "Generations by result (ok, error).",
+		}, []string{"result"}),
+		failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "generation_failures_total",
+			Help:      "Failed generations by reason.",
+		}, []string{"reason"}),
+		duration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "render_duration_seconds",
+			Help:      "Generation duration.",
+			Buckets:   prometheus.ExponentialBuckets(0.005, 2, 12),
+		}),
+		outputBytes: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "output_bytes",
+			Help:      "Encoded output size.",
+			Buckets:   prometheus.ExponentialBuckets(16<<10, 2, 10),
+		}, []string{"format"}),
+		fontCache: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "font_cache_lookups_total",
+			Help:      "Font cache lookups by result (hit, miss).",
+		}, []string{"result"}),
+	}
+}
+
+// ObserveGeneration учитывает генерацию и её длительность
+func (m *Metrics) ObserveGeneration(d time.Duration, err error) {
+	m.duration.Observe(d.Seconds())
+	if err != nil {
+		m.generations.WithLabelValues("error").Inc()
+		m.failures.WithLabelValues(meme.ErrorReason(err)).Inc()
+		return
+	}
+	m.generations.WithLabelValues("ok").Inc()
+}
+
+// ObserveOutput учитывает размер результата
+func (m *Metrics) ObserveOutput(format meme.Format, size int) {
+	m.outputBytes.WithLabelValues(string(format)).Observe(float64(size))
+}
+
+// ObserveFontCache учитывает обращение к кешу шрифтов
+func (m *Metrics) ObserveFontCache(hit bool) {
+	if hit {
+		m.fontCache.WithLabelValues("hit").Inc()
+	} else {
+		m.fontCache.WithLabelValues("miss").Inc()
+	}
+}
+
+// Describe реализует prometheus.Collector
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.generations.Describe(ch)
+	m.failures.Describe(ch)
+	m.duration.Describe(ch)
+	m.outputBytes.Describe(ch)
+	m.fontCache.Describe(ch)
+}
+
+// Collect реализует prometheus.Collector
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.generations.Collect(ch)
+	m.failures.Collect(ch)
+	m.duration.Collect(ch)
+	m.outputBytes.Collect(ch)
+	m.fontCache.Collect(ch)
+}
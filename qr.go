@@ -0,0 +1,506 @@
+package meme
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// QRLevel - уровень коррекции ошибок QR-кода: чем выше, тем больше кода
+// можно закрыть или испортить, но тем крупнее сам код
+type QRLevel int
+
+const (
+	QRMedium   QRLevel = iota // M: восстанавливается ~15% кода
+	QRLow                     // L: ~7%
+	QRQuartile                // Q: ~25%
+	QRHigh                    // H: ~30%
+)
+
+// ErrQRTooLong - данные не помещаются даже в QR-код версии 40
+var ErrQRTooLong = errors.New("qr payload is too long")
+
+// QRCode - QR-код со ссылкой в углу, например для мемов рекламной
+// кампании. Рисуется как Widget: угол выбирается внутри переданного
+// прямоугольника. Данные кодируются в байтовом режиме (UTF-8).
+type QRCode struct {
+	Payload string
+	Level   QRLevel
+	Corner  Corner
+	Size    int // сторона вместе с белым полем, 0 - 1/4 меньшей стороны области
+
+	Foreground color.Color // nil - черный
+	Background color.Color // nil - белый
+}
+
+var _ Widget = QRCode{}
+
+func (q QRCode) drawWidget(g *Generator, dst draw.Image, r image.Rectangle) error {
+	if q.Level < QRMedium || q.Level > QRHigh {
+		return &ConfigError{Field: "QRCode.Level", Reason: "unknown level"}
+	}
+	if q.Size < 0 {
+		return &ConfigError{Field: "QRCode.Size", Reason: "must not be negative"}
+	}
+	if q.Payload == "" {
+		return &ConfigError{Field: "QRCode.Payload", Reason: "must not be empty"}
+	}
+	modules, err := encodeQR([]byte(q.Payload), q.Level)
+	if err != nil {
+		return err
+	}
+	size := q.Size
+	if size == 0 {
+		size = min(r.Dx(), r.Dy()) / 4
+	}
+	// Модуль - целое число пикселей, иначе сканеры ошибаются на краях;
+	// белое поле - 4 модуля с каждой стороны, как требует стандарт
+	n := len(modules) + 2*qrQuietZone
+	px := max(size/n, 1)
+	box := q.Corner.place(r, image.Pt(n*px, n*px), max(size/16, 2))
+	draw.Draw(dst, box, image.NewUniform(colorOr(q.Background, color.White)), image.Point{}, draw.Src)
+	fg := image.NewUniform(colorOr(q.Foreground, color.Black))
+	origin := box.Min.Add(image.Pt(qrQuietZone*px, qrQuietZone*px))
+	for y, row := range modules {
+		for x, dark := range row {
+			if dark {
+				m := image.Rect(0, 0, px, px).Add(origin.Add(image.Pt(x*px, y*px)))
+				draw.Draw(dst, m, fg, image.Point{}, draw.Src)
+			}
+		}
+	}
+	return nil
+}
+
+// Ширина белого поля вокруг кода в модулях
+const qrQuietZone = 4
+
+// Таблицы ISO/IEC 18004 по уровням L, M, Q, H и версиям 1-40 (индекс 0 не
+// используется): байт коррекции на блок и число блоков
+var (
+	qrECCPerBlock = [4][41]int{
+		{-1, 7, 10, 15, 20, 26, 18, 20, 24, 30, 18, 20, 24, 26, 30, 22, 24, 28, 30, 28, 28, 28, 28, 30, 30, 26, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+		{-1, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26, 26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28},
+		{-1, 13, 22, 18, 26, 18, 24, 18, 22, 20, 24, 28, 26, 24, 20, 30, 24, 28, 28, 26, 30, 28, 30, 30, 30, 30, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+		{-1, 17, 28, 22, 16, 22, 28, 26, 26, 24, 28, 24, 28, 22, 24, 24, 30, 28, 28, 26, 28, 30, 24, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	}
+	qrBlocks = [4][41]int{
+		{-1, 1, 1, 1, 1, 1, 2, 2, 2, 2, 4, 4, 4, 4, 4, 6, 6, 6, 6, 7, 8, 8, 9, 9, 10, 12, 12, 12, 13, 14, 15, 16, 17, 18, 19, 19, 20, 21, 22, 24, 25},
+		{-1, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16, 17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49},
+		{-1, 1, 1, 2, 2, 4, 4, 6, 6, 8, 8, 8, 10, 12, 16, 12, 17, 16, 18, 21, 20, 23, 23, 25, 27, 29, 34, 34, 35, 38, 40, 43, 45, 48, 51, 53, 56, 59, 62, 65, 68},
+		{-1, 1, 1, 2, 4, 4, 4, 5, 6, 8, 8, 11, 11, 16, 16, 18, 16, 19, 21, 25, 25, 25, 34, 30, 32, 35, 37, 40, 42, 45, 48, 51, 54, 57, 60, 63, 66, 70, 74, 77, 81},
+	}
+)
+
+// qrOrdinal - индекс уровня в таблицах, qrFormat - его биты в формате кода
+func (l QRLevel) qrOrdinal() int {
+	return [...]int{QRLow: 0, QRMedium: 1, QRQuartile: 2, QRHigh: 3}[l]
+}
+
+func (l QRLevel) qrFormat() int {
+	return [...]int{QRLow: 1, QRMedium: 0, QRQuartile: 3, QRHigh: 2}[l]
+}
+
+// qrRawModules - число модулей версии ver под данные и коррекцию
+func qrRawModules(ver int) int {
+	n := (16*ver+128)*ver + 64
+	if ver >= 2 {
+		align := ver/7 + 2
+		n -= (25*align-10)*align - 55
+		if ver >= 7 {
+			n -= 36
+		}
+	}
+	return n
+}
+
+// qrDataCodewords - вместимость версии ver в байтах данных
+func qrDataCodewords(ver int, level QRLevel) int {
+	o := level.qrOrdinal()
+	return qrRawModules(ver)/8 - qrECCPerBlock[o][ver]*qrBlocks[o][ver]
+}
+
+// encodeQR возвращает модули (true - темный) QR-кода наименьшей версии,
+// в которую помещается data
+func encodeQR(data []byte, level QRLevel) ([][]bool, error) {
+	ver := 0
+	for v := 1; v <= 40; v++ {
+		countBits := 8
+		if v >= 10 {
+			countBits = 16
+		}
+		if 4+countBits+8*len(data) <= qrDataCodewords(v, level)*8 {
+			ver = v
+			break
+		}
+	}
+	if ver == 0 {
+		return nil, fmt.Errorf("%w: %d bytes", ErrQRTooLong, len(data))
+	}
+
+	// Байтовый режим: 0100, длина, данные, терминатор и заполнитель
+	var bits qrBits
+	bits.append(0b0100, 4)
+	if ver >= 10 {
+		bits.append(len(data), 16)
+	} else {
+		bits.append(len(data), 8)
+	}
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+	capacity := qrDataCodewords(ver, level) * 8
+	bits.append(0, min(4, capacity-len(bits)))
+	bits.append(0, (8-len(bits)%8)%8)
+	for pad := 0xec; len(bits) < capacity; pad ^= 0xec ^ 0x11 {
+		bits.append(pad, 8)
+	}
+	codewords := make([]byte, len(bits)/8)
+	for i, b := range bits {
+		if b {
+			codewords[i>>3] |= 1 << (7 - i&7)
+		}
+	}
+
+	q := newQRMatrix(ver)
+	q.drawCodewords(qrInterleave(codewords, ver, level))
+
+	// Маска с наименьшим штрафом по правилам стандарта
+	best, bestPenalty := 0, -1
+	for mask := range 8 {
+		q.applyMask(mask)
+		q.drawFormat(level, mask)
+		if p := q.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		q.applyMask(mask)
+	}
+	q.applyMask(best)
+	q.drawFormat(level, best)
+	return q.modules, nil
+}
+
+// qrBits - битовый буфер, старшие биты первыми
+type qrBits []bool
+
+func (b *qrBits) append(v, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*b = append(*b, v>>i&1 != 0)
+	}
+}
+
+// qrInterleave делит данные на блоки, добавляет к каждому коды Рида-Соломона
+// и перемежает блоки побайтно
+func qrInterleave(data []byte, ver int, level QRLevel) []byte {
+	o := level.qrOrdinal()
+	numBlocks, eccLen := qrBlocks[o][ver], qrECCPerBlock[o][ver]
+	raw := qrRawModules(ver) / 8
+	numShort := numBlocks - raw%numBlocks
+	shortLen := raw / numBlocks
+
+	divisor := rsDivisor(eccLen)
+	blocks := make([][]byte, numBlocks)
+	k := 0
+	for i := range blocks {
+		n := shortLen - eccLen
+		if i >= numShort {
+			n++
+		}
+		dat := data[k : k+n]
+		k += n
+		block := append([]byte(nil), dat...)
+		if i < numShort {
+			// Короткие блоки выравниваются пропуском для перемежения
+			block = append(block, 0)
+		}
+		blocks[i] = append(block, rsRemainder(dat, divisor)...)
+	}
+	out := make([]byte, 0, raw)
+	for i := range blocks[0] {
+		for j, b := range blocks {
+			if i != shortLen-eccLen || j >= numShort {
+				out = append(out, b[i])
+			}
+		}
+	}
+	return out
+}
+
+// gfMul - умножение в GF(2^8) по модулю x^8+x^4+x^3+x^2+1
+func gfMul(x, y byte) byte {
+	var z int
+	for i := 7; i >= 0; i-- {
+		z = z<<1 ^ (z>>7)*0x11d
+		z ^= int(y>>i&1) * int(x)
+	}
+	return byte(z)
+}
+
+// rsDivisor - порождающий многочлен кода Рида-Соломона степени degree без
+// старшего коэффициента
+func rsDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for range degree {
+		for j := range result {
+			result[j] = gfMul(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMul(root, 2)
+	}
+	return result
+}
+
+// rsRemainder - байты коррекции для data
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, d := range divisor {
+			result[i] ^= gfMul(d, factor)
+		}
+	}
+	return result
+}
+
+// qrMatrix - модули кода и отметка служебных (не данных) модулей
+type qrMatrix struct {
+	ver      int
+	size     int
+	modules  [][]bool
+	function [][]bool
+}
+
+// newQRMatrix рисует служебные узоры версии ver: поисковые квадраты,
+// синхронизацию, выравнивание и номер версии
+func newQRMatrix(ver int) *qrMatrix {
+	size := ver*4 + 17
+	q := &qrMatrix{ver: ver, size: size, modules: make([][]bool, size), function: make([][]bool, size)}
+	for i := range size {
+		q.modules[i] = make([]bool, size)
+		q.function[i] = make([]bool, size)
+	}
+	for i := range size {
+		q.set(6, i, i%2 == 0)
+		q.set(i, 6, i%2 == 0)
+	}
+	for _, c := range [][2]int{{3, 3}, {size - 4, 3}, {3, size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := c[0]+dx, c[1]+dy
+				if x >= 0 && x < size && y >= 0 && y < size {
+					d := max(abs(dx), abs(dy))
+					q.set(x, y, d != 2 && d != 4)
+				}
+			}
+		}
+	}
+	pos := qrAlignment(ver)
+	for i, y := range pos {
+		for j, x := range pos {
+			// Углы с поисковыми квадратами пропускаются
+			if (i == 0 && j == 0) || (i == 0 && j == len(pos)-1) || (i == len(pos)-1 && j == 0) {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					q.set(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+	// Область формата резервируется до выбора маски
+	q.drawFormat(QRMedium, 0)
+	if ver >= 7 {
+		rem := ver
+		for range 12 {
+			rem = rem<<1 ^ (rem>>11)*0x1f25
+		}
+		bits := ver<<12 | rem
+		for i := range 18 {
+			a, b := size-11+i%3, i/3
+			q.set(a, b, bits>>i&1 != 0)
+			q.set(b, a, bits>>i&1 != 0)
+		}
+	}
+	return q
+}
+
+// qrAlignment - координаты центров узоров выравнивания
+func qrAlignment(ver int) []int {
+	if ver == 1 {
+		return nil
+	}
+	n := ver/7 + 2
+	step := (ver*8 + n*3 + 5) / (n*4 - 4) * 2
+	pos := make([]int, n)
+	pos[0] = 6
+	for i := n - 1; i >= 1; i-- {
+		pos[i] = ver*4 + 17 - 7 - (n-1-i)*step
+	}
+	return pos
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// set задает служебный модуль в столбце x строки y
+func (q *qrMatrix) set(x, y int, dark bool) {
+	q.modules[y][x] = dark
+	q.function[y][x] = true
+}
+
+// drawFormat записывает уровень коррекции и маску в обе копии формата
+func (q *qrMatrix) drawFormat(level QRLevel, mask int) {
+	data := level.qrFormat()<<3 | mask
+	rem := data
+	for range 10 {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return bits>>i&1 != 0 }
+	for i := range 6 {
+		q.set(8, i, bit(i))
+	}
+	q.set(8, 7, bit(6))
+	q.set(8, 8, bit(7))
+	q.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		q.set(14-i, 8, bit(i))
+	}
+	for i := range 8 {
+		q.set(q.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		q.set(8, q.size-15+i, bit(i))
+	}
+	q.set(8, q.size-8, true)
+}
+
+// drawCodewords раскладывает байты зигзагом по столбцам пар справа налево
+func (q *qrMatrix) drawCodewords(data []byte) {
+	i := 0
+	for right := q.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := range q.size {
+			for j := range 2 {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = q.size - 1 - vert
+				}
+				if !q.function[y][x] && i < len(data)*8 {
+					q.modules[y][x] = data[i>>3]>>(7-i&7)&1 != 0
+					i++
+				}
+			}
+		}
+	}
+}
+
+// applyMask инвертирует модули данных по маске; повторный вызов отменяет ее
+func (q *qrMatrix) applyMask(mask int) {
+	for y := range q.size {
+		for x := range q.size {
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert && !q.function[y][x] {
+				q.modules[y][x] = !q.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty - штраф раскладки: длинные серии, квадраты 2x2, узоры, похожие
+// на поисковые, и перекос темных и светлых модулей
+func (q *qrMatrix) penalty() int {
+	n := q.size
+	at := func(x, y int, transpose bool) bool {
+		if transpose {
+			return q.modules[x][y]
+		}
+		return q.modules[y][x]
+	}
+	score := 0
+	finder := [2][11]bool{
+		{true, false, true, true, true, false, true, false, false, false, false},
+		{false, false, false, false, true, false, true, true, true, false, true},
+	}
+	for _, transpose := range []bool{false, true} {
+		for y := range n {
+			run := 1
+			for x := 1; x <= n; x++ {
+				if x < n && at(x, y, transpose) == at(x-1, y, transpose) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					score += run - 2
+				}
+				run = 1
+			}
+			for x := 0; x+11 <= n; x++ {
+				for _, p := range finder {
+					match := true
+					for k, v := range p {
+						if at(x+k, y, transpose) != v {
+							match = false
+							break
+						}
+					}
+					if match {
+						score += 40
+					}
+				}
+			}
+		}
+	}
+	dark := 0
+	for y := range n {
+		for x := range n {
+			if q.modules[y][x] {
+				dark++
+			}
+			if x+1 < n && y+1 < n {
+				c := q.modules[y][x]
+				if c == q.modules[y][x+1] && c == q.modules[y+1][x] && c == q.modules[y+1][x+1] {
+					score += 3
+				}
+			}
+		}
+	}
+	// Каждые 5% отклонения от половины темных модулей - 10 очков
+	total := n * n
+	k := (abs(dark*20-total*10) + total - 1) / total
+	return score + (k-1)*10
+}
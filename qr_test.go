@@ -0,0 +1,467 @@
+package meme
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Строки форматов из таблицы ISO/IEC 18004 (приложение C), уже с маской
+// 101010000010010: уровень -> маска -> 15 бит
+var qrFormatTable = map[QRLevel][8]int{
+	QRLow:      {0b111011111000100, 0b111001011110011, 0b111110110101010, 0b111100010011101, 0b110011000101111, 0b110001100011000, 0b110110001000001, 0b110100101110110},
+	QRMedium:   {0b101010000010010, 0b101000100100101, 0b101111001111100, 0b101101101001011, 0b100010111111001, 0b100000011001110, 0b100111110010111, 0b100101010100000},
+	QRQuartile: {0b011010101011111, 0b011000001101000, 0b011111100110001, 0b011101000000110, 0b010010010110100, 0b010000110000011, 0b010111011011010, 0b010101111101101},
+	QRHigh:     {0b001011010001001, 0b001001110111110, 0b001110011100111, 0b001100111010000, 0b000011101100010, 0b000001001010101, 0b000110100001100, 0b000100000111011},
+}
+
+// Номера версий 7 и старше с кодом BCH(18,6) из приложения D
+var qrVersionTable = map[int]int{7: 0x07c94, 10: 0x0a4d3, 40: 0x28c69}
+
+// Центры узоров выравнивания из приложения E
+var qrAlignmentTable = map[int][]int{
+	1:  nil,
+	2:  {6, 18},
+	5:  {6, 30},
+	7:  {6, 22, 38},
+	10: {6, 28, 50},
+	40: {6, 30, 58, 86, 114, 142, 170},
+}
+
+// qrBlockLayout - блоки по таблице 9 стандарта: размеры данных в блоках
+// и байт коррекции на блок
+type qrBlockLayout struct {
+	data []int
+	ecc  int
+}
+
+// Примеры из ISO/IEC 18004 (приложение I) и руководства thonky.com:
+// данные версии 1-M и их байты коррекции
+var qrRSVectors = []struct {
+	data, ecc []byte
+}{
+	{
+		[]byte{16, 32, 12, 86, 97, 128, 236, 17, 236, 17, 236, 17, 236, 17, 236, 17},
+		[]byte{165, 36, 212, 193, 237, 54, 199, 135, 44, 85},
+	},
+	{
+		[]byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17},
+		[]byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23},
+	},
+}
+
+func TestQRReedSolomon(t *testing.T) {
+	for _, v := range qrRSVectors {
+		if got := rsRemainder(v.data, rsDivisor(len(v.ecc))); !bytes.Equal(got, v.ecc) {
+			t.Errorf("ecc of %v = %v, want %v", v.data, got, v.ecc)
+		}
+	}
+}
+
+func TestQRTables(t *testing.T) {
+	for ver, want := range qrAlignmentTable {
+		if got := qrAlignment(ver); !slices.Equal(got, want) {
+			t.Errorf("version %d alignment %v, want %v", ver, got, want)
+		}
+	}
+	// Вместимость в байтах данных из таблицы 7 стандарта
+	for _, tc := range []struct {
+		ver   int
+		level QRLevel
+		want  int
+	}{
+		{1, QRLow, 19}, {1, QRMedium, 16}, {1, QRQuartile, 13}, {1, QRHigh, 9},
+		{5, QRQuartile, 62}, {7, QRHigh, 66}, {10, QRLow, 274},
+		{40, QRLow, 2956}, {40, QRHigh, 1276},
+	} {
+		if got := qrDataCodewords(tc.ver, tc.level); got != tc.want {
+			t.Errorf("version %d level %d: %d data codewords, want %d", tc.ver, tc.level, got, tc.want)
+		}
+	}
+}
+
+// Эталонные матрицы: закреплены после проверки qrDecode
+var qrGolden = map[string][]string{
+	"1-M HELLO WORLD": {
+		"#######.##..#.#######",
+		"#.....#....#..#.....#",
+		"#.###.#..#.#..#.###.#",
+		"#.###.#.#..#..#.###.#",
+		"#.###.#.###.#.#.###.#",
+		"#.....#.#..#..#.....#",
+		"#######.#.#.#.#######",
+		"........#..##........",
+		"#...#.######.#####..#",
+		"...#....#.###....####",
+		"..######..##.##.#..#.",
+		"#####...##...#.......",
+		"#####.#.#.#.#.##..##.",
+		"........#.#.####.#.##",
+		"#######.###.#.#.##.#.",
+		"#.....#..#.###.##..##",
+		"#.###.#.##.#.##...##.",
+		"#.###.#..#..#...##.##",
+		"#.###.#..###...###...",
+		"#.....#....#.#.......",
+		"#######.#########.#.#",
+	},
+	"1-H": {
+		"#######.#..##.#######",
+		"#.....#...#...#.....#",
+		"#.###.#..####.#.###.#",
+		"#.###.#.#.#.#.#.###.#",
+		"#.###.#..####.#.###.#",
+		"#.....#..####.#.....#",
+		"#######.#.#.#.#######",
+		".........#.##........",
+		"..#.###.#..#.#...#..#",
+		".###.#.#..#....#...##",
+		"..###.#.##.#.###.####",
+		"#..###..######.##....",
+		"##....#####.#.##...#.",
+		"........#...#.....###",
+		"#######....#.#..#####",
+		"#.....#.####..#....#.",
+		"#.###.#.##.##...#..##",
+		"#.###.#..#.##.##...#.",
+		"#.###.#.####..##.##.#",
+		"#.....#...#....###.#.",
+		"#######..###.#.#..###",
+	},
+}
+
+func qrString(modules [][]bool) []string {
+	rows := make([]string, len(modules))
+	for y, row := range modules {
+		var b strings.Builder
+		for _, dark := range row {
+			if dark {
+				b.WriteByte('#')
+			} else {
+				b.WriteByte('.')
+			}
+		}
+		rows[y] = b.String()
+	}
+	return rows
+}
+
+func TestEncodeQR(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		payload string
+		level   QRLevel
+		ver     int
+		blocks  qrBlockLayout
+	}{
+		{"1-M HELLO WORLD", "HELLO WORLD", QRMedium, 1, qrBlockLayout{[]int{16}, 10}},
+		{"1-H", "meme", QRHigh, 1, qrBlockLayout{[]int{9}, 17}},
+		{"5-Q", strings.Repeat("q", 50), QRQuartile, 5, qrBlockLayout{[]int{15, 15, 16, 16}, 18}},
+		{"7-H", strings.Repeat("h", 60), QRHigh, 7, qrBlockLayout{[]int{13, 13, 13, 13, 14}, 26}},
+		{"10-L", strings.Repeat("https://example.com/", 13)[:250], QRLow, 10, qrBlockLayout{[]int{68, 68, 69, 69}, 18}},
+		{"utf-8", "демотиватор", QRMedium, 2, qrBlockLayout{[]int{28}, 16}},
+	} {
+		modules, err := encodeQR([]byte(tc.payload), tc.level)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if size := tc.ver*4 + 17; len(modules) != size {
+			t.Errorf("%s: %d modules, want version %d (%d)", tc.name, len(modules), tc.ver, size)
+			continue
+		}
+		got, err := qrDecode(modules, tc.level, tc.blocks)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if string(got) != tc.payload {
+			t.Errorf("%s: decoded %q", tc.name, got)
+		}
+		if want, ok := qrGolden[tc.name]; ok {
+			if rows := qrString(modules); strings.Join(rows, "\n") != strings.Join(want, "\n") {
+				t.Errorf("%s: matrix differs from golden:\n%s", tc.name, strings.Join(rows, "\n"))
+			}
+		}
+	}
+}
+
+func TestEncodeQRTooLong(t *testing.T) {
+	// Байтовая вместимость версии 40: 2953 (L) и 1273 (H)
+	for _, tc := range []struct {
+		level  QRLevel
+		max    int
+		blocks qrBlockLayout
+	}{
+		{QRLow, 2953, qrBlockLayout{append(slices.Repeat([]int{118}, 19), slices.Repeat([]int{119}, 6)...), 30}},
+		{QRHigh, 1273, qrBlockLayout{append(slices.Repeat([]int{15}, 20), slices.Repeat([]int{16}, 61)...), 30}},
+	} {
+		payload := bytes.Repeat([]byte("meme"), tc.max/4+1)[:tc.max]
+		modules, err := encodeQR(payload, tc.level)
+		if err != nil || len(modules) != 177 {
+			t.Errorf("level %d, %d bytes: %d modules, %v", tc.level, tc.max, len(modules), err)
+			continue
+		}
+		if got, err := qrDecode(modules, tc.level, tc.blocks); err != nil || !bytes.Equal(got, payload) {
+			t.Errorf("level %d, %d bytes: decoded %d bytes, %v", tc.level, tc.max, len(got), err)
+		}
+		if _, err := encodeQR(make([]byte, tc.max+1), tc.level); !errors.Is(err, ErrQRTooLong) {
+			t.Errorf("level %d, %d bytes: %v, want ErrQRTooLong", tc.level, tc.max+1, err)
+		}
+	}
+
+	g := NewGenerator(DefaultConfig())
+	dst := image.NewRGBA(image.Rect(0, 0, 200, 200))
+	err := g.DrawWidget(dst, dst.Bounds(), QRCode{Payload: strings.Repeat("x", 3000), Level: QRLow})
+	if !errors.Is(err, ErrQRTooLong) {
+		t.Errorf("widget: %v, want ErrQRTooLong", err)
+	}
+}
+
+// qrDecode читает QR-код независимо от кодировщика: проверяет служебные
+// узоры и формат по таблицам стандарта, снимает маску, собирает блоки
+// layout, проверяет синдромы Рида-Соломона и разбирает байтовый режим
+func qrDecode(m [][]bool, level QRLevel, layout qrBlockLayout) ([]byte, error) {
+	size := len(m)
+	ver := (size - 17) / 4
+	at := func(x, y int) bool { return m[y][x] }
+
+	// Поисковые квадраты и синхронизация
+	for _, c := range [][2]int{{0, 0}, {size - 7, 0}, {0, size - 7}} {
+		for dy := range 7 {
+			for dx := range 7 {
+				d := max(abs(dx-3), abs(dy-3))
+				if at(c[0]+dx, c[1]+dy) != (d != 2) {
+					return nil, errors.New("bad finder pattern")
+				}
+			}
+		}
+	}
+	for i := 8; i < size-8; i++ {
+		if at(i, 6) != (i%2 == 0) || at(6, i) != (i%2 == 0) {
+			return nil, errors.New("bad timing pattern")
+		}
+	}
+	if !at(8, size-8) {
+		return nil, errors.New("no dark module")
+	}
+
+	// Формат: обе копии совпадают со строкой таблицы для уровня
+	var f1, f2 int
+	for i := range 15 {
+		var a, b bool
+		switch {
+		case i < 6:
+			a = at(8, i)
+		case i < 8:
+			a = at(8, i+1)
+		case i == 8:
+			a = at(7, 8)
+		default:
+			a = at(14-i, 8)
+		}
+		if i < 8 {
+			b = at(size-1-i, 8)
+		} else {
+			b = at(8, size-15+i)
+		}
+		if a {
+			f1 |= 1 << i
+		}
+		if b {
+			f2 |= 1 << i
+		}
+	}
+	if f1 != f2 {
+		return nil, errors.New("format copies differ")
+	}
+	mask := -1
+	for i, f := range qrFormatTable[level] {
+		if f == f1 {
+			mask = i
+		}
+	}
+	if mask < 0 {
+		return nil, errors.New("format is not in the table for the level")
+	}
+
+	// Номер версии
+	if ver >= 7 {
+		var v1, v2 int
+		for i := range 18 {
+			if at(size-11+i%3, i/3) {
+				v1 |= 1 << i
+			}
+			if at(i/3, size-11+i%3) {
+				v2 |= 1 << i
+			}
+		}
+		if want, ok := qrVersionTable[ver]; !ok || v1 != want || v2 != want {
+			return nil, errors.New("bad version information")
+		}
+	}
+
+	// Служебные модули по стандарту
+	function := func(x, y int) bool {
+		switch {
+		case x < 9 && y < 9, x >= size-8 && y < 9, x < 9 && y >= size-8:
+			return true
+		case x == 6 || y == 6:
+			return true
+		case ver >= 7 && (x >= size-11 && x < size-8 && y < 6 || y >= size-11 && y < size-8 && x < 6):
+			return true
+		}
+		pos := qrAlignmentTable[ver]
+		for i, cy := range pos {
+			for j, cx := range pos {
+				if (i == 0 && j == 0) || (i == 0 && j == len(pos)-1) || (i == len(pos)-1 && j == 0) {
+					continue
+				}
+				if abs(x-cx) <= 2 && abs(y-cy) <= 2 {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	masked := func(x, y int) bool {
+		i, j := y, x
+		switch mask {
+		case 0:
+			return (i+j)%2 == 0
+		case 1:
+			return i%2 == 0
+		case 2:
+			return j%3 == 0
+		case 3:
+			return (i+j)%3 == 0
+		case 4:
+			return (i/2+j/3)%2 == 0
+		case 5:
+			return i*j%2+i*j%3 == 0
+		case 6:
+			return (i*j%2+i*j%3)%2 == 0
+		default:
+			return ((i+j)%2+i*j%3)%2 == 0
+		}
+	}
+
+	// Чтение зигзагом: пары столбцов справа налево, столбец 6 пропускается
+	var bits []bool
+	upward := true
+	for right := size - 1; right > 0; right -= 2 {
+		if right == 6 {
+			right--
+		}
+		for k := range size {
+			y := k
+			if upward {
+				y = size - 1 - k
+			}
+			for _, x := range []int{right, right - 1} {
+				if !function(x, y) {
+					bits = append(bits, at(x, y) != masked(x, y))
+				}
+			}
+		}
+		upward = !upward
+	}
+	codewords := make([]byte, len(bits)/8)
+	for i := range codewords {
+		for _, b := range bits[i*8 : i*8+8] {
+			codewords[i] <<= 1
+			if b {
+				codewords[i] |= 1
+			}
+		}
+	}
+
+	// Обратное перемежение и синдромы
+	blocks := make([][]byte, len(layout.data))
+	k := 0
+	for i := 0; i < layout.data[len(layout.data)-1]; i++ {
+		for b, n := range layout.data {
+			if i < n {
+				blocks[b] = append(blocks[b], codewords[k])
+				k++
+			}
+		}
+	}
+	for range layout.ecc {
+		for b := range blocks {
+			blocks[b] = append(blocks[b], codewords[k])
+			k++
+		}
+	}
+	var data []byte
+	for b, block := range blocks {
+		if !qrSyndromesZero(block, layout.ecc) {
+			return nil, errors.New("reed-solomon syndromes are not zero")
+		}
+		data = append(data, block[:layout.data[b]]...)
+	}
+
+	// Байтовый режим
+	pos := 0
+	read := func(n int) int {
+		v := 0
+		for range n {
+			v = v<<1 | int(data[pos/8]>>(7-pos%8)&1)
+			pos++
+		}
+		return v
+	}
+	if read(4) != 0b0100 {
+		return nil, errors.New("not byte mode")
+	}
+	countBits := 8
+	if ver >= 10 {
+		countBits = 16
+	}
+	n := read(countBits)
+	if (pos+8*n+7)/8 > len(data) {
+		return nil, errors.New("length past the end")
+	}
+	out := make([]byte, n)
+	for i := range out {
+		out[i] = byte(read(8))
+	}
+	return out, nil
+}
+
+// qrSyndromesZero вычисляет многочлен блока в корнях a^0..a^(ecc-1)
+// порождающего многочлена по таблицам логарифмов GF(256)
+func qrSyndromesZero(block []byte, ecc int) bool {
+	var exp [512]byte
+	var log [256]int
+	x := 1
+	for i := range 255 {
+		exp[i], log[x] = byte(x), i
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11d
+		}
+	}
+	for i := 255; i < 512; i++ {
+		exp[i] = exp[i-255]
+	}
+	mul := func(a, b byte) byte {
+		if a == 0 || b == 0 {
+			return 0
+		}
+		return exp[log[a]+log[b]]
+	}
+	for r := range ecc {
+		var s byte
+		for _, c := range block {
+			s = mul(s, exp[r]) ^ c
+		}
+		if s != 0 {
+			return false
+		}
+	}
+	return true
+}
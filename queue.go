@@ -0,0 +1,173 @@
+package meme
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// ErrBusy - очередь генерации переполнена или задание не дождалось
+// свободного исполнителя; запрос стоит повторить позже
+var ErrBusy = errors.New("render queue is full")
+
+// QueueOptions задаёт размеры очереди генерации
+type QueueOptions struct {
+	Workers int // одновременных заданий, 0 - GOMAXPROCS
+
+	// Length - заданий, ожидающих исполнителя; сверх этого Do сразу
+	// возвращает ErrBusy. 0 - 4 на исполнителя, отрицательное - без
+	// ожидания.
+	Length int
+
+	// Deadline ограничивает задание вместе с ожиданием в очереди, 0 - без
+	// предела. Не дождавшееся исполнителя задание получает ErrBusy.
+	Deadline time.Duration
+}
+
+// Queue - ограниченная очередь генерации. Под нагрузкой она отклоняет
+// лишние задания с ErrBusy, а не накапливает их в памяти:
+//
+//	q := meme.NewQueue(&meme.QueueOptions{Workers: 4, Length: 16})
+//	data, err := meme.Submit(ctx, q, func(ctx context.Context) ([]byte, error) {
+//		return g.GenerateBytesContext(ctx, r, opts)
+//	}, nil)
+type Queue struct {
+	admit    chan struct{} // исполняемые и ожидающие задания
+	slots    chan struct{} // исполняемые задания
+	deadline time.Duration
+	waiting  atomic.Int64
+}
+
+// NewQueue создает очередь; nil - настройки по умолчанию
+func NewQueue(opts *QueueOptions) *Queue {
+	if opts == nil {
+		opts = &QueueOptions{}
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	length := opts.Length
+	switch {
+	case length == 0:
+		length = 4 * workers
+	case length < 0:
+		length = 0
+	}
+	return &Queue{
+		admit:    make(chan struct{}, workers+length),
+		slots:    make(chan struct{}, workers),
+		deadline: opts.Deadline,
+	}
+}
+
+// Do выполняет fn, когда освободится исполнитель. Ошибка fn возвращается
+// как есть; паника в fn возвращается как *PanicError. Если ctx отменен
+// или истек Deadline, пока fn работает, Do возвращает ошибку сразу, а
+// fn дорабатывает в фоне и занимает место в очереди до завершения: fn
+// должна следить за своим ctx и не трогать данные вызывающего после
+// отмены.
+func (q *Queue) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	select {
+	case q.admit <- struct{}{}:
+	default:
+		return fmt.Errorf("%w: %d jobs in progress", ErrBusy, cap(q.admit))
+	}
+	jobCtx := ctx
+	if q.deadline > 0 {
+		var cancel context.CancelFunc
+		jobCtx, cancel = context.WithTimeout(ctx, q.deadline)
+		defer cancel()
+	}
+
+	q.waiting.Add(1)
+	select {
+	case q.slots <- struct{}{}:
+		q.waiting.Add(-1)
+	case <-jobCtx.Done():
+		q.waiting.Add(-1)
+		<-q.admit
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: no worker within %v", ErrBusy, q.deadline)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		var err error
+		defer func() {
+			<-q.slots
+			<-q.admit
+			done <- err
+		}()
+		defer recoverPanic(&err)
+		err = fn(jobCtx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-jobCtx.Done():
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return fmt.Errorf("render exceeded deadline %v: %w", q.deadline, context.DeadlineExceeded)
+	}
+}
+
+// Submit выполняет fn через очередь q (nil - сразу) и возвращает её
+// результат. Результат передается через канал, поэтому fn, брошенная после
+// отмены ctx, ничего не пишет в переменные вызывающего. done, если задан,
+// вызывается ровно один раз - когда fn действительно завершилась или когда
+// точно не будет запущена, например чтобы вернуть место семафора не раньше,
+// чем закончится работа.
+func Submit[T any](ctx context.Context, q *Queue, fn func(ctx context.Context) (T, error), done func()) (T, error) {
+	type result struct {
+		value T
+		err   error
+	}
+	results := make(chan result, 1)
+	// 0 - fn не запущена, 1 - запущена, 2 - запуск отменен
+	var state atomic.Int32
+	job := func(ctx context.Context) error {
+		if !state.CompareAndSwap(0, 1) {
+			return ctx.Err()
+		}
+		if done != nil {
+			defer done()
+		}
+		v, err := fn(ctx)
+		results <- result{v, err}
+		return err
+	}
+
+	var err error
+	if q == nil {
+		err = job(ctx)
+	} else {
+		err = q.Do(ctx, job)
+	}
+	select {
+	case r := <-results:
+		return r.value, r.err
+	default:
+	}
+	if state.CompareAndSwap(0, 2) && done != nil {
+		done()
+	}
+	var zero T
+	return zero, err
+}
+
+// Waiting возвращает число заданий, ожидающих исполнителя
+func (q *Queue) Waiting() int {
+	return int(q.waiting.Load())
+}
+
+// Running возвращает число исполняемых заданий
+func (q *Queue) Running() int {
+	return len(q.slots)
+}
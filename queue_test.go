@@ -0,0 +1,176 @@
+package meme
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// hold занимает исполнителя очереди, пока не закрыт release
+func hold(q *Queue, release <-chan struct{}) <-chan error {
+	started := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- q.Do(context.Background(), func(context.Context) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+	return done
+}
+
+// waitFor ждет, пока cond не станет верным
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); !cond(); {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestQueueAdmission(t *testing.T) {
+	q := NewQueue(&QueueOptions{Workers: 1, Length: 1})
+	release := make(chan struct{})
+	running := hold(q, release)
+
+	// Одно задание ждет исполнителя, следующее отклоняется сразу
+	waiting := make(chan error, 1)
+	go func() {
+		waiting <- q.Do(context.Background(), func(context.Context) error { return nil })
+	}()
+	waitFor(t, "waiting job", func() bool { return q.Waiting() == 1 })
+	if q.Running() != 1 {
+		t.Errorf("Running = %d, want 1", q.Running())
+	}
+	ran := false
+	if err := q.Do(context.Background(), func(context.Context) error { ran = true; return nil }); !errors.Is(err, ErrBusy) {
+		t.Fatalf("Do on full queue = %v, want ErrBusy", err)
+	}
+	if ran {
+		t.Error("rejected job ran")
+	}
+
+	close(release)
+	if err := <-running; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-waiting; err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "empty queue", func() bool { return q.Running() == 0 && q.Waiting() == 0 })
+	if err := q.Do(context.Background(), func(context.Context) error { return nil }); err != nil {
+		t.Errorf("Do on drained queue: %v", err)
+	}
+}
+
+func TestQueueNoWaiting(t *testing.T) {
+	q := NewQueue(&QueueOptions{Workers: 1, Length: -1})
+	release := make(chan struct{})
+	defer close(release)
+	hold(q, release)
+	if err := q.Do(context.Background(), func(context.Context) error { return nil }); !errors.Is(err, ErrBusy) {
+		t.Fatalf("Do without waiting room = %v, want ErrBusy", err)
+	}
+}
+
+func TestQueueDeadline(t *testing.T) {
+	q := NewQueue(&QueueOptions{Workers: 1, Deadline: 20 * time.Millisecond})
+	release := make(chan struct{})
+	running := hold(q, release)
+
+	// Не дождалось исполнителя - ErrBusy, и место в очереди освобождается
+	if err := q.Do(context.Background(), func(context.Context) error { return nil }); !errors.Is(err, ErrBusy) {
+		t.Fatalf("Do waiting past deadline = %v, want ErrBusy", err)
+	}
+	if q.Waiting() != 0 {
+		t.Errorf("Waiting = %d after deadline", q.Waiting())
+	}
+	close(release)
+	<-running // hold без ctx дорабатывает до конца
+
+	// Долгое задание - DeadlineExceeded, а fn видит отмену своего ctx
+	canceled := make(chan struct{})
+	err := q.Do(context.Background(), func(ctx context.Context) error {
+		<-ctx.Done()
+		close(canceled)
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrBusy) {
+		t.Fatalf("slow job = %v, want DeadlineExceeded", err)
+	}
+	<-canceled
+	waitFor(t, "slot release", func() bool { return q.Running() == 0 })
+}
+
+func TestQueueCallerCancel(t *testing.T) {
+	q := NewQueue(&QueueOptions{Workers: 1})
+	release := make(chan struct{})
+	defer close(release)
+	hold(q, release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := q.Do(ctx, func(context.Context) error { return nil }); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Do with canceled ctx = %v, want context.Canceled", err)
+	}
+}
+
+func TestQueueErrors(t *testing.T) {
+	q := NewQueue(nil)
+	want := errors.New("boom")
+	if err := q.Do(context.Background(), func(context.Context) error { return want }); err != want {
+		t.Errorf("Do = %v, want fn error as is", err)
+	}
+	err := q.Do(context.Background(), func(context.Context) error { panic("render bug") })
+	var pe *PanicError
+	if !errors.As(err, &pe) {
+		t.Fatalf("Do after panic = %v, want *PanicError", err)
+	}
+	waitFor(t, "slot release", func() bool { return q.Running() == 0 })
+}
+
+func TestSubmitDone(t *testing.T) {
+	q := NewQueue(&QueueOptions{Workers: 1, Length: -1, Deadline: 20 * time.Millisecond})
+
+	// Результат приходит через Submit, done вызывается один раз
+	calls := 0
+	v, err := Submit(context.Background(), q, func(context.Context) (int, error) { return 42, nil }, func() { calls++ })
+	if v != 42 || err != nil || calls != 1 {
+		t.Fatalf("Submit = %d, %v, done called %d times", v, err, calls)
+	}
+
+	// После дедлайна done ждет настоящего завершения fn
+	finish := make(chan struct{})
+	done := make(chan struct{})
+	_, err = Submit(context.Background(), q, func(context.Context) (int, error) {
+		<-finish
+		return 1, nil
+	}, func() { close(done) })
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("slow job = %v, want DeadlineExceeded", err)
+	}
+	select {
+	case <-done:
+		t.Fatal("done called while fn is still running")
+	default:
+	}
+
+	// Отклоненное задание не запускается, а done вызывается сразу
+	rejected := false
+	ran := false
+	_, err = Submit(context.Background(), q, func(context.Context) (int, error) {
+		ran = true
+		return 0, nil
+	}, func() { rejected = true })
+	if !errors.Is(err, ErrBusy) || ran || !rejected {
+		t.Errorf("rejected job: err = %v, ran = %v, done = %v", err, ran, rejected)
+	}
+
+	close(finish)
+	<-done
+}
@@ -0,0 +1,125 @@
+package meme
+
+import (
+	"image"
+	"image/draw"
+	"strings"
+	"time"
+)
+
+// QuoteOptions задаёт оформление постера с цитатой
+type QuoteOptions struct {
+	Quote       string
+	Attribution string // автор; выводится в правом нижнем углу с тире
+
+	Darken float64 // доля затемнения фото 0..1; 0 - 0.5, отрицательное - без затемнения
+	Blur   int     // радиус размытия фото; 0 - 1/100 меньшей стороны, отрицательный - без размытия
+
+	QuoteSize       float64 // максимальный размер цитаты, 0 - 1/8 высоты
+	AttributionSize float64 // размер подписи автора, 0 - 1/24 высоты
+
+	Align     Align
+	Uppercase bool
+}
+
+// Значения QuoteOptions по умолчанию
+const (
+	defaultQuoteDarken = 0.5
+	quoteMarginRatio   = 0.08 // поля вокруг текста от меньшей стороны
+)
+
+// QuotePoster создает постер без рамки: фото размером с исходное
+// затемняется и размывается, поверх по центру крупно пишется цитата,
+// а в углу - автор. Цвета и шрифт берутся из конфигурации генератора;
+// TopText и BottomText не используются.
+func (g *Generator) QuotePoster(img image.Image, opts QuoteOptions) (out *image.RGBA, err error) {
+	defer g.observeGeneration(time.Now(), &err)
+	defer recoverPanic(&err)
+
+	// Цитату и автора проверяем теми же ограничениями, что и подписи
+	cfg := *g.config
+	cfg.TopText, cfg.BottomText = opts.Quote, opts.Attribution
+	dg := g.derive(&cfg)
+	if err := dg.validateInput(img); err != nil {
+		return nil, err
+	}
+	b := img.Bounds()
+	canvas := image.Rect(0, 0, b.Dx(), b.Dy())
+
+	done := dg.stage(StageComposite)
+	out = image.NewRGBA(canvas)
+	draw.Draw(out, canvas, image.NewUniform(cfg.BackgroundColor), image.Point{}, draw.Src)
+	drawSource(out, canvas, img, b.Min)
+
+	short := min(canvas.Dx(), canvas.Dy())
+	blur := opts.Blur
+	if blur == 0 {
+		blur = max(short/100, 1)
+	}
+	blurRGBA(out, blur)
+
+	darken := opts.Darken
+	if darken == 0 {
+		darken = defaultQuoteDarken
+	}
+	darkenRGBA(out, min(darken, 1))
+	done()
+
+	defer dg.stage(StageOutline)()
+	margin := int(float64(short) * quoteMarginRatio)
+	quoteSize := opts.QuoteSize
+	if quoteSize <= 0 {
+		quoteSize = float64(canvas.Dy()) / 8
+	}
+	attrSize := opts.AttributionSize
+	if attrSize <= 0 {
+		attrSize = float64(canvas.Dy()) / 24
+	}
+	attrH := 0
+	if opts.Attribution != "" {
+		attrH = int(attrSize * 2)
+	}
+
+	slot := TextSlot{
+		Name:         "quote",
+		Rect:         image.Rect(margin, margin, canvas.Dx()-margin, canvas.Dy()-margin-attrH),
+		FontSize:     quoteSize,
+		OutlineWidth: cfg.TextOutlineWidth,
+		Align:        opts.Align,
+		Uppercase:    opts.Uppercase,
+	}
+	if opts.Quote != "" {
+		if err := dg.drawSlotText(out, slot, opts.Quote); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.Attribution != "" {
+		attribution := opts.Attribution
+		if !strings.HasPrefix(attribution, "—") && !strings.HasPrefix(attribution, "-") {
+			attribution = "— " + attribution
+		}
+		slot.Name = "attribution"
+		slot.Rect = image.Rect(margin, canvas.Dy()-margin-attrH, canvas.Dx()-margin, canvas.Dy()-margin)
+		slot.FontSize = attrSize
+		slot.Align = AlignRight
+		slot.Uppercase = false
+		if err := dg.drawSlotText(out, slot, attribution); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// darkenRGBA умножает цвет на 1-amount, не меняя прозрачность
+func darkenRGBA(img *image.RGBA, amount float64) {
+	if amount <= 0 {
+		return
+	}
+	k := uint32((1 - amount) * 256)
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i+0] = uint8(uint32(img.Pix[i+0]) * k >> 8)
+		img.Pix[i+1] = uint8(uint32(img.Pix[i+1]) * k >> 8)
+		img.Pix[i+2] = uint8(uint32(img.Pix[i+2]) * k >> 8)
+	}
+}
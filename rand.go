@@ -0,0 +1,14 @@
+package meme
+
+import "math/rand/v2"
+
+// newRand возвращает источник случайности для одной генерации.
+// Эффекты должны брать числа только из него и в фиксированном порядке:
+// тогда в режиме Deterministic результат зависит лишь от Config.Seed.
+func (g *Generator) newRand() *rand.Rand {
+	if g.config.Deterministic {
+		seed := uint64(g.config.Seed)
+		return rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+	}
+	return rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+}
@@ -0,0 +1,284 @@
+package meme
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"time"
+)
+
+// CaptionDetection - найденные на фото старые подписи
+type CaptionDetection struct {
+	// Clean - область фото без полос с подписями и рамки; пустая - на
+	// фото ничего не найдено
+	Clean image.Rectangle
+
+	// Распознанный текст подписей; пусто - не распознан (например,
+	// детектор без OCR)
+	TopText, BottomText string
+}
+
+// CaptionDetector находит на входном фото уже нанесенные подписи: полосы
+// с текстом сверху и снизу или рамку демотиватора. Реализация с OCR
+// может также вернуть их текст. Должна быть безопасна для одновременного
+// использования.
+type CaptionDetector interface {
+	DetectCaptions(ctx context.Context, img image.Image) (CaptionDetection, error)
+}
+
+// HeuristicCaptionDetector - детектор по умолчанию без распознавания
+// текста. Ищет рамку демотиватора: однотонный фон по углам и тонкую
+// рамку другого цвета вокруг фото. Иначе ищет однотонные полосы сверху
+// и снизу: первая строка полосы целиком цвета фона, а в строках с
+// текстом этот цвет занимает не меньше половины ширины.
+type HeuristicCaptionDetector struct {
+	// Tolerance - допустимая разница цветов по сумме каналов (0-765) для
+	// сжатых JPEG с артефактами; 0 - 48
+	Tolerance int
+
+	// MinBand - наименьшая высота полосы в долях высоты фото; 0 - 0.05
+	MinBand float64
+}
+
+var _ CaptionDetector = HeuristicCaptionDetector{}
+
+// Значения HeuristicCaptionDetector по умолчанию
+const (
+	defaultCaptionTolerance = 48
+	defaultMinCaptionBand   = 0.05
+
+	maxCaptionBand   = 0.45 // полоса выше - скорее светлое небо или стена, чем подпись
+	captionBandShare = 0.5  // доля цвета фона в строке полосы с текстом
+
+	frameShare = 0.9 // доля пикселей цвета рамки на каждой ее стороне
+	frameGap   = 3   // наибольший разрыв рамки от артефактов сжатия
+)
+
+func (d HeuristicCaptionDetector) DetectCaptions(ctx context.Context, img image.Image) (CaptionDetection, error) {
+	tol := d.Tolerance
+	if tol == 0 {
+		tol = defaultCaptionTolerance
+	}
+	minBand := d.MinBand
+	if minBand == 0 {
+		minBand = defaultMinCaptionBand
+	}
+	if r, ok := detectFrame(img, tol); ok {
+		return CaptionDetection{Clean: r}, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return CaptionDetection{}, err
+	}
+	b := img.Bounds()
+	top := captionBand(img, b.Min.Y, 1, tol, minBand)
+	bottom := captionBand(img, b.Max.Y-1, -1, tol, minBand)
+	if top == 0 && bottom == 0 {
+		return CaptionDetection{}, nil
+	}
+	clean := image.Rect(b.Min.X, b.Min.Y+top, b.Max.X, b.Max.Y-bottom)
+	if clean.Dy() < b.Dy()/4 {
+		return CaptionDetection{}, nil
+	}
+	return CaptionDetection{Clean: clean}, nil
+}
+
+// captionBand возвращает высоту полосы с подписью, которая начинается со
+// строки y и идет в направлении dir (1 - вниз, -1 - вверх); 0 - полосы нет
+func captionBand(img image.Image, y, dir, tol int, minBand float64) int {
+	b := img.Bounds()
+	bg := nrgbaAt(img, b.Min.X, y)
+	if rowShare(img, y, b.Min.X, b.Max.X, bg, tol) < 0.98 {
+		return 0
+	}
+	limit := int(float64(b.Dy()) * maxCaptionBand)
+	n := 0
+	for ; n < limit; n++ {
+		if rowShare(img, y+dir*n, b.Min.X, b.Max.X, bg, tol) < captionBandShare {
+			break
+		}
+	}
+	if n == limit || n < int(float64(b.Dy())*minBand) {
+		return 0
+	}
+	return n
+}
+
+// DetectFrame проверяет, не демотиватор ли уже img: однотонный фон
+// (обычно черный) по углам и тонкая рамка другого цвета (обычно белая)
+// вокруг фото. Возвращает прямоугольник фото внутри рамки, например
+// чтобы не обрамлять присланный демотиватор второй раз:
+//
+//	if inner, ok := meme.DetectFrame(img); ok {
+//		img = img.(interface{ SubImage(image.Rectangle) image.Image }).SubImage(inner)
+//	}
+//
+// Сжатие JPEG допускается; мелкие (меньше 16px) изображения и рамки
+// толще 1/20 ширины не распознаются.
+func DetectFrame(img image.Image) (image.Rectangle, bool) {
+	if img == nil || img.Bounds().Empty() {
+		return image.Rectangle{}, false
+	}
+	return detectFrame(img, defaultCaptionTolerance)
+}
+
+// detectFrame - DetectFrame с допуском цвета tol
+func detectFrame(img image.Image, tol int) (image.Rectangle, bool) {
+	b := img.Bounds()
+	if b.Dx() < 16 || b.Dy() < 16 {
+		return image.Rectangle{}, false
+	}
+	bg := nrgbaAt(img, b.Min.X, b.Min.Y)
+	for _, p := range []image.Point{{b.Max.X - 1, b.Min.Y}, {b.Min.X, b.Max.Y - 1}, {b.Max.X - 1, b.Max.Y - 1}} {
+		if !nearColor(nrgbaAt(img, p.X, p.Y), bg, tol) {
+			return image.Rectangle{}, false
+		}
+	}
+	// Фото обычно в верхней части холста, подписи - под ним: пробуем
+	// несколько строк, пока одна не пересечет рамку
+	for _, frac := range []int{3, 4, 2} {
+		if r, ok := frameAtRow(img, b.Min.Y+b.Dy()/frac, bg, tol); ok {
+			return r, true
+		}
+	}
+	return image.Rectangle{}, false
+}
+
+// frameAtRow ищет рамку, пересекающую строку y
+func frameAtRow(img image.Image, y int, bg color.NRGBA, tol int) (image.Rectangle, bool) {
+	b := img.Bounds()
+	maxWidth := max(b.Dx()/20, 2)
+	// Слева направо: фон, затем рамка цвета bc толщиной width
+	x0 := b.Min.X
+	for x0 < b.Max.X && nearColor(nrgbaAt(img, x0, y), bg, tol) {
+		x0++
+	}
+	if x0 == b.Min.X || x0 >= b.Min.X+b.Dx()/3 {
+		return image.Rectangle{}, false
+	}
+	bc := nrgbaAt(img, x0, y)
+	if nearColor(bc, bg, tol) {
+		return image.Rectangle{}, false
+	}
+	width := 0
+	for x0+width < b.Max.X && nearColor(nrgbaAt(img, x0+width, y), bc, tol) {
+		width++
+	}
+	if width > maxWidth {
+		return image.Rectangle{}, false
+	}
+	x1 := b.Max.X
+	for x1 > x0 && nearColor(nrgbaAt(img, x1-1, y), bg, tol) {
+		x1--
+	}
+	// По середине левой стороны рамки - вверх и вниз до фона. Сжатие
+	// портит отдельные пиксели тонкой рамки рядом с ярким фото, поэтому
+	// короткие разрывы пропускаются.
+	mx := x0 + width/2
+	along := func(y, dir int) int {
+		for gap := 0; gap < frameGap; {
+			next := y + dir*(gap+1)
+			if next < b.Min.Y || next >= b.Max.Y {
+				break
+			}
+			if nearColor(nrgbaAt(img, mx, next), bc, tol) {
+				y, gap = next, 0
+			} else {
+				gap++
+			}
+		}
+		return y
+	}
+	y0, y1 := along(y, -1), along(y, 1)+1
+	outer := image.Rect(x0, y0, x1, y1)
+	inner := outer.Inset(width)
+	if y0 == b.Min.Y || y1 == b.Max.Y || inner.Empty() || inner.Dx()*inner.Dy()*4 < b.Dx()*b.Dy()/2 {
+		return image.Rectangle{}, false
+	}
+	// Все четыре стороны рамки одного цвета, снаружи - фон
+	sides := rowShare(img, y0+width/2, x0, x1, bc, tol) >= frameShare &&
+		rowShare(img, y1-1-width/2, x0, x1, bc, tol) >= frameShare &&
+		colShare(img, x1-1-width/2, y0, y1, bc, tol) >= frameShare &&
+		rowShare(img, y0-1, x0, x1, bg, tol) >= frameShare
+	if !sides {
+		return image.Rectangle{}, false
+	}
+	return inner, true
+}
+
+// rowShare - доля пикселей строки y на отрезке [x0, x1) цвета c; для
+// скорости широкие строки проверяются с шагом
+func rowShare(img image.Image, y, x0, x1 int, c color.NRGBA, tol int) float64 {
+	step := max((x1-x0)/512, 1)
+	match, total := 0, 0
+	for x := x0; x < x1; x += step {
+		if nearColor(nrgbaAt(img, x, y), c, tol) {
+			match++
+		}
+		total++
+	}
+	return float64(match) / float64(max(total, 1))
+}
+
+// colShare - rowShare для столбца x на отрезке [y0, y1)
+func colShare(img image.Image, x, y0, y1 int, c color.NRGBA, tol int) float64 {
+	step := max((y1-y0)/512, 1)
+	match, total := 0, 0
+	for y := y0; y < y1; y += step {
+		if nearColor(nrgbaAt(img, x, y), c, tol) {
+			match++
+		}
+		total++
+	}
+	return float64(match) / float64(max(total, 1))
+}
+
+func nrgbaAt(img image.Image, x, y int) color.NRGBA {
+	return color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+}
+
+// nearColor сравнивает цвета по сумме разниц каналов
+func nearColor(a, b color.NRGBA, tol int) bool {
+	return abs(int(a.R)-int(b.R))+abs(int(a.G)-int(b.G))+abs(int(a.B)-int(b.B)) <= tol
+}
+
+// SetCaptionDetector подключает поиск старых подписей для Recaption
+// (nil - HeuristicCaptionDetector). Как и SetCache, нельзя вызывать
+// одновременно с генерацией.
+func (g *Generator) SetCaptionDetector(d CaptionDetector) {
+	g.detector = d
+}
+
+// Recaption переподписывает чужой мем: находит подключенным
+// CaptionDetector старые подписи или рамку демотиватора, обрезает их и
+// создает демотиватор из чистого фото с подписями c. Если подписи c
+// пусты, используется распознанный детектором текст - так старые
+// подписи перерисовываются в оформлении генератора. Фото без найденных
+// подписей используется целиком.
+func (g *Generator) Recaption(ctx context.Context, img image.Image, c Content) (out *image.RGBA, err error) {
+	defer g.observeGeneration(time.Now(), &err)
+	if err := g.validateInput(img); err != nil {
+		return nil, err
+	}
+	d := g.detector
+	if d == nil {
+		d = HeuristicCaptionDetector{}
+	}
+	found, err := d.DetectCaptions(ctx, img)
+	if err != nil {
+		return nil, fmt.Errorf("detecting captions: %w", err)
+	}
+	if c.TopText == "" && c.BottomText == "" {
+		c.TopText, c.BottomText = found.TopText, found.BottomText
+	}
+	if clean := found.Clean.Intersect(img.Bounds()); !clean.Empty() && clean != img.Bounds() {
+		photo := image.NewRGBA(image.Rect(0, 0, clean.Dx(), clean.Dy()))
+		drawSource(photo, photo.Bounds(), img, clean.Min)
+		img = photo
+	}
+	dg, err := g.withContent(c)
+	if err != nil {
+		return nil, err
+	}
+	return dg.generateInto(nil, img)
+}
@@ -0,0 +1,166 @@
+package meme
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+// gradientPhoto - цветное фото w x h без однотонных краев
+func gradientPhoto(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.Set(x, y, color.RGBA{uint8(40 + x*180/w), uint8(40 + y*180/h), 128, 0xff})
+		}
+	}
+	return img
+}
+
+// framedMeme создает демотиватор из photo и возвращает его вместе с
+// областью фото на холсте
+func framedMeme(t *testing.T, cfg *Config, photo image.Image) (*image.RGBA, image.Rectangle) {
+	t.Helper()
+	g := NewGenerator(cfg)
+	var layout Layout
+	g.AddHook(HookAfterCompose, func(dc *DrawContext) error {
+		layout = dc.Layout
+		return nil
+	})
+	out, err := g.GenerateContent(photo, Content{TopText: "Заголовок", BottomText: "и подпись под ним"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out, layout.Photo
+}
+
+// recompress пропускает img через JPEG с качеством quality
+func recompress(t *testing.T, img image.Image, quality int) image.Image {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		t.Fatal(err)
+	}
+	out, err := jpeg.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestDetectFrame(t *testing.T) {
+	light := DefaultConfig()
+	light.BackgroundColor = color.RGBA{0xf0, 0xf0, 0xf0, 0xff}
+	light.BorderColor = color.RGBA{0x20, 0x20, 0x20, 0xff}
+	light.TextColor = color.RGBA{0, 0, 0, 0xff}
+	thin := DefaultConfig()
+	thin.Border, thin.Padding = 3, 40
+
+	for _, tc := range []struct {
+		name  string
+		cfg   *Config
+		photo image.Image
+	}{
+		{"default", DefaultConfig(), gradientPhoto(400, 300)},
+		{"tall photo", DefaultConfig(), gradientPhoto(240, 480)},
+		{"light theme", light, gradientPhoto(400, 300)},
+		{"thin border", thin, gradientPhoto(320, 240)},
+	} {
+		out, photo := framedMeme(t, tc.cfg, tc.photo)
+		if got, ok := DetectFrame(out); !ok || got != photo {
+			t.Errorf("%s: DetectFrame = %v, %v; want %v", tc.name, got, ok, photo)
+		}
+
+		// После сжатия рамка находится с точностью до нескольких пикселей
+		got, ok := DetectFrame(recompress(t, out, 75))
+		if d := got.Min.Sub(photo.Min).Add(got.Max.Sub(photo.Max)); !ok || abs(d.X)+abs(d.Y) > 4 {
+			t.Errorf("%s as jpeg: DetectFrame = %v, %v; want about %v", tc.name, got, ok, photo)
+		}
+	}
+}
+
+func TestDetectFrameNegative(t *testing.T) {
+	// Кадр в черном обрамлении без рамки
+	letterbox := image.NewRGBA(image.Rect(0, 0, 400, 300))
+	inner := gradientPhoto(300, 200)
+	for y := range 200 {
+		for x := range 300 {
+			letterbox.Set(50+x, 50+y, inner.At(x, y))
+		}
+	}
+	for name, img := range map[string]image.Image{
+		"photo":      gradientPhoto(400, 300),
+		"jpeg photo": recompress(t, gradientPhoto(400, 300), 75),
+		"plain":      image.NewGray(image.Rect(0, 0, 200, 200)),
+		"letterbox":  letterbox,
+		"tiny":       image.NewGray(image.Rect(0, 0, 8, 8)),
+		"empty":      image.NewRGBA(image.Rectangle{}),
+	} {
+		if got, ok := DetectFrame(img); ok {
+			t.Errorf("%s: found frame %v", name, got)
+		}
+	}
+	if _, ok := DetectFrame(nil); ok {
+		t.Error("nil image: found frame")
+	}
+}
+
+func TestHeuristicCaptionDetector(t *testing.T) {
+	ctx := context.Background()
+	var d HeuristicCaptionDetector
+
+	out, photo := framedMeme(t, DefaultConfig(), gradientPhoto(400, 300))
+	found, err := d.DetectCaptions(ctx, out)
+	if err != nil || found.Clean != photo {
+		t.Errorf("demotivator: Clean %v, %v; want %v", found.Clean, err, photo)
+	}
+
+	// Белая полоса с "текстом" над фото
+	banded := image.NewRGBA(image.Rect(0, 0, 400, 360))
+	src := gradientPhoto(400, 300)
+	for y := range 360 {
+		for x := range 400 {
+			c := color.Color(color.White)
+			if y >= 60 {
+				c = src.At(x, y-60)
+			} else if y >= 20 && y < 40 && x%40 < 15 {
+				c = color.Black
+			}
+			banded.Set(x, y, c)
+		}
+	}
+	found, err = d.DetectCaptions(ctx, banded)
+	if want := image.Rect(0, 60, 400, 360); err != nil || found.Clean != want {
+		t.Errorf("caption band: Clean %v, %v; want %v", found.Clean, err, want)
+	}
+
+	for name, img := range map[string]image.Image{
+		"photo": gradientPhoto(400, 300),
+		"plain": image.NewGray(image.Rect(0, 0, 200, 200)),
+	} {
+		found, err := d.DetectCaptions(ctx, img)
+		if err != nil || !found.Clean.Empty() {
+			t.Errorf("%s: Clean %v, %v; want nothing", name, found.Clean, err)
+		}
+	}
+}
+
+func TestRecaptionStripsFrame(t *testing.T) {
+	out, photo := framedMeme(t, DefaultConfig(), gradientPhoto(400, 300))
+	g := NewGenerator(DefaultConfig())
+	var source image.Rectangle
+	g.AddHook(HookAfterCompose, func(dc *DrawContext) error {
+		source = dc.Layout.Photo
+		return nil
+	})
+	if _, err := g.Recaption(context.Background(), out, Content{TopText: "новая подпись"}); err != nil {
+		t.Fatal(err)
+	}
+	// Второй раз обрамляется только фото
+	if source.Size() != photo.Size() {
+		t.Errorf("recaptioned photo %v, want %v", source.Size(), photo.Size())
+	}
+}
@@ -0,0 +1,38 @@
+package meme
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+)
+
+// ErrPanic - паника внутри генерации, превращённая в ошибку
+var ErrPanic = errors.New("internal panic")
+
+// PanicError содержит значение паники и стек в момент её возникновения.
+// Проверяется через errors.Is(err, ErrPanic); если паника была вызвана
+// ошибкой, errors.Is и errors.As видят и её.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("internal panic: %v", e.Value)
+}
+
+// Unwrap возвращает ErrPanic и исходную ошибку, если паника была вызвана ею
+func (e *PanicError) Unwrap() []error {
+	if err, ok := e.Value.(error); ok {
+		return []error{ErrPanic, err}
+	}
+	return []error{ErrPanic}
+}
+
+// recoverPanic превращает панику в *PanicError; вызывается через defer
+// в функциях с именованным результатом err
+func recoverPanic(err *error) {
+	if r := recover(); r != nil {
+		*err = &PanicError{Value: r, Stack: debug.Stack()}
+	}
+}
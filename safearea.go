@@ -0,0 +1,103 @@
+package meme
+
+import (
+	"image"
+	"slices"
+	"sync"
+)
+
+// SafeArea - поля холста, которые закрывает интерфейс платформы:
+// кнопки сбоку в TikTok и Reels, полоски прогресса историй, описание
+// снизу. Поля задаются в долях высоты (Top, Bottom) и ширины (Left,
+// Right) холста. Подписи не заходят в поля: по ширине они переносятся
+// и центруются между Left и Right, а если нижние строки попадают в
+// Bottom, холст удлиняется снизу. Элементы через AddWidget можно ставить
+// в Layout.Safe.
+type SafeArea struct {
+	Top, Bottom, Left, Right float64
+}
+
+// Поля популярных платформ для вертикального видео 9:16 по их
+// рекомендациям для авторов
+var builtinSafeAreas = map[string]SafeArea{
+	"tiktok":  {Top: 0.08, Bottom: 0.2, Left: 0.06, Right: 0.13},
+	"reels":   {Top: 0.14, Bottom: 0.35, Left: 0.06, Right: 0.06},
+	"stories": {Top: 0.14, Bottom: 0.2, Left: 0.06, Right: 0.06},
+	"shorts":  {Top: 0.06, Bottom: 0.25, Left: 0.05, Right: 0.16},
+}
+
+// Реестр полей платформ
+var (
+	safeAreasMu sync.RWMutex
+	safeAreas   = make(map[string]SafeArea)
+)
+
+func init() {
+	for name, s := range builtinSafeAreas {
+		safeAreas[name] = s
+	}
+}
+
+// RegisterSafeArea добавляет поля платформы под именем name (заменяя
+// существующие с тем же именем)
+func RegisterSafeArea(name string, s SafeArea) {
+	safeAreasMu.Lock()
+	safeAreas[name] = s
+	safeAreasMu.Unlock()
+}
+
+// LookupSafeArea возвращает поля платформы: "tiktok", "reels", "stories",
+// "shorts" или зарегистрированные через RegisterSafeArea
+func LookupSafeArea(name string) (SafeArea, bool) {
+	safeAreasMu.RLock()
+	defer safeAreasMu.RUnlock()
+	s, ok := safeAreas[name]
+	return s, ok
+}
+
+// SafeAreas возвращает отсортированные имена зарегистрированных полей
+func SafeAreas() []string {
+	safeAreasMu.RLock()
+	defer safeAreasMu.RUnlock()
+	names := make([]string, 0, len(safeAreas))
+	for name := range safeAreas {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// sides сообщает, заданы ли боковые поля
+func (s SafeArea) sides() bool {
+	return s.Left > 0 || s.Right > 0
+}
+
+// span возвращает горизонтальный отрезок холста шириной width вне боковых полей
+func (s SafeArea) span(width int) (left, right int) {
+	return int(float64(width) * s.Left), width - int(float64(width)*s.Right)
+}
+
+// rect возвращает область холста вне полей
+func (s SafeArea) rect(canvas image.Rectangle) image.Rectangle {
+	w, h := float64(canvas.Dx()), float64(canvas.Dy())
+	return image.Rect(
+		canvas.Min.X+int(w*s.Left), canvas.Min.Y+int(h*s.Top),
+		canvas.Max.X-int(w*s.Right), canvas.Max.Y-int(h*s.Bottom),
+	)
+}
+
+// check проверяет, что поля не перекрывают весь холст
+func (s SafeArea) check() error {
+	for _, v := range []struct {
+		field string
+		value float64
+	}{{"Top", s.Top}, {"Bottom", s.Bottom}, {"Left", s.Left}, {"Right", s.Right}} {
+		if v.value < 0 || v.value >= 1 {
+			return &ConfigError{Field: "SafeArea." + v.field, Reason: "must be between 0 and 1"}
+		}
+	}
+	if s.Top+s.Bottom > 0.9 || s.Left+s.Right > 0.9 {
+		return &ConfigError{Field: "SafeArea", Reason: "opposite margins must leave at least 10% of the canvas"}
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package meme
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// SidecarFormat - формат файла-спутника с описанием изображения
+type SidecarFormat string
+
+const (
+	SidecarJSON SidecarFormat = "json"
+	SidecarText SidecarFormat = "txt"
+)
+
+// Sidecar - описание сгенерированного мема для альтернативного текста
+type Sidecar struct {
+	AltText  string      `json:"alt_text"`
+	Captions []string    `json:"captions"`
+	Layout   string      `json:"layout"`
+	Width    int         `json:"width"`
+	Height   int         `json:"height"`
+	Photo    SidecarRect `json:"photo"`
+}
+
+// SidecarRect - прямоугольник в пикселях результата
+type SidecarRect struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// Строки описания на английском
+var sidecarDefaults = map[string]string{
+	"alt":           "Demotivator",
+	"alt_captioned": "Demotivator captioned %s",
+	"quote":         "\"%s\"",
+	"layout":        "demotivator: %dx%d image in a %dpx border with %dpx padding",
+	"one_caption":   ", one caption line below the image",
+	"two_captions":  ", title and subtitle below the image",
+}
+
+// Переводы строк описания: язык -> ключ -> текст
+var (
+	sidecarMessagesMu sync.RWMutex
+	sidecarMessages   = map[string]map[string]string{
+		"ru": {
+			"alt":           "Демотиватор",
+			"alt_captioned": "Демотиватор с подписью %s",
+			"quote":         "«%s»",
+			"layout":        "демотиватор: изображение %dx%d в рамке %dpx с полями %dpx",
+			"one_caption":   ", под изображением одна строка подписи",
+			"two_captions":  ", под изображением заголовок и подзаголовок",
+		},
+	}
+)
+
+// RegisterSidecarMessages добавляет или заменяет переводы описания Sidecar
+// для языка lang. Ключи - как в английской таблице (alt, alt_captioned,
+// quote, layout, one_caption, two_captions), глаголы fmt - те же.
+func RegisterSidecarMessages(lang string, messages map[string]string) {
+	sidecarMessagesMu.Lock()
+	defer sidecarMessagesMu.Unlock()
+	table := sidecarMessages[lang]
+	if table == nil {
+		table = make(map[string]string, len(messages))
+		sidecarMessages[lang] = table
+	}
+	for key, msg := range messages {
+		table[key] = msg
+	}
+}
+
+// sidecarMessage возвращает строку описания key на языке lang или на английском
+func sidecarMessage(lang, key string) string {
+	sidecarMessagesMu.RLock()
+	defer sidecarMessagesMu.RUnlock()
+	if msg, ok := sidecarMessages[lang][key]; ok {
+		return msg
+	}
+	return sidecarDefaults[key]
+}
+
+// Sidecar описывает результат, который Generate построит для img, на
+// английском. Подписи берутся в исходном регистре: программы чтения с
+// экрана нередко читают текст в верхнем регистре по буквам.
+func (g *Generator) Sidecar(img image.Image) (*Sidecar, error) {
+	return g.SidecarLang(img, "")
+}
+
+// SidecarLang - Sidecar с описанием на языке lang (переводы регистрирует
+// RegisterSidecarMessages); без перевода - на английском
+func (g *Generator) SidecarLang(img image.Image, lang string) (*Sidecar, error) {
+	msg := func(key string) string { return sidecarMessage(lang, key) }
+	if err := g.validateInput(img); err != nil {
+		return nil, err
+	}
+	cfg := g.config
+	l := g.layout(img)
+
+	var captions []string
+	for _, text := range []string{cfg.TopText, cfg.BottomText} {
+		if text != "" {
+			captions = append(captions, text)
+		}
+	}
+
+	layoutDesc := fmt.Sprintf(msg("layout"), l.photo.Dx(), l.photo.Dy(), cfg.Border, cfg.Padding)
+	switch len(captions) {
+	case 1:
+		layoutDesc += msg("one_caption")
+	case 2:
+		layoutDesc += msg("two_captions")
+	}
+
+	alt := msg("alt")
+	if len(captions) > 0 {
+		quoted := make([]string, len(captions))
+		for i, c := range captions {
+			quoted[i] = fmt.Sprintf(msg("quote"), c)
+		}
+		alt = fmt.Sprintf(msg("alt_captioned"), strings.Join(quoted, ", "))
+	}
+	alt += "."
+
+	return &Sidecar{
+		AltText:  alt,
+		Captions: captions,
+		Layout:   layoutDesc,
+		Width:    l.canvas.Dx(),
+		Height:   l.canvas.Dy(),
+		Photo: SidecarRect{
+			X:      l.photo.Min.X,
+			Y:      l.photo.Min.Y,
+			Width:  l.photo.Dx(),
+			Height: l.photo.Dy(),
+		},
+	}, nil
+}
+
+// WriteJSON записывает описание в формате JSON
+func (s *Sidecar) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// WriteText записывает альтернативный текст и описание компоновки построчно
+func (s *Sidecar) WriteText(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "%s\n%s\n", s.AltText, s.Layout)
+	return err
+}
+
+// Save сохраняет описание рядом с изображением: для out.png это out.json или out.txt
+func (s *Sidecar) Save(imagePath string, format SidecarFormat) error {
+	if format != SidecarJSON && format != SidecarText {
+		return fmt.Errorf("unknown sidecar format: %s", format)
+	}
+	path := strings.TrimSuffix(imagePath, filepath.Ext(imagePath)) + "." + string(format)
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating sidecar file: %w", err)
+	}
+	defer f.Close()
+
+	if format == SidecarJSON {
+		err = s.WriteJSON(f)
+	} else {
+		err = s.WriteText(f)
+	}
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,35 @@
+package meme
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Signer подписывает закодированный результат. Вызывается последним шагом
+// Encode - после встраивания или удаления метаданных, так что подпись
+// покрывает итоговые байты. Sign может вернуть изменённые данные (например,
+// со встроенным манифестом C2PA) или исходные, сохранив отсоединённую
+// подпись у себя. data принадлежат Sign: Encode передает собственную
+// копию, так что ее можно хранить и возвращать.
+type Signer interface {
+	Sign(data []byte, format Format) ([]byte, error)
+}
+
+// SignerFunc позволяет использовать обычную функцию как Signer
+type SignerFunc func(data []byte, format Format) ([]byte, error)
+
+// Sign вызывает f(data, format)
+func (f SignerFunc) Sign(data []byte, format Format) ([]byte, error) {
+	return f(data, format)
+}
+
+// sign применяет подписчика и оборачивает его ошибку. data обычно лежат
+// в буфере из пула, который вернется в пул после Encode, поэтому
+// подписчик получает копию.
+func sign(s Signer, data []byte, format Format) ([]byte, error) {
+	signed, err := s.Sign(bytes.Clone(data), format)
+	if err != nil {
+		return nil, fmt.Errorf("signing output: %w", err)
+	}
+	return signed, nil
+}
@@ -0,0 +1,91 @@
+package meme
+
+import (
+	"fmt"
+	"image"
+	"time"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// Size - один из вариантов результата GenerateSizes
+type Size struct {
+	// Name - метка варианта для вызывающего, например "thumb" или "full"
+	Name string
+
+	// Демотиватор вписывается в MaxWidth x MaxHeight с сохранением
+	// пропорций; 0 - сторона не ограничена, оба 0 - полный размер.
+	// Результат никогда не увеличивается.
+	MaxWidth, MaxHeight int
+
+	// Encode - параметры кодирования варианта; nil - PNG
+	Encode *EncodeOptions
+}
+
+// SizedImage - закодированный вариант из GenerateSizes
+type SizedImage struct {
+	Size          Size
+	Width, Height int
+	Data          []byte
+}
+
+// GenerateSizes создает демотиватор один раз в полном разрешении и выдает
+// его в нескольких размерах, например для галереи:
+//
+//	out, err := g.GenerateSizes(img, []meme.Size{
+//		{Name: "thumb", MaxWidth: 200, Encode: &meme.EncodeOptions{Format: meme.FormatJPEG, Quality: 75}},
+//		{Name: "preview", MaxWidth: 800, Encode: &meme.EncodeOptions{Format: meme.FormatWebP}},
+//		{Name: "full"},
+//	})
+//
+// Раскладка, подписи и эффекты считаются один раз, варианты уменьшаются из
+// готового холста. Результаты идут в порядке sizes.
+func (g *Generator) GenerateSizes(img image.Image, sizes []Size) (_ []SizedImage, err error) {
+	defer g.observeGeneration(time.Now(), &err)
+	for i, s := range sizes {
+		if s.MaxWidth < 0 || s.MaxHeight < 0 {
+			return nil, &ConfigError{Field: fmt.Sprintf("sizes[%d]", i), Reason: "MaxWidth and MaxHeight must not be negative"}
+		}
+	}
+	full, err := g.generateInto(nil, img)
+	if err != nil {
+		return nil, err
+	}
+	defer g.stage(StageEncode)()
+	out := make([]SizedImage, len(sizes))
+	for i, s := range sizes {
+		var v image.Image = full
+		if w, h := fitSize(full.Rect.Dx(), full.Rect.Dy(), s.MaxWidth, s.MaxHeight); w != full.Rect.Dx() || h != full.Rect.Dy() {
+			small := image.NewRGBA(image.Rect(0, 0, w, h))
+			xdraw.CatmullRom.Scale(small, small.Rect, full, full.Rect, xdraw.Src, nil)
+			v = small
+		}
+		opts := s.Encode
+		if opts == nil {
+			opts = &EncodeOptions{Format: FormatPNG}
+		}
+		data, err := EncodeBytes(v, opts)
+		if err != nil {
+			return nil, fmt.Errorf("encoding %q: %w", s.Name, err)
+		}
+		g.observeOutput(opts.Format, len(data))
+		b := v.Bounds()
+		out[i] = SizedImage{Size: s, Width: b.Dx(), Height: b.Dy(), Data: data}
+	}
+	return out, nil
+}
+
+// fitSize вписывает w x h в maxW x maxH (0 - без ограничения) без увеличения
+func fitSize(w, h, maxW, maxH int) (int, int) {
+	scale := 1.0
+	if maxW > 0 && w > maxW {
+		scale = float64(maxW) / float64(w)
+	}
+	if maxH > 0 && h > maxH {
+		scale = min(scale, float64(maxH)/float64(h))
+	}
+	if scale == 1 {
+		return w, h
+	}
+	return max(int(float64(w)*scale+0.5), 1), max(int(float64(h)*scale+0.5), 1)
+}
@@ -0,0 +1,119 @@
+package meme
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"time"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// Corner - угол, в который ставится элемент
+type Corner int
+
+const (
+	CornerBottomRight Corner = iota
+	CornerBottomLeft
+	CornerTopRight
+	CornerTopLeft
+)
+
+// place возвращает прямоугольник размера size в углу r с отступом margin
+func (c Corner) place(r image.Rectangle, size image.Point, margin int) image.Rectangle {
+	p := image.Pt(r.Max.X-margin-size.X, r.Max.Y-margin-size.Y)
+	if c == CornerBottomLeft || c == CornerTopLeft {
+		p.X = r.Min.X + margin
+	}
+	if c == CornerTopRight || c == CornerTopLeft {
+		p.Y = r.Min.Y + margin
+	}
+	return image.Rectangle{Min: p, Max: p.Add(size)}
+}
+
+// DateStamp - штамп с датой в углу, как у старых фотоаппаратов, или
+// обратный отсчет до Until. Рисуется как Widget: угол выбирается внутри
+// переданного прямоугольника, например Layout.Photo.
+type DateStamp struct {
+	Time time.Time // нулевое - текущее время
+
+	// Layout - формат time.Format; пусто - "2006-01-02 15:04". Для
+	// обратного отсчета формат применяется к оставшемуся времени от
+	// полуночи, поэтому подходят только часы, минуты и секунды.
+	Layout string
+
+	// Until - конец обратного отсчета: вместо даты выводится время от Time
+	// до Until, по умолчанию "3d 04:12:45"
+	Until time.Time
+
+	Corner Corner
+	Size   float64     // размер шрифта, 0 - 1/16 высоты области, не меньше 12
+	Color  color.Color // nil - оранжевый
+	Plate  color.Color // подложка, nil - полупрозрачная черная
+}
+
+var _ Widget = DateStamp{}
+
+// Значения DateStamp по умолчанию
+const (
+	defaultStampLayout = "2006-01-02 15:04"
+	minStampSize       = 12
+)
+
+var (
+	stampOrange = color.RGBA{0xff, 0x8c, 0x1a, 0xff}
+	stampPlate  = color.NRGBA{0, 0, 0, 0x80}
+)
+
+// text возвращает строку штампа
+func (s DateStamp) text() string {
+	t := s.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	if s.Until.IsZero() {
+		layout := s.Layout
+		if layout == "" {
+			layout = defaultStampLayout
+		}
+		return t.Format(layout)
+	}
+	left := max(s.Until.Sub(t), 0).Truncate(time.Second)
+	if s.Layout != "" {
+		return time.Time{}.Add(left).Format(s.Layout)
+	}
+	days := int(left / (24 * time.Hour))
+	left -= time.Duration(days) * 24 * time.Hour
+	clock := fmt.Sprintf("%02d:%02d:%02d", int(left/time.Hour), int(left/time.Minute)%60, int(left/time.Second)%60)
+	if days > 0 {
+		return fmt.Sprintf("%dd %s", days, clock)
+	}
+	return clock
+}
+
+func (s DateStamp) drawWidget(g *Generator, dst draw.Image, r image.Rectangle) error {
+	if s.Size < 0 {
+		return &ConfigError{Field: "DateStamp.Size", Reason: "must not be negative"}
+	}
+	size := s.Size
+	if size == 0 {
+		size = max(float64(r.Dy())/16, minStampSize)
+	}
+	face, err := g.loadFont(size)
+	if err != nil {
+		return fmt.Errorf("loading font: %w", err)
+	}
+	defer face.Close()
+
+	text := s.text()
+	tr := g.textRenderer()
+	m := face.Metrics()
+	pad := max(int(size/4), 2)
+	textW := tr.Measure(face, text).Ceil()
+	plate := s.Corner.place(r, image.Pt(textW+2*pad, m.Ascent.Ceil()+m.Descent.Ceil()+2*pad), pad)
+	fillRounded(dst, plate, pad, colorOr(s.Plate, stampPlate))
+	dot := fixed.P(plate.Min.X+pad, plate.Min.Y+pad+m.Ascent.Ceil())
+	tr.Draw(dst, face, text, dot, TextStyle{Color: colorOr(s.Color, stampOrange), Linear: g.config.LinearBlending})
+	return nil
+}
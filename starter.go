@@ -0,0 +1,206 @@
+package meme
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"time"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// StarterItem - предмет "стартового набора": картинка и подпись под ней
+type StarterItem struct {
+	Image image.Image
+	Label string
+
+	// Position - область картинки на холсте; пустая - подобрать автоматически
+	Position image.Rectangle
+}
+
+// StarterPackOptions задаёт раскладку "стартового набора"
+type StarterPackOptions struct {
+	Title string // например "Go developer starter pack"
+
+	Width, Height int // размер холста, 0 - 1200x900
+	ItemSize      int // сторона квадрата под картинку, 0 - 1/5 ширины
+
+	TitleSize float64 // 0 - 64
+	LabelSize float64 // 0 - 28
+
+	Background color.Color // nil - белый
+	TextColor  color.Color // nil - черный
+}
+
+// Значения StarterPackOptions по умолчанию
+const (
+	defaultStarterWidth     = 1200
+	defaultStarterHeight    = 900
+	defaultStarterTitleSize = 64
+	defaultStarterLabelSize = 28
+
+	starterAttempts = 300 // попыток найти свободное место для предмета
+	starterDensity  = 0.5 // доля площади, которую занимают предметы при авторазмере
+)
+
+// StarterPack раскладывает картинки с подписями по холсту под общим
+// заголовком. Предметы без Position размещаются в случайных местах так,
+// чтобы не перекрывать уже размещённые; если места нет, выбирается
+// положение с наименьшим перекрытием. В режиме Deterministic раскладка
+// зависит только от Config.Seed.
+func (g *Generator) StarterPack(items []StarterItem, opts StarterPackOptions) (out *image.RGBA, err error) {
+	defer g.observeGeneration(time.Now(), &err)
+	defer recoverPanic(&err)
+	if len(items) == 0 {
+		return nil, &ConfigError{Field: "items", Reason: "must not be empty"}
+	}
+	for _, it := range items {
+		if it.Image == nil {
+			return nil, ErrNilImage
+		}
+	}
+	if opts.Width < 0 || opts.Height < 0 || opts.ItemSize < 0 {
+		return nil, &ConfigError{Field: "StarterPackOptions", Reason: "sizes must not be negative"}
+	}
+
+	width, height := opts.Width, opts.Height
+	if width == 0 {
+		width = defaultStarterWidth
+	}
+	if height == 0 {
+		height = defaultStarterHeight
+	}
+	canvas := image.Rect(0, 0, width, height)
+	if err := checkCanvas(canvas); err != nil {
+		return nil, err
+	}
+	itemSize := opts.ItemSize
+	if itemSize == 0 {
+		itemSize = width / 5
+	}
+	titleSize := opts.TitleSize
+	if titleSize <= 0 {
+		titleSize = defaultStarterTitleSize
+	}
+	labelSize := opts.LabelSize
+	if labelSize <= 0 {
+		labelSize = defaultStarterLabelSize
+	}
+	bg := opts.Background
+	if bg == nil {
+		bg = color.White
+	}
+	textColor := opts.TextColor
+	if textColor == nil {
+		textColor = color.Black
+	}
+
+	out = image.NewRGBA(canvas)
+	draw.Draw(out, canvas, image.NewUniform(bg), image.Point{}, draw.Src)
+
+	margin := width / 40
+	titleH := 0
+	if opts.Title != "" {
+		titleH = int(titleSize * 1.6)
+		slot := TextSlot{
+			Name:     "title",
+			Rect:     image.Rect(margin, margin, width-margin, margin+titleH),
+			FontSize: titleSize,
+			Color:    textColor,
+			Align:    AlignLeft,
+		}
+		if err := g.drawSlotText(out, slot, opts.Title); err != nil {
+			return nil, err
+		}
+	}
+	area := image.Rect(margin, margin+titleH+margin, width-margin, height-margin)
+	labelH := int(labelSize * 2.4)
+
+	// Сначала занимаем места, заданные вручную, затем расставляем остальные
+	cells := make([]image.Rectangle, len(items))
+	var placed []image.Rectangle
+	for i, it := range items {
+		if !it.Position.Empty() {
+			cells[i] = image.Rect(it.Position.Min.X, it.Position.Min.Y, it.Position.Max.X, it.Position.Max.Y+labelH)
+			placed = append(placed, cells[i])
+		}
+	}
+	// Случайная расстановка без перекрытий редко заполняет больше половины
+	// площади, поэтому при большом числе предметов уменьшаем их
+	if opts.ItemSize == 0 {
+		free := float64(area.Dx() * area.Dy())
+		for itemSize > 32 && float64(len(items)*(itemSize+margin)*(itemSize+labelH+margin)) > free*starterDensity {
+			itemSize = itemSize * 9 / 10
+		}
+	}
+	r := g.newRand()
+	cellW, cellH := itemSize, itemSize+labelH
+	for i, it := range items {
+		if !it.Position.Empty() {
+			continue
+		}
+		best, bestOverlap := image.Rectangle{}, -1
+		for range starterAttempts {
+			x := area.Min.X + r.IntN(max(area.Dx()-cellW, 0)+1)
+			y := area.Min.Y + r.IntN(max(area.Dy()-cellH, 0)+1)
+			cand := image.Rect(x, y, x+cellW, y+cellH)
+			overlap := 0
+			for _, p := range placed {
+				overlap += overlapArea(cand, p.Inset(-margin/2))
+			}
+			if bestOverlap < 0 || overlap < bestOverlap {
+				best, bestOverlap = cand, overlap
+			}
+			if overlap == 0 {
+				break
+			}
+		}
+		cells[i] = best
+		placed = append(placed, best)
+	}
+
+	// Подписи рисуем после всех картинок, чтобы их не закрыл сосед
+	for i, it := range items {
+		cell := cells[i]
+		drawContain(out, image.Rect(cell.Min.X, cell.Min.Y, cell.Max.X, cell.Max.Y-labelH), it.Image)
+	}
+	for i, it := range items {
+		if it.Label == "" {
+			continue
+		}
+		cell := cells[i]
+		slot := TextSlot{
+			Name:     "label",
+			Rect:     image.Rect(cell.Min.X-margin/2, cell.Max.Y-labelH, cell.Max.X+margin/2, cell.Max.Y),
+			FontSize: labelSize,
+			Color:    textColor,
+		}
+		if err := g.drawSlotText(out, slot, it.Label); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// overlapArea возвращает площадь пересечения прямоугольников
+func overlapArea(a, b image.Rectangle) int {
+	in := a.Intersect(b)
+	return in.Dx() * in.Dy()
+}
+
+// drawContain вписывает img в r целиком с сохранением пропорций и центрирует
+func drawContain(dst draw.Image, r image.Rectangle, img image.Image) {
+	b := img.Bounds()
+	if r.Empty() || b.Empty() {
+		return
+	}
+	w, h := r.Dx(), r.Dy()
+	// Сравниваем пропорции: источник шире области - ограничиваем по ширине
+	if b.Dx()*r.Dy() > r.Dx()*b.Dy() {
+		h = max(b.Dy()*r.Dx()/b.Dx(), 1)
+	} else {
+		w = max(b.Dx()*r.Dy()/b.Dy(), 1)
+	}
+	target := image.Rect(0, 0, w, h).Add(r.Min).Add(image.Pt((r.Dx()-w)/2, (r.Dy()-h)/2))
+	xdraw.CatmullRom.Scale(dst, target, img, b, xdraw.Over, nil)
+}
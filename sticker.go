@@ -0,0 +1,102 @@
+package meme
+
+import (
+	"fmt"
+	"image"
+	"io"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// StickerPreset описывает требования платформы к статичным стикерам
+type StickerPreset struct {
+	Name     string
+	Size     int // сторона квадратного холста в пикселях
+	Margin   int // прозрачный отступ от краёв холста
+	MaxBytes int // максимальный размер файла
+}
+
+var (
+	// TelegramSticker - стикер Telegram: WebP 512x512, не более 512 КБ
+	TelegramSticker = StickerPreset{Name: "telegram", Size: 512, Margin: 0, MaxBytes: 512 * 1024}
+
+	// WhatsAppSticker - стикер WhatsApp: WebP 512x512 с отступом 16px, не более 100 КБ
+	WhatsAppSticker = StickerPreset{Name: "whatsapp", Size: 512, Margin: 16, MaxBytes: 100 * 1024}
+)
+
+// Минимальная сторона содержимого при автоматическом уменьшении
+const minStickerContent = 128
+
+// EncodeSticker вписывает изображение в квадратный прозрачный холст пресета
+// и кодирует его в WebP, укладываясь в лимит размера файла.
+// Сначала повышается уровень огрубления цветов, затем уменьшается само изображение.
+func EncodeSticker(w io.Writer, img image.Image, preset StickerPreset) error {
+	if preset.Size <= 0 {
+		return fmt.Errorf("invalid sticker size: %d", preset.Size)
+	}
+
+	content := preset.Size - preset.Margin*2
+	if content <= 0 {
+		return fmt.Errorf("margin %d leaves no room on a %dx%d sticker", preset.Margin, preset.Size, preset.Size)
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	for ; content >= minStickerContent; content = content * 9 / 10 {
+		canvas := fitSticker(img, preset.Size, content)
+
+		for level := 0; level <= 5; level++ {
+			buf.Reset()
+			if err := Encode(buf, canvas, &EncodeOptions{Format: FormatWebP, NearLossless: level}); err != nil {
+				putNRGBA(canvas)
+				return err
+			}
+			if preset.MaxBytes <= 0 || buf.Len() <= preset.MaxBytes {
+				putNRGBA(canvas)
+				_, err := w.Write(buf.Bytes())
+				return err
+			}
+		}
+		putNRGBA(canvas)
+	}
+
+	return fmt.Errorf("cannot fit %s sticker into %d bytes", preset.Name, preset.MaxBytes)
+}
+
+// fitSticker масштабирует изображение в квадрат content и центрирует на прозрачном холсте size
+func fitSticker(img image.Image, size, content int) *image.NRGBA {
+	canvas := getNRGBA(image.Rect(0, 0, size, size))
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w == 0 || h == 0 {
+		return canvas
+	}
+	if w >= h {
+		w, h = content, h*content/w
+	} else {
+		w, h = w*content/h, content
+	}
+	w, h = max(w, 1), max(h, 1)
+
+	x := (size - w) / 2
+	y := (size - h) / 2
+	xdraw.CatmullRom.Scale(canvas, image.Rect(x, y, x+w, y+h), img, b, xdraw.Src, nil)
+	return canvas
+}
+
+// GenerateSticker создает демотиватор и сразу кодирует его как стикер
+func (g *Generator) GenerateSticker(w io.Writer, img image.Image, preset StickerPreset) error {
+	out, err := g.Generate(img)
+	if err != nil {
+		return err
+	}
+	defer g.stage(StageEncode)()
+	cw := &countingWriter{w: w}
+	if err := EncodeSticker(cw, out, preset); err != nil {
+		return err
+	}
+	g.observeOutput(FormatWebP, cw.n)
+	return nil
+}
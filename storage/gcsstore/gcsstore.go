@@ -0,0 +1,66 @@
+// Package gcsstore реализует storage.Source и storage.Sink поверх Google
+// Cloud Storage.
+//
+//	client, err := gcs.NewClient(ctx)
+//	store := gcsstore.New(client, "memes", "uploads/")
+//	w := worker.New(queue, store, worker.Options{})
+package gcsstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+
+	gcs "cloud.google.com/go/storage"
+
+	"github.com/go-goblin/meme/storage"
+)
+
+// Store - объекты бакета с общим префиксом
+type Store struct {
+	bucket *gcs.BucketHandle
+	name   string
+	prefix string
+}
+
+var (
+	_ storage.Source = (*Store)(nil)
+	_ storage.Sink   = (*Store)(nil)
+)
+
+// New создает хранилище; ключи дополняются prefix как есть, поэтому
+// префикс каталога должен оканчиваться на "/"
+func New(client *gcs.Client, bucket, prefix string) *Store {
+	return &Store{bucket: client.Bucket(bucket), name: bucket, prefix: prefix}
+}
+
+// Open читает объект key
+func (s *Store) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := storage.CheckKey(key); err != nil {
+		return nil, err
+	}
+	r, err := s.bucket.Object(s.prefix + key).NewReader(ctx)
+	if errors.Is(err, gcs.ErrObjectNotExist) {
+		return nil, fmt.Errorf("gs://%s/%s%s: %w", s.name, s.prefix, key, fs.ErrNotExist)
+	}
+	return r, err
+}
+
+// Put записывает объект key с типом содержимого по расширению. Объект
+// появляется только после успешного Close, поэтому запись атомарна.
+func (s *Store) Put(ctx context.Context, key string, data []byte) error {
+	if err := storage.CheckKey(key); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // отмена до Close прерывает незавершенную загрузку
+	w := s.bucket.Object(s.prefix + key).NewWriter(ctx)
+	w.ContentType = storage.ContentType(key)
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
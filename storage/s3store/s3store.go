@@ -0,0 +1,74 @@
+// Package s3store реализует storage.Source и storage.Sink поверх Amazon S3
+// и совместимых хранилищ (MinIO, R2 и т.п.).
+//
+//	cfg, err := config.LoadDefaultConfig(ctx)
+//	store := s3store.New(s3.NewFromConfig(cfg), "memes", "uploads/")
+//	w := worker.New(queue, store, worker.Options{})
+package s3store
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/go-goblin/meme/storage"
+)
+
+// Store - объекты бакета с общим префиксом
+type Store struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+var (
+	_ storage.Source = (*Store)(nil)
+	_ storage.Sink   = (*Store)(nil)
+)
+
+// New создает хранилище; ключи дополняются prefix как есть, поэтому
+// префикс каталога должен оканчиваться на "/"
+func New(client *s3.Client, bucket, prefix string) *Store {
+	return &Store{client: client, bucket: bucket, prefix: prefix}
+}
+
+// Open читает объект key
+func (s *Store) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := storage.CheckKey(key); err != nil {
+		return nil, err
+	}
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.prefix + key),
+	})
+	if err != nil {
+		var nsk *types.NoSuchKey
+		if errors.As(err, &nsk) {
+			return nil, fmt.Errorf("s3://%s/%s%s: %w", s.bucket, s.prefix, key, fs.ErrNotExist)
+		}
+		return nil, err
+	}
+	return out.Body, nil
+}
+
+// Put записывает объект key с типом содержимого по расширению
+func (s *Store) Put(ctx context.Context, key string, data []byte) error {
+	if err := storage.CheckKey(key); err != nil {
+		return err
+	}
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(s.prefix + key),
+		Body:          bytes.NewReader(data),
+		ContentLength: aws.Int64(int64(len(data))),
+		ContentType:   aws.String(storage.ContentType(key)),
+	})
+	return err
+}
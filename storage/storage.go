@@ -0,0 +1,107 @@
+// Package storage описывает источники входных фото и приемники
+// результатов, чтобы пакетная обработка, воркер и сервер могли работать
+// с объектными хранилищами напрямую. Локальная файловая система - Dir,
+// S3 и GCS - в пакетах s3store и gcsstore.
+//
+// Отсутствующий объект во всех реализациях - ошибка, для которой
+// errors.Is(err, fs.ErrNotExist) истинно.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Source открывает входные файлы по ключу
+type Source interface {
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+// Sink сохраняет результаты по ключу. Тип содержимого реализации
+// определяют по расширению ключа (см. ContentType).
+type Sink interface {
+	Put(ctx context.Context, key string, data []byte) error
+}
+
+// ErrInvalidKey - ключ недопустим для хранилища (пустой, абсолютный,
+// с выходом за пределы каталога)
+var ErrInvalidKey = errors.New("invalid storage key")
+
+// CheckKey проверяет ключ объекта: относительный путь через "/" без
+// "..", "." и пустых частей (см. fs.ValidPath)
+func CheckKey(key string) error {
+	if key == "." || !fs.ValidPath(key) {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	return nil
+}
+
+// ContentType возвращает MIME-тип по расширению ключа
+func ContentType(key string) string {
+	if t := mime.TypeByExtension(path.Ext(key)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// Dir - хранилище в каталоге локальной файловой системы; ключи - пути
+// относительно каталога, выход за его пределы запрещен
+type Dir string
+
+var (
+	_ Source = Dir("")
+	_ Sink   = Dir("")
+)
+
+func (d Dir) path(key string) (string, error) {
+	if !filepath.IsLocal(key) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	return filepath.Join(string(d), key), nil
+}
+
+// Open открывает файл key
+func (d Dir) Open(_ context.Context, key string) (io.ReadCloser, error) {
+	p, err := d.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+// Put атомарно записывает файл key, создавая каталоги
+func (d Dir) Put(_ context.Context, key string, data []byte) error {
+	p, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), "."+strings.TrimPrefix(filepath.Base(p), ".")+".*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0o644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), p)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
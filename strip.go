@@ -0,0 +1,116 @@
+package meme
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+// StripMetadata удаляет из закодированного файла EXIF (включая GPS), XMP,
+// комментарии и текстовые чанки. ICC-профиль сохраняется: без него
+// изменились бы цвета, а персональных данных он не содержит.
+// Поддерживаются JPEG, PNG и WebP.
+func StripMetadata(data []byte) ([]byte, error) {
+	switch {
+	case len(data) > 2 && data[0] == 0xff && data[1] == 0xd8:
+		return stripJPEG(data)
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return stripPNG(data)
+	case len(data) > 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return stripWebP(data)
+	}
+	return nil, ErrUnknownFormat
+}
+
+// stripJPEG оставляет из сегментов APPn только JFIF (APP0), ICC (APP2) и Adobe (APP14)
+func stripJPEG(data []byte) ([]byte, error) {
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:2]...)
+	for p := 2; ; {
+		if p+4 > len(data) || data[p] != 0xff {
+			return nil, errors.New("corrupt JPEG")
+		}
+		marker := data[p+1]
+		// После начала скана метаданных уже нет, копируем остаток целиком
+		if marker == 0xda {
+			return append(out, data[p:]...), nil
+		}
+		size := int(binary.BigEndian.Uint16(data[p+2:]))
+		if size < 2 || p+2+size > len(data) {
+			return nil, errors.New("corrupt JPEG")
+		}
+		segment := data[p : p+2+size]
+		payload := segment[4:]
+
+		keep := true
+		switch {
+		case marker == 0xfe: // COM
+			keep = false
+		case marker >= 0xe0 && marker <= 0xef:
+			keep = (marker == 0xe0 && bytes.HasPrefix(payload, []byte("JFIF\x00"))) ||
+				(marker == 0xe2 && bytes.HasPrefix(payload, []byte("ICC_PROFILE\x00"))) ||
+				(marker == 0xee && bytes.HasPrefix(payload, []byte("Adobe")))
+		}
+		if keep {
+			out = append(out, segment...)
+		}
+		p += 2 + size
+	}
+}
+
+// Чанки PNG, которые могут нести метаданные
+var pngMetadataChunks = map[string]bool{
+	"tEXt": true, "zTXt": true, "iTXt": true, "eXIf": true, "tIME": true,
+}
+
+func stripPNG(data []byte) ([]byte, error) {
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:8]...)
+	for p := 8; p < len(data); {
+		if p+12 > len(data) {
+			return nil, errors.New("corrupt PNG")
+		}
+		size := int(binary.BigEndian.Uint32(data[p:]))
+		if size < 0 || p+12+size > len(data) {
+			return nil, errors.New("corrupt PNG")
+		}
+		if !pngMetadataChunks[string(data[p+4:p+8])] {
+			out = append(out, data[p:p+12+size]...)
+		}
+		p += 12 + size
+	}
+	return out, nil
+}
+
+// stripWebP удаляет чанки EXIF и XMP и снимает соответствующие флаги VP8X
+func stripWebP(data []byte) ([]byte, error) {
+	body := []byte("WEBP")
+	for p := 12; p < len(data); {
+		if p+8 > len(data) {
+			return nil, errors.New("corrupt WebP")
+		}
+		fourCC := string(data[p : p+4])
+		size := int(binary.LittleEndian.Uint32(data[p+4:]))
+		end := p + 8 + size
+		if size < 0 || end > len(data) {
+			return nil, errors.New("corrupt WebP")
+		}
+		switch fourCC {
+		case "EXIF", "XMP ":
+			// пропускаем
+		case "VP8X":
+			payload := append([]byte(nil), data[p+8:end]...)
+			if len(payload) > 0 {
+				payload[0] &^= 0x08 | 0x04
+			}
+			body = appendRIFFChunk(body, fourCC, payload)
+		default:
+			body = appendRIFFChunk(body, fourCC, data[p+8:end])
+		}
+		p = end + size&1
+	}
+
+	out := append([]byte("RIFF"), 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(body)))
+	return append(out, body...), nil
+}
@@ -0,0 +1,196 @@
+package meme
+
+import (
+	"image"
+	"image/draw"
+	"math"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+// vectorFace - font.Face вместе с шрифтом, из которого он создан: по нему
+// контурный текст (TextStyle.Hollow) обводит векторные контуры глифов
+type vectorFace struct {
+	font.Face
+	font  *sfnt.Font
+	scale fixed.Int26_6 // размер em в пикселях, как у opentype.Face
+}
+
+// newFace создает face размера size для разобранного шрифта f
+func newFace(f *opentype.Font, size float64) (font.Face, error) {
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{
+		Size:    size,
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &vectorFace{Face: face, font: f, scale: fixed.Int26_6(0.5 + size*64)}, nil
+}
+
+// strokeText растеризует в маску dst обводку контуров глифов строки
+// радиусом radius со скругленными углами. Глифы расставляются как в
+// drawGlyphs, с теми же сдвигами и поворотами.
+func strokeText(dst *image.Alpha, vf *vectorFace, text string, dot fixed.Point26_6, style TextStyle, radius float64) {
+	r := dst.Bounds()
+	s := &stroker{
+		z:      vector.NewRasterizer(r.Dx(), r.Dy()),
+		origin: vec{float64(r.Min.X), float64(r.Min.Y)},
+		radius: radius,
+	}
+	var buf sfnt.Buffer
+	forEachGlyph(vf, text, dot, style, func(c rune, at fixed.Point26_6, angle float64) (fixed.Int26_6, bool) {
+		advance, ok := vf.GlyphAdvance(c)
+		if !ok {
+			return 0, false
+		}
+		index, err := vf.font.GlyphIndex(&buf, c)
+		if err != nil {
+			return 0, false
+		}
+		segments, err := vf.font.LoadGlyph(&buf, index, vf.scale, nil)
+		if err != nil {
+			return 0, false
+		}
+		// Глиф поворачивается вокруг центра своих границ, как в rotateGlyph
+		b := segments.Bounds().Add(at)
+		center := vec{float64(b.Min.X+b.Max.X) / 128, float64(b.Min.Y+b.Max.Y) / 128}
+		sin, cos := math.Sincos(angle)
+		place := func(p fixed.Point26_6) vec {
+			v := vec{float64(at.X+p.X) / 64, float64(at.Y+p.Y) / 64}
+			if angle == 0 {
+				return v
+			}
+			d := v.sub(center)
+			return center.add(vec{d.x*cos - d.y*sin, d.x*sin + d.y*cos})
+		}
+		for _, seg := range segments {
+			switch seg.Op {
+			case sfnt.SegmentOpMoveTo:
+				s.moveTo(place(seg.Args[0]))
+			case sfnt.SegmentOpLineTo:
+				s.lineTo(place(seg.Args[0]))
+			case sfnt.SegmentOpQuadTo:
+				s.quadTo(place(seg.Args[0]), place(seg.Args[1]))
+			case sfnt.SegmentOpCubeTo:
+				s.cubeTo(place(seg.Args[0]), place(seg.Args[1]), place(seg.Args[2]))
+			}
+		}
+		s.close()
+		return advance, true
+	})
+	s.z.DrawOp = draw.Src
+	s.z.Draw(dst, r, image.Opaque, image.Point{})
+}
+
+// vec - точка или вектор на холсте
+type vec struct{ x, y float64 }
+
+func (a vec) add(b vec) vec             { return vec{a.x + b.x, a.y + b.y} }
+func (a vec) sub(b vec) vec             { return vec{a.x - b.x, a.y - b.y} }
+func (a vec) scale(k float64) vec       { return vec{a.x * k, a.y * k} }
+func (a vec) len() float64              { return math.Hypot(a.x, a.y) }
+func (a vec) lerp(b vec, t float64) vec { return a.add(b.sub(a).scale(t)) }
+
+// stroker обводит контуры: каждый отрезок ломаной становится
+// прямоугольником шириной 2*radius, каждая вершина - кругом радиуса
+// radius. vector.Rasterizer складывает площади со знаком и ограничивает
+// покрытие единицей, поэтому многоугольники с одинаковым обходом
+// объединяются, а не вычитаются.
+type stroker struct {
+	z           *vector.Rasterizer
+	origin      vec // левый верхний угол маски на холсте
+	radius      float64
+	start, last vec
+	open        bool
+}
+
+// Шаг разбиения кривых на отрезки, в пикселях
+const strokeFlatness = 2.0
+
+func (s *stroker) moveTo(p vec) {
+	s.close()
+	s.start, s.last, s.open = p, p, true
+	s.join(p)
+}
+
+func (s *stroker) lineTo(p vec) {
+	d := p.sub(s.last)
+	if l := d.len(); l > 0 {
+		n := vec{-d.y, d.x}.scale(s.radius / l)
+		s.polygon(s.last.add(n), p.add(n), p.sub(n), s.last.sub(n))
+	}
+	s.last = p
+	s.join(p)
+}
+
+func (s *stroker) quadTo(c, p vec) {
+	a := s.last
+	steps := flattenSteps(c.sub(a).len() + p.sub(c).len())
+	for i := 1; i <= steps; i++ {
+		t := float64(i) / float64(steps)
+		s.lineTo(a.lerp(c, t).lerp(c.lerp(p, t), t))
+	}
+}
+
+func (s *stroker) cubeTo(c1, c2, p vec) {
+	a := s.last
+	steps := flattenSteps(c1.sub(a).len() + c2.sub(c1).len() + p.sub(c2).len())
+	for i := 1; i <= steps; i++ {
+		t := float64(i) / float64(steps)
+		ab, bc, cd := a.lerp(c1, t), c1.lerp(c2, t), c2.lerp(p, t)
+		s.lineTo(ab.lerp(bc, t).lerp(bc.lerp(cd, t), t))
+	}
+}
+
+// close замыкает текущий контур
+func (s *stroker) close() {
+	if s.open && s.last != s.start {
+		s.lineTo(s.start)
+	}
+	s.open = false
+}
+
+// join рисует скругленное соединение в вершине p
+func (s *stroker) join(p vec) {
+	n := max(8, min(64, int(2*math.Pi*s.radius/strokeFlatness)))
+	pts := make([]vec, n)
+	for i := range pts {
+		sin, cos := math.Sincos(2 * math.Pi * float64(i) / float64(n))
+		pts[i] = p.add(vec{cos, sin}.scale(s.radius))
+	}
+	s.polygon(pts...)
+}
+
+// polygon добавляет многоугольник, всегда с одним и тем же обходом
+func (s *stroker) polygon(pts ...vec) {
+	var area float64
+	for i, a := range pts {
+		b := pts[(i+1)%len(pts)]
+		area += a.x*b.y - b.x*a.y
+	}
+	if area > 0 {
+		for i, j := 0, len(pts)-1; i < j; i, j = i+1, j-1 {
+			pts[i], pts[j] = pts[j], pts[i]
+		}
+	}
+	at := func(p vec) (float32, float32) {
+		p = p.sub(s.origin)
+		return float32(p.x), float32(p.y)
+	}
+	s.z.MoveTo(at(pts[0]))
+	for _, p := range pts[1:] {
+		s.z.LineTo(at(p))
+	}
+	s.z.ClosePath()
+}
+
+// flattenSteps - число отрезков для кривой с контрольной ломаной длины length
+func flattenSteps(length float64) int {
+	return max(1, min(64, int(length/strokeFlatness)+1))
+}
@@ -0,0 +1,474 @@
+package meme
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// Align - горизонтальное выравнивание текста в слоте
+type Align int
+
+const (
+	AlignCenter Align = iota
+	AlignLeft
+	AlignRight
+)
+
+// PercentRect - прямоугольник в процентах от размера холста (0..100)
+type PercentRect struct {
+	X, Y, W, H float64
+}
+
+// rect переводит проценты в пиксели холста canvas
+func (p PercentRect) rect(canvas image.Rectangle) image.Rectangle {
+	return image.Rect(
+		canvas.Min.X+int(p.X*float64(canvas.Dx())/100),
+		canvas.Min.Y+int(p.Y*float64(canvas.Dy())/100),
+		canvas.Min.X+int((p.X+p.W)*float64(canvas.Dx())/100),
+		canvas.Min.Y+int((p.Y+p.H)*float64(canvas.Dy())/100),
+	)
+}
+
+// valid проверяет, что прямоугольник не выходит за холст
+func (p PercentRect) valid() bool {
+	return p.X >= 0 && p.Y >= 0 && p.W > 0 && p.H > 0 && p.X+p.W <= 100 && p.Y+p.H <= 100
+}
+
+// TextSlot - область шаблона, в которую вписывается подпись
+type TextSlot struct {
+	Name string
+	Rect image.Rectangle // область на холсте шаблона
+
+	// Percent, если задан, заменяет Rect: область пересчитывается под
+	// размер холста, а размеры шрифта и обводки масштабируются вместе с ним
+	Percent PercentRect
+
+	// Шрифт слота; если не задан, используется шрифт генератора
+	FontPath string
+	FontData []byte
+
+	FontSize    float64 // начальный (максимальный) размер, 0 - 48
+	MinFontSize float64 // до какого размера можно уменьшать, 0 - 12
+
+	Color        color.Color // nil - TextColor генератора
+	OutlineColor color.Color // nil - TextOutlineColor генератора
+	OutlineWidth int
+
+	Align     Align
+	Uppercase bool
+	Case      TextCase // если задан, заменяет Uppercase
+
+	// Priority - важность подписи при авторазметке: при пересечении
+	// сдвигается или уменьшается подпись с меньшим приоритетом
+	Priority int
+}
+
+// ImageSlot - область шаблона, которую заполняет картинка пользователя.
+// Картинка масштабируется с обрезкой так, чтобы закрыть всю область.
+type ImageSlot struct {
+	Name    string
+	Rect    image.Rectangle
+	Percent PercentRect  // если задан, заменяет Rect (см. TextSlot.Percent)
+	Filters []FilterSpec // эффекты над картинкой перед вставкой
+}
+
+// Template - именованный формат мема: холст, места для картинок и подписей
+type Template struct {
+	Name string
+
+	// Base - фоновое изображение шаблона; задаёт размер холста.
+	// Если не задано, холст Width x Height заливается Background.
+	Base          image.Image
+	Width, Height int
+	Background    color.Color
+
+	Images []ImageSlot
+	Slots  []TextSlot
+
+	// AllowOverlap отключает авторазметку: подписи рисуются в своих
+	// слотах, даже если перекрывают друг друга
+	AllowOverlap bool
+}
+
+// TemplateInput - содержимое слотов по именам
+type TemplateInput struct {
+	Text   map[string]string
+	Images map[string]image.Image
+
+	// Base заменяет фоновое изображение шаблона. Холст принимает его размер,
+	// а слоты с Percent подстраиваются, поэтому одно описание шаблона
+	// подходит для фото любого разрешения.
+	Base image.Image
+}
+
+// bounds возвращает размер холста шаблона
+func (t *Template) bounds() image.Rectangle {
+	if t.Base != nil {
+		return image.Rect(0, 0, t.Base.Bounds().Dx(), t.Base.Bounds().Dy())
+	}
+	return image.Rect(0, 0, t.Width, t.Height)
+}
+
+// validate проверяет, что шаблон можно отрисовать
+func (t *Template) validate() error {
+	if t.Name == "" {
+		return &ConfigError{Field: "Template.Name", Reason: "must be set"}
+	}
+	if t.bounds().Empty() {
+		return &ConfigError{Field: "Template " + t.Name, Reason: "needs Base or positive Width and Height"}
+	}
+	seen := make(map[string]bool)
+	check := func(name string, p PercentRect) error {
+		if name == "" || seen[name] {
+			return &ConfigError{Field: "Template " + t.Name, Reason: fmt.Sprintf("slot name %q is empty or duplicated", name)}
+		}
+		seen[name] = true
+		if p != (PercentRect{}) && !p.valid() {
+			return &ConfigError{Field: "Template " + t.Name, Reason: fmt.Sprintf("slot %q percentages must lie within 0..100", name)}
+		}
+		return nil
+	}
+	for _, s := range t.Slots {
+		if err := check(s.Name, s.Percent); err != nil {
+			return err
+		}
+		if err := s.Case.check("Template " + t.Name + " slot " + s.Name); err != nil {
+			return err
+		}
+	}
+	for _, s := range t.Images {
+		if err := check(s.Name, s.Percent); err != nil {
+			return err
+		}
+		if _, err := newChain(s.Filters); err != nil {
+			return &ConfigError{Field: "Template " + t.Name, Reason: fmt.Sprintf("slot %q: %v", s.Name, err)}
+		}
+	}
+	return nil
+}
+
+// Реестр шаблонов
+var (
+	templatesMu sync.RWMutex
+	templates   = make(map[string]*Template)
+)
+
+// RegisterTemplate добавляет шаблон в реестр под именем name
+// (заменяя существующий с тем же именем)
+func RegisterTemplate(name string, t *Template) error {
+	tmpl := *t
+	tmpl.Name = name
+	if err := tmpl.validate(); err != nil {
+		return err
+	}
+	templatesMu.Lock()
+	templates[name] = &tmpl
+	templatesMu.Unlock()
+	return nil
+}
+
+// LookupTemplate возвращает зарегистрированный шаблон
+func LookupTemplate(name string) (*Template, bool) {
+	templatesMu.RLock()
+	defer templatesMu.RUnlock()
+	t, ok := templates[name]
+	return t, ok
+}
+
+// Templates возвращает отсортированные имена зарегистрированных шаблонов
+func Templates() []string {
+	templatesMu.RLock()
+	defer templatesMu.RUnlock()
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// GenerateTemplate заполняет слоты зарегистрированного шаблона по именам.
+// Незаполненные слоты остаются пустыми; неизвестное имя слота - ошибка.
+func (g *Generator) GenerateTemplate(name string, in TemplateInput) (*image.RGBA, error) {
+	t, ok := LookupTemplate(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+	return g.RenderTemplate(t, in)
+}
+
+// RenderTemplate заполняет слоты шаблона, не требуя его регистрации
+func (g *Generator) RenderTemplate(t *Template, in TemplateInput) (_ *image.RGBA, err error) {
+	defer recoverPanic(&err)
+	if err := t.validate(); err != nil {
+		return nil, err
+	}
+	for slot := range in.Text {
+		if !slices.ContainsFunc(t.Slots, func(s TextSlot) bool { return s.Name == slot }) {
+			return nil, fmt.Errorf("%w: template %s has no text slot %q", ErrUnknownSlot, t.Name, slot)
+		}
+	}
+	for slot := range in.Images {
+		if !slices.ContainsFunc(t.Images, func(s ImageSlot) bool { return s.Name == slot }) {
+			return nil, fmt.Errorf("%w: template %s has no image slot %q", ErrUnknownSlot, t.Name, slot)
+		}
+	}
+
+	canvas, base := t.bounds(), t.Base
+	if in.Base != nil {
+		base = in.Base
+		canvas = image.Rect(0, 0, base.Bounds().Dx(), base.Bounds().Dy())
+	}
+	if err := checkCanvas(canvas); err != nil {
+		return nil, err
+	}
+	// Во сколько раз холст больше исходного размера шаблона
+	scale := float64(canvas.Dy()) / float64(t.bounds().Dy())
+	out := image.NewRGBA(canvas)
+
+	bg := t.Background
+	if bg == nil {
+		bg = g.config.BackgroundColor
+	}
+	draw.Draw(out, canvas, image.NewUniform(bg), image.Point{}, draw.Src)
+	if base != nil {
+		drawSource(out, canvas, base, base.Bounds().Min)
+	}
+
+	for _, s := range t.Images {
+		if img := in.Images[s.Name]; img != nil {
+			r := s.Rect
+			if s.Percent != (PercentRect{}) {
+				r = s.Percent.rect(canvas)
+			}
+			if len(s.Filters) > 0 {
+				c, _ := newChain(s.Filters) // проверено в validate
+				if img, err = c.Apply(context.Background(), img); err != nil {
+					return nil, fmt.Errorf("image slot %s: %w", s.Name, err)
+				}
+			}
+			drawCover(out, r, img)
+		}
+	}
+
+	var slots []TextSlot
+	var texts []string
+	for _, s := range t.Slots {
+		text := in.Text[s.Name]
+		if text == "" {
+			continue
+		}
+		if s.Percent != (PercentRect{}) {
+			s = s.scaled(canvas, scale)
+		}
+		slots = append(slots, s)
+		texts = append(texts, text)
+	}
+	fits, err := g.layoutSlots(canvas, slots, texts, !t.AllowOverlap)
+	if err != nil {
+		return nil, err
+	}
+	for i, fit := range fits {
+		g.drawFitted(out, slots[i], fit)
+		fit.face.Close()
+	}
+	return out, nil
+}
+
+// scaled пересчитывает слот с Percent под холст canvas, который в scale раз
+// больше исходного холста шаблона. Размеры шрифта по умолчанию подставляются
+// до умножения, чтобы и они росли вместе с холстом.
+func (s TextSlot) scaled(canvas image.Rectangle, scale float64) TextSlot {
+	if s.FontSize <= 0 {
+		s.FontSize = defaultSlotFontSize
+	}
+	if s.MinFontSize <= 0 {
+		s.MinFontSize = defaultSlotMinFontSize
+	}
+	s.Rect = s.Percent.rect(canvas)
+	s.FontSize *= scale
+	s.MinFontSize *= scale
+	s.OutlineWidth = int(math.Round(float64(s.OutlineWidth) * scale))
+	return s
+}
+
+// drawCover масштабирует img так, чтобы он закрыл r целиком, обрезая лишнее по центру
+func drawCover(dst draw.Image, r image.Rectangle, img image.Image) {
+	b := img.Bounds()
+	if r.Empty() || b.Empty() {
+		return
+	}
+	src := b
+	// Сравниваем пропорции: b.Dx/b.Dy > r.Dx/r.Dy - источник шире области
+	if b.Dx()*r.Dy() > r.Dx()*b.Dy() {
+		w := b.Dy() * r.Dx() / r.Dy()
+		src.Min.X += (b.Dx() - w) / 2
+		src.Max.X = src.Min.X + w
+	} else {
+		h := b.Dx() * r.Dy() / r.Dx()
+		src.Min.Y += (b.Dy() - h) / 2
+		src.Max.Y = src.Min.Y + h
+	}
+	xdraw.CatmullRom.Scale(dst, r, img, src, xdraw.Over, nil)
+}
+
+// Размеры шрифта слота по умолчанию
+const (
+	defaultSlotFontSize    = 48
+	defaultSlotMinFontSize = 12
+)
+
+// slotText - подпись, вписанная в слот: шрифт подобранного размера,
+// строки и занимаемая ими область на холсте
+type slotText struct {
+	face    font.Face
+	lines   []string
+	size    float64
+	minSize float64
+	box     image.Rectangle
+}
+
+// drawSlotText вписывает подпись в слот: переносит по словам и уменьшает
+// шрифт, пока текст не поместится, затем центрирует его по вертикали
+func (g *Generator) drawSlotText(dst draw.Image, s TextSlot, text string) error {
+	fit, err := g.fitSlotText(s, text, s.FontSize)
+	if err != nil {
+		return err
+	}
+	defer fit.face.Close()
+	g.drawFitted(dst, s, fit)
+	return nil
+}
+
+// fitSlotText подбирает размер шрифта не больше size, при котором
+// подпись помещается в слот
+func (g *Generator) fitSlotText(s TextSlot, text string, size float64) (*slotText, error) {
+	cfg := g.config
+	text = clampCombining(s.Case.resolve(s.Uppercase).Apply(text))
+	fontPath, fontData := s.FontPath, s.FontData
+	if fontPath == "" && len(fontData) == 0 {
+		fontPath, fontData = cfg.FontPath, cfg.FontData
+	}
+	if size <= 0 {
+		size = defaultSlotFontSize
+	}
+	minSize := s.MinFontSize
+	if minSize <= 0 {
+		minSize = defaultSlotMinFontSize
+	}
+	maxWidth := s.Rect.Dx() - 2*s.OutlineWidth
+
+	tr := g.textRenderer()
+	var face font.Face
+	var lines []string
+	for {
+		var err error
+		face, err = g.loadFontFrom(fontPath, fontData, size)
+		if err != nil {
+			return nil, fmt.Errorf("loading font for slot %s: %w", s.Name, err)
+		}
+		lines = wrapText(tr, face, text, maxWidth)
+		height := len(lines) * face.Metrics().Height.Ceil()
+		if (height <= s.Rect.Dy() && linesFit(tr, face, lines, maxWidth)) || size*0.9 < minSize {
+			break
+		}
+		face.Close()
+		size *= 0.9
+	}
+
+	// Область текста: по вертикали центр слота, по горизонтали - самые широкие строки
+	lineHeight := face.Metrics().Height.Ceil()
+	top := s.Rect.Min.Y + (s.Rect.Dy()-len(lines)*lineHeight)/2
+	box := image.Rectangle{Min: image.Pt(s.Rect.Max.X, top), Max: image.Pt(s.Rect.Min.X, top+len(lines)*lineHeight)}
+	for _, line := range lines {
+		x, width := slotLineX(tr, s, face, line)
+		box.Min.X = min(box.Min.X, x-s.OutlineWidth)
+		box.Max.X = max(box.Max.X, x+width+s.OutlineWidth)
+	}
+	return &slotText{face: face, lines: lines, size: size, minSize: minSize, box: box}, nil
+}
+
+// slotLineX возвращает начало и ширину строки с учётом выравнивания слота
+func slotLineX(tr TextRenderer, s TextSlot, face font.Face, line string) (x, width int) {
+	width = tr.Measure(face, line).Ceil()
+	switch s.Align {
+	case AlignLeft:
+		x = s.Rect.Min.X + s.OutlineWidth
+	case AlignRight:
+		x = s.Rect.Max.X - s.OutlineWidth - width
+	default:
+		x = s.Rect.Min.X + (s.Rect.Dx()-width)/2
+	}
+	return x, width
+}
+
+// drawFitted рисует подобранную подпись, начиная с верхнего края fit.box
+func (g *Generator) drawFitted(dst draw.Image, s TextSlot, fit *slotText) {
+	cfg := g.config
+	textColor, outlineColor := s.Color, s.OutlineColor
+	if textColor == nil {
+		textColor = cfg.TextColor
+	}
+	if outlineColor == nil {
+		outlineColor = cfg.TextOutlineColor
+	}
+	if outlineColor == nil {
+		outlineColor = color.Black
+	}
+
+	tr := g.textRenderer()
+	m := fit.face.Metrics()
+	lineHeight := m.Height.Ceil()
+	y := fit.box.Min.Y + m.Ascent.Ceil()
+	for _, line := range fit.lines {
+		x, _ := slotLineX(tr, s, fit.face, line)
+		tr.Draw(dst, fit.face, line, fixed.P(x, y), TextStyle{Color: textColor, OutlineColor: outlineColor, OutlineWidth: s.OutlineWidth, Linear: cfg.LinearBlending})
+		y += lineHeight
+	}
+}
+
+// wrapText разбивает текст на строки не шире maxWidth по границам слов.
+// Явные переводы строк сохраняются; слово длиннее строки остаётся целым.
+func wrapText(tr TextRenderer, face font.Face, text string, maxWidth int) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		words := strings.Fields(paragraph)
+		if len(words) == 0 {
+			lines = append(lines, "")
+			continue
+		}
+		line := words[0]
+		for _, w := range words[1:] {
+			candidate := line + " " + w
+			if tr.Measure(face, candidate).Ceil() <= maxWidth {
+				line = candidate
+				continue
+			}
+			lines = append(lines, line)
+			line = w
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// linesFit проверяет, что каждая строка не шире maxWidth
+func linesFit(tr TextRenderer, face font.Face, lines []string, maxWidth int) bool {
+	for _, l := range lines {
+		if tr.Measure(face, l).Ceil() > maxWidth {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,75 @@
+package meme
+
+import (
+	"image"
+	"image/color"
+)
+
+// Встроенные шаблоны. Картинок они не содержат: фото подставляет
+// пользователь через слоты изображений, поэтому шаблоны - чистые данные.
+var builtinTemplates = []*Template{
+	{
+		// Две реакции слева, подписи справа: "нет" сверху, "да" снизу
+		Name:       "drake",
+		Width:      1200,
+		Height:     1200,
+		Background: color.White,
+		Images: []ImageSlot{
+			{Name: "reject_image", Rect: image.Rect(0, 0, 600, 600)},
+			{Name: "approve_image", Rect: image.Rect(0, 600, 600, 1200)},
+		},
+		Slots: []TextSlot{
+			{Name: "reject", Rect: image.Rect(630, 30, 1170, 570), FontSize: 72, Color: color.Black},
+			{Name: "approve", Rect: image.Rect(630, 630, 1170, 1170), FontSize: 72, Color: color.Black},
+		},
+	},
+	{
+		// Одно фото и три подписи над героями: слева, в центре и справа
+		Name:   "distracted",
+		Width:  1200,
+		Height: 800,
+		Images: []ImageSlot{
+			{Name: "image", Rect: image.Rect(0, 0, 1200, 800)},
+		},
+		Slots: []TextSlot{
+			{Name: "left", Rect: image.Rect(60, 420, 460, 580), FontSize: 56, OutlineWidth: 3, Uppercase: true},
+			{Name: "center", Rect: image.Rect(460, 300, 800, 460), FontSize: 56, OutlineWidth: 3, Uppercase: true},
+			{Name: "right", Rect: image.Rect(800, 380, 1160, 540), FontSize: 56, OutlineWidth: 3, Uppercase: true},
+		},
+	},
+	{
+		// Два кадра друг над другом, у каждого подпись внизу
+		Name:   "two-panel",
+		Width:  800,
+		Height: 1000,
+		Images: []ImageSlot{
+			{Name: "top_image", Rect: image.Rect(0, 0, 800, 500)},
+			{Name: "bottom_image", Rect: image.Rect(0, 500, 800, 1000)},
+		},
+		Slots: []TextSlot{
+			{Name: "top", Rect: image.Rect(20, 370, 780, 490), FontSize: 56, OutlineWidth: 3, Uppercase: true},
+			{Name: "bottom", Rect: image.Rect(20, 870, 780, 990), FontSize: 56, OutlineWidth: 3, Uppercase: true},
+		},
+	},
+	{
+		// Классический мем: текст сверху и снизу поверх одной картинки
+		Name:   "classic",
+		Width:  800,
+		Height: 800,
+		Images: []ImageSlot{
+			{Name: "image", Rect: image.Rect(0, 0, 800, 800)},
+		},
+		Slots: []TextSlot{
+			{Name: "top", Rect: image.Rect(20, 20, 780, 200), FontSize: 72, OutlineWidth: 4, Uppercase: true},
+			{Name: "bottom", Rect: image.Rect(20, 600, 780, 780), FontSize: 72, OutlineWidth: 4, Uppercase: true},
+		},
+	},
+}
+
+func init() {
+	for _, t := range builtinTemplates {
+		if err := RegisterTemplate(t.Name, t); err != nil {
+			panic(err)
+		}
+	}
+}
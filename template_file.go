@@ -0,0 +1,224 @@
+package meme
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"io/fs"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Описание шаблона в файле JSON или YAML. Координаты слотов задаются
+// в процентах от размера холста, цвета - строками "#rrggbb".
+//
+//	name: drake
+//	width: 1200
+//	height: 1200
+//	background: "#ffffff"
+//	images:
+//	  - {name: reject_image, rect: {x: 0, y: 0, w: 50, h: 50}, filters: [{name: grayscale}]}
+//	slots:
+//	  - name: reject
+//	    rect: {x: 52, y: 2, w: 46, h: 46}
+//	    font: bold            # встроенный шрифт или путь к файлу в том же fs.FS
+//	    font_size: 72
+//	    color: "#000000"
+//	    align: center        # left, center, right
+//	    uppercase: false
+//	    priority: 1          # при пересечении подписей сдвигается менее важная
+type templateFile struct {
+	Name       string              `json:"name" yaml:"name"`
+	Base       string              `json:"base,omitempty" yaml:"base,omitempty"`
+	Width      int                 `json:"width,omitempty" yaml:"width,omitempty"`
+	Height     int                 `json:"height,omitempty" yaml:"height,omitempty"`
+	Background string              `json:"background,omitempty" yaml:"background,omitempty"`
+	Images     []templateFileImage `json:"images,omitempty" yaml:"images,omitempty"`
+	Slots      []templateFileSlot  `json:"slots,omitempty" yaml:"slots,omitempty"`
+
+	AllowOverlap bool `json:"allow_overlap,omitempty" yaml:"allow_overlap,omitempty"`
+}
+
+// templateFileRect - прямоугольник в процентах от холста
+type templateFileRect struct {
+	X float64 `json:"x" yaml:"x"`
+	Y float64 `json:"y" yaml:"y"`
+	W float64 `json:"w" yaml:"w"`
+	H float64 `json:"h" yaml:"h"`
+}
+
+type templateFileImage struct {
+	Name    string           `json:"name" yaml:"name"`
+	Rect    templateFileRect `json:"rect" yaml:"rect"`
+	Filters []FilterSpec     `json:"filters,omitempty" yaml:"filters,omitempty"`
+}
+
+type templateFileSlot struct {
+	Name         string           `json:"name" yaml:"name"`
+	Rect         templateFileRect `json:"rect" yaml:"rect"`
+	Font         string           `json:"font,omitempty" yaml:"font,omitempty"`
+	FontSize     float64          `json:"font_size,omitempty" yaml:"font_size,omitempty"`
+	MinFontSize  float64          `json:"min_font_size,omitempty" yaml:"min_font_size,omitempty"`
+	Color        string           `json:"color,omitempty" yaml:"color,omitempty"`
+	OutlineColor string           `json:"outline_color,omitempty" yaml:"outline_color,omitempty"`
+	OutlineWidth int              `json:"outline_width,omitempty" yaml:"outline_width,omitempty"`
+	Align        string           `json:"align,omitempty" yaml:"align,omitempty"`
+	Uppercase    bool             `json:"uppercase,omitempty" yaml:"uppercase,omitempty"`
+	Case         TextCase         `json:"case,omitempty" yaml:"case,omitempty"`
+	Priority     int              `json:"priority,omitempty" yaml:"priority,omitempty"`
+}
+
+// LoadTemplate читает описание шаблона из файла .json, .yaml или .yml.
+// Фоновое изображение и шрифты ищутся в fsys относительно каталога файла.
+// Шаблон не регистрируется: для этого передайте его в RegisterTemplate.
+// Фон поворачивается по EXIF и переводится в sRGB, его размер ограничен
+// DefaultMaxBytes и DefaultMaxPixels.
+func LoadTemplate(fsys fs.FS, name string) (*Template, error) {
+	return LoadTemplateWithOptions(fsys, name, &DecodeOptions{AutoOrient: true, ColorManagement: true})
+}
+
+// LoadTemplateWithOptions - LoadTemplate с настройками декодирования фона
+// opts (nil - без поворота и перевода цвета). Нулевые поля Limits
+// заменяются на DefaultMaxBytes и DefaultMaxPixels.
+func LoadTemplateWithOptions(fsys fs.FS, name string, opts *DecodeOptions) (*Template, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, fmt.Errorf("reading template: %w", err)
+	}
+
+	var tf templateFile
+	switch ext := strings.ToLower(path.Ext(name)); ext {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&tf)
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(&tf)
+	default:
+		return nil, fmt.Errorf("unsupported template file extension %q", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing template %s: %w", name, err)
+	}
+
+	t, err := tf.template(fsys, path.Dir(name), opts)
+	if err != nil {
+		return nil, fmt.Errorf("template %s: %w", name, err)
+	}
+	if err := t.validate(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// template переводит описание из файла в Template
+func (tf *templateFile) template(fsys fs.FS, dir string, opts *DecodeOptions) (*Template, error) {
+	t := &Template{Name: tf.Name, Width: tf.Width, Height: tf.Height, AllowOverlap: tf.AllowOverlap}
+
+	if tf.Base != "" {
+		f, err := fsys.Open(path.Join(dir, tf.Base))
+		if err != nil {
+			return nil, fmt.Errorf("opening base image: %w", err)
+		}
+		defer f.Close()
+		if t.Base, _, err = DecodeImage(f, baseDecodeOptions(opts)); err != nil {
+			return nil, fmt.Errorf("base image: %w", err)
+		}
+	}
+	var err error
+	if t.Background, err = optionalColor(tf.Background); err != nil {
+		return nil, err
+	}
+
+	// Проценты сохраняем в слотах, чтобы шаблон подстраивался под
+	// TemplateInput.Base, а Rect заполняем для размера самого шаблона
+	canvas := t.bounds()
+	for _, img := range tf.Images {
+		p := PercentRect(img.Rect)
+		t.Images = append(t.Images, ImageSlot{Name: img.Name, Rect: p.rect(canvas), Percent: p, Filters: img.Filters})
+	}
+
+	for _, s := range tf.Slots {
+		p := PercentRect(s.Rect)
+		slot := TextSlot{
+			Name:         s.Name,
+			Rect:         p.rect(canvas),
+			Percent:      p,
+			FontSize:     s.FontSize,
+			MinFontSize:  s.MinFontSize,
+			OutlineWidth: s.OutlineWidth,
+			Uppercase:    s.Uppercase,
+			Case:         s.Case,
+			Priority:     s.Priority,
+		}
+		if slot.FontData, err = templateFont(fsys, dir, s.Font); err != nil {
+			return nil, fmt.Errorf("slot %s: %w", s.Name, err)
+		}
+		if slot.Color, err = optionalColor(s.Color); err != nil {
+			return nil, fmt.Errorf("slot %s: %w", s.Name, err)
+		}
+		if slot.OutlineColor, err = optionalColor(s.OutlineColor); err != nil {
+			return nil, fmt.Errorf("slot %s: %w", s.Name, err)
+		}
+		switch s.Align {
+		case "", "center":
+			slot.Align = AlignCenter
+		case "left":
+			slot.Align = AlignLeft
+		case "right":
+			slot.Align = AlignRight
+		default:
+			return nil, fmt.Errorf("slot %s: unknown align %q", s.Name, s.Align)
+		}
+		t.Slots = append(t.Slots, slot)
+	}
+	return t, nil
+}
+
+// baseDecodeOptions дополняет настройки вызывающего ограничениями по умолчанию
+func baseDecodeOptions(opts *DecodeOptions) *DecodeOptions {
+	var o DecodeOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Limits.MaxBytes == 0 {
+		o.Limits.MaxBytes = DefaultMaxBytes
+	}
+	if o.Limits.MaxPixels == 0 {
+		o.Limits.MaxPixels = DefaultMaxPixels
+	}
+	return &o
+}
+
+// templateFont возвращает данные шрифта по имени встроенного шрифта
+// или пути в fsys; пустое имя - шрифт генератора
+func templateFont(fsys fs.FS, dir, name string) ([]byte, error) {
+	if name == "" {
+		return nil, nil
+	}
+	if data, ok := GetAvailableFonts()[name]; ok {
+		return data, nil
+	}
+	data, err := fs.ReadFile(fsys, path.Join(dir, name))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrFontNotFound, name)
+	}
+	return data, nil
+}
+
+// optionalColor разбирает цвет; пустая строка даёт nil (значение по умолчанию)
+func optionalColor(s string) (color.Color, error) {
+	if s == "" {
+		return nil, nil
+	}
+	c, err := ParseColor(s)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
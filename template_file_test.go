@@ -0,0 +1,78 @@
+package meme
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"testing"
+	"testing/fstest"
+)
+
+const baseTemplateYAML = "name: base\nbase: base.png\n"
+
+func TestLoadTemplateBaseLimits(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatal(err)
+	}
+	// Заголовок объявляет 100000x100000, больше DefaultMaxPixels
+	bomb := bytes.Clone(buf.Bytes())
+	binary.BigEndian.PutUint32(bomb[16:], 100000)
+	binary.BigEndian.PutUint32(bomb[20:], 100000)
+	binary.BigEndian.PutUint32(bomb[29:], crc32.ChecksumIEEE(bomb[12:29]))
+
+	fsys := fstest.MapFS{
+		"t.yaml":   {Data: []byte(baseTemplateYAML)},
+		"base.png": {Data: bomb},
+	}
+	if _, err := LoadTemplate(fsys, "t.yaml"); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("LoadTemplate: %v, want ErrImageTooLarge", err)
+	}
+	if _, err := LoadTemplateWithOptions(fsys, "t.yaml", &DecodeOptions{AutoOrient: true}); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("options without limits: %v, want ErrImageTooLarge", err)
+	}
+	if _, err := LoadTemplateWithOptions(fsys, "t.yaml", &DecodeOptions{Limits: Limits{MaxBytes: 16}}); !errors.Is(err, ErrInputTooLarge) {
+		t.Errorf("caller byte limit: %v, want ErrInputTooLarge", err)
+	}
+}
+
+func TestLoadTemplateBaseOrientation(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 4, 2))); err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{
+		"t.yaml":   {Data: []byte(baseTemplateYAML)},
+		"base.png": {Data: withPNGChunk(t, buf.Bytes(), "eXIf", exifTIFF(binary.BigEndian, orientRotate90))},
+	}
+
+	// LoadTemplate поворачивает фон по EXIF
+	tmpl, err := LoadTemplate(fsys, "t.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tmpl.Base.Bounds().Size(); got != image.Pt(2, 4) {
+		t.Errorf("LoadTemplate: base %v, want 2x4", got)
+	}
+
+	// LoadTemplateWithOptions поворачивает, только если попросили
+	for _, tc := range []struct {
+		opts *DecodeOptions
+		want image.Point
+	}{
+		{nil, image.Pt(4, 2)},
+		{&DecodeOptions{}, image.Pt(4, 2)},
+		{&DecodeOptions{AutoOrient: true}, image.Pt(2, 4)},
+	} {
+		tmpl, err := LoadTemplateWithOptions(fsys, "t.yaml", tc.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := tmpl.Base.Bounds().Size(); got != tc.want {
+			t.Errorf("options %+v: base %v, want %v", tc.opts, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package meme
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// inkHeight возвращает высоту области, где канал R ярче половины
+func inkHeight(img *image.RGBA) int {
+	top, bottom := -1, -1
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if img.RGBAAt(x, y).R > 0x80 {
+				if top < 0 {
+					top = y
+				}
+				bottom = y
+				break
+			}
+		}
+	}
+	if top < 0 {
+		return 0
+	}
+	return bottom - top + 1
+}
+
+func TestPercentSlotDefaultFontScales(t *testing.T) {
+	tmpl := &Template{
+		Name:       "percent",
+		Width:      400,
+		Height:     200,
+		Background: color.Black,
+		Slots: []TextSlot{{
+			Name:    "text",
+			Percent: PercentRect{X: 0, Y: 0, W: 100, H: 100},
+			Color:   color.White,
+		}},
+	}
+	g := NewGenerator(nil)
+	in := TemplateInput{Text: map[string]string{"text": "HI"}}
+	small, err := g.RenderTemplate(tmpl, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in.Base = image.NewRGBA(image.Rect(0, 0, 800, 400))
+	large, err := g.RenderTemplate(tmpl, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hs, hl := inkHeight(small), inkHeight(large)
+	if hs == 0 {
+		t.Fatal("no text on the 1x canvas")
+	}
+	if ratio := float64(hl) / float64(hs); ratio < 1.8 || ratio > 2.2 {
+		t.Errorf("text height %d on 2x base vs %d on 1x, ratio %.2f, want ~2", hl, hs, ratio)
+	}
+}
@@ -0,0 +1,78 @@
+package meme
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TextCase - смена регистра отдельной подписи
+type TextCase string
+
+const (
+	// CaseDefault - регистр по общей настройке: Config.TextUppercase для
+	// подписей демотиватора, TextSlot.Uppercase для слотов шаблона
+	CaseDefault  TextCase = ""
+	CaseAsIs     TextCase = "none"     // как написано
+	CaseUpper    TextCase = "upper"    // ВСЕ ЗАГЛАВНЫЕ
+	CaseLower    TextCase = "lower"    // все строчные
+	CaseSentence TextCase = "sentence" // Первая буква заглавная, остальное как написано
+	CaseTitle    TextCase = "title"    // Каждое Слово С Заглавной
+)
+
+// valid сообщает, что регистр известен
+func (c TextCase) valid() bool {
+	switch c {
+	case CaseDefault, CaseAsIs, CaseUpper, CaseLower, CaseSentence, CaseTitle:
+		return true
+	}
+	return false
+}
+
+// check возвращает ошибку поля field для неизвестного регистра
+func (c TextCase) check(field string) error {
+	if c.valid() {
+		return nil
+	}
+	return &ConfigError{Field: field, Reason: fmt.Sprintf("unknown case %q, want none, upper, lower, sentence or title", string(c))}
+}
+
+// resolve заменяет CaseDefault общей настройкой uppercase
+func (c TextCase) resolve(uppercase bool) TextCase {
+	if c != CaseDefault {
+		return c
+	}
+	if uppercase {
+		return CaseUpper
+	}
+	return CaseAsIs
+}
+
+// Apply меняет регистр s; CaseDefault оставляет s как есть
+func (c TextCase) Apply(s string) string {
+	switch c {
+	case CaseUpper:
+		return toUpperSafe(s)
+	case CaseLower:
+		return strings.ToLower(s)
+	case CaseSentence:
+		r, n := utf8.DecodeRuneInString(s)
+		if n == 0 || r == utf8.RuneError {
+			return s
+		}
+		return string(unicode.ToUpper(r)) + s[n:]
+	case CaseTitle:
+		var b strings.Builder
+		start := true
+		for _, r := range s {
+			if start {
+				r = unicode.ToTitle(r)
+			}
+			start = unicode.IsSpace(r)
+			b.WriteRune(r)
+		}
+		return b.String()
+	}
+	return s
+}
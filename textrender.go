@@ -0,0 +1,87 @@
+package meme
+
+import (
+	"image/color"
+	"image/draw"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// TextStyle - оформление строки, которое рисует TextRenderer
+type TextStyle struct {
+	Color        color.Color
+	OutlineColor color.Color // nil, если OutlineWidth равен 0
+	OutlineWidth int         // ширина обводки в пикселях, 0 - без обводки
+
+	// Hollow - только контур шириной OutlineWidth (не меньше 1) цвета
+	// Color, без заливки, по векторным контурам глифов; OutlineColor не
+	// используется
+	Hollow bool
+
+	// Extrude - объемные буквы позади заливки и обводки
+	Extrude Extrude
+	// Glow - свечение позади всего остального
+	Glow Glow
+
+	// Jitter - случайный сдвиг и поворот глифов; узор задает JitterSeed
+	Jitter     Jitter
+	JitterSeed uint64
+	// Wave - волна по вертикали вдоль строки
+	Wave Wave
+
+	// Linear - смешивать края глифов с фоном в линейном свете
+	// (Config.LinearBlending), а не в значениях sRGB
+	Linear bool
+}
+
+// TextRenderer измеряет и рисует строки подписей. Генератор сам
+// выбирает шрифт, размер, перенос строк и положение, а рендерер отвечает
+// только за глифы, поэтому его можно заменить реализацией с шейпингом
+// (лигатуры, письмо справа налево) или отрисовкой на GPU, не меняя
+// остальной конвейер. Реализация должна быть безопасна для
+// одновременного использования: ParallelRender и серверы рисуют
+// несколько подписей сразу.
+type TextRenderer interface {
+	// Measure возвращает ширину строки - по ней подписи центрируются и
+	// переносятся
+	Measure(face font.Face, text string) fixed.Int26_6
+
+	// Draw рисует строку с началом базовой линии в точке dot. С
+	// Config.SubpixelText dot.X может быть дробным.
+	Draw(dst draw.Image, face font.Face, text string, dot fixed.Point26_6, style TextStyle)
+}
+
+// DrawerTextRenderer - рендерер по умолчанию на font.Drawer: глифы без
+// шейпинга, обводка расширением альфа-маски, контурный текст - обводкой
+// векторных контуров глифов
+type DrawerTextRenderer struct{}
+
+var _ TextRenderer = DrawerTextRenderer{}
+
+// Measure возвращает сумму advance глифов с учетом кернинга
+func (DrawerTextRenderer) Measure(face font.Face, text string) fixed.Int26_6 {
+	return font.MeasureString(face, text)
+}
+
+// Draw рисует строку с обводкой style.OutlineWidth или контур; с style.Linear
+// смешивание идет по таблицам и заметно медленнее
+func (DrawerTextRenderer) Draw(dst draw.Image, face font.Face, text string, dot fixed.Point26_6, style TextStyle) {
+	drawOutlinedText(dst, face, text, dot, style)
+}
+
+// SetTextRenderer заменяет отрисовку текста во всех режимах генератора
+// (nil - DrawerTextRenderer). Как и SetCache, нельзя вызывать
+// одновременно с генерацией; со своим рендерером кеш результатов не
+// используется (см. SetCache).
+func (g *Generator) SetTextRenderer(r TextRenderer) {
+	g.text = r
+}
+
+// textRenderer возвращает подключенный рендерер или рендерер по умолчанию
+func (g *Generator) textRenderer() TextRenderer {
+	if g.text == nil {
+		return DrawerTextRenderer{}
+	}
+	return g.text
+}
@@ -0,0 +1,260 @@
+package meme
+
+import (
+	"fmt"
+	"image/color"
+	"slices"
+	"sync"
+
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/gosmallcaps"
+)
+
+// Theme - набор оформления: цвета, шрифт, рамка и отступы
+type Theme struct {
+	Name string
+
+	FontPath string
+	FontData []byte
+	FontSize float64
+
+	Padding int
+	Border  int
+
+	BackgroundColor  color.Color
+	BorderColor      color.Color
+	TextColor        color.Color
+	TextOutlineColor color.Color
+	TextOutlineWidth int
+
+	TextUppercase bool
+}
+
+// Встроенные темы
+var builtinThemes = []*Theme{
+	{
+		// Классический демотиватор: белая рамка и текст на черном
+		Name:             "classic-black",
+		FontSize:         48,
+		Padding:          80,
+		Border:           10,
+		BackgroundColor:  color.RGBA{0, 0, 0, 255},
+		BorderColor:      color.RGBA{255, 255, 255, 255},
+		TextColor:        color.RGBA{255, 255, 255, 255},
+		TextOutlineColor: color.RGBA{0, 0, 0, 255},
+		TextUppercase:    true,
+	},
+	{
+		// Светлый вариант: тонкая черная рамка, текст без капса
+		Name:             "clean-white",
+		FontSize:         44,
+		Padding:          60,
+		Border:           4,
+		BackgroundColor:  color.RGBA{255, 255, 255, 255},
+		BorderColor:      color.RGBA{0, 0, 0, 255},
+		TextColor:        color.RGBA{17, 17, 17, 255},
+		TextOutlineColor: color.RGBA{255, 255, 255, 255},
+	},
+	{
+		// Неон на фиолетовом с цветной обводкой
+		Name:             "vaporwave",
+		FontData:         gobold.TTF,
+		FontSize:         52,
+		Padding:          80,
+		Border:           8,
+		BackgroundColor:  color.RGBA{0x1a, 0x0b, 0x2e, 255},
+		BorderColor:      color.RGBA{0xff, 0x71, 0xce, 255},
+		TextColor:        color.RGBA{0x01, 0xcd, 0xfe, 255},
+		TextOutlineColor: color.RGBA{0xb9, 0x67, 0xff, 255},
+		TextOutlineWidth: 2,
+		TextUppercase:    true,
+	},
+	{
+		// Газетная полоса: капитель на желтоватой бумаге
+		Name:             "newspaper",
+		FontData:         gosmallcaps.TTF,
+		FontSize:         44,
+		Padding:          70,
+		Border:           2,
+		BackgroundColor:  color.RGBA{0xf4, 0xf1, 0xea, 255},
+		BorderColor:      color.RGBA{0x22, 0x22, 0x22, 255},
+		TextColor:        color.RGBA{0x11, 0x11, 0x11, 255},
+		TextOutlineColor: color.RGBA{0xf4, 0xf1, 0xea, 255},
+	},
+	{
+		// Для дальтоников (палитра Okabe-Ito): желтый текст и оранжевая
+		// рамка на черном различимы при любом типе нарушения цветовосприятия
+		Name:             "cvd-dark",
+		FontSize:         48,
+		Padding:          80,
+		Border:           8,
+		BackgroundColor:  color.RGBA{0, 0, 0, 255},
+		BorderColor:      color.RGBA{0xe6, 0x9f, 0x00, 255},
+		TextColor:        color.RGBA{0xf0, 0xe4, 0x42, 255},
+		TextOutlineColor: color.RGBA{0, 0, 0, 255},
+		TextUppercase:    true,
+	},
+	{
+		// Светлый вариант для дальтоников: синяя рамка, черный текст
+		Name:             "cvd-light",
+		FontSize:         44,
+		Padding:          60,
+		Border:           6,
+		BackgroundColor:  color.RGBA{255, 255, 255, 255},
+		BorderColor:      color.RGBA{0x00, 0x72, 0xb2, 255},
+		TextColor:        color.RGBA{0, 0, 0, 255},
+		TextOutlineColor: color.RGBA{255, 255, 255, 255},
+	},
+	{
+		// Наибольший контраст для слабовидящих: крупный жирный текст,
+		// толстая рамка
+		Name:             "high-contrast",
+		FontData:         gobold.TTF,
+		FontSize:         56,
+		Padding:          80,
+		Border:           14,
+		BackgroundColor:  color.RGBA{0, 0, 0, 255},
+		BorderColor:      color.RGBA{255, 255, 255, 255},
+		TextColor:        color.RGBA{255, 255, 255, 255},
+		TextOutlineColor: color.RGBA{0, 0, 0, 255},
+		TextUppercase:    true,
+	},
+}
+
+// Реестр тем
+var (
+	themesMu sync.RWMutex
+	themes   = make(map[string]*Theme)
+)
+
+func init() {
+	for _, t := range builtinThemes {
+		themes[t.Name] = t
+	}
+}
+
+// RegisterTheme добавляет тему в реестр под именем name
+// (заменяя существующую с тем же именем)
+func RegisterTheme(name string, t *Theme) {
+	theme := *t
+	theme.Name = name
+	themesMu.Lock()
+	themes[name] = &theme
+	themesMu.Unlock()
+}
+
+// LookupTheme возвращает зарегистрированную тему
+func LookupTheme(name string) (*Theme, bool) {
+	themesMu.RLock()
+	defer themesMu.RUnlock()
+	t, ok := themes[name]
+	return t, ok
+}
+
+// Themes возвращает отсортированные имена зарегистрированных тем
+func Themes() []string {
+	themesMu.RLock()
+	defer themesMu.RUnlock()
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// ThemedConfig возвращает DefaultConfig с выбранной темой: поля оформления
+// сброшены, поэтому их значения берутся из темы, пока их не задали явно
+func ThemedConfig(name string) *Config {
+	cfg := DefaultConfig()
+	cfg.Theme = name
+	cfg.resetThemeStyle(nil)
+	return cfg
+}
+
+// ApplyTheme выбирает тему name поверх конфигурации: поля оформления
+// (шрифт, его размер, отступы, рамка, цвета, обводка, регистр)
+// сбрасываются, и их заполняет тема; подписи, фильтры, ограничения и
+// прочие поля сохраняются. Неизвестная тема - ошибка *ConfigError.
+func (c *Config) ApplyTheme(name string) error {
+	if _, ok := LookupTheme(name); !ok {
+		return &ConfigError{Field: "Theme", Reason: fmt.Sprintf("unknown theme %q", name)}
+	}
+	c.Theme = name
+	c.resetThemeStyle(nil)
+	return nil
+}
+
+// themeStyle - поля оформления, которые задает тема, и их ключи в файле
+// конфигурации
+var themeStyle = []struct {
+	key   string
+	reset func(c *Config)
+}{
+	{"font", func(c *Config) { c.FontPath, c.FontData = "", nil }},
+	{"font_size", func(c *Config) { c.FontSize = 0 }},
+	{"padding", func(c *Config) { c.Padding = 0 }},
+	{"border", func(c *Config) { c.Border = 0 }},
+	{"background_color", func(c *Config) { c.BackgroundColor = nil }},
+	{"border_color", func(c *Config) { c.BorderColor = nil }},
+	{"text_color", func(c *Config) { c.TextColor = nil }},
+	{"text_outline_color", func(c *Config) { c.TextOutlineColor = nil }},
+	{"text_outline_width", func(c *Config) { c.TextOutlineWidth = 0 }},
+	{"text_uppercase", func(c *Config) { c.TextUppercase = false }},
+}
+
+// resetThemeStyle сбрасывает поля оформления, кроме ключей из keep, чтобы
+// их заполнила тема
+func (c *Config) resetThemeStyle(keep map[string]bool) {
+	for _, f := range themeStyle {
+		if !keep[f.key] {
+			f.reset(c)
+		}
+	}
+}
+
+// withTheme возвращает копию конфигурации с примененной темой.
+// Тема заполняет только незаданные (нулевые) поля, так что явно
+// заданные значения Config важнее темы. TextUppercase включается,
+// если его включает тема или конфигурация. Неизвестная тема не
+// применяется - об этом сообщает Validate.
+func withTheme(c *Config) *Config {
+	if c.Theme == "" {
+		return c
+	}
+	t, ok := LookupTheme(c.Theme)
+	if !ok {
+		return c
+	}
+	cfg := *c
+	if cfg.FontPath == "" && len(cfg.FontData) == 0 {
+		cfg.FontPath, cfg.FontData = t.FontPath, t.FontData
+	}
+	if cfg.FontSize == 0 {
+		cfg.FontSize = t.FontSize
+	}
+	if cfg.Padding == 0 {
+		cfg.Padding = t.Padding
+	}
+	if cfg.Border == 0 {
+		cfg.Border = t.Border
+	}
+	if cfg.TextOutlineWidth == 0 {
+		cfg.TextOutlineWidth = t.TextOutlineWidth
+	}
+	for _, f := range []struct {
+		dst *color.Color
+		src color.Color
+	}{
+		{&cfg.BackgroundColor, t.BackgroundColor},
+		{&cfg.BorderColor, t.BorderColor},
+		{&cfg.TextColor, t.TextColor},
+		{&cfg.TextOutlineColor, t.TextOutlineColor},
+	} {
+		if *f.dst == nil {
+			*f.dst = f.src
+		}
+	}
+	cfg.TextUppercase = cfg.TextUppercase || t.TextUppercase
+	return &cfg
+}
@@ -0,0 +1,82 @@
+package meme
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"math/rand/v2"
+	"slices"
+)
+
+// Сдвиги оттенка акцента относительно основного для гармоничных схем:
+// аналоговая, триада, раздельно-дополнительная и дополнительная
+var harmonyOffsets = []float64{30, 120, 150, 180}
+
+// RandomStyle создает случайную, но согласованную тему: фон и текст одного
+// оттенка с контрастной светлотой, рамка - гармоничный акцент. Одинаковый
+// seed дает одинаковую тему. Чтобы выбрать её в Config.Theme,
+// зарегистрируйте результат через RegisterTheme.
+func RandomStyle(seed int64) *Theme {
+	r := rand.New(rand.NewPCG(uint64(seed), uint64(seed)^0x9e3779b97f4a7c15))
+	between := func(lo, hi float64) float64 { return lo + r.Float64()*(hi-lo) }
+
+	hue := between(0, 360)
+	accent := math.Mod(hue+harmonyOffsets[r.IntN(len(harmonyOffsets))], 360)
+	dark := r.IntN(2) == 0
+
+	t := &Theme{
+		Name:        fmt.Sprintf("random-%d", seed),
+		FontSize:    float64(40 + 4*r.IntN(5)),
+		Padding:     50 + 10*r.IntN(6),
+		Border:      []int{2, 4, 6, 10, 14}[r.IntN(5)],
+		BorderColor: hsl(accent, between(0.6, 0.9), between(0.5, 0.65)),
+	}
+	if dark {
+		t.BackgroundColor = hsl(hue, between(0.3, 0.6), between(0.06, 0.14))
+		t.TextColor = hsl(hue, between(0.2, 0.6), between(0.88, 0.96))
+		t.TextOutlineColor = hsl(accent, between(0.5, 0.8), between(0.2, 0.35))
+	} else {
+		t.BackgroundColor = hsl(hue, between(0.2, 0.4), between(0.9, 0.97))
+		t.TextColor = hsl(hue, between(0.3, 0.7), between(0.08, 0.18))
+		t.TextOutlineColor = hsl(accent, between(0.4, 0.7), between(0.8, 0.9))
+	}
+	if r.IntN(2) == 0 {
+		t.TextOutlineWidth = 1 + r.IntN(3)
+	}
+	t.TextUppercase = r.IntN(2) == 0
+
+	// Имена шрифтов сортируем: порядок обхода map случаен
+	fonts := GetAvailableFonts()
+	names := make([]string, 0, len(fonts))
+	for name := range fonts {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	t.FontData = fonts[names[r.IntN(len(names))]]
+	return t
+}
+
+// hsl переводит оттенок (в градусах), насыщенность и светлоту (0..1) в RGB
+func hsl(h, s, l float64) color.RGBA {
+	c := (1 - math.Abs(2*l-1)) * s
+	hp := h / 60
+	x := c * (1 - math.Abs(math.Mod(hp, 2)-1))
+	var r, g, b float64
+	switch {
+	case hp < 1:
+		r, g = c, x
+	case hp < 2:
+		r, g = x, c
+	case hp < 3:
+		g, b = c, x
+	case hp < 4:
+		g, b = x, c
+	case hp < 5:
+		r, b = x, c
+	default:
+		r, b = c, x
+	}
+	m := l - c/2
+	to8 := func(v float64) uint8 { return uint8(math.Round((v + m) * 255)) }
+	return color.RGBA{to8(r), to8(g), to8(b), 255}
+}
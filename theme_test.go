@@ -0,0 +1,36 @@
+package meme
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestApplyTheme(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.TopText, cfg.BottomText = "top", "bottom"
+	cfg.Filters = []FilterSpec{{Name: "grayscale"}}
+	cfg.MaxPixels, cfg.MaxBytes, cfg.MaxTextLength = 100, 200, 30
+	if err := cfg.ApplyTheme("vaporwave"); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.TopText != "top" || cfg.BottomText != "bottom" || len(cfg.Filters) != 1 {
+		t.Errorf("content lost: %q %q %v", cfg.TopText, cfg.BottomText, cfg.Filters)
+	}
+	if cfg.MaxPixels != 100 || cfg.MaxBytes != 200 || cfg.MaxTextLength != 30 {
+		t.Errorf("limits lost: %d %d %d", cfg.MaxPixels, cfg.MaxBytes, cfg.MaxTextLength)
+	}
+	got := withTheme(cfg)
+	if c := FormatColor(got.BackgroundColor); c != "#1a0b2e" {
+		t.Errorf("BackgroundColor = %s, want #1a0b2e", c)
+	}
+	if got.Border != 8 {
+		t.Errorf("Border = %d, want 8", got.Border)
+	}
+
+	if err := cfg.ApplyTheme("no-such-theme"); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("unknown theme: got %v, want ErrInvalidConfig", err)
+	}
+	if cfg.Theme != "vaporwave" {
+		t.Errorf("failed ApplyTheme changed Theme to %q", cfg.Theme)
+	}
+}
@@ -0,0 +1,95 @@
+package meme
+
+import (
+	"context"
+	"image"
+	"time"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// DefaultThumbnailSide - наибольшая сторона GenerateThumbnail по умолчанию
+const DefaultThumbnailSide = 256
+
+// GenerateThumbnail быстро создает уменьшенную копию демотиватора для
+// превью в чатах и списках: большая сторона не больше maxSide (0 -
+// DefaultThumbnailSide). Раскладка считается как у Generate и
+// уменьшается целиком, поэтому миниатюра повторяет полный результат, а
+// фото уменьшается сразу, до отрисовки. Ради скорости пропускаются
+// фильтры, обводка и эффекты текста (выдавливание, свечение, дрожание,
+// волна, полый текст), линейное смешивание и отладочная разметка.
+// Хуки вызываются с уменьшенной раскладкой.
+func (g *Generator) GenerateThumbnail(img image.Image, maxSide int) (out *image.RGBA, err error) {
+	defer g.observeGeneration(time.Now(), &err)
+	defer recoverPanic(&err)
+	if maxSide < 0 {
+		return nil, &ConfigError{Field: "maxSide", Reason: "must not be negative"}
+	}
+	if maxSide == 0 {
+		maxSide = DefaultThumbnailSide
+	}
+	if err := g.validateInput(img); err != nil {
+		return nil, err
+	}
+	dg := g.derive(g.thumbnailConfig())
+	if img, err = dg.beforeLayout(context.Background(), img); err != nil {
+		return nil, err
+	}
+	done := g.stage(StageLayout)
+	l := dg.layout(img)
+	done()
+	if err := checkCanvas(l.canvas); err != nil {
+		return nil, err
+	}
+	if s := float64(maxSide) / float64(max(l.canvas.Dx(), l.canvas.Dy())); s < 1 {
+		l = l.scaled(s)
+		if dg.config.Border > 0 {
+			dg.config.Border = max(int(float64(dg.config.Border)*s+0.5), 1)
+		}
+		// Фото уменьшается до места на холсте сразу: дальше все рисуется
+		// в размере миниатюры
+		small := image.NewRGBA(image.Rect(0, 0, l.photo.Dx(), l.photo.Dy()))
+		xdraw.ApproxBiLinear.Scale(small, small.Rect, img, img.Bounds(), xdraw.Src, nil)
+		img = small
+	}
+	out = image.NewRGBA(l.canvas)
+	if err := dg.render(out, img, l); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// thumbnailConfig - копия конфигурации без дорогих в отрисовке настроек
+func (g *Generator) thumbnailConfig() *Config {
+	cfg := *g.config
+	cfg.Filters = nil
+	cfg.TextOutlineWidth = 0
+	cfg.TextHollow = false
+	cfg.TextExtrude = Extrude{}
+	cfg.TextGlow = Glow{}
+	cfg.TextJitter = Jitter{}
+	cfg.TextWave = Wave{}
+	cfg.LinearBlending = false
+	cfg.ParallelRender = false
+	cfg.Debug = false
+	return &cfg
+}
+
+// scaled возвращает раскладку, уменьшенную в s раз; строки переноса
+// остаются прежними
+func (l layout) scaled(s float64) layout {
+	px := func(v int) int { return int(float64(v)*s + 0.5) }
+	rect := func(r image.Rectangle) image.Rectangle {
+		return image.Rect(px(r.Min.X), px(r.Min.Y), px(r.Max.X), px(r.Max.Y))
+	}
+	l.fontSize *= s
+	l.lineStep = px(l.lineStep)
+	l.canvas = rect(l.canvas)
+	l.canvas.Max = l.canvas.Max.Add(image.Pt(max(1-l.canvas.Dx(), 0), max(1-l.canvas.Dy(), 0)))
+	l.photo = rect(l.photo)
+	l.photo.Max = l.photo.Max.Add(image.Pt(max(1-l.photo.Dx(), 0), max(1-l.photo.Dy(), 0)))
+	l.safe = rect(l.safe)
+	l.textLeft, l.textRight = px(l.textLeft), px(l.textRight)
+	l.topBaseline, l.bottomBaseline = px(l.topBaseline), px(l.bottomBaseline)
+	return l
+}
@@ -0,0 +1,135 @@
+package meme
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Translator переводит подписи перед расчетом геометрии, чтобы выпускать
+// один и тот же мем на нескольких языках. Коды языков - как их понимает
+// реализация, обычно ISO 639-1 ("en", "ru"); пустой from - определить
+// язык автоматически. Реализация должна быть безопасна для одновременного
+// использования.
+type Translator interface {
+	Translate(ctx context.Context, text, from, to string) (string, error)
+}
+
+// NopTranslator возвращает текст без изменений (для тестов и языка оригинала)
+type NopTranslator struct{}
+
+func (NopTranslator) Translate(_ context.Context, text, _, _ string) (string, error) {
+	return text, nil
+}
+
+// SetTranslator подключает перевод подписей для GenerateTranslated
+// (nil - NopTranslator). Как и SetCache, нельзя вызывать одновременно с
+// генерацией.
+func (g *Generator) SetTranslator(t Translator) {
+	g.translator = t
+}
+
+// GenerateTranslated - GenerateContent с подписями c, переведенными с
+// языка from на язык to подключенным Translator. Плейсхолдеры
+// раскрываются до перевода; пустые подписи не переводятся. Варианты для
+// нескольких языков:
+//
+//	for _, lang := range []string{"en", "de", "es"} {
+//		out, err := g.GenerateTranslated(ctx, img, c, "ru", lang)
+//		...
+//	}
+func (g *Generator) GenerateTranslated(ctx context.Context, img image.Image, c Content, from, to string) (out *image.RGBA, err error) {
+	defer g.observeGeneration(time.Now(), &err)
+	dg, err := g.withContent(c)
+	if err != nil {
+		return nil, err
+	}
+	t := g.translator
+	if t == nil {
+		t = NopTranslator{}
+	}
+	cfg := dg.config
+	for _, caption := range []*string{&cfg.TopText, &cfg.BottomText} {
+		if *caption == "" {
+			continue
+		}
+		if *caption, err = t.Translate(ctx, *caption, from, to); err != nil {
+			return nil, fmt.Errorf("translating caption to %s: %w", to, err)
+		}
+	}
+	return dg.generateInto(nil, img)
+}
+
+// Значения HTTPTranslator по умолчанию
+const (
+	defaultTranslateTimeout = 10 * time.Second
+	maxTranslateResponse    = 1 << 20
+)
+
+// HTTPTranslator - пример Translator для сервиса с API LibreTranslate:
+// POST {"q", "source", "target", "format", "api_key"} на Endpoint
+// (например "https://libretranslate.com/translate") и ответ
+// {"translatedText": "..."}. Для других сервисов напишите свою реализацию
+// по этому образцу.
+type HTTPTranslator struct {
+	Endpoint string
+	APIKey   string // пусто - без ключа
+
+	Client  *http.Client  // nil - http.DefaultClient
+	Timeout time.Duration // предел времени на запрос, 0 - 10 секунд
+}
+
+var (
+	_ Translator = NopTranslator{}
+	_ Translator = HTTPTranslator{}
+)
+
+func (t HTTPTranslator) Translate(ctx context.Context, text, from, to string) (string, error) {
+	if from == "" {
+		from = "auto"
+	}
+	body, err := json.Marshal(map[string]string{"q": text, "source": from, "target": to, "format": "text", "api_key": t.APIKey})
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, orDefault(t.Timeout, defaultTranslateTimeout))
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-goblin-meme")
+	client := t.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		TranslatedText string `json:"translatedText"`
+		Error          string `json:"error"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxTranslateResponse)).Decode(&result); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("unexpected status %s", resp.Status)
+		}
+		return "", fmt.Errorf("decoding translation: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if result.Error != "" {
+			return "", fmt.Errorf("unexpected status %s: %s", resp.Status, result.Error)
+		}
+		return "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return result.TranslatedText, nil
+}
@@ -0,0 +1,160 @@
+package meme
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"unicode/utf8"
+)
+
+// Ошибки проверки входных данных
+var (
+	ErrNilImage      = errors.New("image is nil")
+	ErrEmptyImage    = errors.New("image is empty")
+	ErrImageTooLarge = errors.New("image is too large")
+	ErrInputTooLarge = errors.New("input file is too large")
+	ErrInvalidConfig = errors.New("invalid config")
+)
+
+// Значения ограничений по умолчанию для DefaultConfig
+const (
+	DefaultMaxPixels = 50_000_000 // ~50 мегапикселей
+	DefaultMaxBytes  = 50 << 20   // 50 МБ
+
+	DefaultMaxTextLength = 500 // символов в одной подписи
+)
+
+// Предел размера холста, который мы вообще готовы выделить (4 байта на пиксель)
+const maxCanvasPixels = 1 << 28
+
+// ImageSizeError сообщает о превышении допустимого числа пикселей
+type ImageSizeError struct {
+	Width, Height int
+	MaxPixels     int
+}
+
+func (e *ImageSizeError) Error() string {
+	return fmt.Sprintf("image %dx%d exceeds the limit of %d pixels", e.Width, e.Height, e.MaxPixels)
+}
+
+// Unwrap позволяет проверять ошибку через errors.Is(err, ErrImageTooLarge)
+func (e *ImageSizeError) Unwrap() error {
+	return ErrImageTooLarge
+}
+
+// ConfigError сообщает о некорректном поле конфигурации
+type ConfigError struct {
+	Field  string
+	Reason string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("invalid config: %s: %s", e.Field, e.Reason)
+}
+
+// Unwrap позволяет проверять ошибку через errors.Is(err, ErrInvalidConfig)
+func (e *ConfigError) Unwrap() error {
+	return ErrInvalidConfig
+}
+
+// Validate проверяет конфигурацию на значения, с которыми генерация невозможна
+func (c *Config) Validate() error {
+	if c.Theme != "" {
+		if _, ok := LookupTheme(c.Theme); !ok {
+			return &ConfigError{Field: "Theme", Reason: fmt.Sprintf("unknown theme %q", c.Theme)}
+		}
+	}
+	switch {
+	case c.Padding < 0:
+		return &ConfigError{Field: "Padding", Reason: "must not be negative"}
+	case c.Border < 0:
+		return &ConfigError{Field: "Border", Reason: "must not be negative"}
+	case c.TextOutlineWidth < 0:
+		return &ConfigError{Field: "TextOutlineWidth", Reason: "must not be negative"}
+	case !c.AutoFontSize && c.FontSize <= 0:
+		return &ConfigError{Field: "FontSize", Reason: "must be positive"}
+	case c.MaxPixels < 0:
+		return &ConfigError{Field: "MaxPixels", Reason: "must not be negative"}
+	case c.MaxBytes < 0:
+		return &ConfigError{Field: "MaxBytes", Reason: "must not be negative"}
+	case c.MaxTextLength < 0:
+		return &ConfigError{Field: "MaxTextLength", Reason: "must not be negative"}
+	case c.BackgroundColor == nil:
+		return &ConfigError{Field: "BackgroundColor", Reason: "must be set"}
+	case c.BorderColor == nil:
+		return &ConfigError{Field: "BorderColor", Reason: "must be set"}
+	case c.TextColor == nil:
+		return &ConfigError{Field: "TextColor", Reason: "must be set"}
+	case c.TextExtrude.Depth < 0 || c.TextExtrude.Depth > maxExtrudeDepth:
+		return &ConfigError{Field: "TextExtrude.Depth", Reason: fmt.Sprintf("must be between 0 and %d", maxExtrudeDepth)}
+	case c.TextGlow.Radius < 0 || c.TextGlow.Radius > maxGlowRadius:
+		return &ConfigError{Field: "TextGlow.Radius", Reason: fmt.Sprintf("must be between 0 and %d", maxGlowRadius)}
+	case c.TextGlow.Intensity < 0:
+		return &ConfigError{Field: "TextGlow.Intensity", Reason: "must not be negative"}
+	case c.TextJitter.Offset < 0 || c.TextJitter.Offset > maxJitterOffset:
+		return &ConfigError{Field: "TextJitter.Offset", Reason: fmt.Sprintf("must be between 0 and %d", maxJitterOffset)}
+	case c.TextJitter.Rotation < 0 || c.TextJitter.Rotation > maxJitterRotation:
+		return &ConfigError{Field: "TextJitter.Rotation", Reason: fmt.Sprintf("must be between 0 and %d", maxJitterRotation)}
+	case c.TextWave.Amplitude < 0 || c.TextWave.Amplitude > maxWaveAmplitude:
+		return &ConfigError{Field: "TextWave.Amplitude", Reason: fmt.Sprintf("must be between 0 and %d", maxWaveAmplitude)}
+	case c.TextWave.Wavelength < 0:
+		return &ConfigError{Field: "TextWave.Wavelength", Reason: "must not be negative"}
+	case c.TopMaxWidthRatio < 0 || c.TopMaxWidthRatio > 1:
+		return &ConfigError{Field: "TopMaxWidthRatio", Reason: "must be between 0 and 1"}
+	case c.BottomMaxWidthRatio < 0 || c.BottomMaxWidthRatio > 1:
+		return &ConfigError{Field: "BottomMaxWidthRatio", Reason: "must be between 0 and 1"}
+	case c.TextOutlineWidth > 0 && !c.TextHollow && c.TextOutlineColor == nil:
+		return &ConfigError{Field: "TextOutlineColor", Reason: "must be set when outline is enabled"}
+	}
+	if err := c.TopCase.check("TopCase"); err != nil {
+		return err
+	}
+	if err := c.SafeArea.check(); err != nil {
+		return err
+	}
+	if err := c.BottomCase.check("BottomCase"); err != nil {
+		return err
+	}
+	if _, err := newChain(c.Filters); err != nil {
+		return &ConfigError{Field: "Filters", Reason: err.Error()}
+	}
+	for _, caption := range []struct{ field, text string }{{"TopText", c.TopText}, {"BottomText", c.BottomText}} {
+		if n := utf8.RuneCountInString(caption.text); c.MaxTextLength > 0 && n > c.MaxTextLength {
+			return fmt.Errorf("%w: %s has %d characters, limit is %d", ErrTextTooLong, caption.field, n, c.MaxTextLength)
+		}
+	}
+	return nil
+}
+
+// checkDimensions проверяет размеры изображения на соответствие лимиту
+func checkDimensions(width, height, maxPixels int) error {
+	if width <= 0 || height <= 0 {
+		return ErrEmptyImage
+	}
+	if maxPixels > 0 && (width > maxPixels/height || width*height > maxPixels) {
+		return &ImageSizeError{Width: width, Height: height, MaxPixels: maxPixels}
+	}
+	return nil
+}
+
+// validateInput проверяет конфигурацию и изображение перед генерацией
+func (g *Generator) validateInput(img image.Image) error {
+	if err := g.config.Validate(); err != nil {
+		g.debug("invalid config", "error", err)
+		return err
+	}
+	g.warnConfig()
+	if img == nil {
+		return ErrNilImage
+	}
+	b := img.Bounds()
+	return checkDimensions(b.Dx(), b.Dy(), g.config.MaxPixels)
+}
+
+// checkCanvas защищает от выделения гигантского холста из-за огромных отступов
+func checkCanvas(r image.Rectangle) error {
+	if err := checkDimensions(r.Dx(), r.Dy(), maxCanvasPixels); err != nil {
+		return fmt.Errorf("output size: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package meme
+
+import "math"
+
+// Wave - волна: глифы подписи смещаются по вертикали по синусоиде
+type Wave struct {
+	Amplitude  float64 // наибольший сдвиг в пикселях, 0 - без волны
+	Wavelength float64 // период в пикселях, 0 - ширина строки
+}
+
+// Предел амплитуды: строка с волной выше на 2*Amplitude
+const maxWaveAmplitude = 128
+
+// active сообщает, что эффект включен
+func (w Wave) active() bool {
+	return w.Amplitude > 0
+}
+
+// pad - на сколько пикселей глифы могут выйти за границы строки
+func (w Wave) pad() int {
+	return int(math.Ceil(min(w.Amplitude, maxWaveAmplitude)))
+}
+
+// offset - сдвиг глифа с центром в x пикселях от начала строки ширины width
+func (w Wave) offset(x, width float64) float64 {
+	l := w.Wavelength
+	if l <= 0 {
+		l = width
+	}
+	if l <= 0 {
+		return 0
+	}
+	return min(w.Amplitude, maxWaveAmplitude) * math.Sin(2*math.Pi*x/l)
+}
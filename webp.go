@@ -0,0 +1,787 @@
+package meme
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"io"
+	"sort"
+)
+
+// Кодировщик WebP без потерь (VP8L).
+//
+// golang.org/x/image умеет только декодировать WebP, поэтому здесь реализовано
+// подмножество спецификации VP8L, достаточное для стикеров и мемов:
+// преобразования subtract-green и predictor, обратные ссылки LZ77 и
+// канонические коды Хаффмана. Цветовой кеш и мета-коды не используются.
+
+const (
+	vp8lSignature    = 0x2f
+	vp8lMaxDimension = 1 << 14
+
+	vp8lPredictorBits = 4 // размер тайла предиктора 16x16
+	vp8lNumPredictors = 14
+
+	vp8lMinMatch    = 3
+	vp8lMaxMatch    = 4096
+	vp8lWindowSize  = 1 << 18
+	vp8lHashBits    = 16
+	vp8lChainLength = 32
+
+	vp8lNumLiterals  = 256
+	vp8lNumLengths   = 24
+	vp8lNumDistances = 40
+	vp8lMaxCodeLen   = 15
+)
+
+// Порядок записи длин кодов для кода длин (секция 5.2.2 спецификации)
+var vp8lCodeLengthOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// Таблица двумерных смещений для коротких дистанций (секция 4.2.2 спецификации)
+var vp8lDistanceMap = [120]uint8{
+	0x18, 0x07, 0x17, 0x19, 0x28, 0x06, 0x27, 0x29, 0x16, 0x1a,
+	0x26, 0x2a, 0x38, 0x05, 0x37, 0x39, 0x15, 0x1b, 0x36, 0x3a,
+	0x25, 0x2b, 0x48, 0x04, 0x47, 0x49, 0x14, 0x1c, 0x35, 0x3b,
+	0x46, 0x4a, 0x24, 0x2c, 0x58, 0x45, 0x4b, 0x34, 0x3c, 0x03,
+	0x57, 0x59, 0x13, 0x1d, 0x56, 0x5a, 0x23, 0x2d, 0x44, 0x4c,
+	0x55, 0x5b, 0x33, 0x3d, 0x68, 0x02, 0x67, 0x69, 0x12, 0x1e,
+	0x66, 0x6a, 0x22, 0x2e, 0x54, 0x5c, 0x43, 0x4d, 0x65, 0x6b,
+	0x32, 0x3e, 0x78, 0x01, 0x77, 0x79, 0x53, 0x5d, 0x11, 0x1f,
+	0x64, 0x6c, 0x42, 0x4e, 0x76, 0x7a, 0x21, 0x2f, 0x75, 0x7b,
+	0x31, 0x3f, 0x63, 0x6d, 0x52, 0x5e, 0x00, 0x74, 0x7c, 0x41,
+	0x4f, 0x10, 0x20, 0x62, 0x6e, 0x30, 0x73, 0x7d, 0x51, 0x5f,
+	0x40, 0x72, 0x7e, 0x61, 0x6f, 0x50, 0x71, 0x7f, 0x60, 0x70,
+}
+
+// bitWriter пишет биты младшими вперёд, как того требует VP8L
+type bitWriter struct {
+	buf   []byte
+	acc   uint64
+	nbits uint
+}
+
+func (b *bitWriter) write(v uint32, n uint) {
+	b.acc |= uint64(v) << b.nbits
+	b.nbits += n
+	for b.nbits >= 8 {
+		b.buf = append(b.buf, byte(b.acc))
+		b.acc >>= 8
+		b.nbits -= 8
+	}
+}
+
+func (b *bitWriter) flush() []byte {
+	if b.nbits > 0 {
+		b.buf = append(b.buf, byte(b.acc))
+		b.acc, b.nbits = 0, 0
+	}
+	return b.buf
+}
+
+// encodeWebP кодирует изображение в WebP без потерь.
+// nearLossless > 0 предварительно огрубляет младшие биты цветовых каналов,
+// что заметно уменьшает размер файла ценой почти незаметной потери качества.
+func encodeWebP(w io.Writer, img image.Image, nearLossless int) error {
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if width <= 0 || height <= 0 {
+		return errors.New("webp: empty image")
+	}
+	if width > vp8lMaxDimension || height > vp8lMaxDimension {
+		return errors.New("webp: image side exceeds 16384 pixels")
+	}
+
+	argb, hasAlpha := toARGB(img, nearLossless)
+	defer putUint32s(argb)
+
+	bw := &bitWriter{}
+	bw.write(vp8lSignature, 8)
+	bw.write(uint32(width-1), 14)
+	bw.write(uint32(height-1), 14)
+	if hasAlpha {
+		bw.write(1, 1)
+	} else {
+		bw.write(0, 1)
+	}
+	bw.write(0, 3) // версия
+
+	// Преобразование subtract-green
+	bw.write(1, 1)
+	bw.write(2, 2)
+	for i, p := range argb {
+		g := (p >> 8) & 0xff
+		r := ((p>>16)&0xff - g) & 0xff
+		bl := (p&0xff - g) & 0xff
+		argb[i] = p&0xff00ff00 | r<<16 | bl
+	}
+
+	// Преобразование predictor
+	modes := choosePredictors(argb, width, height)
+	tilesX := tileCount(width, vp8lPredictorBits)
+	bw.write(1, 1)
+	bw.write(0, 2)
+	bw.write(vp8lPredictorBits-2, 3)
+	modeImg := make([]uint32, len(modes))
+	for i, m := range modes {
+		modeImg[i] = 0xff000000 | uint32(m)<<8
+	}
+	writeEntropyImage(bw, modeImg, tilesX, false)
+	residuals := applyPredictors(argb, width, height, modes, tilesX)
+	defer putUint32s(residuals)
+
+	bw.write(0, 1) // больше преобразований нет
+
+	writeEntropyImage(bw, residuals, width, true)
+	data := bw.flush()
+
+	return writeRIFF(w, "VP8L", data)
+}
+
+// writeRIFF оборачивает один чанк в контейнер RIFF/WEBP
+func writeRIFF(w io.Writer, fourCC string, data []byte) error {
+	pad := len(data) & 1
+	header := make([]byte, 20)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(4+8+len(data)+pad))
+	copy(header[8:], "WEBP")
+	copy(header[12:], fourCC)
+	binary.LittleEndian.PutUint32(header[16:], uint32(len(data)))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if pad != 0 {
+		_, err := w.Write([]byte{0})
+		return err
+	}
+	return nil
+}
+
+// toARGB переводит изображение в непремультиплицированные ARGB пиксели
+func toARGB(img image.Image, nearLossless int) ([]uint32, bool) {
+	b := img.Bounds()
+	nrgba, ok := img.(*image.NRGBA)
+	if !ok {
+		nrgba = getNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+		defer putNRGBA(nrgba)
+		draw.Draw(nrgba, nrgba.Bounds(), img, b.Min, draw.Src)
+		b = nrgba.Bounds()
+	}
+
+	shift := uint(nearLossless)
+	if shift > 5 {
+		shift = 5
+	}
+	quantize := func(c uint32) uint32 {
+		if shift == 0 {
+			return c
+		}
+		c += 1 << (shift - 1)
+		if c > 255 {
+			// Округление вверх выходит за 255, а вниз ошиблось бы
+			// больше чем на половину шага: оставляем максимум
+			return 255
+		}
+		return c &^ (1<<shift - 1)
+	}
+
+	out := getUint32s(b.Dx() * b.Dy())[:0]
+	hasAlpha := false
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := nrgba.Pix[nrgba.PixOffset(b.Min.X, y):]
+		for x := 0; x < b.Dx(); x++ {
+			r, g, bl, a := uint32(row[4*x]), uint32(row[4*x+1]), uint32(row[4*x+2]), uint32(row[4*x+3])
+			if a != 0xff {
+				hasAlpha = true
+			}
+			if a == 0 {
+				// Цвет полностью прозрачных пикселей не важен, обнуляем его
+				out = append(out, 0)
+				continue
+			}
+			out = append(out, a<<24|quantize(r)<<16|quantize(g)<<8|quantize(bl))
+		}
+	}
+	return out, hasAlpha
+}
+
+func tileCount(size, bits int) int {
+	return (size + 1<<bits - 1) >> bits
+}
+
+// predict вычисляет предсказание пикселя в позиции i по режиму mode
+func predict(pix []uint32, i, width, mode int) uint32 {
+	l := pix[i-1]
+	t := pix[i-width]
+	tl := pix[i-width-1]
+	tr := pix[i-width+1] // для последней колонки это первый пиксель текущей строки
+	switch mode {
+	case 0:
+		return 0xff000000
+	case 1:
+		return l
+	case 2:
+		return t
+	case 3:
+		return tr
+	case 4:
+		return tl
+	case 5:
+		return average2(average2(l, tr), t)
+	case 6:
+		return average2(l, tl)
+	case 7:
+		return average2(l, t)
+	case 8:
+		return average2(tl, t)
+	case 9:
+		return average2(t, tr)
+	case 10:
+		return average2(average2(l, tl), average2(t, tr))
+	case 11:
+		return selectPredictor(l, t, tl)
+	case 12:
+		return clampAddSubtractFull(l, t, tl)
+	default:
+		return clampAddSubtractHalf(average2(l, t), tl)
+	}
+}
+
+func average2(a, b uint32) uint32 {
+	return (((a ^ b) & 0xfefefefe) >> 1) + (a & b)
+}
+
+func channelAbs(a, b uint32, shift uint) int {
+	d := int((a>>shift)&0xff) - int((b>>shift)&0xff)
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+func selectPredictor(l, t, tl uint32) uint32 {
+	pl, pt := 0, 0
+	for shift := uint(0); shift < 32; shift += 8 {
+		pl += channelAbs(tl, t, shift)
+		pt += channelAbs(tl, l, shift)
+	}
+	if pl < pt {
+		return l
+	}
+	return t
+}
+
+func clamp255(v int) uint32 {
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return uint32(v)
+}
+
+func clampAddSubtractFull(a, b, c uint32) uint32 {
+	var out uint32
+	for shift := uint(0); shift < 32; shift += 8 {
+		v := int((a>>shift)&0xff) + int((b>>shift)&0xff) - int((c>>shift)&0xff)
+		out |= clamp255(v) << shift
+	}
+	return out
+}
+
+func clampAddSubtractHalf(a, b uint32) uint32 {
+	var out uint32
+	for shift := uint(0); shift < 32; shift += 8 {
+		av, bv := int((a>>shift)&0xff), int((b>>shift)&0xff)
+		out |= clamp255(av+(av-bv)/2) << shift
+	}
+	return out
+}
+
+func subPixels(a, b uint32) uint32 {
+	var out uint32
+	for shift := uint(0); shift < 32; shift += 8 {
+		out |= (((a >> shift) - (b >> shift)) & 0xff) << shift
+	}
+	return out
+}
+
+// residualCost - грубая оценка стоимости остатка: сумма модулей знаковых байтов
+func residualCost(r uint32) int {
+	cost := 0
+	for shift := uint(0); shift < 32; shift += 8 {
+		v := int(int8(r >> shift))
+		if v < 0 {
+			v = -v
+		}
+		cost += v
+	}
+	return cost
+}
+
+// choosePredictors подбирает для каждого тайла режим с минимальными остатками
+func choosePredictors(pix []uint32, width, height int) []uint8 {
+	tilesX := tileCount(width, vp8lPredictorBits)
+	tilesY := tileCount(height, vp8lPredictorBits)
+	modes := make([]uint8, tilesX*tilesY)
+	tile := 1 << vp8lPredictorBits
+
+	for ty := 0; ty < tilesY; ty++ {
+		for tx := 0; tx < tilesX; tx++ {
+			var costs [vp8lNumPredictors]int
+			for y := ty * tile; y < (ty+1)*tile && y < height; y++ {
+				if y == 0 {
+					continue // первая строка всегда предсказывается слева
+				}
+				for x := tx * tile; x < (tx+1)*tile && x < width; x++ {
+					if x == 0 {
+						continue // первая колонка всегда предсказывается сверху
+					}
+					i := y*width + x
+					for m := 0; m < vp8lNumPredictors; m++ {
+						costs[m] += residualCost(subPixels(pix[i], predict(pix, i, width, m)))
+					}
+				}
+			}
+			best := 0
+			for m := 1; m < vp8lNumPredictors; m++ {
+				if costs[m] < costs[best] {
+					best = m
+				}
+			}
+			modes[ty*tilesX+tx] = uint8(best)
+		}
+	}
+	return modes
+}
+
+// applyPredictors возвращает остатки после предсказания
+func applyPredictors(pix []uint32, width, height int, modes []uint8, tilesX int) []uint32 {
+	out := getUint32s(len(pix))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			i := y*width + x
+			var pred uint32
+			switch {
+			case x == 0 && y == 0:
+				pred = 0xff000000
+			case y == 0:
+				pred = pix[i-1]
+			case x == 0:
+				pred = pix[i-width]
+			default:
+				mode := modes[(y>>vp8lPredictorBits)*tilesX+(x>>vp8lPredictorBits)]
+				pred = predict(pix, i, width, int(mode))
+			}
+			out[i] = subPixels(pix[i], pred)
+		}
+	}
+	return out
+}
+
+// vp8lSymbol - либо литерал (пиксель), либо обратная ссылка LZ77
+type vp8lSymbol struct {
+	pixel  uint32
+	length int // 0 для литерала
+	dist   int // код дистанции (с учётом таблицы коротких смещений)
+}
+
+// prefixEncode раскладывает значение (начиная с 1) на префиксный код и extra-биты
+func prefixEncode(v int) (code int, extraBits uint, extra uint32) {
+	d := v - 1
+	if d < 4 {
+		return d, 0, 0
+	}
+	h := 0
+	for t := d; t > 1; t >>= 1 {
+		h++
+	}
+	second := (d >> (h - 1)) & 1
+	extraBits = uint(h - 1)
+	return 2*h + second, extraBits, uint32(d & (1<<extraBits - 1))
+}
+
+// distanceCodes строит обратную таблицу коротких смещений для ширины width
+func distanceCodes(width int) map[int]int {
+	codes := make(map[int]int, len(vp8lDistanceMap))
+	for i := len(vp8lDistanceMap) - 1; i >= 0; i-- {
+		m := int(vp8lDistanceMap[i])
+		d := (m>>4)*width + 8 - m&0xf
+		if d >= 1 {
+			codes[d] = i + 1
+		}
+	}
+	return codes
+}
+
+// lz77 ищет повторы пикселей с помощью хеш-цепочек
+func lz77(pix []uint32, width int, useBackRefs bool) []vp8lSymbol {
+	symbols := make([]vp8lSymbol, 0, len(pix)/2)
+	if !useBackRefs || len(pix) < vp8lMinMatch {
+		for _, p := range pix {
+			symbols = append(symbols, vp8lSymbol{pixel: p})
+		}
+		return symbols
+	}
+
+	short := distanceCodes(width)
+	head := make([]int32, 1<<vp8lHashBits)
+	for i := range head {
+		head[i] = -1
+	}
+	prev := make([]int32, len(pix))
+	hash := func(i int) uint32 {
+		h := pix[i]*0x9e3779b1 ^ pix[i+1]*0x85ebca6b
+		return h >> (32 - vp8lHashBits)
+	}
+	insert := func(i int) {
+		if i+1 >= len(pix) {
+			return
+		}
+		h := hash(i)
+		prev[i] = head[h]
+		head[h] = int32(i)
+	}
+
+	for i := 0; i < len(pix); {
+		bestLen, bestDist := 0, 0
+		if i+1 < len(pix) {
+			maxLen := len(pix) - i
+			if maxLen > vp8lMaxMatch {
+				maxLen = vp8lMaxMatch
+			}
+			// Самый частый случай - повтор предыдущего пикселя
+			if i > 0 {
+				n := 0
+				for n < maxLen && pix[i+n] == pix[i-1+n] {
+					n++
+				}
+				bestLen, bestDist = n, 1
+			}
+			cand := head[hash(i)]
+			if bestLen == maxLen {
+				cand = -1
+			}
+			for chain := 0; cand >= 0 && chain < vp8lChainLength; chain++ {
+				d := i - int(cand)
+				if d > vp8lWindowSize {
+					break
+				}
+				if pix[int(cand)+bestLen] == pix[i+bestLen] || bestLen == 0 {
+					n := 0
+					for n < maxLen && pix[int(cand)+n] == pix[i+n] {
+						n++
+					}
+					if n > bestLen {
+						bestLen, bestDist = n, d
+						if n == maxLen {
+							break
+						}
+					}
+				}
+				cand = prev[cand]
+			}
+		}
+
+		if bestLen >= vp8lMinMatch {
+			code, ok := short[bestDist]
+			if !ok {
+				code = bestDist + len(vp8lDistanceMap)
+			}
+			symbols = append(symbols, vp8lSymbol{length: bestLen, dist: code})
+			for k := 0; k < bestLen; k++ {
+				insert(i + k)
+			}
+			i += bestLen
+			continue
+		}
+		symbols = append(symbols, vp8lSymbol{pixel: pix[i]})
+		insert(i)
+		i++
+	}
+	return symbols
+}
+
+// writeEntropyImage кодирует изображение одной группой кодов Хаффмана
+func writeEntropyImage(bw *bitWriter, pix []uint32, width int, topLevel bool) {
+	symbols := lz77(pix, width, topLevel)
+
+	green := make([]int, vp8lNumLiterals+vp8lNumLengths)
+	red := make([]int, vp8lNumLiterals)
+	blue := make([]int, vp8lNumLiterals)
+	alpha := make([]int, vp8lNumLiterals)
+	dist := make([]int, vp8lNumDistances)
+	for _, s := range symbols {
+		if s.length == 0 {
+			green[(s.pixel>>8)&0xff]++
+			red[(s.pixel>>16)&0xff]++
+			blue[s.pixel&0xff]++
+			alpha[s.pixel>>24]++
+			continue
+		}
+		lc, _, _ := prefixEncode(s.length)
+		green[vp8lNumLiterals+lc]++
+		dc, _, _ := prefixEncode(s.dist)
+		dist[dc]++
+	}
+
+	bw.write(0, 1) // без цветового кеша
+	if topLevel {
+		bw.write(0, 1) // без мета-кодов
+	}
+	codes := [5]huffmanCode{}
+	for i, hist := range [][]int{green, red, blue, alpha, dist} {
+		codes[i] = writeHuffmanCode(bw, hist)
+	}
+
+	for _, s := range symbols {
+		if s.length == 0 {
+			codes[0].emit(bw, int((s.pixel>>8)&0xff))
+			codes[1].emit(bw, int((s.pixel>>16)&0xff))
+			codes[2].emit(bw, int(s.pixel&0xff))
+			codes[3].emit(bw, int(s.pixel>>24))
+			continue
+		}
+		lc, lbits, lextra := prefixEncode(s.length)
+		codes[0].emit(bw, vp8lNumLiterals+lc)
+		bw.write(lextra, lbits)
+		dc, dbits, dextra := prefixEncode(s.dist)
+		codes[4].emit(bw, dc)
+		bw.write(dextra, dbits)
+	}
+}
+
+// huffmanCode - канонический код Хаффмана, готовый к записи в поток
+type huffmanCode struct {
+	codes   []uint32 // коды с уже развёрнутым порядком битов
+	lengths []uint8
+}
+
+func (h huffmanCode) emit(bw *bitWriter, symbol int) {
+	if n := h.lengths[symbol]; n > 0 {
+		bw.write(h.codes[symbol], uint(n))
+	}
+}
+
+// newHuffmanCode строит канонические коды по длинам.
+// Если используется единственный символ, декодер читает его за 0 бит.
+func newHuffmanCode(lengths []uint8) huffmanCode {
+	h := huffmanCode{codes: make([]uint32, len(lengths)), lengths: make([]uint8, len(lengths))}
+	used := 0
+	for _, l := range lengths {
+		if l > 0 {
+			used++
+		}
+	}
+	if used <= 1 {
+		return h
+	}
+	copy(h.lengths, lengths)
+
+	var count [vp8lMaxCodeLen + 1]uint32
+	for _, l := range lengths {
+		count[l]++
+	}
+	count[0] = 0
+	var next [vp8lMaxCodeLen + 1]uint32
+	code := uint32(0)
+	for l := 1; l <= vp8lMaxCodeLen; l++ {
+		code = (code + count[l-1]) << 1
+		next[l] = code
+	}
+	for s, l := range lengths {
+		if l == 0 {
+			continue
+		}
+		c := next[l]
+		next[l]++
+		// Коды пишутся старшим битом вперёд в поток младших битов
+		var rev uint32
+		for k := uint8(0); k < l; k++ {
+			rev = rev<<1 | (c>>k)&1
+		}
+		h.codes[s] = rev
+	}
+	return h
+}
+
+// writeHuffmanCode записывает код для гистограммы и возвращает его
+func writeHuffmanCode(bw *bitWriter, hist []int) huffmanCode {
+	var symbols []int
+	for s, c := range hist {
+		if c > 0 {
+			symbols = append(symbols, s)
+		}
+	}
+
+	// Простой код для 0-2 символов, помещающихся в 8 бит
+	if len(symbols) <= 2 && (len(symbols) == 0 || symbols[len(symbols)-1] < 256) {
+		lengths := make([]uint8, len(hist))
+		bw.write(1, 1)
+		if len(symbols) == 0 {
+			symbols = []int{0}
+		}
+		bw.write(uint32(len(symbols)-1), 1)
+		if symbols[0] < 2 {
+			bw.write(0, 1)
+			bw.write(uint32(symbols[0]), 1)
+		} else {
+			bw.write(1, 1)
+			bw.write(uint32(symbols[0]), 8)
+		}
+		if len(symbols) == 2 {
+			bw.write(uint32(symbols[1]), 8)
+			lengths[symbols[0]], lengths[symbols[1]] = 1, 1
+		}
+		return newHuffmanCode(lengths)
+	}
+
+	lengths := huffmanLengths(hist, vp8lMaxCodeLen)
+	bw.write(0, 1)
+	writeCodeLengths(bw, lengths)
+	return newHuffmanCode(lengths)
+}
+
+// writeCodeLengths кодирует длины кодов с помощью кода длин (RLE 16/17/18)
+func writeCodeLengths(bw *bitWriter, lengths []uint8) {
+	type token struct {
+		sym   int
+		extra uint32
+		bits  uint
+	}
+	var tokens []token
+	prev := uint8(8)
+	for i := 0; i < len(lengths); {
+		l := lengths[i]
+		run := 1
+		for i+run < len(lengths) && lengths[i+run] == l {
+			run++
+		}
+		i += run
+		if l == 0 {
+			for run > 0 {
+				switch {
+				case run >= 11:
+					n := min(run, 138)
+					tokens = append(tokens, token{18, uint32(n - 11), 7})
+					run -= n
+				case run >= 3:
+					tokens = append(tokens, token{17, uint32(run - 3), 3})
+					run = 0
+				default:
+					tokens = append(tokens, token{0, 0, 0})
+					run--
+				}
+			}
+			continue
+		}
+		if l != prev {
+			tokens = append(tokens, token{int(l), 0, 0})
+			prev = l
+			run--
+		}
+		for run > 0 {
+			if run >= 3 {
+				n := min(run, 6)
+				tokens = append(tokens, token{16, uint32(n - 3), 2})
+				run -= n
+			} else {
+				tokens = append(tokens, token{int(l), 0, 0})
+				run--
+			}
+		}
+	}
+
+	hist := make([]int, len(vp8lCodeLengthOrder))
+	for _, t := range tokens {
+		hist[t.sym]++
+	}
+	clLengths := huffmanLengths(hist, 7)
+	n := len(vp8lCodeLengthOrder)
+	for n > 4 && clLengths[vp8lCodeLengthOrder[n-1]] == 0 {
+		n--
+	}
+	bw.write(uint32(n-4), 4)
+	for i := 0; i < n; i++ {
+		bw.write(uint32(clLengths[vp8lCodeLengthOrder[i]]), 3)
+	}
+	bw.write(0, 1) // max_symbol не используется: пишем все длины
+
+	cl := newHuffmanCode(clLengths)
+	for _, t := range tokens {
+		cl.emit(bw, t.sym)
+		if t.bits > 0 {
+			bw.write(t.extra, t.bits)
+		}
+	}
+}
+
+// huffmanLengths строит длины кодов Хаффмана, ограниченные maxLen битами.
+// При превышении лимита малые частоты подтягиваются вверх и дерево строится заново.
+func huffmanLengths(hist []int, maxLen int) []uint8 {
+	lengths := make([]uint8, len(hist))
+	var used []int
+	for s, c := range hist {
+		if c > 0 {
+			used = append(used, s)
+		}
+	}
+	switch len(used) {
+	case 0:
+		return lengths
+	case 1:
+		lengths[used[0]] = 1
+		return lengths
+	}
+
+	type node struct {
+		weight      int
+		left, right int // индексы детей, -1 для листа
+		symbol      int
+	}
+	for floor := 1; ; floor *= 2 {
+		nodes := make([]node, 0, 2*len(used))
+		for _, s := range used {
+			nodes = append(nodes, node{weight: max(hist[s], floor), left: -1, right: -1, symbol: s})
+		}
+		sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].weight < nodes[j].weight })
+
+		// Метод двух очередей: листья отсортированы, внутренние узлы растут монотонно
+		leaf, inner := 0, len(nodes)
+		pick := func() int {
+			if leaf < len(used) && (inner >= len(nodes) || nodes[leaf].weight <= nodes[inner].weight) {
+				leaf++
+				return leaf - 1
+			}
+			inner++
+			return inner - 1
+		}
+		for k := 0; k < len(used)-1; k++ {
+			a := pick()
+			b := pick()
+			nodes = append(nodes, node{weight: nodes[a].weight + nodes[b].weight, left: a, right: b})
+		}
+
+		depth := make([]int, len(nodes))
+		tooDeep := false
+		for i := len(nodes) - 1; i >= 0; i-- {
+			n := nodes[i]
+			if n.left < 0 {
+				if depth[i] > maxLen {
+					tooDeep = true
+				}
+				lengths[n.symbol] = uint8(depth[i])
+				continue
+			}
+			depth[n.left] = depth[i] + 1
+			depth[n.right] = depth[i] + 1
+		}
+		if !tooDeep {
+			return lengths
+		}
+	}
+}
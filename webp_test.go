@@ -0,0 +1,167 @@
+package meme
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math/rand/v2"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+// testNRGBA заполняет изображение шумом и градиентом; alpha задает
+// прозрачность пикселя по его номеру
+func testNRGBA(w, h int, alpha func(i int, rnd *rand.Rand) uint8) *image.NRGBA {
+	rnd := rand.New(rand.NewPCG(uint64(w), uint64(h)))
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := y*w + x
+			c := color.NRGBA{R: uint8(x * 7), G: uint8(y * 5), B: uint8(rnd.IntN(256)), A: alpha(i, rnd)}
+			if i%5 == 0 {
+				c.R = uint8(rnd.IntN(256))
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func opaque(int, *rand.Rand) uint8 { return 0xff }
+
+func partialAlpha(i int, rnd *rand.Rand) uint8 {
+	switch i % 3 {
+	case 0:
+		return 0
+	case 1:
+		return uint8(rnd.IntN(256))
+	}
+	return 0xff
+}
+
+// decodeWebP кодирует img и декодирует результат golang.org/x/image/webp
+func decodeWebP(t *testing.T, img image.Image, nearLossless int) (*image.NRGBA, int) {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := encodeWebP(&buf, img, nearLossless); err != nil {
+		t.Fatal(err)
+	}
+	size := buf.Len()
+	out, err := webp.Decode(&buf)
+	if err != nil {
+		t.Fatalf("decoding %v: %v", img.Bounds(), err)
+	}
+	nrgba, ok := out.(*image.NRGBA)
+	if !ok {
+		t.Fatalf("decoded %T, want *image.NRGBA", out)
+	}
+	if nrgba.Bounds() != img.Bounds().Sub(img.Bounds().Min) {
+		t.Fatalf("decoded bounds %v, want %v", nrgba.Bounds(), img.Bounds())
+	}
+	return nrgba, size
+}
+
+// diff возвращает наибольшее отличие цветовых каналов и отличается ли
+// альфа; цвет полностью прозрачных пикселей не сравнивается
+func diff(a, b *image.NRGBA) (int, bool) {
+	worst, alpha := 0, false
+	for y := 0; y < a.Rect.Dy(); y++ {
+		for x := 0; x < a.Rect.Dx(); x++ {
+			ca, cb := a.NRGBAAt(a.Rect.Min.X+x, a.Rect.Min.Y+y), b.NRGBAAt(b.Rect.Min.X+x, b.Rect.Min.Y+y)
+			if ca.A != cb.A {
+				alpha = true
+			}
+			if ca.A == 0 {
+				continue
+			}
+			for _, d := range []int{int(ca.R) - int(cb.R), int(ca.G) - int(cb.G), int(ca.B) - int(cb.B)} {
+				worst = max(worst, d, -d)
+			}
+		}
+	}
+	return worst, alpha
+}
+
+func TestWebPRoundTrip(t *testing.T) {
+	for _, size := range []image.Point{{1, 1}, {1, 37}, {37, 1}, {13, 17}, {16, 16}, {129, 65}} {
+		for name, alpha := range map[string]func(int, *rand.Rand) uint8{"opaque": opaque, "alpha": partialAlpha} {
+			src := testNRGBA(size.X, size.Y, alpha)
+			got, _ := decodeWebP(t, src, 0)
+			if d, a := diff(src, got); d != 0 || a {
+				t.Errorf("%v %s: max channel diff %d, alpha differs %v", size, name, d, a)
+			}
+		}
+	}
+}
+
+func TestWebPSubImage(t *testing.T) {
+	src := testNRGBA(40, 30, partialAlpha)
+	sub := src.SubImage(image.Rect(7, 5, 30, 28)).(*image.NRGBA)
+	got, _ := decodeWebP(t, sub, 0)
+	if d, a := diff(sub, got); d != 0 || a {
+		t.Errorf("sub-image: max channel diff %d, alpha differs %v", d, a)
+	}
+}
+
+func TestWebPNearLossless(t *testing.T) {
+	src := testNRGBA(61, 43, partialAlpha)
+	_, lossless := decodeWebP(t, src, 0)
+	for level := 1; level <= 5; level++ {
+		got, size := decodeWebP(t, src, level)
+		d, a := diff(src, got)
+		if limit := 1 << (level - 1); d > limit {
+			t.Errorf("level %d: max channel diff %d, want at most %d", level, d, limit)
+		}
+		if a {
+			t.Errorf("level %d: alpha changed", level)
+		}
+		if level == 5 && size >= lossless {
+			t.Errorf("level 5: %d bytes, lossless is %d", size, lossless)
+		}
+	}
+	// Уровни выше 5 работают как 5
+	_, at5 := decodeWebP(t, src, 5)
+	if _, at9 := decodeWebP(t, src, 9); at9 != at5 {
+		t.Errorf("level 9: %d bytes, want %d as level 5", at9, at5)
+	}
+}
+
+func TestWebPSize(t *testing.T) {
+	// Однотонная картинка сжимается почти в ничто
+	flat := image.NewNRGBA(image.Rect(0, 0, 256, 256))
+	for i := range flat.Pix {
+		flat.Pix[i] = 0x80
+	}
+	if _, size := decodeWebP(t, flat, 0); size > 256 {
+		t.Errorf("flat 256x256: %d bytes", size)
+	}
+	// Градиент без шума сжимается за счет предсказания
+	gradient := image.NewNRGBA(image.Rect(0, 0, 256, 256))
+	for y := 0; y < 256; y++ {
+		for x := 0; x < 256; x++ {
+			gradient.SetNRGBA(x, y, color.NRGBA{uint8(x), uint8(y), uint8(x + y), 0xff})
+		}
+	}
+	if _, size := decodeWebP(t, gradient, 0); size > 4096 {
+		t.Errorf("gradient 256x256: %d bytes", size)
+	}
+	// Шум не должен раздуваться больше, чем на несколько процентов
+	noise := testNRGBA(128, 128, func(_ int, rnd *rand.Rand) uint8 { return uint8(rnd.IntN(256)) })
+	for i := range noise.Pix {
+		noise.Pix[i] = uint8(rand.IntN(256))
+	}
+	if _, size := decodeWebP(t, noise, 0); size > 128*128*4*105/100 {
+		t.Errorf("noise 128x128: %d bytes, raw is %d", size, 128*128*4)
+	}
+}
+
+func TestWebPLimits(t *testing.T) {
+	var buf bytes.Buffer
+	if err := encodeWebP(&buf, image.NewNRGBA(image.Rect(0, 0, 0, 5)), 0); err == nil {
+		t.Error("empty image encoded")
+	}
+	if err := encodeWebP(&buf, image.NewNRGBA(image.Rect(0, 0, vp8lMaxDimension+1, 1)), 0); err == nil {
+		t.Error("image wider than the VP8L limit encoded")
+	}
+}
@@ -0,0 +1,199 @@
+package meme
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strconv"
+)
+
+// Widget - элемент поверх изображения: полоса загрузки ProgressBar,
+// опрос Poll, штамп с датой DateStamp, QR-код QRCode. Рисуется методом
+// DrawWidget или автоматически на каждом результате через AddWidget.
+type Widget interface {
+	// drawWidget рисует элемент в прямоугольнике r холста dst
+	drawWidget(g *Generator, dst draw.Image, r image.Rectangle) error
+}
+
+var (
+	_ Widget = ProgressBar{}
+	_ Widget = Poll{}
+)
+
+// DrawWidget рисует w в прямоугольнике r холста dst шрифтом генератора
+func (g *Generator) DrawWidget(dst draw.Image, r image.Rectangle, w Widget) (err error) {
+	defer recoverPanic(&err)
+	if r.Empty() {
+		return &ConfigError{Field: "rect", Reason: "must not be empty"}
+	}
+	return w.drawWidget(g, dst, r)
+}
+
+// AddWidget рисует w поверх каждого готового результата (HookBeforeEncode)
+// в прямоугольнике, который place рассчитывает по геометрии, например
+// над нижним краем фото:
+//
+//	g.AddWidget(meme.ProgressBar{Value: 0.99}, func(l meme.Layout) image.Rectangle {
+//		return image.Rect(l.Photo.Min.X+20, l.Photo.Max.Y-60, l.Photo.Max.X-20, l.Photo.Max.Y-20)
+//	})
+func (g *Generator) AddWidget(w Widget, place func(Layout) image.Rectangle) {
+	g.AddHook(HookBeforeEncode, func(dc *DrawContext) error {
+		return g.DrawWidget(dc.Canvas, place(dc.Layout), w)
+	})
+}
+
+// ProgressBar - полоса загрузки с процентом, для мемов "загрузка..."
+type ProgressBar struct {
+	Value float64 // заполнение от 0 до 1
+
+	Label     string // подпись по центру полосы; пусто - процент, например "99%"
+	HideLabel bool   // без подписи
+
+	BarColor   color.Color // nil - зеленый
+	TrackColor color.Color // nil - светло-серый
+	TextColor  color.Color // nil - белый с черной обводкой
+}
+
+var (
+	widgetGreen = color.RGBA{0x3c, 0xb3, 0x4a, 0xff}
+	widgetTrack = color.RGBA{0xdd, 0xdd, 0xdd, 0xff}
+	widgetBlue  = color.RGBA{0x1d, 0x9b, 0xf0, 0xff}
+	widgetPale  = color.RGBA{0xcf, 0xd9, 0xde, 0xff}
+	widgetInk   = color.RGBA{0x0f, 0x14, 0x19, 0xff}
+)
+
+// percent форматирует долю как целый процент
+func percent(v float64) string {
+	return strconv.Itoa(int(math.Round(v*100))) + "%"
+}
+
+func (p ProgressBar) drawWidget(g *Generator, dst draw.Image, r image.Rectangle) error {
+	if p.Value < 0 || p.Value > 1 || math.IsNaN(p.Value) {
+		return &ConfigError{Field: "ProgressBar.Value", Reason: "must be between 0 and 1"}
+	}
+	radius := r.Dy() / 2
+	fillRounded(dst, r, radius, colorOr(p.TrackColor, widgetTrack))
+	if w := int(math.Round(float64(r.Dx()) * p.Value)); w > 0 {
+		// Короткая заливка не уже высоты, иначе скругление ломается
+		fill := r
+		fill.Max.X = r.Min.X + max(w, min(r.Dy(), r.Dx()))
+		fillRounded(dst, fill, radius, colorOr(p.BarColor, widgetGreen))
+	}
+	if p.HideLabel {
+		return nil
+	}
+	label := p.Label
+	if label == "" {
+		label = percent(p.Value)
+	}
+	slot := TextSlot{Name: "progress", Rect: r.Inset(r.Dy() / 8), FontSize: float64(r.Dy()) * 0.6, MinFontSize: 6, Color: color.White, OutlineColor: color.Black, OutlineWidth: max(r.Dy()/16, 1)}
+	if p.TextColor != nil {
+		slot.Color, slot.OutlineWidth = p.TextColor, 0
+	}
+	return g.drawSlotText(dst, slot, label)
+}
+
+// PollOption - вариант ответа опроса
+type PollOption struct {
+	Text  string
+	Votes int
+}
+
+// Poll - результаты опроса: полосы вариантов с процентами, вариант с
+// наибольшим числом голосов выделяется цветом. Высота r делится поровну
+// между заголовком, вариантами и строкой с числом голосов.
+type Poll struct {
+	Question string // заголовок над вариантами; пусто - без заголовка
+	Options  []PollOption
+
+	Footer     string // строка под вариантами; пусто - "N votes"
+	HideFooter bool
+
+	Background  color.Color // nil - белый
+	BarColor    color.Color // nil - серо-голубой
+	WinnerColor color.Color // nil - синий
+	TextColor   color.Color // nil - почти черный
+}
+
+func (p Poll) drawWidget(g *Generator, dst draw.Image, r image.Rectangle) error {
+	if len(p.Options) == 0 {
+		return &ConfigError{Field: "Poll.Options", Reason: "must not be empty"}
+	}
+	total, best := 0, 0
+	for i, o := range p.Options {
+		if o.Votes < 0 {
+			return &ConfigError{Field: "Poll.Options", Reason: fmt.Sprintf("option %d has negative votes", i)}
+		}
+		total += o.Votes
+		if o.Votes > p.Options[best].Votes {
+			best = i
+		}
+	}
+	rows := len(p.Options)
+	if p.Question != "" {
+		rows++
+	}
+	if !p.HideFooter {
+		rows++
+	}
+	ink := colorOr(p.TextColor, widgetInk)
+	rowH := r.Dy() / rows
+	pad := max(rowH/8, 1)
+	fillRounded(dst, r, min(rowH/3, r.Dx()/2), colorOr(p.Background, color.White))
+
+	text := func(name string, rect image.Rectangle, s string, align Align) error {
+		slot := TextSlot{Name: name, Rect: rect, FontSize: float64(rect.Dy()) * 0.6, MinFontSize: 6, Color: ink, Align: align}
+		return g.drawSlotText(dst, slot, s)
+	}
+	inner := image.Rect(r.Min.X+2*pad, r.Min.Y, r.Max.X-2*pad, r.Min.Y+rowH)
+	if p.Question != "" {
+		if err := text("question", inner.Inset(pad), p.Question, AlignLeft); err != nil {
+			return err
+		}
+		inner = inner.Add(image.Pt(0, rowH))
+	}
+	for i, o := range p.Options {
+		share := 0.0
+		if total > 0 {
+			share = float64(o.Votes) / float64(total)
+		}
+		bar := inner.Inset(pad)
+		if w := int(math.Round(float64(bar.Dx()) * share)); w > 0 {
+			fill := bar
+			fill.Max.X = bar.Min.X + w
+			c := colorOr(p.BarColor, widgetPale)
+			if i == best && total > 0 {
+				c = colorOr(p.WinnerColor, widgetBlue)
+			}
+			fillRounded(dst, fill, min(bar.Dy()/4, w/2), c)
+		}
+		// Текст - слева в полосе, процент - справа
+		label := bar.Inset(pad)
+		pct := percent(share)
+		if err := text("percent", label, pct, AlignRight); err != nil {
+			return err
+		}
+		label.Max.X -= label.Dy() * 2
+		if !label.Empty() {
+			if err := text("option", label, o.Text, AlignLeft); err != nil {
+				return err
+			}
+		}
+		inner = inner.Add(image.Pt(0, rowH))
+	}
+	if !p.HideFooter {
+		footer := p.Footer
+		if footer == "" {
+			footer = strconv.Itoa(total) + " votes"
+			if total == 1 {
+				footer = "1 vote"
+			}
+		}
+		if err := text("footer", inner.Inset(pad*2), footer, AlignLeft); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package meme
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"time"
+
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// WindowStyle - вид кнопок окна
+type WindowStyle int
+
+const (
+	WindowMac     WindowStyle = iota // три цветных кружка слева, заголовок по центру
+	WindowWindows                    // кнопки "свернуть", "развернуть" и "закрыть" справа
+)
+
+// WindowOptions задает "скриншот" окна вокруг фото
+type WindowOptions struct {
+	Title string // заголовок окна
+	URL   string // адресная строка браузера; пусто - без нее
+
+	Style WindowStyle
+	Dark  bool // темная тема оформления
+
+	// BarHeight - высота заголовка; 0 - 1/16 ширины фото, не меньше 28
+	BarHeight int
+
+	// Framed оборачивает окно в рамку демотиватора
+	// с подписями TopText и BottomText конфигурации
+	Framed bool
+}
+
+// Минимальная высота заголовка окна по умолчанию
+const minWindowBarHeight = 28
+
+// windowPalette - цвета оформления окна
+type windowPalette struct {
+	bar, field, border, text, hint color.Color
+}
+
+var (
+	windowLight = windowPalette{
+		bar:    color.RGBA{0xe9, 0xe9, 0xeb, 0xff},
+		field:  color.White,
+		border: color.RGBA{0xc4, 0xc4, 0xc8, 0xff},
+		text:   color.RGBA{0x33, 0x33, 0x33, 0xff},
+		hint:   color.RGBA{0x6e, 0x6e, 0x73, 0xff},
+	}
+	windowDark = windowPalette{
+		bar:    color.RGBA{0x2c, 0x2c, 0x2e, 0xff},
+		field:  color.RGBA{0x1c, 0x1c, 0x1e, 0xff},
+		border: color.RGBA{0x48, 0x48, 0x4a, 0xff},
+		text:   color.RGBA{0xe5, 0xe5, 0xea, 0xff},
+		hint:   color.RGBA{0x98, 0x98, 0x9d, 0xff},
+	}
+
+	// Кнопки окна macOS: закрыть, свернуть, развернуть
+	windowMacButtons = []color.Color{
+		color.RGBA{0xff, 0x5f, 0x57, 0xff},
+		color.RGBA{0xfe, 0xbc, 0x2e, 0xff},
+		color.RGBA{0x28, 0xc8, 0x40, 0xff},
+	}
+)
+
+// WindowChrome помещает фото в окно программы: заголовок с кнопками и,
+// если задан URL, адресная строка браузера над фото. Шрифт берется из
+// конфигурации; если он не задан, используется Go Regular.
+func (g *Generator) WindowChrome(img image.Image, opts WindowOptions) (out *image.RGBA, err error) {
+	defer g.observeGeneration(time.Now(), &err)
+	defer recoverPanic(&err)
+
+	// Заголовок и адрес проверяем ограничениями подписей
+	cfg := *g.config
+	cfg.TopText, cfg.BottomText = opts.Title, opts.URL
+	dg := g.derive(&cfg)
+	if err := dg.validateInput(img); err != nil {
+		return nil, err
+	}
+	if opts.BarHeight < 0 {
+		return nil, &ConfigError{Field: "WindowOptions.BarHeight", Reason: "must not be negative"}
+	}
+	if opts.Style != WindowMac && opts.Style != WindowWindows {
+		return nil, &ConfigError{Field: "WindowOptions.Style", Reason: "unknown style"}
+	}
+	pal := windowLight
+	if opts.Dark {
+		pal = windowDark
+	}
+	fontData := cfg.FontData
+	if cfg.FontPath == "" && len(fontData) == 0 {
+		fontData = goregular.TTF
+	}
+
+	b := img.Bounds()
+	barH := opts.BarHeight
+	if barH == 0 {
+		barH = max(b.Dx()/16, minWindowBarHeight)
+	}
+	chromeH := barH
+	if opts.URL != "" {
+		chromeH += barH
+	}
+	canvas := image.Rect(0, 0, b.Dx()+2, b.Dy()+chromeH+1)
+	if err := checkCanvas(canvas); err != nil {
+		return nil, err
+	}
+	out = image.NewRGBA(canvas)
+	draw.Draw(out, canvas, image.NewUniform(pal.border), image.Point{}, draw.Src)
+	chrome := image.Rect(1, 1, canvas.Max.X-1, chromeH)
+	draw.Draw(out, chrome, image.NewUniform(pal.bar), image.Point{}, draw.Src)
+	photo := image.Rect(1, chromeH, canvas.Max.X-1, canvas.Max.Y-1)
+	drawSource(out, photo, img, b.Min)
+
+	defer dg.stage(StageOutline)()
+	bar := image.Rect(chrome.Min.X, chrome.Min.Y, chrome.Max.X, barH)
+	title := bar.Inset(barH / 4)
+	align := AlignCenter
+	switch opts.Style {
+	case WindowMac:
+		// Кружки слева; заголовок по центру, но не поверх них
+		d := barH / 2
+		for i, c := range windowMacButtons {
+			x := bar.Min.X + barH/3 + i*(d+d/3)
+			fillRounded(out, image.Rect(x, bar.Min.Y+(barH-d)/2, x+d, bar.Min.Y+(barH+d)/2), d/2, c)
+		}
+		side := barH/3 + 3*d + 2*(d/3) + barH/4
+		title.Min.X, title.Max.X = bar.Min.X+side, bar.Max.X-side
+	case WindowWindows:
+		// Кнопки по ширине 1.4 высоты справа, значки - тонкими линиями
+		align = AlignLeft
+		bw, s, t := barH*7/5, barH/3, max(barH/28, 1)
+		ink := image.NewUniform(pal.text)
+		for i := range 3 {
+			cell := image.Rect(bar.Max.X-(3-i)*bw, bar.Min.Y, bar.Max.X-(2-i)*bw, bar.Max.Y)
+			icon := image.Rect(0, 0, s, s).Add(image.Pt(cell.Min.X+(bw-s)/2, cell.Min.Y+(barH-s)/2))
+			switch i {
+			case 0: // свернуть
+				mid := (icon.Min.Y + icon.Max.Y) / 2
+				draw.Draw(out, image.Rect(icon.Min.X, mid, icon.Max.X, mid+t), ink, image.Point{}, draw.Src)
+			case 1: // развернуть
+				drawFrame(out, icon, t, pal.text)
+			case 2: // закрыть
+				for k := range s {
+					for _, x := range []int{icon.Min.X + k, icon.Max.X - 1 - k} {
+						draw.Draw(out, image.Rect(x, icon.Min.Y+k, x+t, icon.Min.Y+k+t), ink, image.Point{}, draw.Src)
+					}
+				}
+			}
+		}
+		title.Max.X = bar.Max.X - 3*bw - barH/4
+	}
+	if opts.Title != "" && !title.Empty() {
+		slot := TextSlot{Name: "title", Rect: title, FontPath: cfg.FontPath, FontData: fontData, FontSize: float64(barH) * 0.45, MinFontSize: 8, Color: pal.text, Align: align}
+		if err := dg.drawSlotText(out, slot, opts.Title); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.URL != "" {
+		// Поле адреса со скругленными краями во всю ширину с полями
+		row := image.Rect(chrome.Min.X, barH, chrome.Max.X, chromeH)
+		field := row.Inset(barH / 6)
+		field.Min.X, field.Max.X = row.Min.X+barH/2, row.Max.X-barH/2
+		fillRounded(out, field, field.Dy()/2, pal.field)
+		text := field.Inset(field.Dy() / 6)
+		text.Min.X, text.Max.X = field.Min.X+field.Dy()/2, field.Max.X-field.Dy()/2
+		if !text.Empty() {
+			slot := TextSlot{Name: "url", Rect: text, FontPath: cfg.FontPath, FontData: fontData, FontSize: float64(barH) * 0.4, MinFontSize: 8, Color: pal.hint, Align: AlignLeft}
+			if err := dg.drawSlotText(out, slot, opts.URL); err != nil {
+				return nil, err
+			}
+		}
+		draw.Draw(out, image.Rect(chrome.Min.X, chromeH-1, chrome.Max.X, chromeH), image.NewUniform(pal.border), image.Point{}, draw.Src)
+	}
+
+	if opts.Framed {
+		return g.generateInto(nil, out)
+	}
+	return out, nil
+}
@@ -0,0 +1,94 @@
+// Package natsqueue реализует worker.Queue поверх потребителя NATS JetStream.
+//
+//	js, _ := jetstream.New(nc)
+//	cons, _ := js.CreateOrUpdateConsumer(ctx, "MEMES", jetstream.ConsumerConfig{
+//		Durable:   "workers",
+//		AckPolicy: jetstream.AckExplicitPolicy,
+//		AckWait:   2 * time.Minute,
+//	})
+//	w := worker.New(natsqueue.New(cons), storage, worker.Options{})
+//
+// Повтор использует NakWithDelay, номер попытки берется из NumDelivered,
+// так что MaxDeliver потребителя стоит задать не меньше worker.Options.MaxAttempts.
+package natsqueue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/go-goblin/meme/worker"
+)
+
+// pollInterval - сколько ждать сообщения в одном запросе к серверу;
+// между запросами проверяется отмена контекста
+const pollInterval = 5 * time.Second
+
+// Queue получает задания из потребителя JetStream
+type Queue struct {
+	consumer jetstream.Consumer
+}
+
+var _ worker.Queue = (*Queue)(nil)
+
+// New создает очередь поверх pull-потребителя
+func New(consumer jetstream.Consumer) *Queue {
+	return &Queue{consumer: consumer}
+}
+
+// Receive ждет следующее сообщение
+func (q *Queue) Receive(ctx context.Context) (worker.Message, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		msg, err := q.consumer.Next(jetstream.FetchMaxWait(pollInterval))
+		if errors.Is(err, nats.ErrTimeout) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return message{msg}, nil
+	}
+}
+
+// message адаптирует jetstream.Msg к worker.Message
+type message struct {
+	msg jetstream.Msg
+}
+
+func (m message) Body() []byte { return m.msg.Data() }
+
+func (m message) Attempt() int {
+	md, err := m.msg.Metadata()
+	if err != nil {
+		return 1
+	}
+	return int(md.NumDelivered)
+}
+
+func (m message) Ack(ctx context.Context) error { return m.msg.DoubleAck(ctx) }
+
+func (m message) Retry(_ context.Context, delay time.Duration) error {
+	return m.msg.NakWithDelay(delay)
+}
+
+// Publish отправляет задание в subject потока. ID задания передается
+// как Nats-Msg-Id, и JetStream отбрасывает дубликаты в окне дедупликации.
+func Publish(ctx context.Context, js jetstream.JetStream, subject string, job worker.Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	var opts []jetstream.PublishOpt
+	if job.ID != "" {
+		opts = append(opts, jetstream.WithMsgID(job.ID))
+	}
+	_, err = js.Publish(ctx, subject, data, opts...)
+	return err
+}
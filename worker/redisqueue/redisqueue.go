@@ -0,0 +1,173 @@
+// Package redisqueue реализует worker.Queue и worker.Completed поверх Redis.
+//
+//	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+//	q := redisqueue.New(rdb, "memes")
+//	w := worker.New(q, storage, worker.Options{Completed: redisqueue.NewCompleted(rdb, "memes", 24*time.Hour)})
+//
+// Очередь надежная: получение атомарно переносит сообщение из списка
+// prefix:ready в prefix:processing, и оно удаляется только после Ack.
+// Повторы ждут своего времени в отсортированном множестве prefix:delayed.
+// Сообщения процесса, упавшего до Ack, остаются в prefix:processing,
+// вернуть их можно вызовом Recover.
+package redisqueue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/go-goblin/meme/worker"
+)
+
+// Параметры ожидания
+const (
+	blockTimeout = time.Second // одно ожидание BLMOVE; между ними переносятся отложенные повторы
+	promoteBatch = 100         // сколько отложенных сообщений переносится за раз
+)
+
+// promoteScript атомарно переносит наступившие повторы в начало очереди
+var promoteScript = redis.NewScript(`
+local due = redis.call("ZRANGEBYSCORE", KEYS[1], "-inf", ARGV[1], "LIMIT", 0, ARGV[2])
+for _, m in ipairs(due) do
+	redis.call("ZREM", KEYS[1], m)
+	redis.call("RPUSH", KEYS[2], m)
+end
+return #due
+`)
+
+// envelope - сообщение в Redis: задание и номер попытки
+type envelope struct {
+	Attempt int             `json:"attempt"`
+	Job     json.RawMessage `json:"job"`
+}
+
+// Queue - очередь заданий в Redis
+type Queue struct {
+	rdb        redis.UniversalClient
+	ready      string
+	processing string
+	delayed    string
+}
+
+var _ worker.Queue = (*Queue)(nil)
+
+// New создает очередь с ключами prefix:ready, prefix:processing и prefix:delayed
+func New(rdb redis.UniversalClient, prefix string) *Queue {
+	return &Queue{
+		rdb:        rdb,
+		ready:      prefix + ":ready",
+		processing: prefix + ":processing",
+		delayed:    prefix + ":delayed",
+	}
+}
+
+// Publish добавляет задание в очередь
+func (q *Queue) Publish(ctx context.Context, job worker.Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(envelope{Attempt: 1, Job: data})
+	if err != nil {
+		return err
+	}
+	return q.rdb.LPush(ctx, q.ready, raw).Err()
+}
+
+// Receive ждет следующее сообщение
+func (q *Queue) Receive(ctx context.Context) (worker.Message, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+		if err := promoteScript.Run(ctx, q.rdb, []string{q.delayed, q.ready}, now, promoteBatch).Err(); err != nil && !errors.Is(err, redis.Nil) {
+			return nil, err
+		}
+		raw, err := q.rdb.BLMove(ctx, q.ready, q.processing, "RIGHT", "LEFT", blockTimeout).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var env envelope
+		if err := json.Unmarshal([]byte(raw), &env); err != nil {
+			// Чужое сообщение отдаем воркеру как есть: он подтвердит и отбросит его
+			env = envelope{Attempt: 1, Job: json.RawMessage(raw)}
+		}
+		return &message{q: q, raw: raw, env: env}, nil
+	}
+}
+
+// Recover возвращает в очередь все сообщения из prefix:processing. Вызывайте
+// только когда ни один воркер не работает, например при старте единственного.
+func (q *Queue) Recover(ctx context.Context) (int, error) {
+	n := 0
+	for {
+		err := q.rdb.LMove(ctx, q.processing, q.ready, "RIGHT", "RIGHT").Err()
+		if errors.Is(err, redis.Nil) {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+}
+
+// message - полученное сообщение
+type message struct {
+	q   *Queue
+	raw string
+	env envelope
+}
+
+func (m *message) Body() []byte { return m.env.Job }
+func (m *message) Attempt() int { return max(m.env.Attempt, 1) }
+
+func (m *message) Ack(ctx context.Context) error {
+	return m.q.rdb.LRem(ctx, m.q.processing, 1, m.raw).Err()
+}
+
+// Retry переносит сообщение в отложенные с увеличенным номером попытки
+func (m *message) Retry(ctx context.Context, delay time.Duration) error {
+	next, err := json.Marshal(envelope{Attempt: m.Attempt() + 1, Job: m.env.Job})
+	if err != nil {
+		return err
+	}
+	at := float64(time.Now().Add(delay).UnixMilli())
+	_, err = m.q.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.LRem(ctx, m.q.processing, 1, m.raw)
+		p.ZAdd(ctx, m.q.delayed, redis.Z{Score: at, Member: string(next)})
+		return nil
+	})
+	return err
+}
+
+// Completed хранит ID выполненных заданий в ключах prefix:done:ID
+type Completed struct {
+	rdb    redis.UniversalClient
+	prefix string
+	ttl    time.Duration
+}
+
+var _ worker.Completed = (*Completed)(nil)
+
+// NewCompleted создает хранилище; ttl - сколько помнить задание, 0 - всегда
+func NewCompleted(rdb redis.UniversalClient, prefix string, ttl time.Duration) *Completed {
+	return &Completed{rdb: rdb, prefix: prefix + ":done:", ttl: ttl}
+}
+
+func (c *Completed) Done(ctx context.Context, id string) (bool, error) {
+	n, err := c.rdb.Exists(ctx, c.prefix+id).Result()
+	return n > 0, err
+}
+
+func (c *Completed) MarkDone(ctx context.Context, id string) error {
+	return c.rdb.Set(ctx, c.prefix+id, 1, c.ttl).Err()
+}
@@ -0,0 +1,378 @@
+// Package worker обрабатывает задания генерации из очереди.
+//
+//	w := worker.New(queue, worker.Dir("/data"), worker.Options{Concurrency: 4})
+//	err := w.Run(ctx)
+//
+// Задание читает фото из Storage, создает мем и записывает результат туда
+// же. Неудачные попытки повторяются с экспоненциальной задержкой, кроме
+// ошибок, которые повтор не исправит (слишком длинная подпись, неизвестный
+// формат и т.п.). ID задания служит ключом идемпотентности: повторно
+// доставленное выполненное задание подтверждается без работы. После
+// завершения или окончательной ошибки вызывается webhook задания.
+//
+// Реализации очередей - в пакетах natsqueue (JetStream) и redisqueue.
+package worker
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-goblin/meme"
+	"github.com/go-goblin/meme/storage"
+)
+
+// Job - задание генерации; сериализуется в очередь как JSON
+type Job struct {
+	ID     string `json:"id"`     // ключ идемпотентности
+	Input  string `json:"input"`  // ключ исходного фото в Storage
+	Output string `json:"output"` // ключ результата; расширение задает формат
+
+	Top    string `json:"top,omitempty"`
+	Bottom string `json:"bottom,omitempty"`
+	Theme  string `json:"theme,omitempty"`
+
+	// Webhook получает POST с Result после завершения или окончательной ошибки
+	Webhook string `json:"webhook,omitempty"`
+}
+
+// Result - тело запроса к webhook
+type Result struct {
+	ID       string `json:"id"`
+	Status   string `json:"status"` // "done" или "failed"
+	Output   string `json:"output,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Reason   string `json:"reason,omitempty"` // код из meme.ErrorReason
+	Attempts int    `json:"attempts"`
+}
+
+// Queue - источник заданий. Receive блокируется до появления сообщения
+// или отмены ctx.
+type Queue interface {
+	Receive(ctx context.Context) (Message, error)
+}
+
+// Message - доставленное задание
+type Message interface {
+	Body() []byte
+	Attempt() int // номер доставки, начиная с 1
+
+	Ack(ctx context.Context) error                        // задание выполнено
+	Retry(ctx context.Context, delay time.Duration) error // вернуть в очередь через delay
+}
+
+// Storage - хранилище входных файлов и результатов. Подходят реализации
+// из пакета storage и его подпакетов s3store и gcsstore.
+type Storage interface {
+	storage.Source
+	storage.Sink
+}
+
+// Completed запоминает выполненные задания для идемпотентности.
+// Реализация должна быть безопасна для конкурентного использования.
+type Completed interface {
+	Done(ctx context.Context, id string) (bool, error)
+	MarkDone(ctx context.Context, id string) error
+}
+
+// Значения Options по умолчанию
+const (
+	DefaultMaxAttempts = 5
+	DefaultMaxBackoff  = 5 * time.Minute
+
+	webhookTimeout = 10 * time.Second
+)
+
+// Options задаёт поведение воркера
+type Options struct {
+	// Config - базовая конфигурация; подписи и тема берутся из задания.
+	// nil - meme.DefaultConfig().
+	Config *meme.Config
+
+	// Setup вызывается для генератора каждого задания
+	Setup func(*meme.Generator)
+
+	Concurrency int // число параллельных заданий, 0 - 1
+	MaxAttempts int // попыток до окончательной ошибки, 0 - 5
+
+	// Backoff - задержка перед попыткой attempt+1. nil - 1s*2^(attempt-1)
+	// со случайным разбросом, не больше DefaultMaxBackoff.
+	Backoff func(attempt int) time.Duration
+
+	// Completed хранит ID выполненных заданий. nil - в памяти процесса,
+	// не больше 100000 последних ID, чего хватает для одного воркера;
+	// нескольким нужна общая реализация, например redisqueue.Completed.
+	Completed Completed
+
+	// Client отправляет webhook. Адрес приходит из задания, поэтому nil -
+	// meme.PublicHTTPClient с таймаутом 10 секунд: внутренние адреса
+	// запрещены. Свой клиент снимает это ограничение.
+	Client *http.Client
+	Logger *slog.Logger // nil - без журнала
+}
+
+// Worker выполняет задания из очереди
+type Worker struct {
+	queue   Queue
+	storage Storage
+	opts    Options
+}
+
+// New создает воркер
+func New(q Queue, s Storage, opts Options) *Worker {
+	if opts.Config == nil {
+		opts.Config = meme.DefaultConfig()
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = DefaultMaxAttempts
+	}
+	if opts.Backoff == nil {
+		opts.Backoff = defaultBackoff
+	}
+	if opts.Completed == nil {
+		opts.Completed = newMemoryCompleted(memoryCompletedSize)
+	}
+	if opts.Client == nil {
+		opts.Client = meme.PublicHTTPClient(webhookTimeout)
+	}
+	return &Worker{queue: q, storage: s, opts: opts}
+}
+
+// Run принимает задания до отмены ctx и ждет завершения начатых.
+// Возвращает nil при отмене ctx или первую ошибку очереди.
+func (w *Worker) Run(ctx context.Context) error {
+	var (
+		wg   sync.WaitGroup
+		once sync.Once
+		qerr error
+	)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	for range w.opts.Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				msg, err := w.queue.Receive(ctx)
+				if ctx.Err() != nil {
+					return
+				}
+				if err != nil {
+					once.Do(func() { qerr = err; cancel() })
+					return
+				}
+				// Начатое задание доводим до конца даже после отмены Run
+				w.Process(context.WithoutCancel(ctx), msg)
+			}
+		}()
+	}
+	wg.Wait()
+	return qerr
+}
+
+// Process выполняет одно сообщение: создает мем, подтверждает или
+// возвращает сообщение в очередь и вызывает webhook
+func (w *Worker) Process(ctx context.Context, msg Message) {
+	var job Job
+	if err := json.Unmarshal(msg.Body(), &job); err != nil {
+		// Испорченное сообщение не исправится повтором
+		w.log("dropping malformed job", "error", err)
+		msg.Ack(ctx)
+		return
+	}
+	if job.ID == "" {
+		job.ID = job.Output
+	}
+	if done, err := w.opts.Completed.Done(ctx, job.ID); err == nil && done {
+		w.log("skipping completed job", "id", job.ID)
+		msg.Ack(ctx)
+		return
+	}
+
+	err := w.run(ctx, &job)
+	attempt := msg.Attempt()
+	if err == nil {
+		if err := w.opts.Completed.MarkDone(ctx, job.ID); err != nil {
+			w.log("marking job done", "id", job.ID, "error", err)
+		}
+		msg.Ack(ctx)
+		w.notify(ctx, &job, Result{ID: job.ID, Status: "done", Output: job.Output, Attempts: attempt})
+		return
+	}
+
+	if !permanent(err) && attempt < w.opts.MaxAttempts {
+		delay := w.opts.Backoff(attempt)
+		w.log("retrying job", "id", job.ID, "attempt", attempt, "delay", delay, "error", err)
+		if rerr := msg.Retry(ctx, delay); rerr != nil {
+			w.log("returning job to queue", "id", job.ID, "error", rerr)
+		}
+		return
+	}
+	w.log("job failed", "id", job.ID, "attempt", attempt, "error", err)
+	msg.Ack(ctx)
+	w.notify(ctx, &job, Result{ID: job.ID, Status: "failed", Error: err.Error(), Reason: meme.ErrorReason(err), Attempts: attempt})
+}
+
+// run создает мем для задания и сохраняет результат
+func (w *Worker) run(ctx context.Context, job *Job) error {
+	if job.Input == "" || job.Output == "" {
+		return &meme.ConfigError{Field: "Job", Reason: "input and output are required"}
+	}
+	cfg := *w.opts.Config
+	if job.Theme != "" {
+		if err := cfg.ApplyTheme(job.Theme); err != nil {
+			return err
+		}
+	}
+	cfg.TopText, cfg.BottomText = job.Top, job.Bottom
+	format := meme.FormatPNG
+	if ext := filepath.Ext(job.Output); ext != "" {
+		f, err := meme.ParseFormat(ext)
+		if err != nil {
+			return &meme.ConfigError{Field: "Output", Reason: err.Error()}
+		}
+		format = f
+	}
+
+	in, err := w.storage.Open(ctx, job.Input)
+	if err != nil {
+		return fmt.Errorf("opening input: %w", err)
+	}
+	defer in.Close()
+	g := meme.NewGenerator(&cfg)
+	if w.opts.Setup != nil {
+		w.opts.Setup(g)
+	}
+	data, err := g.GenerateBytes(in, &meme.EncodeOptions{Format: format})
+	if err != nil {
+		return err
+	}
+	if err := w.storage.Put(ctx, job.Output, data); err != nil {
+		return fmt.Errorf("storing output: %w", err)
+	}
+	return nil
+}
+
+// Permanent помечает ошибку как неисправимую повтором. Реализации
+// Storage и Queue используют её для неверных ключей и т.п.
+func Permanent(err error) error {
+	return &permanentError{err}
+}
+
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// permanent сообщает, что ошибка не исправится повтором: помеченные
+// Permanent, отсутствующие файлы, недопустимые ключи и ошибки генератора с известным кодом,
+// вызванные входными данными или конфигурацией. Перегрузка (meme.ErrBusy)
+// и истекший или отмененный контекст проходят со временем.
+func permanent(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var pe *permanentError
+	if errors.As(err, &pe) || errors.Is(err, os.ErrNotExist) || errors.Is(err, storage.ErrInvalidKey) {
+		return true
+	}
+	switch meme.ErrorReason(err) {
+	case "other", "panic", "busy":
+		return false
+	}
+	return true
+}
+
+// notify отправляет результат на webhook задания; ошибки только журналируются
+func (w *Worker) notify(ctx context.Context, job *Job, res Result) {
+	if job.Webhook == "" {
+		return
+	}
+	body, _ := json.Marshal(res)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.Webhook, bytes.NewReader(body))
+	if err != nil {
+		w.log("webhook", "id", job.ID, "error", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.opts.Client.Do(req)
+	if err != nil {
+		w.log("webhook", "id", job.ID, "error", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		w.log("webhook", "id", job.ID, "status", resp.Status)
+	}
+}
+
+func (w *Worker) log(msg string, args ...any) {
+	if w.opts.Logger != nil {
+		w.opts.Logger.Info(msg, args...)
+	}
+}
+
+// defaultBackoff - 1s, 2s, 4s... с разбросом ±25%, не больше DefaultMaxBackoff
+func defaultBackoff(attempt int) time.Duration {
+	d := min(time.Second<<min(attempt-1, 20), DefaultMaxBackoff)
+	return time.Duration(float64(d) * (0.75 + rand.Float64()/2))
+}
+
+// Сколько ID выполненных заданий помнит memoryCompleted
+const memoryCompletedSize = 100_000
+
+// memoryCompleted - Completed в памяти процесса. Помнит последние size
+// ID: при переполнении забывается давнее всего использованное, поэтому
+// память долгоживущего воркера не растет без предела.
+type memoryCompleted struct {
+	mu   sync.Mutex
+	size int
+	ids  map[string]*list.Element
+	lru  list.List // ID, недавно использованные - в начале
+}
+
+func newMemoryCompleted(size int) *memoryCompleted {
+	return &memoryCompleted{size: size, ids: make(map[string]*list.Element)}
+}
+
+func (m *memoryCompleted) Done(_ context.Context, id string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.ids[id]
+	if ok {
+		m.lru.MoveToFront(e)
+	}
+	return ok, nil
+}
+
+func (m *memoryCompleted) MarkDone(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e, ok := m.ids[id]; ok {
+		m.lru.MoveToFront(e)
+		return nil
+	}
+	m.ids[id] = m.lru.PushFront(id)
+	if m.lru.Len() > m.size {
+		oldest := m.lru.Back()
+		m.lru.Remove(oldest)
+		delete(m.ids, oldest.Value.(string))
+	}
+	return nil
+}
+
+// Dir - Storage в каталоге локальной файловой системы (см. storage.Dir)
+type Dir = storage.Dir
@@ -0,0 +1,196 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-goblin/meme"
+	"github.com/go-goblin/meme/storage"
+)
+
+func TestPermanent(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want bool
+	}{
+		{errors.New("connection reset"), false},
+		{fmt.Errorf("generate: %w", meme.ErrBusy), false},
+		{fmt.Errorf("storing output: %w", context.DeadlineExceeded), false},
+		{context.Canceled, false},
+		{&meme.PanicError{Value: "boom"}, false},
+		{fmt.Errorf("opening input: %w", os.ErrNotExist), true},
+		{storage.ErrInvalidKey, true},
+		{Permanent(errors.New("bad bucket")), true},
+		{meme.ErrTextTooLong, true},
+		{&meme.ConfigError{Field: "Job", Reason: "input and output are required"}, true},
+	} {
+		if got := permanent(tc.err); got != tc.want {
+			t.Errorf("permanent(%v) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
+
+// testMessage - доставленное задание, запоминающее подтверждение и повтор
+type testMessage struct {
+	body    []byte
+	attempt int
+	acked   bool
+	retried bool
+	delay   time.Duration
+}
+
+func (m *testMessage) Body() []byte { return m.body }
+func (m *testMessage) Attempt() int { return m.attempt }
+
+func (m *testMessage) Ack(context.Context) error {
+	m.acked = true
+	return nil
+}
+
+func (m *testMessage) Retry(_ context.Context, delay time.Duration) error {
+	m.retried, m.delay = true, delay
+	return nil
+}
+
+func newTestMessage(t *testing.T, job Job, attempt int) *testMessage {
+	t.Helper()
+	body, err := json.Marshal(job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testMessage{body: body, attempt: attempt}
+}
+
+// testWorker - воркер над временным каталогом с фото in.png
+func testWorker(t *testing.T, opts Options) (*Worker, string) {
+	t.Helper()
+	dir := t.TempDir()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 32, 32))); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "in.png"), buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opts.Backoff = func(attempt int) time.Duration { return time.Duration(attempt) * time.Second }
+	return New(nil, Dir(dir), opts), dir
+}
+
+func TestProcessRetryOrFail(t *testing.T) {
+	// failWith подключает хук, который прерывает генерацию ошибкой err
+	failWith := func(err error) func(*meme.Generator) {
+		return func(g *meme.Generator) {
+			g.AddHook(meme.HookBeforeLayout, func(*meme.DrawContext) error { return err })
+		}
+	}
+	job := Job{ID: "job", Input: "in.png", Output: "out.png"}
+
+	for _, tc := range []struct {
+		name    string
+		job     Job
+		setup   func(*meme.Generator)
+		attempt int
+		retried bool
+	}{
+		{"busy", job, failWith(meme.ErrBusy), 1, true},
+		{"deadline", job, failWith(context.DeadlineExceeded), 2, true},
+		{"transient", job, failWith(errors.New("flaky")), 1, true},
+		{"last attempt", job, failWith(meme.ErrBusy), DefaultMaxAttempts, false},
+		{"caption too long", job, failWith(meme.ErrTextTooLong), 1, false},
+		{"missing input", Job{ID: "job", Input: "missing.png", Output: "out.png"}, nil, 1, false},
+		{"no output", Job{ID: "job", Input: "in.png"}, nil, 1, false},
+		{"unknown format", Job{ID: "job", Input: "in.png", Output: "out.xyz"}, nil, 1, false},
+	} {
+		w, dir := testWorker(t, Options{Setup: tc.setup})
+		msg := newTestMessage(t, tc.job, tc.attempt)
+		w.Process(context.Background(), msg)
+
+		if msg.retried != tc.retried || msg.acked == tc.retried {
+			t.Errorf("%s: retried %v, acked %v; want retried %v", tc.name, msg.retried, msg.acked, tc.retried)
+		}
+		if tc.retried && msg.delay != time.Duration(tc.attempt)*time.Second {
+			t.Errorf("%s: delay %v", tc.name, msg.delay)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "out.png")); err == nil {
+			t.Errorf("%s: output written", tc.name)
+		}
+		if done, _ := w.opts.Completed.Done(context.Background(), "job"); done {
+			t.Errorf("%s: failed job marked done", tc.name)
+		}
+	}
+
+	// Испорченное сообщение подтверждается без повтора
+	w, _ := testWorker(t, Options{})
+	msg := &testMessage{body: []byte("{"), attempt: 1}
+	w.Process(context.Background(), msg)
+	if !msg.acked || msg.retried {
+		t.Errorf("malformed job: acked %v, retried %v", msg.acked, msg.retried)
+	}
+}
+
+func TestProcessSkipsCompleted(t *testing.T) {
+	generated := 0
+	w, dir := testWorker(t, Options{Setup: func(g *meme.Generator) {
+		g.AddHook(meme.HookBeforeLayout, func(*meme.DrawContext) error {
+			generated++
+			return nil
+		})
+	}})
+	job := Job{ID: "job", Input: "in.png", Output: "out.png"}
+	out := filepath.Join(dir, "out.png")
+
+	first := newTestMessage(t, job, 1)
+	w.Process(context.Background(), first)
+	if !first.acked || generated != 1 {
+		t.Fatalf("first delivery: acked %v, generated %d", first.acked, generated)
+	}
+	if _, err := os.Stat(out); err != nil {
+		t.Fatal(err)
+	}
+
+	// Повторная доставка выполненного задания только подтверждается
+	if err := os.Remove(out); err != nil {
+		t.Fatal(err)
+	}
+	second := newTestMessage(t, job, 1)
+	w.Process(context.Background(), second)
+	if !second.acked || second.retried || generated != 1 {
+		t.Errorf("redelivery: acked %v, retried %v, generated %d", second.acked, second.retried, generated)
+	}
+	if _, err := os.Stat(out); err == nil {
+		t.Error("redelivery wrote the output again")
+	}
+
+	// Без ID ключом служит Output
+	w.Process(context.Background(), newTestMessage(t, Job{Input: "in.png", Output: "other.png"}, 1))
+	if done, _ := w.opts.Completed.Done(context.Background(), "other.png"); !done {
+		t.Error("job without ID not marked done by its output")
+	}
+}
+
+func TestMemoryCompletedBounded(t *testing.T) {
+	ctx := context.Background()
+	m := newMemoryCompleted(2)
+	m.MarkDone(ctx, "a")
+	m.MarkDone(ctx, "b")
+	m.Done(ctx, "a") // a использован недавно, вытесняется b
+	m.MarkDone(ctx, "c")
+
+	for id, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if done, _ := m.Done(ctx, id); done != want {
+			t.Errorf("Done(%q) = %v, want %v", id, done, want)
+		}
+	}
+	if len(m.ids) != 2 || m.lru.Len() != 2 {
+		t.Errorf("%d ids, %d in list; want 2", len(m.ids), m.lru.Len())
+	}
+}
@@ -0,0 +1,119 @@
+package meme
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"time"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// ZoomOptions задает врезку "zoom and enhance"
+type ZoomOptions struct {
+	Region image.Rectangle // увеличиваемая область в координатах фото
+
+	// Scale - увеличение области; 0 - врезка шириной 2/5 фото. Врезка
+	// не больше половины ширины и высоты фото.
+	Scale  float64
+	Corner Corner
+
+	BorderWidth int         // толщина рамок и линий, 0 - 1/150 большей стороны фото, не меньше 3
+	Color       color.Color // рамки и линии, nil - желтый
+
+	// Frame - обернуть результат в демотиватор с подписями и рамкой генератора
+	Frame bool
+}
+
+// Значения ZoomOptions по умолчанию
+const (
+	defaultZoomWidthRatio = 0.4
+	minZoomBorder         = 3
+)
+
+var zoomYellow = color.RGBA{0xff, 0xd6, 0x00, 0xff}
+
+// ZoomInset увеличивает область Region и ставит ее врезкой в угол фото:
+// область и врезка обводятся рамками, а две линии соединяют их углы,
+// чтобы было видно, что именно увеличено.
+func (g *Generator) ZoomInset(img image.Image, opts ZoomOptions) (out *image.RGBA, err error) {
+	defer g.observeGeneration(time.Now(), &err)
+	defer recoverPanic(&err)
+	if err := g.validateInput(img); err != nil {
+		return nil, err
+	}
+	b := img.Bounds()
+	region := opts.Region
+	if region.Empty() || !region.In(b) {
+		return nil, &ConfigError{Field: "ZoomOptions.Region", Reason: "must be a non-empty area inside the image"}
+	}
+	if opts.Scale != 0 && (opts.Scale < 1 || math.IsNaN(opts.Scale) || math.IsInf(opts.Scale, 0)) {
+		return nil, &ConfigError{Field: "ZoomOptions.Scale", Reason: "must be at least 1"}
+	}
+	if opts.BorderWidth < 0 {
+		return nil, &ConfigError{Field: "ZoomOptions.BorderWidth", Reason: "must not be negative"}
+	}
+	if opts.Corner < CornerBottomRight || opts.Corner > CornerTopLeft {
+		return nil, &ConfigError{Field: "ZoomOptions.Corner", Reason: "unknown corner"}
+	}
+	border := opts.BorderWidth
+	if border == 0 {
+		border = max(max(b.Dx(), b.Dy())/150, minZoomBorder)
+	}
+	c := colorOr(opts.Color, zoomYellow)
+
+	scale := opts.Scale
+	if scale == 0 {
+		scale = defaultZoomWidthRatio * float64(b.Dx()) / float64(region.Dx())
+	}
+	scale = min(scale, float64(b.Dx())/2/float64(region.Dx()), float64(b.Dy())/2/float64(region.Dy()))
+	size := image.Pt(max(int(float64(region.Dx())*scale), 1), max(int(float64(region.Dy())*scale), 1))
+
+	canvas := image.Rect(0, 0, b.Dx(), b.Dy())
+	if err := checkCanvas(canvas); err != nil {
+		return nil, err
+	}
+	out = image.NewRGBA(canvas)
+	drawSource(out, canvas, img, b.Min)
+	area := region.Sub(b.Min)
+	inset := opts.Corner.place(canvas, size, border*3)
+
+	// Линии соединяют углы, обращенные в стороны от диагонали к врезке,
+	// и рисуются под врезкой
+	pairs := [][2]image.Point{
+		{{area.Max.X, area.Min.Y}, {inset.Max.X, inset.Min.Y}},
+		{{area.Min.X, area.Max.Y}, {inset.Min.X, inset.Max.Y}},
+	}
+	if opts.Corner == CornerBottomLeft || opts.Corner == CornerTopRight {
+		pairs = [][2]image.Point{
+			{area.Min, inset.Min},
+			{area.Max, inset.Max},
+		}
+	}
+	for _, p := range pairs {
+		drawLine(out, p[0], p[1], max(border/2, 2), c)
+	}
+	drawFrame(out, area.Inset(-border), border, c)
+	zoomed := image.NewRGBA(image.Rect(0, 0, size.X, size.Y))
+	xdraw.CatmullRom.Scale(zoomed, zoomed.Bounds(), img, region, xdraw.Src, nil)
+	draw.Draw(out, inset, zoomed, image.Point{}, draw.Src)
+	drawFrame(out, inset.Inset(-border), border, c)
+
+	if opts.Frame {
+		return g.generateInto(nil, out)
+	}
+	return out, nil
+}
+
+// drawLine рисует отрезок от a до b толщиной t
+func drawLine(dst draw.Image, a, b image.Point, t int, c color.Color) {
+	src := image.NewUniform(c)
+	d := b.Sub(a)
+	steps := max(abs(d.X), abs(d.Y), 1)
+	for i := 0; i <= steps; i++ {
+		x := a.X + d.X*i/steps - t/2
+		y := a.Y + d.Y*i/steps - t/2
+		draw.Draw(dst, image.Rect(x, y, x+t, y+t), src, image.Point{}, draw.Over)
+	}
+}